@@ -3,7 +3,6 @@ package api
 
 import (
 	"github.com/ONSdigital/go-ns/audit"
-	"github.com/ONSdigital/go-ns/healthcheck"
 	"github.com/gorilla/mux"
 )
 
@@ -17,10 +16,38 @@ func New(host string, identityService IdentityService, tokenService TokenService
 	}
 }
 
-//RegisterEndpoints provides a way to register the HandlerFunc's defined in the api package with a mux.Router.
+//RegisterEndpoints provides a way to register the HandlerFunc's defined in the api package with a mux.Router. The
+//endpoints are registered on a subrouter which assigns each request an ID, logs it and recovers handler panics, and if
+//a RateLimiter is configured limits its requests. Routes registered on r afterwards, such as the health check, are
+//not wrapped.
+//
+//Each version of the API is mounted under its own path prefix. The unversioned paths are deprecated aliases of v1.
 func (api *API) RegisterEndpoints(r *mux.Router) {
-	r.HandleFunc("/identity", api.CreateIdentityHandler).Methods("POST")
-	r.HandleFunc("/identity", api.GetIdentityHandler).Methods("GET")
-	r.HandleFunc("/token", api.CreateTokenHandler).Methods("POST")
-	r.Path("/healthcheck").HandlerFunc(healthcheck.Do)
+	r = r.NewRoute().Subrouter()
+	r.Use(api.requestID, api.accessLog, api.recoverPanic)
+	if api.RateLimiter != nil {
+		r.Use(api.rateLimit)
+	}
+
+	api.registerV1(r.PathPrefix(v1PathPrefix).Subrouter())
+
+	unversioned := r.NewRoute().Subrouter()
+	unversioned.Use(api.deprecated)
+	api.registerV1(unversioned)
+}
+
+//registerV1 registers the v1 endpoints, with paths relative to the version prefix.
+func (api *API) registerV1(r *mux.Router) {
+	r.HandleFunc("/identity", api.instrument(createIdentityAction, api.CreateIdentityHandler)).Methods("POST")
+	r.HandleFunc("/identity", api.instrument(getIdentityAction, api.GetIdentityHandler)).Methods("GET")
+	r.HandleFunc("/identity/import", api.instrument(importIdentitiesAction, api.ImportIdentitiesHandler)).Methods("POST")
+	r.HandleFunc("/identity/verify/{token}", api.instrument(verifyIdentityAction, api.VerifyIdentityHandler)).Methods("POST")
+	r.HandleFunc("/identity/email-change/{token}", api.instrument(confirmEmailChangeAction, api.ConfirmEmailChangeHandler)).Methods("POST")
+	r.HandleFunc("/identity/{id}/email-change", api.instrument(requestEmailChangeAction, api.RequestEmailChangeHandler)).Methods("POST")
+	r.HandleFunc("/identity/{id}/disable", api.instrument(disableIdentityAction, api.DisableIdentityHandler)).Methods("POST")
+	r.HandleFunc("/identity/{id}/enable", api.instrument(enableIdentityAction, api.EnableIdentityHandler)).Methods("POST")
+	r.HandleFunc("/identity/{id}/export", api.instrument(exportIdentityAction, api.ExportIdentityHandler)).Methods("GET")
+	r.HandleFunc("/identity/{id}/erase", api.instrument(eraseIdentityAction, api.EraseIdentityHandler)).Methods("POST")
+	r.HandleFunc("/identity/{id}/impersonate", api.instrument(impersonateIdentityAction, api.ImpersonateIdentityHandler)).Methods("POST")
+	r.HandleFunc("/token", api.instrument(createToken, api.CreateTokenHandler)).Methods("POST")
 }
@@ -5,14 +5,25 @@ package apitest
 
 import (
 	"context"
+	"github.com/ONSdigital/dp-identity-api/identity"
+	"github.com/ONSdigital/dp-identity-api/ratelimit"
 	"github.com/ONSdigital/dp-identity-api/schema"
 	"sync"
 	"time"
 )
 
 var (
-	lockIdentityServiceMockCreate         sync.RWMutex
-	lockIdentityServiceMockVerifyPassword sync.RWMutex
+	lockIdentityServiceMockConfirmEmailChange sync.RWMutex
+	lockIdentityServiceMockCreate             sync.RWMutex
+	lockIdentityServiceMockDisable            sync.RWMutex
+	lockIdentityServiceMockEnable             sync.RWMutex
+	lockIdentityServiceMockErase              sync.RWMutex
+	lockIdentityServiceMockExport             sync.RWMutex
+	lockIdentityServiceMockImpersonate        sync.RWMutex
+	lockIdentityServiceMockImport             sync.RWMutex
+	lockIdentityServiceMockRequestEmailChange sync.RWMutex
+	lockIdentityServiceMockVerify             sync.RWMutex
+	lockIdentityServiceMockVerifyPassword     sync.RWMutex
 )
 
 // IdentityServiceMock is a mock implementation of IdentityService.
@@ -21,9 +32,36 @@ var (
 //
 //         // make and configure a mocked IdentityService
 //         mockedIdentityService := &IdentityServiceMock{
+//             ConfirmEmailChangeFunc: func(ctx context.Context, token string) (*schema.Identity, error) {
+// 	               panic("TODO: mock out the ConfirmEmailChange method")
+//             },
 //             CreateFunc: func(ctx context.Context, i *schema.Identity) (string, error) {
 // 	               panic("TODO: mock out the Create method")
 //             },
+//             DisableFunc: func(ctx context.Context, id string, reason string, disabledBy string) error {
+// 	               panic("TODO: mock out the Disable method")
+//             },
+//             EnableFunc: func(ctx context.Context, id string) error {
+// 	               panic("TODO: mock out the Enable method")
+//             },
+//             EraseFunc: func(ctx context.Context, id string, erasedBy string) (*schema.Tombstone, error) {
+// 	               panic("TODO: mock out the Erase method")
+//             },
+//             ExportFunc: func(ctx context.Context, id string) (*identity.Export, error) {
+// 	               panic("TODO: mock out the Export method")
+//             },
+//             ImpersonateFunc: func(ctx context.Context, id string, admin schema.Identity) (*schema.Identity, error) {
+// 	               panic("TODO: mock out the Impersonate method")
+//             },
+//             ImportFunc: func(ctx context.Context, identities []schema.Identity) (*identity.ImportReport, error) {
+// 	               panic("TODO: mock out the Import method")
+//             },
+//             RequestEmailChangeFunc: func(ctx context.Context, id string, email string) error {
+// 	               panic("TODO: mock out the RequestEmailChange method")
+//             },
+//             VerifyFunc: func(ctx context.Context, token string) (*schema.Identity, error) {
+// 	               panic("TODO: mock out the Verify method")
+//             },
 //             VerifyPasswordFunc: func(ctx context.Context, email string, password string) (*schema.Identity, error) {
 // 	               panic("TODO: mock out the VerifyPassword method")
 //             },
@@ -34,14 +72,48 @@ var (
 //
 //     }
 type IdentityServiceMock struct {
+	// ConfirmEmailChangeFunc mocks the ConfirmEmailChange method.
+	ConfirmEmailChangeFunc func(ctx context.Context, token string) (*schema.Identity, error)
+
 	// CreateFunc mocks the Create method.
 	CreateFunc func(ctx context.Context, i *schema.Identity) (string, error)
 
+	// DisableFunc mocks the Disable method.
+	DisableFunc func(ctx context.Context, id string, reason string, disabledBy string) error
+
+	// EnableFunc mocks the Enable method.
+	EnableFunc func(ctx context.Context, id string) error
+
+	// EraseFunc mocks the Erase method.
+	EraseFunc func(ctx context.Context, id string, erasedBy string) (*schema.Tombstone, error)
+
+	// ExportFunc mocks the Export method.
+	ExportFunc func(ctx context.Context, id string) (*identity.Export, error)
+
+	// ImpersonateFunc mocks the Impersonate method.
+	ImpersonateFunc func(ctx context.Context, id string, admin schema.Identity) (*schema.Identity, error)
+
+	// ImportFunc mocks the Import method.
+	ImportFunc func(ctx context.Context, identities []schema.Identity) (*identity.ImportReport, error)
+
+	// RequestEmailChangeFunc mocks the RequestEmailChange method.
+	RequestEmailChangeFunc func(ctx context.Context, id string, email string) error
+
+	// VerifyFunc mocks the Verify method.
+	VerifyFunc func(ctx context.Context, token string) (*schema.Identity, error)
+
 	// VerifyPasswordFunc mocks the VerifyPassword method.
 	VerifyPasswordFunc func(ctx context.Context, email string, password string) (*schema.Identity, error)
 
 	// calls tracks calls to the methods.
 	calls struct {
+		// ConfirmEmailChange holds details about calls to the ConfirmEmailChange method.
+		ConfirmEmailChange []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Token is the token argument value.
+			Token string
+		}
 		// Create holds details about calls to the Create method.
 		Create []struct {
 			// Ctx is the ctx argument value.
@@ -49,6 +121,72 @@ type IdentityServiceMock struct {
 			// I is the i argument value.
 			I *schema.Identity
 		}
+		// Disable holds details about calls to the Disable method.
+		Disable []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID string
+			// Reason is the reason argument value.
+			Reason string
+			// DisabledBy is the disabledBy argument value.
+			DisabledBy string
+		}
+		// Enable holds details about calls to the Enable method.
+		Enable []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID string
+		}
+		// Erase holds details about calls to the Erase method.
+		Erase []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID string
+			// ErasedBy is the erasedBy argument value.
+			ErasedBy string
+		}
+		// Export holds details about calls to the Export method.
+		Export []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID string
+		}
+		// Impersonate holds details about calls to the Impersonate method.
+		Impersonate []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID string
+			// Admin is the admin argument value.
+			Admin schema.Identity
+		}
+		// Import holds details about calls to the Import method.
+		Import []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Identities is the identities argument value.
+			Identities []schema.Identity
+		}
+		// RequestEmailChange holds details about calls to the RequestEmailChange method.
+		RequestEmailChange []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID string
+			// Email is the email argument value.
+			Email string
+		}
+		// Verify holds details about calls to the Verify method.
+		Verify []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Token is the token argument value.
+			Token string
+		}
 		// VerifyPassword holds details about calls to the VerifyPassword method.
 		VerifyPassword []struct {
 			// Ctx is the ctx argument value.
@@ -61,6 +199,41 @@ type IdentityServiceMock struct {
 	}
 }
 
+// ConfirmEmailChange calls ConfirmEmailChangeFunc.
+func (mock *IdentityServiceMock) ConfirmEmailChange(ctx context.Context, token string) (*schema.Identity, error) {
+	if mock.ConfirmEmailChangeFunc == nil {
+		panic("moq: IdentityServiceMock.ConfirmEmailChangeFunc is nil but IdentityService.ConfirmEmailChange was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Token string
+	}{
+		Ctx:   ctx,
+		Token: token,
+	}
+	lockIdentityServiceMockConfirmEmailChange.Lock()
+	mock.calls.ConfirmEmailChange = append(mock.calls.ConfirmEmailChange, callInfo)
+	lockIdentityServiceMockConfirmEmailChange.Unlock()
+	return mock.ConfirmEmailChangeFunc(ctx, token)
+}
+
+// ConfirmEmailChangeCalls gets all the calls that were made to ConfirmEmailChange.
+// Check the length with:
+//     len(mockedIdentityService.ConfirmEmailChangeCalls())
+func (mock *IdentityServiceMock) ConfirmEmailChangeCalls() []struct {
+	Ctx   context.Context
+	Token string
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Token string
+	}
+	lockIdentityServiceMockConfirmEmailChange.RLock()
+	calls = mock.calls.ConfirmEmailChange
+	lockIdentityServiceMockConfirmEmailChange.RUnlock()
+	return calls
+}
+
 // Create calls CreateFunc.
 func (mock *IdentityServiceMock) Create(ctx context.Context, i *schema.Identity) (string, error) {
 	if mock.CreateFunc == nil {
@@ -96,6 +269,306 @@ func (mock *IdentityServiceMock) CreateCalls() []struct {
 	return calls
 }
 
+// Disable calls DisableFunc.
+func (mock *IdentityServiceMock) Disable(ctx context.Context, id string, reason string, disabledBy string) error {
+	if mock.DisableFunc == nil {
+		panic("moq: IdentityServiceMock.DisableFunc is nil but IdentityService.Disable was just called")
+	}
+	callInfo := struct {
+		Ctx        context.Context
+		ID         string
+		Reason     string
+		DisabledBy string
+	}{
+		Ctx:        ctx,
+		ID:         id,
+		Reason:     reason,
+		DisabledBy: disabledBy,
+	}
+	lockIdentityServiceMockDisable.Lock()
+	mock.calls.Disable = append(mock.calls.Disable, callInfo)
+	lockIdentityServiceMockDisable.Unlock()
+	return mock.DisableFunc(ctx, id, reason, disabledBy)
+}
+
+// DisableCalls gets all the calls that were made to Disable.
+// Check the length with:
+//     len(mockedIdentityService.DisableCalls())
+func (mock *IdentityServiceMock) DisableCalls() []struct {
+	Ctx        context.Context
+	ID         string
+	Reason     string
+	DisabledBy string
+} {
+	var calls []struct {
+		Ctx        context.Context
+		ID         string
+		Reason     string
+		DisabledBy string
+	}
+	lockIdentityServiceMockDisable.RLock()
+	calls = mock.calls.Disable
+	lockIdentityServiceMockDisable.RUnlock()
+	return calls
+}
+
+// Enable calls EnableFunc.
+func (mock *IdentityServiceMock) Enable(ctx context.Context, id string) error {
+	if mock.EnableFunc == nil {
+		panic("moq: IdentityServiceMock.EnableFunc is nil but IdentityService.Enable was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  string
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	lockIdentityServiceMockEnable.Lock()
+	mock.calls.Enable = append(mock.calls.Enable, callInfo)
+	lockIdentityServiceMockEnable.Unlock()
+	return mock.EnableFunc(ctx, id)
+}
+
+// EnableCalls gets all the calls that were made to Enable.
+// Check the length with:
+//     len(mockedIdentityService.EnableCalls())
+func (mock *IdentityServiceMock) EnableCalls() []struct {
+	Ctx context.Context
+	ID  string
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  string
+	}
+	lockIdentityServiceMockEnable.RLock()
+	calls = mock.calls.Enable
+	lockIdentityServiceMockEnable.RUnlock()
+	return calls
+}
+
+// Erase calls EraseFunc.
+func (mock *IdentityServiceMock) Erase(ctx context.Context, id string, erasedBy string) (*schema.Tombstone, error) {
+	if mock.EraseFunc == nil {
+		panic("moq: IdentityServiceMock.EraseFunc is nil but IdentityService.Erase was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		ID       string
+		ErasedBy string
+	}{
+		Ctx:      ctx,
+		ID:       id,
+		ErasedBy: erasedBy,
+	}
+	lockIdentityServiceMockErase.Lock()
+	mock.calls.Erase = append(mock.calls.Erase, callInfo)
+	lockIdentityServiceMockErase.Unlock()
+	return mock.EraseFunc(ctx, id, erasedBy)
+}
+
+// EraseCalls gets all the calls that were made to Erase.
+// Check the length with:
+//     len(mockedIdentityService.EraseCalls())
+func (mock *IdentityServiceMock) EraseCalls() []struct {
+	Ctx      context.Context
+	ID       string
+	ErasedBy string
+} {
+	var calls []struct {
+		Ctx      context.Context
+		ID       string
+		ErasedBy string
+	}
+	lockIdentityServiceMockErase.RLock()
+	calls = mock.calls.Erase
+	lockIdentityServiceMockErase.RUnlock()
+	return calls
+}
+
+// Export calls ExportFunc.
+func (mock *IdentityServiceMock) Export(ctx context.Context, id string) (*identity.Export, error) {
+	if mock.ExportFunc == nil {
+		panic("moq: IdentityServiceMock.ExportFunc is nil but IdentityService.Export was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  string
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	lockIdentityServiceMockExport.Lock()
+	mock.calls.Export = append(mock.calls.Export, callInfo)
+	lockIdentityServiceMockExport.Unlock()
+	return mock.ExportFunc(ctx, id)
+}
+
+// ExportCalls gets all the calls that were made to Export.
+// Check the length with:
+//     len(mockedIdentityService.ExportCalls())
+func (mock *IdentityServiceMock) ExportCalls() []struct {
+	Ctx context.Context
+	ID  string
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  string
+	}
+	lockIdentityServiceMockExport.RLock()
+	calls = mock.calls.Export
+	lockIdentityServiceMockExport.RUnlock()
+	return calls
+}
+
+// Impersonate calls ImpersonateFunc.
+func (mock *IdentityServiceMock) Impersonate(ctx context.Context, id string, admin schema.Identity) (*schema.Identity, error) {
+	if mock.ImpersonateFunc == nil {
+		panic("moq: IdentityServiceMock.ImpersonateFunc is nil but IdentityService.Impersonate was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		ID    string
+		Admin schema.Identity
+	}{
+		Ctx:   ctx,
+		ID:    id,
+		Admin: admin,
+	}
+	lockIdentityServiceMockImpersonate.Lock()
+	mock.calls.Impersonate = append(mock.calls.Impersonate, callInfo)
+	lockIdentityServiceMockImpersonate.Unlock()
+	return mock.ImpersonateFunc(ctx, id, admin)
+}
+
+// ImpersonateCalls gets all the calls that were made to Impersonate.
+// Check the length with:
+//     len(mockedIdentityService.ImpersonateCalls())
+func (mock *IdentityServiceMock) ImpersonateCalls() []struct {
+	Ctx   context.Context
+	ID    string
+	Admin schema.Identity
+} {
+	var calls []struct {
+		Ctx   context.Context
+		ID    string
+		Admin schema.Identity
+	}
+	lockIdentityServiceMockImpersonate.RLock()
+	calls = mock.calls.Impersonate
+	lockIdentityServiceMockImpersonate.RUnlock()
+	return calls
+}
+
+// Import calls ImportFunc.
+func (mock *IdentityServiceMock) Import(ctx context.Context, identities []schema.Identity) (*identity.ImportReport, error) {
+	if mock.ImportFunc == nil {
+		panic("moq: IdentityServiceMock.ImportFunc is nil but IdentityService.Import was just called")
+	}
+	callInfo := struct {
+		Ctx        context.Context
+		Identities []schema.Identity
+	}{
+		Ctx:        ctx,
+		Identities: identities,
+	}
+	lockIdentityServiceMockImport.Lock()
+	mock.calls.Import = append(mock.calls.Import, callInfo)
+	lockIdentityServiceMockImport.Unlock()
+	return mock.ImportFunc(ctx, identities)
+}
+
+// ImportCalls gets all the calls that were made to Import.
+// Check the length with:
+//     len(mockedIdentityService.ImportCalls())
+func (mock *IdentityServiceMock) ImportCalls() []struct {
+	Ctx        context.Context
+	Identities []schema.Identity
+} {
+	var calls []struct {
+		Ctx        context.Context
+		Identities []schema.Identity
+	}
+	lockIdentityServiceMockImport.RLock()
+	calls = mock.calls.Import
+	lockIdentityServiceMockImport.RUnlock()
+	return calls
+}
+
+// RequestEmailChange calls RequestEmailChangeFunc.
+func (mock *IdentityServiceMock) RequestEmailChange(ctx context.Context, id string, email string) error {
+	if mock.RequestEmailChangeFunc == nil {
+		panic("moq: IdentityServiceMock.RequestEmailChangeFunc is nil but IdentityService.RequestEmailChange was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		ID    string
+		Email string
+	}{
+		Ctx:   ctx,
+		ID:    id,
+		Email: email,
+	}
+	lockIdentityServiceMockRequestEmailChange.Lock()
+	mock.calls.RequestEmailChange = append(mock.calls.RequestEmailChange, callInfo)
+	lockIdentityServiceMockRequestEmailChange.Unlock()
+	return mock.RequestEmailChangeFunc(ctx, id, email)
+}
+
+// RequestEmailChangeCalls gets all the calls that were made to RequestEmailChange.
+// Check the length with:
+//     len(mockedIdentityService.RequestEmailChangeCalls())
+func (mock *IdentityServiceMock) RequestEmailChangeCalls() []struct {
+	Ctx   context.Context
+	ID    string
+	Email string
+} {
+	var calls []struct {
+		Ctx   context.Context
+		ID    string
+		Email string
+	}
+	lockIdentityServiceMockRequestEmailChange.RLock()
+	calls = mock.calls.RequestEmailChange
+	lockIdentityServiceMockRequestEmailChange.RUnlock()
+	return calls
+}
+
+// Verify calls VerifyFunc.
+func (mock *IdentityServiceMock) Verify(ctx context.Context, token string) (*schema.Identity, error) {
+	if mock.VerifyFunc == nil {
+		panic("moq: IdentityServiceMock.VerifyFunc is nil but IdentityService.Verify was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Token string
+	}{
+		Ctx:   ctx,
+		Token: token,
+	}
+	lockIdentityServiceMockVerify.Lock()
+	mock.calls.Verify = append(mock.calls.Verify, callInfo)
+	lockIdentityServiceMockVerify.Unlock()
+	return mock.VerifyFunc(ctx, token)
+}
+
+// VerifyCalls gets all the calls that were made to Verify.
+// Check the length with:
+//     len(mockedIdentityService.VerifyCalls())
+func (mock *IdentityServiceMock) VerifyCalls() []struct {
+	Ctx   context.Context
+	Token string
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Token string
+	}
+	lockIdentityServiceMockVerify.RLock()
+	calls = mock.calls.Verify
+	lockIdentityServiceMockVerify.RUnlock()
+	return calls
+}
+
 // VerifyPassword calls VerifyPasswordFunc.
 func (mock *IdentityServiceMock) VerifyPassword(ctx context.Context, email string, password string) (*schema.Identity, error) {
 	if mock.VerifyPasswordFunc == nil {
@@ -253,3 +726,79 @@ func (mock *TokenServiceMock) NewTokenCalls() []struct {
 	lockTokenServiceMockNewToken.RUnlock()
 	return calls
 }
+
+var (
+	lockRateLimiterMockTake sync.RWMutex
+)
+
+// RateLimiterMock is a mock implementation of RateLimiter.
+//
+//     func TestSomethingThatUsesRateLimiter(t *testing.T) {
+//
+//         // make and configure a mocked RateLimiter
+//         mockedRateLimiter := &RateLimiterMock{
+//             TakeFunc: func(ctx context.Context, key string, limit ratelimit.Limit) (time.Duration, error) {
+// 	               panic("TODO: mock out the Take method")
+//             },
+//         }
+//
+//         // TODO: use mockedRateLimiter in code that requires RateLimiter
+//         //       and then make assertions.
+//
+//     }
+type RateLimiterMock struct {
+	// TakeFunc mocks the Take method.
+	TakeFunc func(ctx context.Context, key string, limit ratelimit.Limit) (time.Duration, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Take holds details about calls to the Take method.
+		Take []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Key is the key argument value.
+			Key string
+			// Limit is the limit argument value.
+			Limit ratelimit.Limit
+		}
+	}
+}
+
+// Take calls TakeFunc.
+func (mock *RateLimiterMock) Take(ctx context.Context, key string, limit ratelimit.Limit) (time.Duration, error) {
+	if mock.TakeFunc == nil {
+		panic("moq: RateLimiterMock.TakeFunc is nil but RateLimiter.Take was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Key   string
+		Limit ratelimit.Limit
+	}{
+		Ctx:   ctx,
+		Key:   key,
+		Limit: limit,
+	}
+	lockRateLimiterMockTake.Lock()
+	mock.calls.Take = append(mock.calls.Take, callInfo)
+	lockRateLimiterMockTake.Unlock()
+	return mock.TakeFunc(ctx, key, limit)
+}
+
+// TakeCalls gets all the calls that were made to Take.
+// Check the length with:
+//     len(mockedRateLimiter.TakeCalls())
+func (mock *RateLimiterMock) TakeCalls() []struct {
+	Ctx   context.Context
+	Key   string
+	Limit ratelimit.Limit
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Key   string
+		Limit ratelimit.Limit
+	}
+	lockRateLimiterMockTake.RLock()
+	calls = mock.calls.Take
+	lockRateLimiterMockTake.RUnlock()
+	return calls
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/ONSdigital/go-ns/audit"
+	"github.com/ONSdigital/go-ns/common"
+	"github.com/ONSdigital/go-ns/log"
+	"github.com/pkg/errors"
+	"net/http"
+	"time"
+)
+
+const (
+	bootstrapSecretHeaderKey = "bootstrap-secret"
+
+	// createdByBootstrap and createdBySelfRegistration identify the creator in audit events of identities not created
+	// by an authenticated identity.
+	createdByBootstrap        = "bootstrap"
+	createdBySelfRegistration = "self-registration"
+)
+
+var (
+	ErrAuthorizationRequired  = errors.New("an admin or service token or the bootstrap secret is required")
+	ErrInvalidBootstrapSecret = errors.New("invalid bootstrap secret")
+	ErrForbidden              = errors.New("caller is not permitted to perform this action")
+	ErrImpersonationDisabled  = errors.New("impersonation is disabled")
+)
+
+// creator is the caller creating an identity. ID is the ID of the caller's identity, or createdByBootstrap or
+// createdBySelfRegistration if the caller is not authenticated by a token.
+type creator struct {
+	ID       string
+	UserType string
+}
+
+// authorizeCreate return the creator of an identity. Callers must present the token of an admin or service identity,
+// or the configured bootstrap secret. If self-registration is enabled callers presenting neither are permitted.
+// Identities can only be created through CreateIdentityHandler, authorized by authorizeCreate, or imported through
+// ImportIdentitiesHandler, authorized by authorizeAdmin.
+func (api *API) authorizeCreate(ctx context.Context, r *http.Request) (*creator, error) {
+	if secret := r.Header.Get(bootstrapSecretHeaderKey); secret != "" {
+		if api.BootstrapSecret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(api.BootstrapSecret)) != 1 {
+			return nil, ErrInvalidBootstrapSecret
+		}
+		return &creator{ID: createdByBootstrap}, nil
+	}
+
+	if tokenStr := r.Header.Get(tokenHeaderKey); tokenStr != "" {
+		i, _, err := api.identityByToken(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+
+		if i.UserType != schema.UserTypeAdmin && i.UserType != schema.UserTypeService {
+			return nil, ErrForbidden
+		}
+		return &creator{ID: i.ID, UserType: i.UserType}, nil
+	}
+
+	if api.SelfRegistration {
+		return &creator{ID: createdBySelfRegistration}, nil
+	}
+	return nil, ErrAuthorizationRequired
+}
+
+// authorize return ErrForbidden if the creator is not permitted to create the identity. Self-registered identities
+// are always assigned the non-privileged user type.
+func (c *creator) authorize(i *schema.Identity) error {
+	switch {
+	case c.ID == createdBySelfRegistration:
+		i.UserType = schema.UserTypeUser
+	case c.UserType == schema.UserTypeService && i.UserType == schema.UserTypeAdmin:
+		return ErrForbidden
+	}
+	return nil
+}
+
+// authorizeAdmin return the identity of the caller if they present a token for an admin identity.
+func (api *API) authorizeAdmin(ctx context.Context, r *http.Request) (*schema.Identity, error) {
+	caller, _, err := api.identityByToken(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	if caller.UserType != schema.UserTypeAdmin {
+		return nil, ErrForbidden
+	}
+	return caller, nil
+}
+
+// authorizeIdentity return the identity of the caller if they are permitted to manage the identity with the provided
+// ID. Callers must present a token for that identity or for an admin identity.
+func (api *API) authorizeIdentity(ctx context.Context, r *http.Request, id string) (*schema.Identity, error) {
+	caller, _, err := api.identityByToken(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	if caller.ID != id && caller.UserType != schema.UserTypeAdmin {
+		return nil, ErrForbidden
+	}
+	return caller, nil
+}
+
+// identityByToken return the identity of the token in the request and the token's time to live. Each use of an
+// impersonation token is recorded as an audit event about the impersonated identity, impersonation tokens are refused
+// with ErrImpersonationDisabled if impersonation is disabled.
+func (api *API) identityByToken(ctx context.Context, r *http.Request) (*schema.Identity, time.Duration, error) {
+	tokenStr := r.Header.Get(tokenHeaderKey)
+	if tokenStr == "" {
+		log.ErrorCtx(ctx, ErrNoTokenProvided, nil)
+		return nil, 0, ErrNoTokenProvided
+	}
+
+	i, ttl, err := api.Tokens.GetIdentityByToken(ctx, tokenStr)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	logIdentity(ctx, i.ID)
+	if i.Impersonator == nil {
+		return i, ttl, nil
+	}
+
+	if !api.Impersonation {
+		return nil, 0, ErrImpersonationDisabled
+	}
+
+	p := common.Params{"id": i.ID, "impersonated_by": i.Impersonator.ID, "request": r.Method + " " + r.URL.Path}
+	if err := api.auditor.Record(ctx, useImpersonationTokenAction, audit.Successful, p); err != nil {
+		return nil, 0, err
+	}
+	return i, ttl, nil
+}
@@ -0,0 +1,237 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/ONSdigital/dp-identity-api/api/apitest"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/ONSdigital/go-ns/audit"
+	"github.com/ONSdigital/go-ns/audit/auditortest"
+	"github.com/ONSdigital/go-ns/common"
+	"github.com/gorilla/mux"
+	. "github.com/smartystreets/goconvey/convey"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func tokenServiceReturning(i *schema.Identity, err error) *apitest.TokenServiceMock {
+	return &apitest.TokenServiceMock{
+		GetIdentityByTokenFunc: func(ctx context.Context, tokenStr string) (*schema.Identity, time.Duration, error) {
+			return i, time.Minute, err
+		},
+	}
+}
+
+func TestAPI_AuthorizeCreate(t *testing.T) {
+	Convey("given a request presenting the bootstrap secret", t, func() {
+		r := newBootstrapRequest(nil)
+
+		Convey("when the secret matches the configured secret", func() {
+			c, err := (&API{BootstrapSecret: testBootstrapSecret}).authorizeCreate(context.Background(), r)
+
+			Convey("then the creator is the bootstrap", func() {
+				So(err, ShouldBeNil)
+				So(c, ShouldResemble, &creator{ID: createdByBootstrap})
+			})
+		})
+
+		Convey("when the secret does not match the configured secret", func() {
+			c, err := (&API{BootstrapSecret: "ectoplasm"}).authorizeCreate(context.Background(), r)
+
+			Convey("then ErrInvalidBootstrapSecret is returned", func() {
+				So(err, ShouldEqual, ErrInvalidBootstrapSecret)
+				So(c, ShouldBeNil)
+			})
+		})
+
+		Convey("when no secret is configured", func() {
+			c, err := (&API{}).authorizeCreate(context.Background(), r)
+
+			Convey("then ErrInvalidBootstrapSecret is returned", func() {
+				So(err, ShouldEqual, ErrInvalidBootstrapSecret)
+				So(c, ShouldBeNil)
+			})
+		})
+	})
+
+	Convey("given a request presenting a token", t, func() {
+		r := httptest.NewRequest("POST", createIdentityURL, nil)
+		r.Header.Set(tokenHeaderKey, "666")
+
+		Convey("when the token belongs to an admin or service identity", func() {
+			for _, userType := range []string{schema.UserTypeAdmin, schema.UserTypeService} {
+				tokens := tokenServiceReturning(&schema.Identity{ID: "123", UserType: userType}, nil)
+				c, err := (&API{Tokens: tokens}).authorizeCreate(context.Background(), r)
+
+				So(err, ShouldBeNil)
+				So(c, ShouldResemble, &creator{ID: "123", UserType: userType})
+				So(tokens.GetIdentityByTokenCalls()[0].TokenStr, ShouldEqual, "666")
+			}
+		})
+
+		Convey("when the token belongs to a non-privileged identity", func() {
+			tokens := tokenServiceReturning(&schema.Identity{ID: "123", UserType: schema.UserTypeUser}, nil)
+			c, err := (&API{Tokens: tokens, SelfRegistration: true}).authorizeCreate(context.Background(), r)
+
+			Convey("then ErrForbidden is returned", func() {
+				So(err, ShouldEqual, ErrForbidden)
+				So(c, ShouldBeNil)
+			})
+		})
+
+		Convey("when the token is not found", func() {
+			tokens := tokenServiceReturning(nil, schema.ErrTokenNotFound)
+			c, err := (&API{Tokens: tokens}).authorizeCreate(context.Background(), r)
+
+			Convey("then the token error is returned", func() {
+				So(err, ShouldEqual, schema.ErrTokenNotFound)
+				So(c, ShouldBeNil)
+			})
+		})
+	})
+
+	Convey("given a request presenting no credentials", t, func() {
+		r := httptest.NewRequest("POST", createIdentityURL, nil)
+
+		Convey("when self-registration is disabled", func() {
+			c, err := (&API{}).authorizeCreate(context.Background(), r)
+
+			Convey("then ErrAuthorizationRequired is returned", func() {
+				So(err, ShouldEqual, ErrAuthorizationRequired)
+				So(c, ShouldBeNil)
+			})
+		})
+
+		Convey("when self-registration is enabled", func() {
+			c, err := (&API{SelfRegistration: true}).authorizeCreate(context.Background(), r)
+
+			Convey("then the creator is self-registration", func() {
+				So(err, ShouldBeNil)
+				So(c, ShouldResemble, &creator{ID: createdBySelfRegistration})
+			})
+		})
+	})
+}
+
+func TestCreator_Authorize(t *testing.T) {
+	Convey("self-registered identities should be assigned the non-privileged user type", t, func() {
+		i := &schema.Identity{UserType: schema.UserTypeAdmin}
+		So((&creator{ID: createdBySelfRegistration}).authorize(i), ShouldBeNil)
+		So(i.UserType, ShouldEqual, schema.UserTypeUser)
+	})
+
+	Convey("service identities should not be permitted to create admin identities", t, func() {
+		c := &creator{ID: "123", UserType: schema.UserTypeService}
+		So(c.authorize(&schema.Identity{UserType: schema.UserTypeAdmin}), ShouldEqual, ErrForbidden)
+		So(c.authorize(&schema.Identity{UserType: schema.UserTypeService}), ShouldBeNil)
+	})
+
+	Convey("admin identities and the bootstrap should be permitted to create any user type", t, func() {
+		for _, c := range []*creator{{ID: "123", UserType: schema.UserTypeAdmin}, bootstrapCreator} {
+			i := &schema.Identity{UserType: schema.UserTypeAdmin}
+			So(c.authorize(i), ShouldBeNil)
+			So(i.UserType, ShouldEqual, schema.UserTypeAdmin)
+		}
+	})
+}
+
+func TestAPI_CreateIdentityHandlerUnauthorized(t *testing.T) {
+	Convey("given a request presenting no credentials and self-registration is disabled", t, func() {
+		auditMock := auditortest.New()
+		serviceMock := &apitest.IdentityServiceMock{}
+		identityAPI := &API{auditor: auditMock, IdentityService: serviceMock}
+
+		b, err := json.Marshal(&CreateIdentityRequest{Name: "Eleven"})
+		So(err, ShouldBeNil)
+
+		w := httptest.NewRecorder()
+		identityAPI.CreateIdentityHandler(w, httptest.NewRequest("POST", createIdentityURL, bytes.NewReader(b)))
+
+		Convey("then 401 is returned and no identity is created", func() {
+			assertErrorResponse(w.Code, http.StatusUnauthorized, w.Body.String(), ErrAuthorizationRequired.Error())
+			So(serviceMock.CreateCalls(), ShouldHaveLength, 0)
+		})
+
+		Convey("and attempted and unsuccessful audit events are recorded", func() {
+			auditMock.AssertRecordCalls(
+				auditortest.Expected{Action: createIdentityAction, Result: audit.Attempted, Params: nil},
+				auditortest.Expected{Action: createIdentityAction, Result: audit.Unsuccessful, Params: nil},
+			)
+		})
+	})
+}
+
+func TestAPI_CreateIdentityHandlerSelfRegistration(t *testing.T) {
+	Convey("given self-registration is enabled", t, func() {
+		auditMock := auditortest.New()
+		serviceMock := &apitest.IdentityServiceMock{
+			CreateFunc: func(ctx context.Context, i *schema.Identity) (string, error) {
+				return ID, nil
+			},
+		}
+		identityAPI := &API{auditor: auditMock, IdentityService: serviceMock, SelfRegistration: true}
+
+		Convey("when an identity requesting the admin user type is created without credentials", func() {
+			b, err := json.Marshal(&CreateIdentityRequest{Name: "Eleven", UserType: schema.UserTypeAdmin})
+			So(err, ShouldBeNil)
+
+			w := httptest.NewRecorder()
+			identityAPI.CreateIdentityHandler(w, httptest.NewRequest("POST", createIdentityURL, bytes.NewReader(b)))
+
+			Convey("then the identity is created with the non-privileged user type", func() {
+				So(w.Code, ShouldEqual, http.StatusCreated)
+				So(serviceMock.CreateCalls(), ShouldHaveLength, 1)
+				So(serviceMock.CreateCalls()[0].I.UserType, ShouldEqual, schema.UserTypeUser)
+			})
+
+			Convey("and the successful audit event records the identity as self-registered", func() {
+				auditMock.AssertRecordCalls(
+					auditortest.Expected{Action: createIdentityAction, Result: audit.Attempted, Params: nil},
+					auditortest.Expected{Action: createIdentityAction, Result: audit.Successful, Params: common.Params{"id": ID, "created_by": createdBySelfRegistration}},
+				)
+			})
+		})
+	})
+}
+
+func TestAPI_CreationRoutesRequireAuthorization(t *testing.T) {
+	Convey("given the endpoints are registered", t, func() {
+		serviceMock := &apitest.IdentityServiceMock{}
+		identityAPI := &API{auditor: auditortest.New(), IdentityService: serviceMock, BootstrapSecret: testBootstrapSecret}
+
+		router := mux.NewRouter()
+		identityAPI.RegisterEndpoints(router)
+
+		Convey("then every route creating identities refuses requests without credentials", func() {
+			for _, path := range []string{"/identity", "/v1/identity", "/identity/import", "/v1/identity/import"} {
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "http://localhost:23800"+path, bytes.NewReader([]byte("{}"))))
+				So(w.Code, ShouldEqual, http.StatusUnauthorized)
+			}
+			So(serviceMock.CreateCalls(), ShouldHaveLength, 0)
+			So(serviceMock.ImportCalls(), ShouldHaveLength, 0)
+		})
+
+		Convey("and self-registration does not permit importing identities", func() {
+			identityAPI.SelfRegistration = true
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "http://localhost:23800/v1/identity/import", bytes.NewReader([]byte("{}"))))
+			So(w.Code, ShouldEqual, http.StatusUnauthorized)
+			So(serviceMock.ImportCalls(), ShouldHaveLength, 0)
+		})
+
+		Convey("and the bootstrap secret does not permit importing identities", func() {
+			r := httptest.NewRequest(http.MethodPost, "http://localhost:23800/v1/identity/import", bytes.NewReader([]byte("{}")))
+			r.Header.Set(bootstrapSecretHeaderKey, testBootstrapSecret)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+			So(w.Code, ShouldEqual, http.StatusUnauthorized)
+			So(serviceMock.ImportCalls(), ShouldHaveLength, 0)
+		})
+	})
+}
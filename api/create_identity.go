@@ -1,66 +1,76 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"github.com/ONSdigital/dp-identity-api/schema"
 	"github.com/ONSdigital/go-ns/audit"
 	"github.com/ONSdigital/go-ns/common"
 	"github.com/ONSdigital/go-ns/log"
 	"github.com/pkg/errors"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 //CreateIdentityHandler is a POST HTTP handler for creating a new Identity. A request to this endpoint will create an
 // audit event showing an attempt to create a new identity was made followed by another event - successful or unsuccessful
 // depending on outcome of processing the request.If a  request is successful then a URL to the new identity will be
-// returned as a HTTP location header in the response
+// returned as a HTTP location header in the response. The caller must be permitted to create identities, see
+// authorizeCreate, and is recorded as the creator in the audit events.
 func (api *API) CreateIdentityHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	if auditErr := api.auditor.Record(ctx, createIdentityAction, audit.Attempted, nil); auditErr != nil {
-		http.Error(w, auditErr.Error(), http.StatusInternalServerError)
+		createIdentityResponse.writeError(ctx, w, auditErr)
 		return
 	}
 
-	response, err := api.createIdentity(ctx, r)
-
+	c, err := api.authorizeCreate(ctx, r)
 	if err != nil {
-		log.ErrorCtx(ctx, errors.Wrap(err, "createIdentity: error"), nil)
+		log.ErrorCtx(ctx, errors.Wrap(err, "createIdentity: caller not authorized"), nil)
 		api.auditor.Record(ctx, createIdentityAction, audit.Unsuccessful, nil)
 		createIdentityResponse.writeError(ctx, w, err)
 		return
 	}
 
-	err = api.auditor.Record(ctx, createIdentityAction, audit.Successful, common.Params{"id": response.ID})
+	p := common.Params{"created_by": c.ID}
+	response, err := api.createIdentity(ctx, r, c)
+
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.ErrorCtx(ctx, errors.Wrap(err, "createIdentity: error"), log.Data{"created_by": c.ID})
+		api.auditor.Record(ctx, createIdentityAction, audit.Unsuccessful, p)
+		createIdentityResponse.writeError(ctx, w, err)
 		return
 	}
 
+	p["id"] = response.ID
+	err = api.auditor.Record(ctx, createIdentityAction, audit.Successful, p)
+	if err != nil {
+		createIdentityResponse.writeError(ctx, w, err)
+		return
+	}
+
+	api.recorder().IdentityCreated()
 	createIdentityResponse.writeEntity(ctx, w, response, http.StatusCreated)
-	log.InfoCtx(ctx, "createIdentity: identity created successfully", log.Data{"id": response.ID})
+	log.InfoCtx(ctx, "createIdentity: identity created successfully", log.Data{"id": response.ID, "created_by": c.ID})
 }
 
-func (api *API) createIdentity(ctx context.Context, r *http.Request) (*IdentityCreated, error) {
-	body, err := ioutil.ReadAll(r.Body)
+func (api *API) createIdentity(ctx context.Context, r *http.Request, c *creator) (*IdentityCreated, error) {
+	req, err := getCreateIdentityRequest(r.Body)
 	if err != nil {
-		return nil, ErrFailedToReadRequestBody
-	}
-	defer r.Body.Close()
-
-	if len(body) == 0 {
-		return nil, ErrRequestBodyNil
+		return nil, err
 	}
 
-	var i schema.Identity
-	if err := json.Unmarshal(body, &i); err != nil {
-		return nil, ErrFailedToUnmarshalRequestBody
+	i := req.toIdentity()
+	if err := c.authorize(i); err != nil {
+		return nil, err
 	}
 
-	id, err := api.IdentityService.Create(ctx, &i)
+	id, err := api.IdentityService.Create(ctx, i)
 	if err != nil {
 		return nil, err
 	}
@@ -70,3 +80,55 @@ func (api *API) createIdentity(ctx context.Context, r *http.Request) (*IdentityC
 		ID:  id,
 	}, nil
 }
+
+// getCreateIdentityRequest strictly decodes the request body, see decodeRequest.
+func getCreateIdentityRequest(r io.ReadCloser) (*CreateIdentityRequest, error) {
+	var req CreateIdentityRequest
+	if err := decodeRequest(r, &req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// decodeRequest strictly decodes a JSON request body into v. Returns ErrRequestBodyTooLarge if the body exceeds
+// maxRequestBodyBytes and ErrUnknownRequestField if it contains a field not in v.
+func decodeRequest(r io.ReadCloser, v interface{}) error {
+	return decodeRequestLimit(r, v, maxRequestBodyBytes)
+}
+
+// decodeRequestLimit strictly decodes a JSON request body of at most limit bytes into v.
+func decodeRequestLimit(r io.ReadCloser, v interface{}, limit int64) error {
+	defer r.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return ErrFailedToReadRequestBody
+	}
+
+	if len(body) == 0 {
+		return ErrRequestBodyNil
+	}
+
+	if int64(len(body)) > limit {
+		return ErrRequestBodyTooLarge
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(v); err != nil {
+		if field := strings.TrimPrefix(err.Error(), "json: unknown field "); field != err.Error() {
+			if unquoted, err := strconv.Unquote(field); err == nil {
+				field = unquoted
+			}
+			return UnknownFieldErr{field: field}
+		}
+		return ErrFailedToUnmarshalRequestBody
+	}
+
+	// only a single JSON object is permitted.
+	if _, err := dec.Token(); err != io.EOF {
+		return ErrFailedToUnmarshalRequestBody
+	}
+	return nil
+}
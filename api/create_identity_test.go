@@ -12,6 +12,7 @@ import (
 	"github.com/ONSdigital/go-ns/common"
 	"github.com/pkg/errors"
 	. "github.com/smartystreets/goconvey/convey"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -22,17 +23,29 @@ const (
 	ID = "666"
 
 	createIdentityURL = "http://localhost:23800/identity"
+
+	testBootstrapSecret = "who you gonna call"
 )
 
 var (
 	expectedIdentity = IdentityCreated{
 		ID:  ID,
-		URI: "http://localhost:23800/identity/" + ID,
+		URI: "http://localhost:23800/v1/identity/" + ID,
 	}
 
 	errTest = errors.New("boom!")
+
+	bootstrapParams  = common.Params{"created_by": createdByBootstrap}
+	bootstrapCreator = &creator{ID: createdByBootstrap}
 )
 
+// newBootstrapRequest return a create identity request presenting the bootstrap secret.
+func newBootstrapRequest(body io.Reader) *http.Request {
+	r := httptest.NewRequest("POST", createIdentityURL, body)
+	r.Header.Set(bootstrapSecretHeaderKey, testBootstrapSecret)
+	return r
+}
+
 //IOReaderErroring is an io.Reader for unit testing that returns the specified error when Read() is called.
 type IOReaderErroring struct {
 	err error
@@ -51,6 +64,7 @@ func TestIdentityAPI_CreateIdentityAuditAttemptedFailed(t *testing.T) {
 			identityAPI := &API{
 				auditor:         auditMock,
 				IdentityService: serviceMock,
+				BootstrapSecret: testBootstrapSecret,
 			}
 
 			r := httptest.NewRequest("POST", createIdentityURL, nil)
@@ -58,7 +72,7 @@ func TestIdentityAPI_CreateIdentityAuditAttemptedFailed(t *testing.T) {
 			identityAPI.CreateIdentityHandler(w, r)
 
 			Convey("then the expected error response is returned", func() {
-				assertErrorResponse(w.Code, http.StatusInternalServerError, w.Body.String(), auditortest.ErrAudit.Error())
+				assertErrorResponse(w.Code, http.StatusInternalServerError, w.Body.String(), ErrInternalServerError.Error())
 			})
 
 			Convey("and no identity is created", func() {
@@ -81,12 +95,13 @@ func TestIdentityAPI_CreateIdentityError(t *testing.T) {
 			identityAPI := &API{
 				auditor:         auditMock,
 				IdentityService: serviceMock,
+				BootstrapSecret: testBootstrapSecret,
 			}
 
 			b, err := json.Marshal([]int{1, 2, 3})
 			So(err, ShouldBeNil)
 
-			r := httptest.NewRequest("POST", createIdentityURL, bytes.NewReader(b))
+			r := newBootstrapRequest(bytes.NewReader(b))
 			w := httptest.NewRecorder()
 			identityAPI.CreateIdentityHandler(w, r)
 
@@ -101,7 +116,7 @@ func TestIdentityAPI_CreateIdentityError(t *testing.T) {
 			Convey("and an unsuccessful audit event is recorded", func() {
 				auditMock.AssertRecordCalls(
 					auditortest.Expected{Action: createIdentityAction, Result: audit.Attempted, Params: nil},
-					auditortest.Expected{Action: createIdentityAction, Result: audit.Unsuccessful, Params: nil},
+					auditortest.Expected{Action: createIdentityAction, Result: audit.Unsuccessful, Params: bootstrapParams},
 				)
 			})
 		})
@@ -121,18 +136,19 @@ func TestIdentityAPI_CreateIdentityAuditSuccessfulError(t *testing.T) {
 			identityAPI := &API{
 				auditor:         auditMock,
 				IdentityService: serviceMock,
+				BootstrapSecret: testBootstrapSecret,
 			}
 
-			newIdentity := &schema.Identity{Name: "Eleven"}
+			newIdentity := &CreateIdentityRequest{Name: "Eleven"}
 			b, err := json.Marshal(newIdentity)
 			So(err, ShouldBeNil)
 
-			r := httptest.NewRequest("POST", createIdentityURL, bytes.NewReader(b))
+			r := newBootstrapRequest(bytes.NewReader(b))
 			w := httptest.NewRecorder()
 			identityAPI.CreateIdentityHandler(w, r)
 
 			Convey("then the expected error response is returned", func() {
-				assertErrorResponse(w.Code, http.StatusInternalServerError, w.Body.String(), auditortest.ErrAudit.Error())
+				assertErrorResponse(w.Code, http.StatusInternalServerError, w.Body.String(), ErrInternalServerError.Error())
 			})
 
 			Convey("and the identity is created", func() {
@@ -142,7 +158,7 @@ func TestIdentityAPI_CreateIdentityAuditSuccessfulError(t *testing.T) {
 			Convey("and attempted and successful audit events are recorded", func() {
 				auditMock.AssertRecordCalls(
 					auditortest.Expected{Action: createIdentityAction, Result: audit.Attempted, Params: nil},
-					auditortest.Expected{Action: createIdentityAction, Result: audit.Successful, Params: common.Params{"id": ID}},
+					auditortest.Expected{Action: createIdentityAction, Result: audit.Successful, Params: common.Params{"id": ID, "created_by": createdByBootstrap}},
 				)
 			})
 		})
@@ -163,13 +179,14 @@ func TestIdentityAPI_CreateIdentitySuccess(t *testing.T) {
 				Host:            "http://localhost:23800",
 				auditor:         auditMock,
 				IdentityService: serviceMock,
+				BootstrapSecret: testBootstrapSecret,
 			}
 
-			newIdentity := &schema.Identity{Name: "Eleven"}
+			newIdentity := &CreateIdentityRequest{Name: "Eleven"}
 			b, err := json.Marshal(newIdentity)
 			So(err, ShouldBeNil)
 
-			r := httptest.NewRequest("POST", createIdentityURL, bytes.NewReader(b))
+			r := newBootstrapRequest(bytes.NewReader(b))
 			w := httptest.NewRecorder()
 			identityAPI.CreateIdentityHandler(w, r)
 
@@ -190,7 +207,7 @@ func TestIdentityAPI_CreateIdentitySuccess(t *testing.T) {
 			Convey("and attempted and successful audit events are recorded", func() {
 				auditMock.AssertRecordCalls(
 					auditortest.Expected{Action: createIdentityAction, Result: audit.Attempted, Params: nil},
-					auditortest.Expected{Action: createIdentityAction, Result: audit.Successful, Params: common.Params{"id": ID}},
+					auditortest.Expected{Action: createIdentityAction, Result: audit.Successful, Params: common.Params{"id": ID, "created_by": createdByBootstrap}},
 				)
 			})
 		})
@@ -207,12 +224,12 @@ func TestCreateIdentity_IdentityServiceError(t *testing.T) {
 
 		identityAPI := &API{IdentityService: serviceMock}
 
-		newIdentity := &schema.Identity{Name: "Eleven"}
+		newIdentity := &CreateIdentityRequest{Name: "Eleven"}
 		b, err := json.Marshal(newIdentity)
 		So(err, ShouldBeNil)
 
 		r := httptest.NewRequest("POST", createIdentityURL, bytes.NewReader(b))
-		i, err := identityAPI.createIdentity(context.Background(), r)
+		i, err := identityAPI.createIdentity(context.Background(), r, bootstrapCreator)
 		So(err, ShouldNotBeNil)
 		So(err, ShouldEqual, errTest)
 		So(i, ShouldBeNil)
@@ -225,7 +242,7 @@ func TestCreateIdentity_ReadBodyErr(t *testing.T) {
 		identityAPI := &API{IdentityService: serviceMock}
 
 		r := httptest.NewRequest("POST", createIdentityURL, &IOReaderErroring{err: errors.New("")})
-		i, err := identityAPI.createIdentity(context.Background(), r)
+		i, err := identityAPI.createIdentity(context.Background(), r, bootstrapCreator)
 		So(err, ShouldEqual, ErrFailedToReadRequestBody)
 		So(i, ShouldBeNil)
 		So(serviceMock.CreateCalls(), ShouldHaveLength, 0)
@@ -238,13 +255,93 @@ func TestCreateIdentity_BodyEmpty(t *testing.T) {
 		identityAPI := &API{IdentityService: serviceMock}
 
 		r := httptest.NewRequest("POST", createIdentityURL, bytes.NewReader([]byte{}))
-		i, err := identityAPI.createIdentity(context.Background(), r)
+		i, err := identityAPI.createIdentity(context.Background(), r, bootstrapCreator)
 		So(err, ShouldEqual, ErrRequestBodyNil)
 		So(i, ShouldBeNil)
 		So(serviceMock.CreateCalls(), ShouldHaveLength, 0)
 	})
 }
 
+func TestCreateIdentity_UnknownField(t *testing.T) {
+	Convey("should return expected error if the request body contains a field the caller can't set", t, func() {
+		serviceMock := &apitest.IdentityServiceMock{}
+		identityAPI := &API{IdentityService: serviceMock}
+
+		for _, body := range []string{
+			`{"name":"Eleven","id":"666"}`,
+			`{"name":"Eleven","deleted":true}`,
+			`{"name":"Eleven","migrated":true}`,
+			`{"name":"Eleven","created_date":"2018-01-01T00:00:00Z"}`,
+			`{"name":"Eleven","temporary_password":true}`,
+		} {
+			r := httptest.NewRequest("POST", createIdentityURL, strings.NewReader(body))
+			i, err := identityAPI.createIdentity(context.Background(), r, bootstrapCreator)
+			So(errors.Cause(err), ShouldEqual, ErrUnknownRequestField)
+			So(i, ShouldBeNil)
+		}
+
+		r := httptest.NewRequest("POST", createIdentityURL, strings.NewReader(`{"name":"Eleven","deleted":true}`))
+		_, err := identityAPI.createIdentity(context.Background(), r, bootstrapCreator)
+		So(err, ShouldResemble, UnknownFieldErr{field: "deleted"})
+		So(serviceMock.CreateCalls(), ShouldHaveLength, 0)
+	})
+}
+
+func TestCreateIdentity_TrailingData(t *testing.T) {
+	Convey("should return expected error if the request body contains more than one JSON object", t, func() {
+		serviceMock := &apitest.IdentityServiceMock{}
+		identityAPI := &API{IdentityService: serviceMock}
+
+		r := httptest.NewRequest("POST", createIdentityURL, strings.NewReader(`{"name":"Eleven"}{"name":"Twelve"}`))
+		i, err := identityAPI.createIdentity(context.Background(), r, bootstrapCreator)
+		So(err, ShouldEqual, ErrFailedToUnmarshalRequestBody)
+		So(i, ShouldBeNil)
+		So(serviceMock.CreateCalls(), ShouldHaveLength, 0)
+	})
+}
+
+func TestAPI_CreateIdentityHandlerBodyTooLarge(t *testing.T) {
+	Convey("given a create identity request body exceeding the maximum size", t, func() {
+		auditMock := auditortest.New()
+		serviceMock := &apitest.IdentityServiceMock{}
+		identityAPI := &API{
+			auditor:         auditMock,
+			IdentityService: serviceMock,
+			BootstrapSecret: testBootstrapSecret,
+		}
+
+		b, err := json.Marshal(&CreateIdentityRequest{Name: strings.Repeat("a", maxRequestBodyBytes)})
+		So(err, ShouldBeNil)
+
+		r := newBootstrapRequest(bytes.NewReader(b))
+		w := httptest.NewRecorder()
+		identityAPI.CreateIdentityHandler(w, r)
+
+		Convey("then status 413 is returned and the identity is not created", func() {
+			assertErrorResponse(w.Code, http.StatusRequestEntityTooLarge, w.Body.String(), ErrRequestBodyTooLarge.Error())
+			So(serviceMock.CreateCalls(), ShouldHaveLength, 0)
+		})
+	})
+}
+
+func TestCreateIdentityRequest_toIdentity(t *testing.T) {
+	Convey("should map each field of the request to the identity", t, func() {
+		req := CreateIdentityRequest{
+			Name:     "Eleven",
+			Email:    "eleven@hawkins.com",
+			Password: "WAFFLES",
+			UserType: schema.UserTypeUser,
+		}
+
+		So(req.toIdentity(), ShouldResemble, &schema.Identity{
+			Name:     "Eleven",
+			Email:    "eleven@hawkins.com",
+			Password: "WAFFLES",
+			UserType: schema.UserTypeUser,
+		})
+	})
+}
+
 func TestAPI_CreateIdentityHandlerEmailAlreadyInUse(t *testing.T) {
 	Convey("should return bad request if email already in use", t, func() {
 		serviceMock := &apitest.IdentityServiceMock{
@@ -258,22 +355,22 @@ func TestAPI_CreateIdentityHandlerEmailAlreadyInUse(t *testing.T) {
 		identityAPI := &API{
 			IdentityService: serviceMock,
 			auditor:         auitorMock,
+			BootstrapSecret: testBootstrapSecret,
 		}
 
-		newIdentity := &schema.Identity{
+		newIdentity := &CreateIdentityRequest{
 			Name:  "Jamie",
 			Email: "JamieLannister@GOT.com",
 		}
 		b, err := json.Marshal(newIdentity)
 		So(err, ShouldBeNil)
 
-		r := httptest.NewRequest("POST", createIdentityURL, bytes.NewReader(b))
+		r := newBootstrapRequest(bytes.NewReader(b))
 		w := httptest.NewRecorder()
 
 		identityAPI.CreateIdentityHandler(w, r)
 
-		So(w.Code, ShouldEqual, http.StatusConflict)
-		So(strings.TrimSpace(w.Body.String()), ShouldEqual, identity.ErrEmailAlreadyExists.Error())
+		assertErrorResponse(w.Code, http.StatusConflict, w.Body.String(), identity.ErrEmailAlreadyExists.Error())
 
 		So(serviceMock.CreateCalls(), ShouldHaveLength, 1)
 		So(serviceMock.CreateCalls()[0].I.Name, ShouldEqual, "Jamie")
@@ -281,12 +378,16 @@ func TestAPI_CreateIdentityHandlerEmailAlreadyInUse(t *testing.T) {
 
 		auitorMock.AssertRecordCalls(
 			auditortest.Expected{Action: createIdentityAction, Result: audit.Attempted, Params: nil},
-			auditortest.Expected{Action: createIdentityAction, Result: audit.Unsuccessful, Params: nil},
+			auditortest.Expected{Action: createIdentityAction, Result: audit.Unsuccessful, Params: bootstrapParams},
 		)
 	})
 }
 
-func assertErrorResponse(actualStatus int, expectedStatus int, actualBody string, expectedBody string) {
+func assertErrorResponse(actualStatus int, expectedStatus int, actualBody string, expectedMessage string) {
 	So(actualStatus, ShouldEqual, expectedStatus)
-	So(strings.TrimSpace(actualBody), ShouldEqual, expectedBody)
+
+	var body ErrorResponse
+	So(json.Unmarshal([]byte(actualBody), &body), ShouldBeNil)
+	So(body.Code, ShouldNotBeEmpty)
+	So(body.Message, ShouldEqual, expectedMessage)
 }
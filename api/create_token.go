@@ -32,7 +32,7 @@ func (api *API) CreateTokenHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	authToken, err := api.createToken(ctx, tokenReq)
+	authToken, err := api.createToken(ctx, tokenReq, p)
 
 	if err != nil {
 		log.ErrorCtx(ctx, errors.Wrap(err, "createToken: returned error"), logD)
@@ -53,11 +53,14 @@ func (api *API) CreateTokenHandler(w http.ResponseWriter, r *http.Request) {
 	newTokenResponse.writeEntity(ctx, w, authToken, http.StatusOK)
 }
 
-func (api *API) createToken(ctx context.Context, tokenReq *NewTokenRequest) (*AuthToken, error) {
+// createToken verify the credentials and issue a new token, adding the ID of the authenticated identity to the audit
+// params so the event is stored against the identity.
+func (api *API) createToken(ctx context.Context, tokenReq *NewTokenRequest, p common.Params) (*AuthToken, error) {
 	logD := log.Data{"email": tokenReq.Email}
 
 	identity, err := api.IdentityService.VerifyPassword(ctx, tokenReq.Email, tokenReq.Password)
 	if err != nil {
+		api.recorder().LoginFailed(errorCode(errors.Cause(err), newTokenResponse.resolveError(err)))
 		log.ErrorCtx(ctx, errors.Wrap(err, "createToken: request unsuccessful"), logD)
 		return nil, err
 	}
@@ -68,6 +71,8 @@ func (api *API) createToken(ctx context.Context, tokenReq *NewTokenRequest) (*Au
 		return nil, err
 	}
 
+	p["id"] = identity.ID
+	logIdentity(ctx, identity.ID)
 	logD["identity_id"] = token.IdentityID
 	log.InfoCtx(ctx, "createToken: user credential successfully verified", logD)
 	return &AuthToken{Token: token.ID, TTL: ttl}, nil
@@ -13,6 +13,7 @@ import (
 	. "github.com/smartystreets/goconvey/convey"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
@@ -28,6 +29,7 @@ var (
 	}
 
 	testIdentity = &schema.Identity{
+		ID:                ID,
 		Name:              "John Paul Jones",
 		Email:             "blackdog@ons.gov.uk",
 		Password:          "foo",
@@ -38,6 +40,9 @@ var (
 	}
 
 	expectedParams = common.Params{"email": "666@testuser.com"}
+
+	// successParams include the ID of the authenticated identity.
+	successParams = common.Params{"email": "666@testuser.com", "id": ID}
 )
 
 func TestAPI_AuthenticateEmptyRequestBody(t *testing.T) {
@@ -60,6 +65,26 @@ func TestAPI_AuthenticateEmptyRequestBody(t *testing.T) {
 	})
 }
 
+func TestAPI_AuthenticateMalformedRequestBody(t *testing.T) {
+	Convey("should return expected error status if request body is not valid JSON", t, func() {
+		a := auditortest.New()
+		s := &apitest.IdentityServiceMock{}
+
+		r := httptest.NewRequest(http.MethodPost, authenticateURL, strings.NewReader(`{"email":`))
+		w := httptest.NewRecorder()
+		authAPI := API{
+			auditor:         a,
+			IdentityService: s,
+		}
+
+		authAPI.CreateTokenHandler(w, r)
+
+		assertErrorResponse(w.Code, http.StatusBadRequest, w.Body.String(), ErrFailedToUnmarshalRequestBody.Error())
+		a.AssertRecordCalls()
+		So(s.VerifyPasswordCalls(), ShouldHaveLength, 0)
+	})
+}
+
 func TestAPI_AuthenticationUnsuccessful(t *testing.T) {
 	Convey("should return expected error status createToken unsuccessful", t, func() {
 		a := auditortest.New()
@@ -94,6 +119,77 @@ func TestAPI_AuthenticationUnsuccessful(t *testing.T) {
 	})
 }
 
+func TestAPI_AuthenticationIdentityDisabled(t *testing.T) {
+	Convey("should return 403 status if the identity is disabled", t, func() {
+		a := auditortest.New()
+		s := &apitest.IdentityServiceMock{
+			VerifyPasswordFunc: func(ctx context.Context, id string, password string) (*schema.Identity, error) {
+				return nil, identity.ErrIdentityDisabled
+			},
+		}
+		tokens := &apitest.TokenServiceMock{}
+
+		b, err := json.Marshal(testAuthReq)
+		So(err, ShouldBeNil)
+
+		r := httptest.NewRequest(http.MethodPost, authenticateURL, bytes.NewReader(b))
+		w := httptest.NewRecorder()
+
+		authAPI := API{
+			auditor:         a,
+			IdentityService: s,
+			Tokens:          tokens,
+		}
+
+		authAPI.CreateTokenHandler(w, r)
+
+		assertErrorResponse(w.Code, http.StatusForbidden, w.Body.String(), identity.ErrIdentityDisabled.Error())
+
+		var body ErrorResponse
+		So(json.Unmarshal(w.Body.Bytes(), &body), ShouldBeNil)
+		So(body.Code, ShouldEqual, "identity_disabled")
+		So(tokens.NewTokenCalls(), ShouldHaveLength, 0)
+	})
+}
+
+func TestAPI_AuthenticationIdentityNotVerified(t *testing.T) {
+	Convey("should return 403 status if the identity email address has not been verified", t, func() {
+		a := auditortest.New()
+		s := &apitest.IdentityServiceMock{
+			VerifyPasswordFunc: func(ctx context.Context, id string, password string) (*schema.Identity, error) {
+				return nil, identity.ErrIdentityNotVerified
+			},
+		}
+		tokens := &apitest.TokenServiceMock{}
+
+		b, err := json.Marshal(testAuthReq)
+		So(err, ShouldBeNil)
+
+		r := httptest.NewRequest(http.MethodPost, authenticateURL, bytes.NewReader(b))
+		w := httptest.NewRecorder()
+
+		authAPI := API{
+			auditor:         a,
+			IdentityService: s,
+			Tokens:          tokens,
+		}
+
+		authAPI.CreateTokenHandler(w, r)
+
+		assertErrorResponse(w.Code, http.StatusForbidden, w.Body.String(), identity.ErrIdentityNotVerified.Error())
+
+		var body ErrorResponse
+		So(json.Unmarshal(w.Body.Bytes(), &body), ShouldBeNil)
+		So(body.Code, ShouldEqual, "identity_not_verified")
+
+		a.AssertRecordCalls(
+			auditortest.Expected{Action: createToken, Result: audit.Attempted, Params: expectedParams},
+			auditortest.Expected{Action: createToken, Result: audit.Unsuccessful, Params: expectedParams},
+		)
+		So(tokens.NewTokenCalls(), ShouldHaveLength, 0)
+	})
+}
+
 func TestAPI_AuthenticationHandlerIdentityServiceError(t *testing.T) {
 	Convey("should return 403 status status if authentication is unsuccessful", t, func() {
 		a := auditortest.New()
@@ -198,7 +294,7 @@ func TestAPI_AuthenticationHandlerSuccess(t *testing.T) {
 
 		a.AssertRecordCalls(
 			auditortest.Expected{Action: createToken, Result: audit.Attempted, Params: expectedParams},
-			auditortest.Expected{Action: createToken, Result: audit.Successful, Params: expectedParams},
+			auditortest.Expected{Action: createToken, Result: audit.Successful, Params: successParams},
 		)
 		So(s.VerifyPasswordCalls(), ShouldHaveLength, 1)
 		So(s.VerifyPasswordCalls()[0].Email, ShouldEqual, testAuthReq.Email)
@@ -301,7 +397,7 @@ func TestAPI_AuthenticationUnsuccessfulAuditSuccessfulError(t *testing.T) {
 
 		a.AssertRecordCalls(
 			auditortest.Expected{Action: createToken, Result: audit.Attempted, Params: expectedParams},
-			auditortest.Expected{Action: createToken, Result: audit.Successful, Params: expectedParams},
+			auditortest.Expected{Action: createToken, Result: audit.Successful, Params: successParams},
 		)
 		So(s.VerifyPasswordCalls(), ShouldHaveLength, 1)
 		So(s.VerifyPasswordCalls()[0].Email, ShouldEqual, testAuthReq.Email)
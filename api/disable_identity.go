@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"github.com/ONSdigital/go-ns/audit"
+	"github.com/ONSdigital/go-ns/common"
+	"github.com/ONSdigital/go-ns/log"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"net/http"
+)
+
+// DisableIdentityHandler is a POST HTTP handler suspending the identity in the request path and revoking its tokens.
+// The caller must present a token for an admin identity and is recorded as the requester in the audit events along
+// with the reason provided.
+func (api *API) DisableIdentityHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := mux.Vars(r)["id"]
+	p := common.Params{"id": id}
+
+	if auditErr := api.auditor.Record(ctx, disableIdentityAction, audit.Attempted, p); auditErr != nil {
+		disableIdentityResponse.writeError(ctx, w, auditErr)
+		return
+	}
+
+	caller, err := api.authorizeAdmin(ctx, r)
+	if err != nil {
+		log.ErrorCtx(ctx, errors.Wrap(err, "disableIdentity: caller not authorized"), log.Data{"id": id})
+		api.auditor.Record(ctx, disableIdentityAction, audit.Unsuccessful, p)
+		disableIdentityResponse.writeError(ctx, w, err)
+		return
+	}
+
+	p["requested_by"] = caller.ID
+	response, err := api.disableIdentity(ctx, r, id, caller.ID, p)
+	if err != nil {
+		log.ErrorCtx(ctx, errors.Wrap(err, "disableIdentity: error"), log.Data{"id": id, "requested_by": caller.ID})
+		api.auditor.Record(ctx, disableIdentityAction, audit.Unsuccessful, p)
+		disableIdentityResponse.writeError(ctx, w, err)
+		return
+	}
+
+	if err := api.auditor.Record(ctx, disableIdentityAction, audit.Successful, p); err != nil {
+		disableIdentityResponse.writeError(ctx, w, err)
+		return
+	}
+
+	disableIdentityResponse.writeEntity(ctx, w, response, http.StatusOK)
+	log.InfoCtx(ctx, "disableIdentity: identity disabled successfully", log.Data{"id": id, "requested_by": caller.ID})
+}
+
+func (api *API) disableIdentity(ctx context.Context, r *http.Request, id string, disabledBy string, p common.Params) (*IdentityStatus, error) {
+	var req DisableIdentityRequest
+	if err := decodeRequest(r.Body, &req); err != nil {
+		return nil, err
+	}
+
+	p["reason"] = req.Reason
+	if err := api.IdentityService.Disable(ctx, id, req.Reason, disabledBy); err != nil {
+		return nil, err
+	}
+	return &IdentityStatus{ID: id, Disabled: true, DisabledReason: req.Reason, DisabledBy: disabledBy}, nil
+}
+
+// EnableIdentityHandler is a POST HTTP handler reinstating the disabled identity in the request path. The caller must
+// present a token for an admin identity and is recorded as the requester in the audit events.
+func (api *API) EnableIdentityHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := mux.Vars(r)["id"]
+	p := common.Params{"id": id}
+
+	if auditErr := api.auditor.Record(ctx, enableIdentityAction, audit.Attempted, p); auditErr != nil {
+		enableIdentityResponse.writeError(ctx, w, auditErr)
+		return
+	}
+
+	caller, err := api.authorizeAdmin(ctx, r)
+	if err != nil {
+		log.ErrorCtx(ctx, errors.Wrap(err, "enableIdentity: caller not authorized"), log.Data{"id": id})
+		api.auditor.Record(ctx, enableIdentityAction, audit.Unsuccessful, p)
+		enableIdentityResponse.writeError(ctx, w, err)
+		return
+	}
+
+	p["requested_by"] = caller.ID
+	if err := api.IdentityService.Enable(ctx, id); err != nil {
+		log.ErrorCtx(ctx, errors.Wrap(err, "enableIdentity: error"), log.Data{"id": id, "requested_by": caller.ID})
+		api.auditor.Record(ctx, enableIdentityAction, audit.Unsuccessful, p)
+		enableIdentityResponse.writeError(ctx, w, err)
+		return
+	}
+
+	if err := api.auditor.Record(ctx, enableIdentityAction, audit.Successful, p); err != nil {
+		enableIdentityResponse.writeError(ctx, w, err)
+		return
+	}
+
+	enableIdentityResponse.writeEntity(ctx, w, &IdentityStatus{ID: id}, http.StatusOK)
+	log.InfoCtx(ctx, "enableIdentity: identity enabled successfully", log.Data{"id": id, "requested_by": caller.ID})
+}
@@ -0,0 +1,212 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/ONSdigital/dp-identity-api/api/apitest"
+	"github.com/ONSdigital/dp-identity-api/identity"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/ONSdigital/go-ns/audit"
+	"github.com/ONSdigital/go-ns/audit/auditortest"
+	"github.com/ONSdigital/go-ns/common"
+	"github.com/gorilla/mux"
+	. "github.com/smartystreets/goconvey/convey"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const (
+	disableIdentityURL = "http://localhost:23800/identity/666/disable"
+	enableIdentityURL  = "http://localhost:23800/identity/666/enable"
+	adminID            = "999"
+)
+
+func newIdentityStatusRequest(url string, tokenStr string, body string) *http.Request {
+	r := httptest.NewRequest("POST", url, bytes.NewBufferString(body))
+	if tokenStr != "" {
+		r.Header.Set(tokenHeaderKey, tokenStr)
+	}
+	return mux.SetURLVars(r, map[string]string{"id": ID})
+}
+
+func TestAPI_DisableIdentityHandler(t *testing.T) {
+	Convey("given a request to disable an identity", t, func() {
+		auditMock := auditortest.New()
+		tokensMock := tokenServiceReturning(&schema.Identity{ID: adminID, UserType: schema.UserTypeAdmin}, nil)
+		serviceMock := &apitest.IdentityServiceMock{
+			DisableFunc: func(ctx context.Context, id string, reason string, disabledBy string) error {
+				return nil
+			},
+		}
+
+		identityAPI := &API{auditor: auditMock, IdentityService: serviceMock, Tokens: tokensMock}
+		body := `{"reason": "under investigation"}`
+
+		Convey("when the caller presents a token for an admin identity", func() {
+			w := httptest.NewRecorder()
+			identityAPI.DisableIdentityHandler(w, newIdentityStatusRequest(disableIdentityURL, "1234", body))
+
+			Convey("then the identity is disabled with the reason and the admin as the actor", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+
+				var resp IdentityStatus
+				So(json.Unmarshal(w.Body.Bytes(), &resp), ShouldBeNil)
+				So(resp, ShouldResemble, IdentityStatus{ID: ID, Disabled: true, DisabledReason: "under investigation", DisabledBy: adminID})
+
+				So(serviceMock.DisableCalls(), ShouldHaveLength, 1)
+				So(serviceMock.DisableCalls()[0].ID, ShouldEqual, ID)
+				So(serviceMock.DisableCalls()[0].Reason, ShouldEqual, "under investigation")
+				So(serviceMock.DisableCalls()[0].DisabledBy, ShouldEqual, adminID)
+			})
+
+			Convey("and attempted and successful audit events are recorded", func() {
+				auditMock.AssertRecordCalls(
+					auditortest.Expected{Action: disableIdentityAction, Result: audit.Attempted, Params: common.Params{"id": ID}},
+					auditortest.Expected{Action: disableIdentityAction, Result: audit.Successful, Params: common.Params{"id": ID, "requested_by": adminID, "reason": "under investigation"}},
+				)
+			})
+		})
+
+		Convey("when the caller presents a token for a non-admin identity", func() {
+			identityAPI.Tokens = tokenServiceReturning(&schema.Identity{ID: ID, UserType: schema.UserTypeService}, nil)
+
+			w := httptest.NewRecorder()
+			identityAPI.DisableIdentityHandler(w, newIdentityStatusRequest(disableIdentityURL, "1234", body))
+
+			Convey("then status 403 is returned and the identity is not disabled", func() {
+				assertErrorResponse(w.Code, http.StatusForbidden, w.Body.String(), ErrForbidden.Error())
+				So(serviceMock.DisableCalls(), ShouldHaveLength, 0)
+			})
+
+			Convey("and attempted and unsuccessful audit events are recorded", func() {
+				auditMock.AssertRecordCalls(
+					auditortest.Expected{Action: disableIdentityAction, Result: audit.Attempted, Params: common.Params{"id": ID}},
+					auditortest.Expected{Action: disableIdentityAction, Result: audit.Unsuccessful, Params: common.Params{"id": ID}},
+				)
+			})
+		})
+
+		Convey("when the request does not contain a token", func() {
+			w := httptest.NewRecorder()
+			identityAPI.DisableIdentityHandler(w, newIdentityStatusRequest(disableIdentityURL, "", body))
+
+			Convey("then status 401 is returned and the identity is not disabled", func() {
+				assertErrorResponse(w.Code, http.StatusUnauthorized, w.Body.String(), ErrNoTokenProvided.Error())
+				So(serviceMock.DisableCalls(), ShouldHaveLength, 0)
+			})
+		})
+
+		Convey("when no reason is provided", func() {
+			serviceMock.DisableFunc = func(ctx context.Context, id string, reason string, disabledBy string) error {
+				return identity.ErrDisabledReasonRequired
+			}
+
+			w := httptest.NewRecorder()
+			identityAPI.DisableIdentityHandler(w, newIdentityStatusRequest(disableIdentityURL, "1234", `{}`))
+
+			Convey("then status 400 is returned", func() {
+				assertErrorResponse(w.Code, http.StatusBadRequest, w.Body.String(), identity.ErrDisabledReasonRequired.Error())
+			})
+		})
+
+		Convey("when the identity does not exist", func() {
+			serviceMock.DisableFunc = func(ctx context.Context, id string, reason string, disabledBy string) error {
+				return identity.ErrIdentityNotFound
+			}
+
+			w := httptest.NewRecorder()
+			identityAPI.DisableIdentityHandler(w, newIdentityStatusRequest(disableIdentityURL, "1234", body))
+
+			Convey("then status 404 is returned", func() {
+				assertErrorResponse(w.Code, http.StatusNotFound, w.Body.String(), identity.ErrIdentityNotFound.Error())
+			})
+
+			Convey("and attempted and unsuccessful audit events are recorded", func() {
+				auditMock.AssertRecordCalls(
+					auditortest.Expected{Action: disableIdentityAction, Result: audit.Attempted, Params: common.Params{"id": ID}},
+					auditortest.Expected{Action: disableIdentityAction, Result: audit.Unsuccessful, Params: common.Params{"id": ID, "requested_by": adminID, "reason": "under investigation"}},
+				)
+			})
+		})
+	})
+
+	Convey("given audit action attempted returns an error", t, func() {
+		auditMock := auditortest.NewErroring(disableIdentityAction, audit.Attempted)
+		tokensMock := tokenServiceReturning(&schema.Identity{ID: adminID, UserType: schema.UserTypeAdmin}, nil)
+		serviceMock := &apitest.IdentityServiceMock{}
+		identityAPI := &API{auditor: auditMock, IdentityService: serviceMock, Tokens: tokensMock}
+
+		w := httptest.NewRecorder()
+		identityAPI.DisableIdentityHandler(w, newIdentityStatusRequest(disableIdentityURL, "1234", `{"reason": "under investigation"}`))
+
+		Convey("then status 500 is returned and the identity is not disabled", func() {
+			assertErrorResponse(w.Code, http.StatusInternalServerError, w.Body.String(), ErrInternalServerError.Error())
+			So(tokensMock.GetIdentityByTokenCalls(), ShouldHaveLength, 0)
+			So(serviceMock.DisableCalls(), ShouldHaveLength, 0)
+		})
+	})
+}
+
+func TestAPI_EnableIdentityHandler(t *testing.T) {
+	Convey("given a request to enable an identity", t, func() {
+		auditMock := auditortest.New()
+		tokensMock := tokenServiceReturning(&schema.Identity{ID: adminID, UserType: schema.UserTypeAdmin}, nil)
+		serviceMock := &apitest.IdentityServiceMock{
+			EnableFunc: func(ctx context.Context, id string) error {
+				return nil
+			},
+		}
+
+		identityAPI := &API{auditor: auditMock, IdentityService: serviceMock, Tokens: tokensMock}
+
+		Convey("when the caller presents a token for an admin identity", func() {
+			w := httptest.NewRecorder()
+			identityAPI.EnableIdentityHandler(w, newIdentityStatusRequest(enableIdentityURL, "1234", ""))
+
+			Convey("then the identity is enabled", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+
+				var resp IdentityStatus
+				So(json.Unmarshal(w.Body.Bytes(), &resp), ShouldBeNil)
+				So(resp, ShouldResemble, IdentityStatus{ID: ID})
+
+				So(serviceMock.EnableCalls(), ShouldHaveLength, 1)
+				So(serviceMock.EnableCalls()[0].ID, ShouldEqual, ID)
+			})
+
+			Convey("and attempted and successful audit events are recorded", func() {
+				auditMock.AssertRecordCalls(
+					auditortest.Expected{Action: enableIdentityAction, Result: audit.Attempted, Params: common.Params{"id": ID}},
+					auditortest.Expected{Action: enableIdentityAction, Result: audit.Successful, Params: common.Params{"id": ID, "requested_by": adminID}},
+				)
+			})
+		})
+
+		Convey("when the caller presents a token for the identity itself", func() {
+			identityAPI.Tokens = tokenServiceReturning(&schema.Identity{ID: ID, UserType: schema.UserTypeUser}, nil)
+
+			w := httptest.NewRecorder()
+			identityAPI.EnableIdentityHandler(w, newIdentityStatusRequest(enableIdentityURL, "1234", ""))
+
+			Convey("then status 403 is returned and the identity is not enabled", func() {
+				assertErrorResponse(w.Code, http.StatusForbidden, w.Body.String(), ErrForbidden.Error())
+				So(serviceMock.EnableCalls(), ShouldHaveLength, 0)
+			})
+		})
+
+		Convey("when the identity does not exist", func() {
+			serviceMock.EnableFunc = func(ctx context.Context, id string) error {
+				return identity.ErrIdentityNotFound
+			}
+
+			w := httptest.NewRecorder()
+			identityAPI.EnableIdentityHandler(w, newIdentityStatusRequest(enableIdentityURL, "1234", ""))
+
+			Convey("then status 404 is returned", func() {
+				assertErrorResponse(w.Code, http.StatusNotFound, w.Body.String(), identity.ErrIdentityNotFound.Error())
+			})
+		})
+	})
+}
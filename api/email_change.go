@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"github.com/ONSdigital/go-ns/audit"
+	"github.com/ONSdigital/go-ns/common"
+	"github.com/ONSdigital/go-ns/log"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"net/http"
+)
+
+// RequestEmailChangeHandler is a POST HTTP handler requesting a change to the email of the identity in the request
+// path. The caller must present a token for the identity or for an admin identity, see authorizeIdentity, and is
+// recorded as the requester in the audit events. A confirmation token is sent to the new email, the change is not
+// applied until it is confirmed using ConfirmEmailChangeHandler.
+func (api *API) RequestEmailChangeHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := mux.Vars(r)["id"]
+	p := common.Params{"id": id}
+
+	if auditErr := api.auditor.Record(ctx, requestEmailChangeAction, audit.Attempted, p); auditErr != nil {
+		requestEmailChangeResponse.writeError(ctx, w, auditErr)
+		return
+	}
+
+	caller, err := api.authorizeIdentity(ctx, r, id)
+	if err != nil {
+		log.ErrorCtx(ctx, errors.Wrap(err, "requestEmailChange: caller not authorized"), log.Data{"id": id})
+		api.auditor.Record(ctx, requestEmailChangeAction, audit.Unsuccessful, p)
+		requestEmailChangeResponse.writeError(ctx, w, err)
+		return
+	}
+
+	p["requested_by"] = caller.ID
+	response, err := api.requestEmailChange(ctx, r, id)
+	if err != nil {
+		log.ErrorCtx(ctx, errors.Wrap(err, "requestEmailChange: error"), log.Data{"id": id, "requested_by": caller.ID})
+		api.auditor.Record(ctx, requestEmailChangeAction, audit.Unsuccessful, p)
+		requestEmailChangeResponse.writeError(ctx, w, err)
+		return
+	}
+
+	if err := api.auditor.Record(ctx, requestEmailChangeAction, audit.Successful, p); err != nil {
+		requestEmailChangeResponse.writeError(ctx, w, err)
+		return
+	}
+
+	requestEmailChangeResponse.writeEntity(ctx, w, response, http.StatusAccepted)
+	log.InfoCtx(ctx, "requestEmailChange: email change requested successfully", log.Data{"id": id, "requested_by": caller.ID})
+}
+
+func (api *API) requestEmailChange(ctx context.Context, r *http.Request, id string) (*EmailChangeRequested, error) {
+	var req EmailChangeRequest
+	if err := decodeRequest(r.Body, &req); err != nil {
+		return nil, err
+	}
+
+	if err := api.IdentityService.RequestEmailChange(ctx, id, req.Email); err != nil {
+		return nil, err
+	}
+	return &EmailChangeRequested{ID: id, PendingEmail: req.Email}, nil
+}
+
+// ConfirmEmailChangeHandler is a POST HTTP handler applying the email change the token in the request path was issued
+// for. A request to this endpoint will create an audit event showing an attempt to confirm an email change was made
+// followed by another event - successful or unsuccessful depending on outcome of processing the request. The
+// identity's existing tokens are revoked.
+func (api *API) ConfirmEmailChangeHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if auditErr := api.auditor.Record(ctx, confirmEmailChangeAction, audit.Attempted, nil); auditErr != nil {
+		confirmEmailChangeResponse.writeError(ctx, w, auditErr)
+		return
+	}
+
+	response, err := api.confirmEmailChange(ctx, r)
+	if err != nil {
+		log.ErrorCtx(ctx, errors.Wrap(err, "confirmEmailChange: error"), nil)
+		api.auditor.Record(ctx, confirmEmailChangeAction, audit.Unsuccessful, nil)
+		confirmEmailChangeResponse.writeError(ctx, w, err)
+		return
+	}
+
+	if err := api.auditor.Record(ctx, confirmEmailChangeAction, audit.Successful, common.Params{"id": response.ID}); err != nil {
+		confirmEmailChangeResponse.writeError(ctx, w, err)
+		return
+	}
+
+	confirmEmailChangeResponse.writeEntity(ctx, w, response, http.StatusOK)
+	log.InfoCtx(ctx, "confirmEmailChange: email changed successfully", log.Data{"id": response.ID})
+}
+
+func (api *API) confirmEmailChange(ctx context.Context, r *http.Request) (*EmailChanged, error) {
+	changeToken := mux.Vars(r)["token"]
+	if changeToken == "" {
+		return nil, ErrNoEmailChangeToken
+	}
+
+	i, err := api.IdentityService.ConfirmEmailChange(ctx, changeToken)
+	if err != nil {
+		return nil, err
+	}
+	return &EmailChanged{ID: i.ID, Email: i.Email}, nil
+}
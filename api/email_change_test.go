@@ -0,0 +1,246 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/ONSdigital/dp-identity-api/api/apitest"
+	"github.com/ONSdigital/dp-identity-api/identity"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/ONSdigital/go-ns/audit"
+	"github.com/ONSdigital/go-ns/audit/auditortest"
+	"github.com/ONSdigital/go-ns/common"
+	"github.com/gorilla/mux"
+	. "github.com/smartystreets/goconvey/convey"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const (
+	requestEmailChangeURL = "http://localhost:23800/identity/666/email-change"
+	confirmEmailChangeURL = "http://localhost:23800/identity/email-change/dpide_token"
+	changedEmail          = "eleven@hawkins.com"
+)
+
+func newRequestEmailChangeRequest(tokenStr string, body string) *http.Request {
+	r := httptest.NewRequest("POST", requestEmailChangeURL, bytes.NewBufferString(body))
+	if tokenStr != "" {
+		r.Header.Set(tokenHeaderKey, tokenStr)
+	}
+	return mux.SetURLVars(r, map[string]string{"id": ID})
+}
+
+func newConfirmEmailChangeRequest(token string) *http.Request {
+	r := httptest.NewRequest("POST", confirmEmailChangeURL, nil)
+	return mux.SetURLVars(r, map[string]string{"token": token})
+}
+
+func TestAPI_RequestEmailChangeHandler(t *testing.T) {
+	Convey("given a request to change the email of an identity", t, func() {
+		auditMock := auditortest.New()
+		tokensMock := tokenServiceReturning(&schema.Identity{ID: ID, UserType: schema.UserTypeUser}, nil)
+		serviceMock := &apitest.IdentityServiceMock{
+			RequestEmailChangeFunc: func(ctx context.Context, id string, email string) error {
+				return nil
+			},
+		}
+
+		identityAPI := &API{auditor: auditMock, IdentityService: serviceMock, Tokens: tokensMock}
+		body := `{"email": "` + changedEmail + `"}`
+
+		Convey("when the caller presents a token for the identity", func() {
+			w := httptest.NewRecorder()
+			identityAPI.RequestEmailChangeHandler(w, newRequestEmailChangeRequest("1234", body))
+
+			Convey("then status 202 is returned with the pending email", func() {
+				So(w.Code, ShouldEqual, http.StatusAccepted)
+
+				var resp EmailChangeRequested
+				So(json.Unmarshal(w.Body.Bytes(), &resp), ShouldBeNil)
+				So(resp, ShouldResemble, EmailChangeRequested{ID: ID, PendingEmail: changedEmail})
+
+				So(serviceMock.RequestEmailChangeCalls(), ShouldHaveLength, 1)
+				So(serviceMock.RequestEmailChangeCalls()[0].ID, ShouldEqual, ID)
+				So(serviceMock.RequestEmailChangeCalls()[0].Email, ShouldEqual, changedEmail)
+			})
+
+			Convey("and attempted and successful audit events are recorded", func() {
+				auditMock.AssertRecordCalls(
+					auditortest.Expected{Action: requestEmailChangeAction, Result: audit.Attempted, Params: common.Params{"id": ID}},
+					auditortest.Expected{Action: requestEmailChangeAction, Result: audit.Successful, Params: common.Params{"id": ID, "requested_by": ID}},
+				)
+			})
+		})
+
+		Convey("when the caller presents a token for an admin identity", func() {
+			identityAPI.Tokens = tokenServiceReturning(&schema.Identity{ID: "999", UserType: schema.UserTypeAdmin}, nil)
+
+			w := httptest.NewRecorder()
+			identityAPI.RequestEmailChangeHandler(w, newRequestEmailChangeRequest("1234", body))
+
+			Convey("then status 202 is returned and the admin is recorded as the requester", func() {
+				So(w.Code, ShouldEqual, http.StatusAccepted)
+				auditMock.AssertRecordCalls(
+					auditortest.Expected{Action: requestEmailChangeAction, Result: audit.Attempted, Params: common.Params{"id": ID}},
+					auditortest.Expected{Action: requestEmailChangeAction, Result: audit.Successful, Params: common.Params{"id": ID, "requested_by": "999"}},
+				)
+			})
+		})
+
+		Convey("when the caller presents a token for another non-admin identity", func() {
+			identityAPI.Tokens = tokenServiceReturning(&schema.Identity{ID: "999", UserType: schema.UserTypeUser}, nil)
+
+			w := httptest.NewRecorder()
+			identityAPI.RequestEmailChangeHandler(w, newRequestEmailChangeRequest("1234", body))
+
+			Convey("then status 403 is returned and the change is not requested", func() {
+				assertErrorResponse(w.Code, http.StatusForbidden, w.Body.String(), ErrForbidden.Error())
+				So(serviceMock.RequestEmailChangeCalls(), ShouldHaveLength, 0)
+			})
+
+			Convey("and attempted and unsuccessful audit events are recorded", func() {
+				auditMock.AssertRecordCalls(
+					auditortest.Expected{Action: requestEmailChangeAction, Result: audit.Attempted, Params: common.Params{"id": ID}},
+					auditortest.Expected{Action: requestEmailChangeAction, Result: audit.Unsuccessful, Params: common.Params{"id": ID}},
+				)
+			})
+		})
+
+		Convey("when the request does not contain a token", func() {
+			w := httptest.NewRecorder()
+			identityAPI.RequestEmailChangeHandler(w, newRequestEmailChangeRequest("", body))
+
+			Convey("then status 401 is returned and the change is not requested", func() {
+				assertErrorResponse(w.Code, http.StatusUnauthorized, w.Body.String(), ErrNoTokenProvided.Error())
+				So(tokensMock.GetIdentityByTokenCalls(), ShouldHaveLength, 0)
+				So(serviceMock.RequestEmailChangeCalls(), ShouldHaveLength, 0)
+			})
+		})
+
+		Convey("when the request body contains an unknown field", func() {
+			w := httptest.NewRecorder()
+			identityAPI.RequestEmailChangeHandler(w, newRequestEmailChangeRequest("1234", `{"email": "`+changedEmail+`", "id": "999"}`))
+
+			Convey("then status 400 is returned and the change is not requested", func() {
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+				So(serviceMock.RequestEmailChangeCalls(), ShouldHaveLength, 0)
+			})
+		})
+
+		Convey("when the new email is used by another identity", func() {
+			serviceMock.RequestEmailChangeFunc = func(ctx context.Context, id string, email string) error {
+				return identity.ErrEmailAlreadyExists
+			}
+
+			w := httptest.NewRecorder()
+			identityAPI.RequestEmailChangeHandler(w, newRequestEmailChangeRequest("1234", body))
+
+			Convey("then status 409 is returned", func() {
+				assertErrorResponse(w.Code, http.StatusConflict, w.Body.String(), identity.ErrEmailAlreadyExists.Error())
+			})
+
+			Convey("and attempted and unsuccessful audit events are recorded", func() {
+				auditMock.AssertRecordCalls(
+					auditortest.Expected{Action: requestEmailChangeAction, Result: audit.Attempted, Params: common.Params{"id": ID}},
+					auditortest.Expected{Action: requestEmailChangeAction, Result: audit.Unsuccessful, Params: common.Params{"id": ID, "requested_by": ID}},
+				)
+			})
+		})
+	})
+
+	Convey("given audit action attempted returns an error", t, func() {
+		auditMock := auditortest.NewErroring(requestEmailChangeAction, audit.Attempted)
+		tokensMock := tokenServiceReturning(&schema.Identity{ID: ID}, nil)
+		serviceMock := &apitest.IdentityServiceMock{}
+		identityAPI := &API{auditor: auditMock, IdentityService: serviceMock, Tokens: tokensMock}
+
+		w := httptest.NewRecorder()
+		identityAPI.RequestEmailChangeHandler(w, newRequestEmailChangeRequest("1234", `{"email": "`+changedEmail+`"}`))
+
+		Convey("then status 500 is returned and the change is not requested", func() {
+			assertErrorResponse(w.Code, http.StatusInternalServerError, w.Body.String(), ErrInternalServerError.Error())
+			So(tokensMock.GetIdentityByTokenCalls(), ShouldHaveLength, 0)
+			So(serviceMock.RequestEmailChangeCalls(), ShouldHaveLength, 0)
+		})
+	})
+}
+
+func TestAPI_ConfirmEmailChangeHandler(t *testing.T) {
+	Convey("given an email change token", t, func() {
+		auditMock := auditortest.New()
+		serviceMock := &apitest.IdentityServiceMock{
+			ConfirmEmailChangeFunc: func(ctx context.Context, token string) (*schema.Identity, error) {
+				return &schema.Identity{ID: ID, Email: changedEmail}, nil
+			},
+		}
+
+		identityAPI := &API{auditor: auditMock, IdentityService: serviceMock}
+
+		Convey("when the token is valid", func() {
+			w := httptest.NewRecorder()
+			identityAPI.ConfirmEmailChangeHandler(w, newConfirmEmailChangeRequest("dpide_token"))
+
+			Convey("then the changed email is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+
+				var body EmailChanged
+				So(json.Unmarshal(w.Body.Bytes(), &body), ShouldBeNil)
+				So(body, ShouldResemble, EmailChanged{ID: ID, Email: changedEmail})
+
+				So(serviceMock.ConfirmEmailChangeCalls(), ShouldHaveLength, 1)
+				So(serviceMock.ConfirmEmailChangeCalls()[0].Token, ShouldEqual, "dpide_token")
+			})
+
+			Convey("and attempted and successful audit events are recorded", func() {
+				auditMock.AssertRecordCalls(
+					auditortest.Expected{Action: confirmEmailChangeAction, Result: audit.Attempted, Params: nil},
+					auditortest.Expected{Action: confirmEmailChangeAction, Result: audit.Successful, Params: common.Params{"id": ID}},
+				)
+			})
+		})
+
+		Convey("when the token is not found or has expired", func() {
+			serviceMock.ConfirmEmailChangeFunc = func(ctx context.Context, token string) (*schema.Identity, error) {
+				return nil, identity.ErrEmailChangeTokenNotFound
+			}
+
+			w := httptest.NewRecorder()
+			identityAPI.ConfirmEmailChangeHandler(w, newConfirmEmailChangeRequest("dpide_token"))
+
+			Convey("then status 404 is returned", func() {
+				assertErrorResponse(w.Code, http.StatusNotFound, w.Body.String(), identity.ErrEmailChangeTokenNotFound.Error())
+			})
+
+			Convey("and attempted and unsuccessful audit events are recorded", func() {
+				auditMock.AssertRecordCalls(
+					auditortest.Expected{Action: confirmEmailChangeAction, Result: audit.Attempted, Params: nil},
+					auditortest.Expected{Action: confirmEmailChangeAction, Result: audit.Unsuccessful, Params: nil},
+				)
+			})
+		})
+
+		Convey("when the new email has since been used by another identity", func() {
+			serviceMock.ConfirmEmailChangeFunc = func(ctx context.Context, token string) (*schema.Identity, error) {
+				return nil, identity.ErrEmailAlreadyExists
+			}
+
+			w := httptest.NewRecorder()
+			identityAPI.ConfirmEmailChangeHandler(w, newConfirmEmailChangeRequest("dpide_token"))
+
+			Convey("then status 409 is returned", func() {
+				assertErrorResponse(w.Code, http.StatusConflict, w.Body.String(), identity.ErrEmailAlreadyExists.Error())
+			})
+		})
+
+		Convey("when the request does not contain a token", func() {
+			w := httptest.NewRecorder()
+			identityAPI.ConfirmEmailChangeHandler(w, newConfirmEmailChangeRequest(""))
+
+			Convey("then status 400 is returned and the identity service is not called", func() {
+				assertErrorResponse(w.Code, http.StatusBadRequest, w.Body.String(), ErrNoEmailChangeToken.Error())
+				So(serviceMock.ConfirmEmailChangeCalls(), ShouldHaveLength, 0)
+			})
+		})
+	})
+}
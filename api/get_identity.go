@@ -40,25 +40,30 @@ func (api *API) GetIdentityHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (api *API) getIdentity(ctx context.Context, r *http.Request) (*GetIdentityResponse, error) {
-
-	tokenStr := r.Header.Get(tokenHeaderKey)
-	if tokenStr == "" {
-		log.ErrorCtx(ctx, ErrNoTokenProvided, nil)
-		return nil, ErrNoTokenProvided
-	}
-
-	i, ttl, err := api.Tokens.GetIdentityByToken(ctx, tokenStr)
+	i, ttl, err := api.identityByToken(ctx, r)
 	if err != nil {
 		return nil, err
 	}
 
-	return &GetIdentityResponse{
+	response := &GetIdentityResponse{
 		ID:          i.ID,
 		Name:        i.Name,
 		Email:       i.Email,
 		UserType:    i.UserType,
 		Deleted:     i.Deleted,
+		Verified:    i.Verified,
+		Disabled:    i.Disabled,
 		CreatedDate: i.CreatedDate,
 		TokenTTL:    ttl,
-	}, nil
+	}
+
+	if i.Impersonator != nil {
+		response.ImpersonatedBy = &Impersonator{
+			ID:       i.Impersonator.ID,
+			Name:     i.Impersonator.Name,
+			Email:    i.Impersonator.Email,
+			UserType: i.Impersonator.UserType,
+		}
+	}
+	return response, nil
 }
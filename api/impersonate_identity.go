@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/ONSdigital/go-ns/audit"
+	"github.com/ONSdigital/go-ns/common"
+	"github.com/ONSdigital/go-ns/log"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"net/http"
+)
+
+// ImpersonateIdentityHandler is a POST HTTP handler issuing a short lived token authenticating the caller as the
+// identity in the request path, so support staff can reproduce issues as that identity without knowing its password.
+// The caller must present a token for an admin identity and is recorded as the requester in the audit events, each
+// use of the token issued is audited by identityByToken. Returns ErrImpersonationDisabled if impersonation is
+// disabled.
+func (api *API) ImpersonateIdentityHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := mux.Vars(r)["id"]
+	p := common.Params{"id": id}
+
+	if auditErr := api.auditor.Record(ctx, impersonateIdentityAction, audit.Attempted, p); auditErr != nil {
+		impersonateIdentityResponse.writeError(ctx, w, auditErr)
+		return
+	}
+
+	if !api.Impersonation {
+		log.ErrorCtx(ctx, errors.Wrap(ErrImpersonationDisabled, "impersonateIdentity: error"), log.Data{"id": id})
+		api.auditor.Record(ctx, impersonateIdentityAction, audit.Unsuccessful, p)
+		impersonateIdentityResponse.writeError(ctx, w, ErrImpersonationDisabled)
+		return
+	}
+
+	caller, err := api.authorizeAdmin(ctx, r)
+	if err != nil {
+		log.ErrorCtx(ctx, errors.Wrap(err, "impersonateIdentity: caller not authorized"), log.Data{"id": id})
+		api.auditor.Record(ctx, impersonateIdentityAction, audit.Unsuccessful, p)
+		impersonateIdentityResponse.writeError(ctx, w, err)
+		return
+	}
+
+	p["requested_by"] = caller.ID
+	response, err := api.impersonateIdentity(ctx, id, *caller)
+	if err != nil {
+		log.ErrorCtx(ctx, errors.Wrap(err, "impersonateIdentity: error"), log.Data{"id": id, "requested_by": caller.ID})
+		api.auditor.Record(ctx, impersonateIdentityAction, audit.Unsuccessful, p)
+		impersonateIdentityResponse.writeError(ctx, w, err)
+		return
+	}
+
+	if err := api.auditor.Record(ctx, impersonateIdentityAction, audit.Successful, p); err != nil {
+		impersonateIdentityResponse.writeError(ctx, w, err)
+		return
+	}
+
+	impersonateIdentityResponse.writeEntity(ctx, w, response, http.StatusOK)
+	log.InfoCtx(ctx, "impersonateIdentity: impersonation token issued successfully", log.Data{"id": id, "requested_by": caller.ID})
+}
+
+func (api *API) impersonateIdentity(ctx context.Context, id string, admin schema.Identity) (*ImpersonationToken, error) {
+	i, err := api.IdentityService.Impersonate(ctx, id, admin)
+	if err != nil {
+		return nil, err
+	}
+
+	tkn, ttl, err := api.Tokens.NewToken(ctx, *i)
+	if err != nil {
+		return nil, err
+	}
+	return &ImpersonationToken{Token: tkn.ID, TTL: ttl, ID: i.ID, ImpersonatedBy: admin.ID}, nil
+}
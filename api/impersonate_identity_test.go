@@ -0,0 +1,217 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/ONSdigital/dp-identity-api/api/apitest"
+	"github.com/ONSdigital/dp-identity-api/identity"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/ONSdigital/go-ns/audit"
+	"github.com/ONSdigital/go-ns/audit/auditortest"
+	"github.com/ONSdigital/go-ns/common"
+	. "github.com/smartystreets/goconvey/convey"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const impersonateIdentityURL = "http://localhost:23800/identity/666/impersonate"
+
+func TestAPI_ImpersonateIdentityHandler(t *testing.T) {
+	Convey("given a request to impersonate an identity", t, func() {
+		admin := &schema.Identity{ID: adminID, UserType: schema.UserTypeAdmin}
+
+		auditMock := auditortest.New()
+		tokensMock := tokenServiceReturning(admin, nil)
+		tokensMock.NewTokenFunc = func(ctx context.Context, i schema.Identity) (*schema.Token, time.Duration, error) {
+			return &schema.Token{ID: "impersonation", IdentityID: i.ID, ImpersonatedBy: i.Impersonator.ID}, time.Minute * 15, nil
+		}
+
+		serviceMock := &apitest.IdentityServiceMock{
+			ImpersonateFunc: func(ctx context.Context, id string, admin schema.Identity) (*schema.Identity, error) {
+				return &schema.Identity{ID: id, UserType: schema.UserTypeUser, Impersonator: &admin}, nil
+			},
+		}
+
+		identityAPI := &API{auditor: auditMock, IdentityService: serviceMock, Tokens: tokensMock, Impersonation: true}
+
+		Convey("when the caller presents a token for an admin identity", func() {
+			w := httptest.NewRecorder()
+			identityAPI.ImpersonateIdentityHandler(w, newIdentityStatusRequest(impersonateIdentityURL, "1234", ""))
+
+			Convey("then an impersonation token is issued for the identity", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+
+				var resp ImpersonationToken
+				So(json.Unmarshal(w.Body.Bytes(), &resp), ShouldBeNil)
+				So(resp, ShouldResemble, ImpersonationToken{Token: "impersonation", TTL: time.Minute * 15, ID: ID, ImpersonatedBy: adminID})
+
+				So(serviceMock.ImpersonateCalls(), ShouldHaveLength, 1)
+				So(serviceMock.ImpersonateCalls()[0].ID, ShouldEqual, ID)
+				So(serviceMock.ImpersonateCalls()[0].Admin.ID, ShouldEqual, adminID)
+
+				So(tokensMock.NewTokenCalls(), ShouldHaveLength, 1)
+				So(tokensMock.NewTokenCalls()[0].Identity.Impersonator.ID, ShouldEqual, adminID)
+			})
+
+			Convey("and attempted and successful audit events are recorded", func() {
+				auditMock.AssertRecordCalls(
+					auditortest.Expected{Action: impersonateIdentityAction, Result: audit.Attempted, Params: common.Params{"id": ID}},
+					auditortest.Expected{Action: impersonateIdentityAction, Result: audit.Successful, Params: common.Params{"id": ID, "requested_by": adminID}},
+				)
+			})
+		})
+
+		Convey("when impersonation is disabled", func() {
+			identityAPI.Impersonation = false
+
+			w := httptest.NewRecorder()
+			identityAPI.ImpersonateIdentityHandler(w, newIdentityStatusRequest(impersonateIdentityURL, "1234", ""))
+
+			Convey("then status 403 is returned and no token is issued", func() {
+				assertErrorResponse(w.Code, http.StatusForbidden, w.Body.String(), ErrImpersonationDisabled.Error())
+				So(serviceMock.ImpersonateCalls(), ShouldHaveLength, 0)
+				So(tokensMock.NewTokenCalls(), ShouldHaveLength, 0)
+			})
+
+			Convey("and attempted and unsuccessful audit events are recorded", func() {
+				auditMock.AssertRecordCalls(
+					auditortest.Expected{Action: impersonateIdentityAction, Result: audit.Attempted, Params: common.Params{"id": ID}},
+					auditortest.Expected{Action: impersonateIdentityAction, Result: audit.Unsuccessful, Params: common.Params{"id": ID}},
+				)
+			})
+		})
+
+		Convey("when the caller presents a token for a non-admin identity", func() {
+			identityAPI.Tokens = tokenServiceReturning(&schema.Identity{ID: ID, UserType: schema.UserTypeService}, nil)
+
+			w := httptest.NewRecorder()
+			identityAPI.ImpersonateIdentityHandler(w, newIdentityStatusRequest(impersonateIdentityURL, "1234", ""))
+
+			Convey("then status 403 is returned and no token is issued", func() {
+				assertErrorResponse(w.Code, http.StatusForbidden, w.Body.String(), ErrForbidden.Error())
+				So(serviceMock.ImpersonateCalls(), ShouldHaveLength, 0)
+			})
+		})
+
+		Convey("when the identity is an admin", func() {
+			serviceMock.ImpersonateFunc = func(ctx context.Context, id string, admin schema.Identity) (*schema.Identity, error) {
+				return nil, identity.ErrImpersonateAdmin
+			}
+
+			w := httptest.NewRecorder()
+			identityAPI.ImpersonateIdentityHandler(w, newIdentityStatusRequest(impersonateIdentityURL, "1234", ""))
+
+			Convey("then status 403 is returned and no token is issued", func() {
+				assertErrorResponse(w.Code, http.StatusForbidden, w.Body.String(), identity.ErrImpersonateAdmin.Error())
+				So(tokensMock.NewTokenCalls(), ShouldHaveLength, 0)
+			})
+
+			Convey("and attempted and unsuccessful audit events are recorded", func() {
+				auditMock.AssertRecordCalls(
+					auditortest.Expected{Action: impersonateIdentityAction, Result: audit.Attempted, Params: common.Params{"id": ID}},
+					auditortest.Expected{Action: impersonateIdentityAction, Result: audit.Unsuccessful, Params: common.Params{"id": ID, "requested_by": adminID}},
+				)
+			})
+		})
+
+		Convey("when the identity does not exist", func() {
+			serviceMock.ImpersonateFunc = func(ctx context.Context, id string, admin schema.Identity) (*schema.Identity, error) {
+				return nil, identity.ErrIdentityNotFound
+			}
+
+			w := httptest.NewRecorder()
+			identityAPI.ImpersonateIdentityHandler(w, newIdentityStatusRequest(impersonateIdentityURL, "1234", ""))
+
+			Convey("then status 404 is returned", func() {
+				assertErrorResponse(w.Code, http.StatusNotFound, w.Body.String(), identity.ErrIdentityNotFound.Error())
+			})
+		})
+	})
+}
+
+func TestAPI_ImpersonationTokenUse(t *testing.T) {
+	Convey("given a request presenting an impersonation token", t, func() {
+		impersonated := &schema.Identity{
+			ID:           ID,
+			Name:         "Eleven",
+			Email:        "11@strangerthings.com",
+			UserType:     schema.UserTypeUser,
+			Impersonator: &schema.Identity{ID: adminID, Name: "Hopper", Email: "hopper@hawkins.gov", UserType: schema.UserTypeAdmin},
+		}
+
+		auditMock := auditortest.New()
+		identityAPI := &API{auditor: auditMock, Tokens: tokenServiceReturning(impersonated, nil), Impersonation: true}
+
+		r := httptest.NewRequest("GET", getIdentityURL, nil)
+		r.Header.Set(tokenHeaderKey, "1234")
+
+		Convey("when the identity of the token is requested", func() {
+			w := httptest.NewRecorder()
+			identityAPI.GetIdentityHandler(w, r)
+
+			Convey("then both the impersonated identity and the admin identity are returned", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+
+				var resp GetIdentityResponse
+				So(json.Unmarshal(w.Body.Bytes(), &resp), ShouldBeNil)
+				So(resp.ID, ShouldEqual, ID)
+				So(resp.Email, ShouldEqual, "11@strangerthings.com")
+				So(resp.ImpersonatedBy, ShouldResemble, &Impersonator{ID: adminID, Name: "Hopper", Email: "hopper@hawkins.gov", UserType: schema.UserTypeAdmin})
+			})
+
+			Convey("and the use of the impersonation token is audited", func() {
+				auditMock.AssertRecordCalls(
+					auditortest.Expected{Action: getIdentityAction, Result: audit.Attempted, Params: nil},
+					auditortest.Expected{Action: useImpersonationTokenAction, Result: audit.Successful, Params: common.Params{"id": ID, "impersonated_by": adminID, "request": "GET /identity"}},
+					auditortest.Expected{Action: getIdentityAction, Result: audit.Successful, Params: nil},
+				)
+			})
+		})
+
+		Convey("when impersonation is disabled", func() {
+			identityAPI.Impersonation = false
+
+			w := httptest.NewRecorder()
+			identityAPI.GetIdentityHandler(w, r)
+
+			Convey("then status 403 is returned", func() {
+				assertErrorResponse(w.Code, http.StatusForbidden, w.Body.String(), ErrImpersonationDisabled.Error())
+			})
+		})
+
+		Convey("when recording the use of the impersonation token fails", func() {
+			identityAPI.auditor = auditortest.NewErroring(useImpersonationTokenAction, audit.Successful)
+
+			w := httptest.NewRecorder()
+			identityAPI.GetIdentityHandler(w, r)
+
+			Convey("then status 500 is returned", func() {
+				assertErrorResponse(w.Code, http.StatusInternalServerError, w.Body.String(), ErrInternalServerError.Error())
+			})
+		})
+	})
+
+	Convey("given a request presenting a token issued to the identity itself", t, func() {
+		auditMock := auditortest.New()
+		identityAPI := &API{auditor: auditMock, Tokens: tokenServiceReturning(&schema.Identity{ID: ID}, nil)}
+
+		r := httptest.NewRequest("GET", getIdentityURL, nil)
+		r.Header.Set(tokenHeaderKey, "1234")
+
+		Convey("when the identity of the token is requested", func() {
+			w := httptest.NewRecorder()
+			identityAPI.GetIdentityHandler(w, r)
+
+			Convey("then no impersonator is returned and no impersonation is audited", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Body.String(), ShouldNotContainSubstring, "impersonated_by")
+				auditMock.AssertRecordCalls(
+					auditortest.Expected{Action: getIdentityAction, Result: audit.Attempted, Params: nil},
+					auditortest.Expected{Action: getIdentityAction, Result: audit.Successful, Params: nil},
+				)
+			})
+		})
+	})
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"github.com/ONSdigital/dp-identity-api/identity"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/ONSdigital/go-ns/audit"
+	"github.com/ONSdigital/go-ns/common"
+	"github.com/ONSdigital/go-ns/log"
+	"github.com/pkg/errors"
+	"net/http"
+	"strconv"
+)
+
+// ImportIdentitiesHandler is a POST HTTP handler for importing identities migrated from Zebedee, including their legacy
+// password hashes. A request to this endpoint will create an audit event showing an attempt to import identities was
+// made followed by another event - successful or unsuccessful depending on outcome of processing the request. If a
+// request is successful a report of the imported, skipped and conflicting emails is returned in the response. The
+// caller must present a token for an admin identity and is recorded as the requester in the audit events. The request
+// body may be up to maxImportRequestBodyBytes, rather than maxRequestBodyBytes, so identities can be imported in bulk.
+func (api *API) ImportIdentitiesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if auditErr := api.auditor.Record(ctx, importIdentitiesAction, audit.Attempted, nil); auditErr != nil {
+		importIdentitiesResponse.writeError(ctx, w, auditErr)
+		return
+	}
+
+	caller, err := api.authorizeAdmin(ctx, r)
+	if err != nil {
+		log.ErrorCtx(ctx, errors.Wrap(err, "importIdentities: caller not authorized"), nil)
+		api.auditor.Record(ctx, importIdentitiesAction, audit.Unsuccessful, nil)
+		importIdentitiesResponse.writeError(ctx, w, err)
+		return
+	}
+
+	p := common.Params{"requested_by": caller.ID}
+	report, err := api.importIdentities(ctx, r)
+
+	if err != nil {
+		log.ErrorCtx(ctx, errors.Wrap(err, "importIdentities: error"), log.Data{"requested_by": caller.ID})
+		api.auditor.Record(ctx, importIdentitiesAction, audit.Unsuccessful, p)
+		importIdentitiesResponse.writeError(ctx, w, err)
+		return
+	}
+
+	p["imported"] = strconv.Itoa(len(report.Imported))
+	p["skipped"] = strconv.Itoa(len(report.Skipped))
+	p["conflicting"] = strconv.Itoa(len(report.Conflicting))
+
+	if err = api.auditor.Record(ctx, importIdentitiesAction, audit.Successful, p); err != nil {
+		importIdentitiesResponse.writeError(ctx, w, err)
+		return
+	}
+
+	importIdentitiesResponse.writeEntity(ctx, w, report, http.StatusOK)
+	log.InfoCtx(ctx, "importIdentities: import completed successfully", log.Data{"requested_by": caller.ID})
+}
+
+func (api *API) importIdentities(ctx context.Context, r *http.Request) (*identity.ImportReport, error) {
+	var req ImportIdentitiesRequest
+	if err := decodeRequestLimit(r.Body, &req, maxImportRequestBodyBytes); err != nil {
+		return nil, err
+	}
+
+	identities := make([]schema.Identity, 0, len(req.Identities))
+	for _, legacy := range req.Identities {
+		identities = append(identities, schema.Identity{
+			Name:              legacy.Name,
+			Email:             legacy.Email,
+			Password:          legacy.PasswordHash,
+			UserType:          legacy.UserType,
+			TemporaryPassword: legacy.TemporaryPassword,
+		})
+	}
+
+	return api.IdentityService.Import(ctx, identities)
+}
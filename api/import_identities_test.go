@@ -0,0 +1,318 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/ONSdigital/dp-identity-api/api/apitest"
+	"github.com/ONSdigital/dp-identity-api/identity"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/ONSdigital/go-ns/audit"
+	"github.com/ONSdigital/go-ns/audit/auditortest"
+	"github.com/ONSdigital/go-ns/common"
+	. "github.com/smartystreets/goconvey/convey"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const (
+	importIdentitiesURL = "http://localhost:23800/identity/import"
+)
+
+var (
+	importAdmin = &schema.Identity{ID: "999", UserType: schema.UserTypeAdmin}
+
+	testImportRequest = ImportIdentitiesRequest{
+		Identities: []LegacyIdentity{
+			{
+				Name:         "Peter Venkman",
+				Email:        "venkman@whoyougunnacall.com",
+				PasswordHash: "$2a$10$legacy",
+				UserType:     "admin",
+			},
+		},
+	}
+)
+
+func TestIdentityAPI_ImportIdentitiesSuccess(t *testing.T) {
+	Convey("given import identities is successful", t, func() {
+		auditMock := auditortest.New()
+		report := &identity.ImportReport{
+			Imported:    []string{"venkman@whoyougunnacall.com"},
+			Skipped:     []string{},
+			Conflicting: []string{},
+		}
+
+		serviceMock := &apitest.IdentityServiceMock{
+			ImportFunc: func(ctx context.Context, identities []schema.Identity) (*identity.ImportReport, error) {
+				return report, nil
+			},
+		}
+
+		Convey("when importIdentities is called", func() {
+			identityAPI := &API{
+				auditor:         auditMock,
+				IdentityService: serviceMock,
+				Tokens:          tokenServiceReturning(importAdmin, nil),
+			}
+
+			b, err := json.Marshal(testImportRequest)
+			So(err, ShouldBeNil)
+
+			r := httptest.NewRequest(http.MethodPost, importIdentitiesURL, bytes.NewReader(b))
+			r.Header.Set(tokenHeaderKey, "666")
+			w := httptest.NewRecorder()
+			identityAPI.ImportIdentitiesHandler(w, r)
+
+			Convey("then a HTTP 200 status is returned with the import report", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+
+				var actual identity.ImportReport
+				err := json.Unmarshal(w.Body.Bytes(), &actual)
+				So(err, ShouldBeNil)
+				So(&actual, ShouldResemble, report)
+			})
+
+			Convey("and the legacy password hash is passed to the identity service", func() {
+				So(serviceMock.ImportCalls(), ShouldHaveLength, 1)
+				So(serviceMock.ImportCalls()[0].Identities, ShouldResemble, []schema.Identity{
+					{
+						Name:     "Peter Venkman",
+						Email:    "venkman@whoyougunnacall.com",
+						Password: "$2a$10$legacy",
+						UserType: "admin",
+					},
+				})
+			})
+
+			Convey("and attempted and successful audit events are recorded", func() {
+				auditMock.AssertRecordCalls(
+					auditortest.Expected{Action: importIdentitiesAction, Result: audit.Attempted, Params: nil},
+					auditortest.Expected{Action: importIdentitiesAction, Result: audit.Successful, Params: common.Params{
+						"requested_by": "999",
+						"imported":     "1",
+						"skipped":      "0",
+						"conflicting":  "0",
+					}},
+				)
+			})
+		})
+	})
+}
+
+func TestIdentityAPI_ImportIdentitiesBodySize(t *testing.T) {
+	Convey("given an import request of legacy identities", t, func() {
+		serviceMock := &apitest.IdentityServiceMock{
+			ImportFunc: func(ctx context.Context, identities []schema.Identity) (*identity.ImportReport, error) {
+				return &identity.ImportReport{}, nil
+			},
+		}
+
+		identityAPI := &API{
+			auditor:         auditortest.New(),
+			IdentityService: serviceMock,
+			Tokens:          tokenServiceReturning(importAdmin, nil),
+		}
+
+		record, err := json.Marshal(testImportRequest.Identities[0])
+		So(err, ShouldBeNil)
+
+		// the number of records, each followed by a comma, that fit within the import limit.
+		fit := maxImportRequestBodyBytes/(len(record)+1) - 1
+
+		importRequest := func(records int) *httptest.ResponseRecorder {
+			req := ImportIdentitiesRequest{Identities: make([]LegacyIdentity, records)}
+			for i := range req.Identities {
+				req.Identities[i] = testImportRequest.Identities[0]
+			}
+
+			b, err := json.Marshal(req)
+			So(err, ShouldBeNil)
+
+			r := httptest.NewRequest(http.MethodPost, importIdentitiesURL, bytes.NewReader(b))
+			r.Header.Set(tokenHeaderKey, "666")
+			w := httptest.NewRecorder()
+			identityAPI.ImportIdentitiesHandler(w, r)
+			return w
+		}
+
+		Convey("when the body is just within the import limit, far above the default limit", func() {
+			So(fit*len(record), ShouldBeGreaterThan, maxRequestBodyBytes)
+			w := importRequest(fit)
+
+			Convey("then every identity is imported", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(serviceMock.ImportCalls(), ShouldHaveLength, 1)
+				So(serviceMock.ImportCalls()[0].Identities, ShouldHaveLength, fit)
+			})
+		})
+
+		Convey("when the body exceeds the import limit", func() {
+			w := importRequest(fit + 2)
+
+			Convey("then status 413 is returned and nothing is imported", func() {
+				assertErrorResponse(w.Code, http.StatusRequestEntityTooLarge, w.Body.String(), ErrRequestBodyTooLarge.Error())
+				So(serviceMock.ImportCalls(), ShouldHaveLength, 0)
+			})
+		})
+	})
+}
+
+func TestIdentityAPI_ImportIdentitiesError(t *testing.T) {
+	Convey("given the identity service returns an error", t, func() {
+		auditMock := auditortest.New()
+		serviceMock := &apitest.IdentityServiceMock{
+			ImportFunc: func(ctx context.Context, identities []schema.Identity) (*identity.ImportReport, error) {
+				return nil, identity.ErrPersistence
+			},
+		}
+
+		Convey("when importIdentities is called", func() {
+			identityAPI := &API{
+				auditor:         auditMock,
+				IdentityService: serviceMock,
+				Tokens:          tokenServiceReturning(importAdmin, nil),
+			}
+
+			b, err := json.Marshal(testImportRequest)
+			So(err, ShouldBeNil)
+
+			r := httptest.NewRequest(http.MethodPost, importIdentitiesURL, bytes.NewReader(b))
+			r.Header.Set(tokenHeaderKey, "666")
+			w := httptest.NewRecorder()
+			identityAPI.ImportIdentitiesHandler(w, r)
+
+			Convey("then the expected error response is returned", func() {
+				assertErrorResponse(w.Code, http.StatusInternalServerError, w.Body.String(), ErrInternalServerError.Error())
+			})
+
+			Convey("and attempted and unsuccessful audit events are recorded", func() {
+				auditMock.AssertRecordCalls(
+					auditortest.Expected{Action: importIdentitiesAction, Result: audit.Attempted, Params: nil},
+					auditortest.Expected{Action: importIdentitiesAction, Result: audit.Unsuccessful, Params: common.Params{"requested_by": "999"}},
+				)
+			})
+		})
+	})
+
+	Convey("given the identity service returns identity.ErrInvalidPasswordHash", t, func() {
+		serviceMock := &apitest.IdentityServiceMock{
+			ImportFunc: func(ctx context.Context, identities []schema.Identity) (*identity.ImportReport, error) {
+				return nil, identity.ErrInvalidPasswordHash
+			},
+		}
+
+		identityAPI := &API{
+			auditor:         auditortest.New(),
+			IdentityService: serviceMock,
+			Tokens:          tokenServiceReturning(importAdmin, nil),
+		}
+
+		b, err := json.Marshal(testImportRequest)
+		So(err, ShouldBeNil)
+
+		r := httptest.NewRequest(http.MethodPost, importIdentitiesURL, bytes.NewReader(b))
+		r.Header.Set(tokenHeaderKey, "666")
+		w := httptest.NewRecorder()
+		identityAPI.ImportIdentitiesHandler(w, r)
+
+		So(w.Code, ShouldEqual, http.StatusBadRequest)
+		So(w.Body.String(), ShouldContainSubstring, "invalid_password_hash")
+	})
+
+	Convey("given the request body is empty", t, func() {
+		auditMock := auditortest.New()
+		serviceMock := &apitest.IdentityServiceMock{}
+
+		Convey("when importIdentities is called", func() {
+			identityAPI := &API{
+				auditor:         auditMock,
+				IdentityService: serviceMock,
+				Tokens:          tokenServiceReturning(importAdmin, nil),
+			}
+
+			r := httptest.NewRequest(http.MethodPost, importIdentitiesURL, nil)
+			r.Header.Set(tokenHeaderKey, "666")
+			w := httptest.NewRecorder()
+			identityAPI.ImportIdentitiesHandler(w, r)
+
+			Convey("then the expected error response is returned", func() {
+				assertErrorResponse(w.Code, http.StatusBadRequest, w.Body.String(), ErrRequestBodyNil.Error())
+			})
+
+			Convey("and the identity service is never called", func() {
+				So(serviceMock.ImportCalls(), ShouldHaveLength, 0)
+			})
+		})
+	})
+}
+
+func TestIdentityAPI_ImportIdentitiesUnauthorized(t *testing.T) {
+	Convey("given a request without a token", t, func() {
+		auditMock := auditortest.New()
+		serviceMock := &apitest.IdentityServiceMock{}
+		identityAPI := &API{auditor: auditMock, IdentityService: serviceMock}
+
+		b, err := json.Marshal(testImportRequest)
+		So(err, ShouldBeNil)
+
+		w := httptest.NewRecorder()
+		identityAPI.ImportIdentitiesHandler(w, httptest.NewRequest(http.MethodPost, importIdentitiesURL, bytes.NewReader(b)))
+
+		Convey("then status 401 is returned and the identity service is never called", func() {
+			assertErrorResponse(w.Code, http.StatusUnauthorized, w.Body.String(), ErrNoTokenProvided.Error())
+			So(serviceMock.ImportCalls(), ShouldHaveLength, 0)
+		})
+
+		Convey("and attempted and unsuccessful audit events are recorded", func() {
+			auditMock.AssertRecordCalls(
+				auditortest.Expected{Action: importIdentitiesAction, Result: audit.Attempted, Params: nil},
+				auditortest.Expected{Action: importIdentitiesAction, Result: audit.Unsuccessful, Params: nil},
+			)
+		})
+	})
+
+	Convey("given a request with the token of an identity that is not an admin", t, func() {
+		for _, userType := range []string{schema.UserTypeService, schema.UserTypeUser} {
+			serviceMock := &apitest.IdentityServiceMock{}
+			identityAPI := &API{
+				auditor:         auditortest.New(),
+				IdentityService: serviceMock,
+				Tokens:          tokenServiceReturning(&schema.Identity{ID: "999", UserType: userType}, nil),
+			}
+
+			b, err := json.Marshal(testImportRequest)
+			So(err, ShouldBeNil)
+
+			r := httptest.NewRequest(http.MethodPost, importIdentitiesURL, bytes.NewReader(b))
+			r.Header.Set(tokenHeaderKey, "666")
+			w := httptest.NewRecorder()
+			identityAPI.ImportIdentitiesHandler(w, r)
+
+			assertErrorResponse(w.Code, http.StatusForbidden, w.Body.String(), ErrForbidden.Error())
+			So(serviceMock.ImportCalls(), ShouldHaveLength, 0)
+		}
+	})
+}
+
+func TestIdentityAPI_ImportIdentitiesUnknownField(t *testing.T) {
+	Convey("given a request body containing an unknown field", t, func() {
+		serviceMock := &apitest.IdentityServiceMock{}
+		identityAPI := &API{
+			auditor:         auditortest.New(),
+			IdentityService: serviceMock,
+			Tokens:          tokenServiceReturning(importAdmin, nil),
+		}
+
+		r := httptest.NewRequest(http.MethodPost, importIdentitiesURL, bytes.NewReader([]byte(`{"identities": [], "admin": true}`)))
+		r.Header.Set(tokenHeaderKey, "666")
+		w := httptest.NewRecorder()
+		identityAPI.ImportIdentitiesHandler(w, r)
+
+		Convey("then status 400 is returned and the identity service is never called", func() {
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+			So(serviceMock.ImportCalls(), ShouldHaveLength, 0)
+		})
+	})
+}
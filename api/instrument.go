@@ -0,0 +1,59 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ONSdigital/dp-identity-api/metrics"
+	"github.com/ONSdigital/dp-identity-api/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// statusRecorder is a http.ResponseWriter capturing the status code written by the wrapped handler, and whether the
+// response has been started.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.wroteHeader = true
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	s.wroteHeader = true
+	return s.ResponseWriter.Write(b)
+}
+
+// instrument wraps the handler recording the time taken to handle each request and its response status. Each request
+// is handled in a span named after the handler, continuing any trace propagated in the request headers.
+func (api *API) instrument(handler string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		ctx, span := tracing.Start(tracing.Extract(r), handler,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.HTTPServerAttributesFromHTTPRequest("", "", r)...),
+		)
+		defer span.End()
+
+		h(rec, r.WithContext(ctx))
+
+		span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(rec.status)...)
+		span.SetStatus(semconv.SpanStatusFromHTTPStatusCode(rec.status))
+		api.recorder().ObserveHandler(handler, rec.status, time.Since(start))
+	}
+}
+
+// recorder return the configured metrics.Recorder, or a no-op recorder if none is configured.
+func (api *API) recorder() metrics.Recorder {
+	if api.Metrics == nil {
+		return metrics.Nop{}
+	}
+	return api.Metrics
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/ONSdigital/dp-identity-api/api/apitest"
+	"github.com/ONSdigital/dp-identity-api/identity"
+	"github.com/ONSdigital/dp-identity-api/metrics/metricstest"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/ONSdigital/go-ns/audit/auditortest"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAPI_Instrument(t *testing.T) {
+	Convey("given an instrumented handler", t, func() {
+		m := &metricstest.RecorderMock{
+			ObserveHandlerFunc: func(handler string, status int, duration time.Duration) {},
+		}
+		a := &API{Metrics: m}
+
+		Convey("when the handler writes a status", func() {
+			h := a.instrument(createToken, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+			})
+			w := httptest.NewRecorder()
+			h(w, httptest.NewRequest(http.MethodPost, authenticateURL, nil))
+
+			Convey("then the handler name and status are recorded", func() {
+				So(w.Code, ShouldEqual, http.StatusForbidden)
+				So(m.ObserveHandlerCalls(), ShouldHaveLength, 1)
+				So(m.ObserveHandlerCalls()[0].Handler, ShouldEqual, createToken)
+				So(m.ObserveHandlerCalls()[0].Status, ShouldEqual, http.StatusForbidden)
+			})
+		})
+
+		Convey("when the handler does not write a status", func() {
+			h := a.instrument(getIdentityAction, func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("{}"))
+			})
+			h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, authenticateURL, nil))
+
+			Convey("then status 200 is recorded", func() {
+				So(m.ObserveHandlerCalls(), ShouldHaveLength, 1)
+				So(m.ObserveHandlerCalls()[0].Status, ShouldEqual, http.StatusOK)
+			})
+		})
+	})
+}
+
+func TestAPI_InstrumentNilRecorder(t *testing.T) {
+	Convey("instrumented handler should be called if no recorder is configured", t, func() {
+		called := false
+		h := (&API{}).instrument(createToken, func(w http.ResponseWriter, r *http.Request) {
+			called = true
+		})
+		h(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, authenticateURL, nil))
+
+		So(called, ShouldBeTrue)
+	})
+}
+
+func TestAPI_AuthenticationUnsuccessfulRecordsLoginFailure(t *testing.T) {
+	Convey("should record a login failure with the error code as the reason", t, func() {
+		m := &metricstest.RecorderMock{
+			LoginFailedFunc: func(reason string) {},
+		}
+		s := &apitest.IdentityServiceMock{
+			VerifyPasswordFunc: func(ctx context.Context, id string, password string) (*schema.Identity, error) {
+				return nil, identity.ErrAuthenticateFailed
+			},
+		}
+
+		b, err := json.Marshal(testAuthReq)
+		So(err, ShouldBeNil)
+
+		r := httptest.NewRequest(http.MethodPost, authenticateURL, bytes.NewReader(b))
+		w := httptest.NewRecorder()
+
+		authAPI := API{
+			auditor:         auditortest.New(),
+			IdentityService: s,
+			Metrics:         m,
+		}
+
+		authAPI.CreateTokenHandler(w, r)
+
+		So(w.Code, ShouldEqual, http.StatusForbidden)
+		So(m.LoginFailedCalls(), ShouldHaveLength, 1)
+		So(m.LoginFailedCalls()[0].Reason, ShouldEqual, "authentication_failed")
+	})
+}
+
+func TestAPI_InstrumentTracing(t *testing.T) {
+	Convey("given a request with a W3C traceparent header", t, func() {
+		sr := tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+
+		r := httptest.NewRequest(http.MethodGet, authenticateURL, nil)
+		r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+		Convey("when an instrumented handler returns a server error", func() {
+			var handlerSpan trace.SpanContext
+			h := (&API{}).instrument(getIdentityAction, func(w http.ResponseWriter, r *http.Request) {
+				handlerSpan = trace.SpanContextFromContext(r.Context())
+				w.WriteHeader(http.StatusInternalServerError)
+			})
+			h(httptest.NewRecorder(), r)
+
+			Convey("then the request is handled in a server span continuing the propagated trace", func() {
+				So(sr.Ended(), ShouldHaveLength, 1)
+				span := sr.Ended()[0]
+				So(span.Name(), ShouldEqual, getIdentityAction)
+				So(span.SpanKind(), ShouldEqual, trace.SpanKindServer)
+				So(span.SpanContext().TraceID().String(), ShouldEqual, "4bf92f3577b34da6a3ce929d0e0e4736")
+				So(span.SpanContext().SpanID(), ShouldEqual, handlerSpan.SpanID())
+				So(span.Status().Code, ShouldEqual, codes.Error)
+			})
+		})
+
+		Reset(func() {
+			otel.SetTracerProvider(trace.NewNoopTracerProvider())
+		})
+	})
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"time"
+
+	"github.com/ONSdigital/go-ns/common"
+	"github.com/ONSdigital/go-ns/log"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+// requestIDSize is the length of generated request IDs.
+const requestIDSize = 16
+
+// validRequestID matches the request IDs accepted from callers. Other IDs are replaced so callers can't inject
+// arbitrary content into the logs.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// accessLogKey is the context key of the accessLogEntry of a request.
+type accessLogKey struct{}
+
+// accessLogEntry holds the details of a request only known to the handler, such as the caller's identity.
+type accessLogEntry struct {
+	identity string
+}
+
+// requestID wraps the handler with the request ID in the X-Request-Id header, or a generated ID if none is provided,
+// added to the request context so it is included in logs and error responses. The ID is returned in the response
+// header.
+func (api *API) requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(common.RequestHeaderKey)
+		if !validRequestID.MatchString(id) {
+			id = common.NewRequestID(requestIDSize)
+			r.Header.Set(common.RequestHeaderKey, id)
+		}
+
+		w.Header().Set(common.RequestHeaderKey, id)
+		next.ServeHTTP(w, r.WithContext(common.WithRequestId(r.Context(), id)))
+	})
+}
+
+// accessLog wraps the handler logging each request with its response status and the time taken. The route template
+// is logged rather than the path, as paths may contain verification and email change tokens. The caller's identity is
+// logged as a pseudonym, so requests by the same identity can be correlated without logging its ID.
+func (api *API) accessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		entry := &accessLogEntry{}
+
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), accessLogKey{}, entry)))
+
+		end := time.Now()
+		data := log.Data{
+			"start":    start,
+			"end":      end,
+			"duration": end.Sub(start),
+			"status":   rec.status,
+			"method":   r.Method,
+		}
+
+		if route := mux.CurrentRoute(r); route != nil {
+			if template, err := route.GetPathTemplate(); err == nil {
+				data["route"] = template
+			}
+		}
+
+		if entry.identity != "" {
+			data["identity"] = entry.identity
+		}
+		log.Event("request", common.GetRequestId(r.Context()), data)
+	})
+}
+
+// recoverPanic wraps the handler writing a JSON internal server error response if it panics, unless the handler had
+// already written a response. The panic and stack trace are logged.
+func (api *API) recoverPanic(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+
+			ctx := r.Context()
+			log.ErrorCtx(ctx, errors.Errorf("recovered from panic in handler: %v", v), log.Data{"stack": string(debug.Stack())})
+
+			if !rec.wroteHeader {
+				JSONResponseWriter{}.writeError(ctx, rec, ErrInternalServerError)
+			}
+		}()
+
+		next.ServeHTTP(rec, r)
+	})
+}
+
+// logIdentity records the identity of the caller in the access log of the request, if it is logged.
+func logIdentity(ctx context.Context, id string) {
+	if entry, ok := ctx.Value(accessLogKey{}).(*accessLogEntry); ok {
+		entry.identity = anonymise(id)
+	}
+}
+
+// anonymise return a pseudonym of the identity ID which can't be reversed to the ID.
+func anonymise(id string) string {
+	digest := sha256.Sum256([]byte(id))
+	return fmt.Sprintf("%.16s", hex.EncodeToString(digest[:]))
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/ONSdigital/go-ns/common"
+	"github.com/ONSdigital/go-ns/log"
+	"github.com/gorilla/mux"
+	. "github.com/smartystreets/goconvey/convey"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// loggedEvent is an event written using log.Event.
+type loggedEvent struct {
+	name           string
+	correlationKey string
+	data           log.Data
+}
+
+// captureLogEvents replace log.Event, returning the events logged until the convey scope is reset.
+func captureLogEvents() *[]loggedEvent {
+	events := &[]loggedEvent{}
+	original := log.Event
+	log.Event = func(name string, correlationKey string, data log.Data) {
+		*events = append(*events, loggedEvent{name: name, correlationKey: correlationKey, data: data})
+	}
+	Reset(func() {
+		log.Event = original
+	})
+	return events
+}
+
+// requestEvents return the access log events.
+func requestEvents(events []loggedEvent) []loggedEvent {
+	var requests []loggedEvent
+	for _, e := range events {
+		if e.name == "request" {
+			requests = append(requests, e)
+		}
+	}
+	return requests
+}
+
+func TestAPI_RequestID(t *testing.T) {
+	Convey("given a handler wrapped with the request ID middleware", t, func() {
+		var requestID string
+		h := (&API{}).requestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID = common.GetRequestId(r.Context())
+		}))
+
+		Convey("when a request provides a request ID", func() {
+			r := httptest.NewRequest(http.MethodGet, createIdentityURL, nil)
+			r.Header.Set("X-Request-Id", "abc-123")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			Convey("then the provided ID is added to the context and response", func() {
+				So(requestID, ShouldEqual, "abc-123")
+				So(w.Header().Get("X-Request-Id"), ShouldEqual, "abc-123")
+			})
+		})
+
+		Convey("when a request does not provide a request ID", func() {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, createIdentityURL, nil))
+
+			Convey("then an ID is generated", func() {
+				So(requestID, ShouldHaveLength, requestIDSize)
+				So(w.Header().Get("X-Request-Id"), ShouldEqual, requestID)
+			})
+		})
+
+		Convey("when a request provides an invalid request ID", func() {
+			r := httptest.NewRequest(http.MethodGet, createIdentityURL, nil)
+			r.Header.Set("X-Request-Id", "abc\n{\"level\":\"fake\"}")
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			Convey("then it is replaced with a generated ID", func() {
+				So(requestID, ShouldHaveLength, requestIDSize)
+			})
+		})
+	})
+}
+
+func TestAPI_AccessLog(t *testing.T) {
+	Convey("given a routed handler wrapped with the access log middleware", t, func() {
+		events := captureLogEvents()
+		a := &API{}
+
+		router := mux.NewRouter()
+		router.Use(a.requestID, a.accessLog)
+		router.HandleFunc("/identity/verify/{token}", func(w http.ResponseWriter, r *http.Request) {
+			logIdentity(r.Context(), "666")
+			w.WriteHeader(http.StatusForbidden)
+		})
+
+		Convey("when a request is handled", func() {
+			r := httptest.NewRequest(http.MethodPost, "http://localhost:23800/identity/verify/secret", nil)
+			r.Header.Set("X-Request-Id", "abc-123")
+			router.ServeHTTP(httptest.NewRecorder(), r)
+
+			requests := requestEvents(*events)
+			So(requests, ShouldHaveLength, 1)
+			e := requests[0]
+
+			Convey("then the request is logged with its ID, status and duration", func() {
+				So(e.correlationKey, ShouldEqual, "abc-123")
+				So(e.data["status"], ShouldEqual, http.StatusForbidden)
+				So(e.data["method"], ShouldEqual, http.MethodPost)
+				So(e.data, ShouldContainKey, "duration")
+			})
+
+			Convey("and the route template is logged instead of the path", func() {
+				So(e.data["route"], ShouldEqual, "/identity/verify/{token}")
+				So(e.data, ShouldNotContainKey, "path")
+			})
+
+			Convey("and the identity is logged as a pseudonym", func() {
+				So(e.data["identity"], ShouldEqual, anonymise("666"))
+				So(e.data["identity"], ShouldNotEqual, "666")
+			})
+		})
+	})
+}
+
+func TestAPI_RecoverPanic(t *testing.T) {
+	Convey("given a handler that panics", t, func() {
+		h := (&API{}).recoverPanic(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("ghost in the machine")
+		}))
+
+		Convey("when a request is handled", func() {
+			r := httptest.NewRequest(http.MethodGet, createIdentityURL, nil)
+			r = r.WithContext(common.WithRequestId(r.Context(), "abc-123"))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			Convey("then the standard internal server error response is returned", func() {
+				assertErrorResponse(w.Code, http.StatusInternalServerError, w.Body.String(), ErrInternalServerError.Error())
+
+				var body ErrorResponse
+				So(json.Unmarshal(w.Body.Bytes(), &body), ShouldBeNil)
+				So(body.RequestID, ShouldEqual, "abc-123")
+			})
+		})
+	})
+
+	Convey("given a handler that panics after writing its response", t, func() {
+		h := (&API{}).recoverPanic(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			panic("ghost in the machine")
+		}))
+
+		Convey("when a request is handled", func() {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, createIdentityURL, nil))
+
+			Convey("then the response is not replaced", func() {
+				So(w.Code, ShouldEqual, http.StatusCreated)
+				So(w.Body.Len(), ShouldEqual, 0)
+			})
+		})
+	})
+}
+
+func TestAPI_RegisterEndpointsMiddleware(t *testing.T) {
+	Convey("given the endpoints are registered and a handler panics", t, func() {
+		events := captureLogEvents()
+		// the API has no auditor or token service so every handler panics.
+		a := &API{}
+
+		router := mux.NewRouter()
+		a.RegisterEndpoints(router)
+
+		Convey("when a request is made", func() {
+			r := httptest.NewRequest(http.MethodGet, createIdentityURL, nil)
+			r.Header.Set(tokenHeaderKey, "666")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			Convey("then a JSON 500 is returned with a request ID", func() {
+				assertErrorResponse(w.Code, http.StatusInternalServerError, w.Body.String(), ErrInternalServerError.Error())
+				So(w.Header().Get("X-Request-Id"), ShouldNotBeEmpty)
+			})
+
+			Convey("and the request is logged with the 500 status", func() {
+				requests := requestEvents(*events)
+				So(requests, ShouldHaveLength, 1)
+				So(requests[0].data["status"], ShouldEqual, http.StatusInternalServerError)
+				So(requests[0].correlationKey, ShouldEqual, w.Header().Get("X-Request-Id"))
+			})
+		})
+	})
+}
@@ -3,7 +3,12 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"github.com/ONSdigital/dp-identity-api/identity"
+	"github.com/ONSdigital/dp-identity-api/metrics"
+	"github.com/ONSdigital/dp-identity-api/ratelimit"
 	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/ONSdigital/dp-identity-api/token"
 	"github.com/ONSdigital/go-ns/audit"
 	"github.com/ONSdigital/go-ns/log"
 	"github.com/pkg/errors"
@@ -12,16 +17,34 @@ import (
 	"time"
 )
 
-//go:generate moq -out apitest/generate_mocks.go -pkg apitest . IdentityService TokenService
+//go:generate moq -out apitest/generate_mocks.go -pkg apitest . IdentityService TokenService RateLimiter
 
 const (
-	getIdentityAction    = "getIdentity"
-	createIdentityAction = "createIdentity"
-	createToken          = "createToken"
-	identityURIFormat    = "%s/identity/%s"
-	headerContentType    = "content-type"
-	mimeTypeJSON         = "application/json"
-	tokenHeaderKey       = "token"
+	getIdentityAction      = "getIdentity"
+	createIdentityAction   = "createIdentity"
+	createToken            = "createToken"
+	importIdentitiesAction = "importIdentities"
+	verifyIdentityAction   = "verifyIdentity"
+
+	requestEmailChangeAction    = "requestEmailChange"
+	confirmEmailChangeAction    = "confirmEmailChange"
+	disableIdentityAction       = "disableIdentity"
+	enableIdentityAction        = "enableIdentity"
+	exportIdentityAction        = "exportIdentity"
+	eraseIdentityAction         = "eraseIdentity"
+	impersonateIdentityAction   = "impersonateIdentity"
+	useImpersonationTokenAction = "useImpersonationToken"
+	identityURIFormat           = "%s/v1/identity/%s"
+	headerContentType           = "content-type"
+	mimeTypeJSON                = "application/json"
+	tokenHeaderKey              = "token"
+
+	// maxRequestBodyBytes is the maximum size of a strictly decoded request body.
+	maxRequestBodyBytes = 64 * 1024
+
+	// maxImportRequestBodyBytes is the maximum size of an import identities request body, large enough for tens of
+	// thousands of legacy identities so a Zebedee migration can be made in a few requests.
+	maxImportRequestBodyBytes = 16 * 1024 * 1024
 )
 
 var (
@@ -29,32 +52,217 @@ var (
 	ErrFailedToUnmarshalRequestBody = errors.New("error while attempting to unmarshal request body")
 	ErrRequestBodyNil               = errors.New("error expected request body but was empty")
 	ErrNoTokenProvided              = errors.New("error expected token was not provided.")
+	ErrRequestBodyTooLarge          = errors.New("request body exceeds the maximum size")
+	ErrUnknownRequestField          = errors.New("request body contains an unknown field")
+	ErrNoVerificationToken          = errors.New("error expected verification token was not provided")
+	ErrNoEmailChangeToken           = errors.New("error expected email change token was not provided")
 )
 
+// UnknownFieldErr is returned if a request body contains a field that is not in the request entity. Its cause is
+// ErrUnknownRequestField so it resolves to the same status and code.
+type UnknownFieldErr struct {
+	field string
+}
+
+// Error return the unknown field error message including the name of the field.
+func (e UnknownFieldErr) Error() string {
+	return fmt.Sprintf("%s %q", ErrUnknownRequestField.Error(), e.field)
+}
+
+// Field return the name of the unknown field.
+func (e UnknownFieldErr) Field() string {
+	return e.field
+}
+
+// Cause return ErrUnknownRequestField.
+func (e UnknownFieldErr) Cause() error {
+	return ErrUnknownRequestField
+}
+
 //API defines HTTP HandlerFunc's for the endpoints offered by the Identity API service.
 type API struct {
 	Host               string
 	IdentityService    IdentityService
 	Tokens             TokenService
+	Digester           token.Digester
+	Metrics            metrics.Recorder
+	BootstrapSecret    string
+	SelfRegistration   bool
+	Impersonation      bool
+	RateLimiter        RateLimiter
+	IPRateLimit        ratelimit.Limit
+	TokenRateLimit     ratelimit.Limit
+	TrustForwardedFor  bool
+	UnversionedSunset  time.Time
 	healthCheckTimeout time.Duration
 	auditor            audit.AuditorService
 }
 
+// CreateIdentityRequest is the HTTP request entity for creating an identity. Fields of the identity managed by the
+// service, such as its ID and created date, can't be set by the caller.
+type CreateIdentityRequest struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	UserType string `json:"user_type"`
+}
+
+// toIdentity return a new identity with the fields set in the request.
+func (req CreateIdentityRequest) toIdentity() *schema.Identity {
+	return &schema.Identity{
+		Name:     req.Name,
+		Email:    req.Email,
+		Password: req.Password,
+		UserType: req.UserType,
+	}
+}
+
 //IdentityCreated is the HTTP response entity for create identity success.
 type IdentityCreated struct {
 	ID  string `json:"id"`
 	URI string `json:"uri"`
 }
 
+// IdentityVerified is the HTTP response entity for verify identity success.
+type IdentityVerified struct {
+	ID       string `json:"id"`
+	Verified bool   `json:"verified"`
+}
+
+// EmailChangeRequest is the HTTP request entity for requesting a change to an identity's email.
+type EmailChangeRequest struct {
+	Email string `json:"email"`
+}
+
+// EmailChangeRequested is the HTTP response entity for request email change success. The email is not changed until
+// the change is confirmed using the token sent to the pending email.
+type EmailChangeRequested struct {
+	ID           string `json:"id"`
+	PendingEmail string `json:"pending_email"`
+}
+
+// EmailChanged is the HTTP response entity for confirm email change success.
+type EmailChanged struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+}
+
+// DisableIdentityRequest is the HTTP request entity for disabling an identity.
+type DisableIdentityRequest struct {
+	Reason string `json:"reason"`
+}
+
+// IdentityStatus is the HTTP response entity for disable and enable identity success. DisabledReason and DisabledBy
+// are only set while the identity is disabled.
+type IdentityStatus struct {
+	ID             string `json:"id"`
+	Disabled       bool   `json:"disabled"`
+	DisabledReason string `json:"disabled_reason,omitempty"`
+	DisabledBy     string `json:"disabled_by,omitempty"`
+}
+
+// IdentityExport is the HTTP response entity for export identity success, containing the data stored about the
+// identity. Password hashes and token digests are never included.
+type IdentityExport struct {
+	Identity    ExportedIdentity    `json:"identity"`
+	Tokens      []ExportedToken     `json:"tokens"`
+	AuditEvents []schema.AuditEvent `json:"audit_events"`
+}
+
+// ExportedIdentity is the stored identity in an IdentityExport.
+type ExportedIdentity struct {
+	ID                string     `json:"id"`
+	Name              string     `json:"name"`
+	Email             string     `json:"email"`
+	UserType          string     `json:"user_type"`
+	TemporaryPassword bool       `json:"temporary_password"`
+	Migrated          bool       `json:"migrated"`
+	Deleted           bool       `json:"deleted"`
+	CreatedDate       time.Time  `json:"created_date"`
+	Verified          bool       `json:"verified"`
+	PendingEmail      string     `json:"pending_email,omitempty"`
+	Disabled          bool       `json:"disabled"`
+	DisabledReason    string     `json:"disabled_reason,omitempty"`
+	DisabledBy        string     `json:"disabled_by,omitempty"`
+	DisabledDate      *time.Time `json:"disabled_date,omitempty"`
+}
+
+// ExportedToken is the metadata of a token issued to the identity in an IdentityExport. Deleted is true if the token
+// has been revoked or replaced, ImpersonatedBy is the ID of the admin identity the token was issued to if it is an
+// impersonation token.
+type ExportedToken struct {
+	CreatedDate    time.Time `json:"created_date"`
+	ExpiryDate     time.Time `json:"expiry_date"`
+	LastUsed       time.Time `json:"last_used"`
+	Deleted        bool      `json:"deleted"`
+	ImpersonatedBy string    `json:"impersonated_by,omitempty"`
+}
+
+// IdentityErased is the HTTP response entity for erase identity success, the tombstone recording the erasure.
+type IdentityErased struct {
+	ID         string    `json:"id"`
+	ErasedBy   string    `json:"erased_by"`
+	ErasedDate time.Time `json:"erased_date"`
+}
+
+// ErrorResponse is the HTTP response entity for all unsuccessful requests. Code is a stable machine readable value
+// identifying the error, Message is a human readable description which may change.
+type ErrorResponse struct {
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	RequestID string       `json:"request_id,omitempty"`
+	Details   []FieldError `json:"details,omitempty"`
+}
+
+// FieldError describes an invalid field in the request entity.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
 // GetIdentityResponse is the HTTP response entity for a successful get identity request
 type GetIdentityResponse struct {
-	ID          string        `json:"id"`
-	Name        string        `json:"name"`
-	Email       string        `json:"email"`
-	UserType    string        `json:"user_type"`
-	Deleted     bool          `json:"deleted"`
-	CreatedDate time.Time     `json:"created_date"`
-	TokenTTL    time.Duration `json:"token_ttl"`
+	ID             string        `json:"id"`
+	Name           string        `json:"name"`
+	Email          string        `json:"email"`
+	UserType       string        `json:"user_type"`
+	Deleted        bool          `json:"deleted"`
+	Verified       bool          `json:"verified"`
+	Disabled       bool          `json:"disabled"`
+	CreatedDate    time.Time     `json:"created_date"`
+	TokenTTL       time.Duration `json:"token_ttl"`
+	ImpersonatedBy *Impersonator `json:"impersonated_by,omitempty"`
+}
+
+// Impersonator is the admin identity acting as the identity in a GetIdentityResponse for an impersonation token.
+type Impersonator struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	UserType string `json:"user_type"`
+}
+
+// ImpersonationToken is the HTTP response entity for impersonate identity success. The token authenticates the admin
+// identity with ImpersonatedBy as the identity with ID.
+type ImpersonationToken struct {
+	Token          string        `json:"token"`
+	TTL            time.Duration `json:"ttl"`
+	ID             string        `json:"id"`
+	ImpersonatedBy string        `json:"impersonated_by"`
+}
+
+// ImportIdentitiesRequest is the HTTP request entity for importing identities migrated from Zebedee.
+type ImportIdentitiesRequest struct {
+	Identities []LegacyIdentity `json:"identities"`
+}
+
+// LegacyIdentity is a Zebedee user record including its legacy password hash.
+type LegacyIdentity struct {
+	Name              string `json:"name"`
+	Email             string `json:"email"`
+	PasswordHash      string `json:"password_hash"`
+	UserType          string `json:"user_type"`
+	TemporaryPassword bool   `json:"temporary_password"`
 }
 
 type AuthToken struct {
@@ -98,9 +306,24 @@ func getNewTokenRequest(ctx context.Context, r io.ReadCloser) (*NewTokenRequest,
 type IdentityService interface {
 	Create(ctx context.Context, i *schema.Identity) (string, error)
 	VerifyPassword(ctx context.Context, email string, password string) (*schema.Identity, error)
+	Import(ctx context.Context, identities []schema.Identity) (*identity.ImportReport, error)
+	Verify(ctx context.Context, token string) (*schema.Identity, error)
+	RequestEmailChange(ctx context.Context, id string, email string) error
+	ConfirmEmailChange(ctx context.Context, token string) (*schema.Identity, error)
+	Disable(ctx context.Context, id string, reason string, disabledBy string) error
+	Enable(ctx context.Context, id string) error
+	Export(ctx context.Context, id string) (*identity.Export, error)
+	Erase(ctx context.Context, id string, erasedBy string) (*schema.Tombstone, error)
+	Impersonate(ctx context.Context, id string, admin schema.Identity) (*schema.Identity, error)
 }
 
 type TokenService interface {
 	NewToken(ctx context.Context, identity schema.Identity) (*schema.Token, time.Duration, error)
 	GetIdentityByToken(ctx context.Context, tokenStr string) (*schema.Identity, time.Duration, error)
 }
+
+// RateLimiter takes a request from the bucket with the provided key, returning how long to wait if the bucket is
+// empty or zero if the request is allowed.
+type RateLimiter interface {
+	Take(ctx context.Context, key string, limit ratelimit.Limit) (time.Duration, error)
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"github.com/ONSdigital/dp-identity-api/identity"
+	"github.com/ONSdigital/go-ns/audit"
+	"github.com/ONSdigital/go-ns/common"
+	"github.com/ONSdigital/go-ns/log"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"net/http"
+)
+
+// ExportIdentityHandler is a GET HTTP handler returning the data stored about the identity in the request path: the
+// identity, the metadata of every token issued to it and the audit events about it. The caller must present a token
+// for the identity or for an admin identity, see authorizeIdentity, and is recorded as the requester in the audit
+// events.
+func (api *API) ExportIdentityHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := mux.Vars(r)["id"]
+	p := common.Params{"id": id}
+
+	if auditErr := api.auditor.Record(ctx, exportIdentityAction, audit.Attempted, p); auditErr != nil {
+		exportIdentityResponse.writeError(ctx, w, auditErr)
+		return
+	}
+
+	caller, err := api.authorizeIdentity(ctx, r, id)
+	if err != nil {
+		log.ErrorCtx(ctx, errors.Wrap(err, "exportIdentity: caller not authorized"), log.Data{"id": id})
+		api.auditor.Record(ctx, exportIdentityAction, audit.Unsuccessful, p)
+		exportIdentityResponse.writeError(ctx, w, err)
+		return
+	}
+
+	p["requested_by"] = caller.ID
+	e, err := api.IdentityService.Export(ctx, id)
+	if err != nil {
+		log.ErrorCtx(ctx, errors.Wrap(err, "exportIdentity: error"), log.Data{"id": id, "requested_by": caller.ID})
+		api.auditor.Record(ctx, exportIdentityAction, audit.Unsuccessful, p)
+		exportIdentityResponse.writeError(ctx, w, err)
+		return
+	}
+
+	if err := api.auditor.Record(ctx, exportIdentityAction, audit.Successful, p); err != nil {
+		exportIdentityResponse.writeError(ctx, w, err)
+		return
+	}
+
+	exportIdentityResponse.writeEntity(ctx, w, newIdentityExport(e), http.StatusOK)
+	log.InfoCtx(ctx, "exportIdentity: identity exported successfully", log.Data{"id": id, "requested_by": caller.ID})
+}
+
+// EraseIdentityHandler is a POST HTTP handler permanently erasing the identity in the request path and its tokens and
+// pseudonymising the audit events about it. The caller must present a token for an admin identity and is recorded as
+// the requester in the audit events and in the tombstone returned.
+func (api *API) EraseIdentityHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := mux.Vars(r)["id"]
+	p := common.Params{"id": id}
+
+	if auditErr := api.auditor.Record(ctx, eraseIdentityAction, audit.Attempted, p); auditErr != nil {
+		eraseIdentityResponse.writeError(ctx, w, auditErr)
+		return
+	}
+
+	caller, err := api.authorizeAdmin(ctx, r)
+	if err != nil {
+		log.ErrorCtx(ctx, errors.Wrap(err, "eraseIdentity: caller not authorized"), log.Data{"id": id})
+		api.auditor.Record(ctx, eraseIdentityAction, audit.Unsuccessful, p)
+		eraseIdentityResponse.writeError(ctx, w, err)
+		return
+	}
+
+	p["requested_by"] = caller.ID
+	tombstone, err := api.IdentityService.Erase(ctx, id, caller.ID)
+	if err != nil {
+		log.ErrorCtx(ctx, errors.Wrap(err, "eraseIdentity: error"), log.Data{"id": id, "requested_by": caller.ID})
+		api.auditor.Record(ctx, eraseIdentityAction, audit.Unsuccessful, p)
+		eraseIdentityResponse.writeError(ctx, w, err)
+		return
+	}
+
+	if err := api.auditor.Record(ctx, eraseIdentityAction, audit.Successful, p); err != nil {
+		eraseIdentityResponse.writeError(ctx, w, err)
+		return
+	}
+
+	response := &IdentityErased{ID: tombstone.ID, ErasedBy: tombstone.ErasedBy, ErasedDate: tombstone.ErasedDate}
+	eraseIdentityResponse.writeEntity(ctx, w, response, http.StatusOK)
+	log.InfoCtx(ctx, "eraseIdentity: identity erased successfully", log.Data{"id": id, "requested_by": caller.ID})
+}
+
+func newIdentityExport(e *identity.Export) *IdentityExport {
+	i := e.Identity
+	export := &IdentityExport{
+		Identity: ExportedIdentity{
+			ID:                i.ID,
+			Name:              i.Name,
+			Email:             i.Email,
+			UserType:          i.UserType,
+			TemporaryPassword: i.TemporaryPassword,
+			Migrated:          i.Migrated,
+			Deleted:           i.Deleted,
+			CreatedDate:       i.CreatedDate,
+			Verified:          i.Verified,
+			PendingEmail:      i.PendingEmail,
+			Disabled:          i.Disabled,
+			DisabledReason:    i.DisabledReason,
+			DisabledBy:        i.DisabledBy,
+			DisabledDate:      i.DisabledDate,
+		},
+		Tokens:      make([]ExportedToken, 0, len(e.Tokens)),
+		AuditEvents: e.Events,
+	}
+
+	for _, t := range e.Tokens {
+		export.Tokens = append(export.Tokens, ExportedToken{
+			CreatedDate:    t.CreatedDate,
+			ExpiryDate:     t.ExpiryDate,
+			LastUsed:       t.LastUsed,
+			Deleted:        t.Deleted,
+			ImpersonatedBy: t.ImpersonatedBy,
+		})
+	}
+	return export
+}
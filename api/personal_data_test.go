@@ -0,0 +1,211 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/ONSdigital/dp-identity-api/api/apitest"
+	"github.com/ONSdigital/dp-identity-api/identity"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/ONSdigital/go-ns/audit"
+	"github.com/ONSdigital/go-ns/audit/auditortest"
+	"github.com/ONSdigital/go-ns/common"
+	"github.com/gorilla/mux"
+	. "github.com/smartystreets/goconvey/convey"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	exportIdentityURL = "http://localhost:23800/identity/666/export"
+	eraseIdentityURL  = "http://localhost:23800/identity/666/erase"
+)
+
+func newExportIdentityRequest(tokenStr string) *http.Request {
+	r := httptest.NewRequest("GET", exportIdentityURL, nil)
+	if tokenStr != "" {
+		r.Header.Set(tokenHeaderKey, tokenStr)
+	}
+	return mux.SetURLVars(r, map[string]string{"id": ID})
+}
+
+func TestAPI_ExportIdentityHandler(t *testing.T) {
+	Convey("given a request to export an identity", t, func() {
+		created := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+		export := &identity.Export{
+			Identity: schema.Identity{ID: ID, Name: "Eleven", Email: "11@strangerthings.com", Password: "hash", CreatedDate: created},
+			Tokens:   []schema.Token{{ID: "digest", IdentityID: ID, CreatedDate: created, ExpiryDate: created.Add(time.Hour)}},
+			Events:   []schema.AuditEvent{{IdentityID: ID, Action: "createToken", Result: audit.Successful, Created: created}},
+		}
+
+		auditMock := auditortest.New()
+		tokensMock := tokenServiceReturning(&schema.Identity{ID: ID, UserType: schema.UserTypeUser}, nil)
+		serviceMock := &apitest.IdentityServiceMock{
+			ExportFunc: func(ctx context.Context, id string) (*identity.Export, error) {
+				return export, nil
+			},
+		}
+
+		identityAPI := &API{auditor: auditMock, IdentityService: serviceMock, Tokens: tokensMock}
+
+		Convey("when the caller presents a token for the identity itself", func() {
+			w := httptest.NewRecorder()
+			identityAPI.ExportIdentityHandler(w, newExportIdentityRequest("1234"))
+
+			Convey("then the stored data is returned without password hashes or token digests", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(strings.Contains(w.Body.String(), "hash"), ShouldBeFalse)
+				So(strings.Contains(w.Body.String(), "digest"), ShouldBeFalse)
+
+				var resp IdentityExport
+				So(json.Unmarshal(w.Body.Bytes(), &resp), ShouldBeNil)
+				So(resp.Identity.ID, ShouldEqual, ID)
+				So(resp.Identity.Email, ShouldEqual, "11@strangerthings.com")
+				So(resp.Identity.DisabledDate, ShouldBeNil)
+				So(resp.Tokens, ShouldResemble, []ExportedToken{{CreatedDate: created, ExpiryDate: created.Add(time.Hour)}})
+				So(resp.AuditEvents, ShouldHaveLength, 1)
+				So(resp.AuditEvents[0].Action, ShouldEqual, "createToken")
+
+				So(serviceMock.ExportCalls(), ShouldHaveLength, 1)
+				So(serviceMock.ExportCalls()[0].ID, ShouldEqual, ID)
+			})
+
+			Convey("and attempted and successful audit events are recorded", func() {
+				auditMock.AssertRecordCalls(
+					auditortest.Expected{Action: exportIdentityAction, Result: audit.Attempted, Params: common.Params{"id": ID}},
+					auditortest.Expected{Action: exportIdentityAction, Result: audit.Successful, Params: common.Params{"id": ID, "requested_by": ID}},
+				)
+			})
+		})
+
+		Convey("when the caller presents a token for another non-admin identity", func() {
+			identityAPI.Tokens = tokenServiceReturning(&schema.Identity{ID: "777", UserType: schema.UserTypeUser}, nil)
+
+			w := httptest.NewRecorder()
+			identityAPI.ExportIdentityHandler(w, newExportIdentityRequest("1234"))
+
+			Convey("then status 403 is returned and the identity is not exported", func() {
+				assertErrorResponse(w.Code, http.StatusForbidden, w.Body.String(), ErrForbidden.Error())
+				So(serviceMock.ExportCalls(), ShouldHaveLength, 0)
+			})
+		})
+
+		Convey("when the request does not contain a token", func() {
+			w := httptest.NewRecorder()
+			identityAPI.ExportIdentityHandler(w, newExportIdentityRequest(""))
+
+			Convey("then status 401 is returned", func() {
+				assertErrorResponse(w.Code, http.StatusUnauthorized, w.Body.String(), ErrNoTokenProvided.Error())
+				So(serviceMock.ExportCalls(), ShouldHaveLength, 0)
+			})
+		})
+
+		Convey("when the identity has been erased", func() {
+			identityAPI.Tokens = tokenServiceReturning(&schema.Identity{ID: adminID, UserType: schema.UserTypeAdmin}, nil)
+			serviceMock.ExportFunc = func(ctx context.Context, id string) (*identity.Export, error) {
+				return nil, identity.ErrIdentityErased
+			}
+
+			w := httptest.NewRecorder()
+			identityAPI.ExportIdentityHandler(w, newExportIdentityRequest("1234"))
+
+			Convey("then status 410 is returned", func() {
+				assertErrorResponse(w.Code, http.StatusGone, w.Body.String(), identity.ErrIdentityErased.Error())
+			})
+
+			Convey("and attempted and unsuccessful audit events are recorded", func() {
+				auditMock.AssertRecordCalls(
+					auditortest.Expected{Action: exportIdentityAction, Result: audit.Attempted, Params: common.Params{"id": ID}},
+					auditortest.Expected{Action: exportIdentityAction, Result: audit.Unsuccessful, Params: common.Params{"id": ID, "requested_by": adminID}},
+				)
+			})
+		})
+	})
+}
+
+func TestAPI_EraseIdentityHandler(t *testing.T) {
+	Convey("given a request to erase an identity", t, func() {
+		erased := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		auditMock := auditortest.New()
+		tokensMock := tokenServiceReturning(&schema.Identity{ID: adminID, UserType: schema.UserTypeAdmin}, nil)
+		serviceMock := &apitest.IdentityServiceMock{
+			EraseFunc: func(ctx context.Context, id string, erasedBy string) (*schema.Tombstone, error) {
+				return &schema.Tombstone{ID: id, ErasedBy: erasedBy, ErasedDate: erased}, nil
+			},
+		}
+
+		identityAPI := &API{auditor: auditMock, IdentityService: serviceMock, Tokens: tokensMock}
+
+		Convey("when the caller presents a token for an admin identity", func() {
+			w := httptest.NewRecorder()
+			identityAPI.EraseIdentityHandler(w, newIdentityStatusRequest(eraseIdentityURL, "1234", ""))
+
+			Convey("then the identity is erased and the tombstone is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+
+				var resp IdentityErased
+				So(json.Unmarshal(w.Body.Bytes(), &resp), ShouldBeNil)
+				So(resp, ShouldResemble, IdentityErased{ID: ID, ErasedBy: adminID, ErasedDate: erased})
+
+				So(serviceMock.EraseCalls(), ShouldHaveLength, 1)
+				So(serviceMock.EraseCalls()[0].ID, ShouldEqual, ID)
+				So(serviceMock.EraseCalls()[0].ErasedBy, ShouldEqual, adminID)
+			})
+
+			Convey("and attempted and successful audit events are recorded", func() {
+				auditMock.AssertRecordCalls(
+					auditortest.Expected{Action: eraseIdentityAction, Result: audit.Attempted, Params: common.Params{"id": ID}},
+					auditortest.Expected{Action: eraseIdentityAction, Result: audit.Successful, Params: common.Params{"id": ID, "requested_by": adminID}},
+				)
+			})
+		})
+
+		Convey("when the caller presents a token for the identity itself", func() {
+			identityAPI.Tokens = tokenServiceReturning(&schema.Identity{ID: ID, UserType: schema.UserTypeUser}, nil)
+
+			w := httptest.NewRecorder()
+			identityAPI.EraseIdentityHandler(w, newIdentityStatusRequest(eraseIdentityURL, "1234", ""))
+
+			Convey("then status 403 is returned and the identity is not erased", func() {
+				assertErrorResponse(w.Code, http.StatusForbidden, w.Body.String(), ErrForbidden.Error())
+				So(serviceMock.EraseCalls(), ShouldHaveLength, 0)
+			})
+		})
+
+		Convey("when the identity has already been erased", func() {
+			serviceMock.EraseFunc = func(ctx context.Context, id string, erasedBy string) (*schema.Tombstone, error) {
+				return nil, identity.ErrIdentityErased
+			}
+
+			w := httptest.NewRecorder()
+			identityAPI.EraseIdentityHandler(w, newIdentityStatusRequest(eraseIdentityURL, "1234", ""))
+
+			Convey("then status 410 is returned", func() {
+				assertErrorResponse(w.Code, http.StatusGone, w.Body.String(), identity.ErrIdentityErased.Error())
+			})
+		})
+
+		Convey("when the identity does not exist", func() {
+			serviceMock.EraseFunc = func(ctx context.Context, id string, erasedBy string) (*schema.Tombstone, error) {
+				return nil, identity.ErrIdentityNotFound
+			}
+
+			w := httptest.NewRecorder()
+			identityAPI.EraseIdentityHandler(w, newIdentityStatusRequest(eraseIdentityURL, "1234", ""))
+
+			Convey("then status 404 is returned", func() {
+				assertErrorResponse(w.Code, http.StatusNotFound, w.Body.String(), identity.ErrIdentityNotFound.Error())
+			})
+
+			Convey("and attempted and unsuccessful audit events are recorded", func() {
+				auditMock.AssertRecordCalls(
+					auditortest.Expected{Action: eraseIdentityAction, Result: audit.Attempted, Params: common.Params{"id": ID}},
+					auditortest.Expected{Action: eraseIdentityAction, Result: audit.Unsuccessful, Params: common.Params{"id": ID, "requested_by": adminID}},
+				)
+			})
+		})
+	})
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ONSdigital/dp-identity-api/ratelimit"
+	"github.com/ONSdigital/dp-identity-api/token"
+	"github.com/ONSdigital/go-ns/log"
+	"github.com/pkg/errors"
+)
+
+const (
+	retryAfterHeaderKey     = "Retry-After"
+	forwardedForHeaderKey   = "X-Forwarded-For"
+	ipRateLimitKeyPrefix    = "ip:"
+	tokenRateLimitKeyPrefix = "token:"
+)
+
+// ErrRateLimited is returned if a request exceeds the rate limit of its client IP or token.
+var ErrRateLimited = errors.New("too many requests, retry after the time in the Retry-After header")
+
+var rateLimitResponse = JSONResponseWriter{
+	ErrRateLimited: http.StatusTooManyRequests,
+}
+
+// rateLimit wraps the handler refusing requests once the rate limit of their client IP, or of their token if one is
+// provided, is exceeded. Tokens are limited by their digest, using the Digester tokens are stored by, so plain text
+// tokens are never stored. If the rate limiter fails the request is allowed, so an unavailable store does not prevent
+// every request, and the failure is logged and recorded.
+func (api *API) rateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !api.takeRateLimit(w, r, "ip", ipRateLimitKeyPrefix+api.clientIP(r), api.IPRateLimit) {
+			return
+		}
+
+		if tokenStr := r.Header.Get(tokenHeaderKey); tokenStr != "" {
+			key := tokenRateLimitKeyPrefix + api.digester().Digest(tokenStr)
+			if !api.takeRateLimit(w, r, "token", key, api.TokenRateLimit) {
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// takeRateLimit take a request from the bucket with the provided key, writing a rate limited response and returning
+// false if the request is refused.
+func (api *API) takeRateLimit(w http.ResponseWriter, r *http.Request, limit string, key string, l ratelimit.Limit) bool {
+	ctx := r.Context()
+
+	wait, err := api.RateLimiter.Take(ctx, key, l)
+	if err != nil {
+		log.ErrorCtx(ctx, errors.WithMessage(err, "rate limit: failed to take request, allowing request"), log.Data{"limit": limit})
+		api.recorder().RateLimitFailed(limit)
+		return true
+	}
+
+	if wait <= 0 {
+		return true
+	}
+
+	api.recorder().RequestRateLimited(limit)
+	w.Header().Set(retryAfterHeaderKey, strconv.Itoa(ratelimit.RetryAfter(wait)))
+	rateLimitResponse.writeError(ctx, w, ErrRateLimited)
+	return false
+}
+
+// digester return the configured token.Digester, or a SHA256Digester if none is configured.
+func (api *API) digester() token.Digester {
+	if api.Digester == nil {
+		return token.SHA256Digester{}
+	}
+	return api.Digester
+}
+
+// clientIP return the IP of the client making the request. If TrustForwardedFor the last X-Forwarded-For entry is
+// used, as it is the only entry appended by the trusted proxy rather than provided by the client.
+func (api *API) clientIP(r *http.Request) string {
+	if api.TrustForwardedFor {
+		if forwarded := r.Header.Get(forwardedForHeaderKey); forwarded != "" {
+			entries := strings.Split(forwarded, ",")
+			return strings.TrimSpace(entries[len(entries)-1])
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
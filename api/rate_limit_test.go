@@ -0,0 +1,230 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/ONSdigital/dp-identity-api/api/apitest"
+	"github.com/ONSdigital/dp-identity-api/metrics/metricstest"
+	"github.com/ONSdigital/dp-identity-api/ratelimit"
+	"github.com/ONSdigital/dp-identity-api/token"
+	"github.com/gorilla/mux"
+	. "github.com/smartystreets/goconvey/convey"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var (
+	ipLimit    = ratelimit.Limit{Rate: 50, Burst: 200}
+	tokenLimit = ratelimit.Limit{Rate: 10, Burst: 50}
+)
+
+// rateLimiterWaiting return a RateLimiterMock returning the wait for each key, allowing keys without a wait.
+func rateLimiterWaiting(waits map[string]time.Duration) *apitest.RateLimiterMock {
+	return &apitest.RateLimiterMock{
+		TakeFunc: func(ctx context.Context, key string, limit ratelimit.Limit) (time.Duration, error) {
+			return waits[key], nil
+		},
+	}
+}
+
+func newRateLimitedAPI(limiter RateLimiter) (*API, *metricstest.RecorderMock) {
+	m := &metricstest.RecorderMock{
+		RequestRateLimitedFunc: func(limit string) {},
+		RateLimitFailedFunc:    func(limit string) {},
+	}
+	return &API{RateLimiter: limiter, IPRateLimit: ipLimit, TokenRateLimit: tokenLimit, Metrics: m}, m
+}
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func TestAPI_RateLimit(t *testing.T) {
+	Convey("given a request without a token within the client IP limit", t, func() {
+		limiter := rateLimiterWaiting(nil)
+		a, m := newRateLimitedAPI(limiter)
+
+		r := httptest.NewRequest(http.MethodPost, createIdentityURL, nil)
+		r.RemoteAddr = "10.0.0.1:52000"
+		w := httptest.NewRecorder()
+
+		a.rateLimit(okHandler()).ServeHTTP(w, r)
+
+		Convey("then the request is allowed", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(m.RequestRateLimitedCalls(), ShouldHaveLength, 0)
+		})
+
+		Convey("and only the client IP limit is taken", func() {
+			So(limiter.TakeCalls(), ShouldHaveLength, 1)
+			So(limiter.TakeCalls()[0].Key, ShouldEqual, "ip:10.0.0.1")
+			So(limiter.TakeCalls()[0].Limit, ShouldResemble, ipLimit)
+		})
+	})
+
+	Convey("given a request with a token within both limits", t, func() {
+		limiter := rateLimiterWaiting(nil)
+		a, _ := newRateLimitedAPI(limiter)
+
+		r := httptest.NewRequest(http.MethodGet, createIdentityURL, nil)
+		r.Header.Set(tokenHeaderKey, "666")
+		w := httptest.NewRecorder()
+
+		a.rateLimit(okHandler()).ServeHTTP(w, r)
+
+		Convey("then the request is allowed", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+
+		Convey("and the token limit is taken by the digest of the token", func() {
+			So(limiter.TakeCalls(), ShouldHaveLength, 2)
+			So(limiter.TakeCalls()[1].Key, ShouldEqual, "token:"+token.SHA256Digester{}.Digest("666"))
+			So(limiter.TakeCalls()[1].Limit, ShouldResemble, tokenLimit)
+		})
+	})
+
+	Convey("given a request with a token and a configured digester", t, func() {
+		limiter := rateLimiterWaiting(nil)
+		a, _ := newRateLimitedAPI(limiter)
+		a.Digester = token.NewDigester("secret")
+
+		r := httptest.NewRequest(http.MethodGet, createIdentityURL, nil)
+		r.Header.Set(tokenHeaderKey, "666")
+		w := httptest.NewRecorder()
+
+		a.rateLimit(okHandler()).ServeHTTP(w, r)
+
+		Convey("then the token limit is taken by the digest of the configured digester", func() {
+			So(limiter.TakeCalls(), ShouldHaveLength, 2)
+			So(limiter.TakeCalls()[1].Key, ShouldEqual, "token:"+token.NewDigester("secret").Digest("666"))
+			So(limiter.TakeCalls()[1].Key, ShouldNotEqual, "token:"+token.SHA256Digester{}.Digest("666"))
+		})
+	})
+
+	Convey("given a request exceeding the client IP limit", t, func() {
+		limiter := rateLimiterWaiting(map[string]time.Duration{"ip:10.0.0.1": 1500 * time.Millisecond})
+		a, m := newRateLimitedAPI(limiter)
+
+		r := httptest.NewRequest(http.MethodPost, createIdentityURL, nil)
+		r.RemoteAddr = "10.0.0.1:52000"
+		r.Header.Set(tokenHeaderKey, "666")
+		w := httptest.NewRecorder()
+
+		a.rateLimit(okHandler()).ServeHTTP(w, r)
+
+		Convey("then status 429 is returned with the seconds to wait rounded up", func() {
+			assertErrorResponse(w.Code, http.StatusTooManyRequests, w.Body.String(), ErrRateLimited.Error())
+			So(w.Header().Get("Retry-After"), ShouldEqual, "2")
+
+			var body ErrorResponse
+			So(json.Unmarshal(w.Body.Bytes(), &body), ShouldBeNil)
+			So(body.Code, ShouldEqual, "rate_limited")
+		})
+
+		Convey("and the token limit is not taken", func() {
+			So(limiter.TakeCalls(), ShouldHaveLength, 1)
+		})
+
+		Convey("and the refused request is recorded", func() {
+			So(m.RequestRateLimitedCalls(), ShouldHaveLength, 1)
+			So(m.RequestRateLimitedCalls()[0].Limit, ShouldEqual, "ip")
+		})
+	})
+
+	Convey("given a request exceeding the token limit", t, func() {
+		limiter := rateLimiterWaiting(map[string]time.Duration{
+			"token:" + token.SHA256Digester{}.Digest("666"): time.Second,
+		})
+		a, m := newRateLimitedAPI(limiter)
+
+		r := httptest.NewRequest(http.MethodGet, createIdentityURL, nil)
+		r.Header.Set(tokenHeaderKey, "666")
+		w := httptest.NewRecorder()
+
+		a.rateLimit(okHandler()).ServeHTTP(w, r)
+
+		Convey("then status 429 is returned", func() {
+			assertErrorResponse(w.Code, http.StatusTooManyRequests, w.Body.String(), ErrRateLimited.Error())
+			So(w.Header().Get("Retry-After"), ShouldEqual, "1")
+			So(m.RequestRateLimitedCalls()[0].Limit, ShouldEqual, "token")
+		})
+	})
+
+	Convey("given a rate limiter returning an error", t, func() {
+		limiter := &apitest.RateLimiterMock{
+			TakeFunc: func(ctx context.Context, key string, limit ratelimit.Limit) (time.Duration, error) {
+				return 0, errors.New("store unavailable")
+			},
+		}
+		a, m := newRateLimitedAPI(limiter)
+
+		w := httptest.NewRecorder()
+		a.rateLimit(okHandler()).ServeHTTP(w, httptest.NewRequest(http.MethodPost, createIdentityURL, nil))
+
+		Convey("then the request is allowed", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+
+		Convey("and the failure is recorded", func() {
+			So(m.RateLimitFailedCalls(), ShouldHaveLength, 1)
+			So(m.RateLimitFailedCalls()[0].Limit, ShouldEqual, "ip")
+			So(m.RequestRateLimitedCalls(), ShouldHaveLength, 0)
+		})
+	})
+}
+
+func TestAPI_ClientIP(t *testing.T) {
+	Convey("given a request forwarded by a proxy", t, func() {
+		r := httptest.NewRequest(http.MethodGet, createIdentityURL, nil)
+		r.RemoteAddr = "10.0.0.1:52000"
+		r.Header.Set("X-Forwarded-For", "192.168.0.1, 172.16.0.1")
+
+		Convey("when X-Forwarded-For is not trusted", func() {
+			a := &API{}
+
+			Convey("then the remote address is used", func() {
+				So(a.clientIP(r), ShouldEqual, "10.0.0.1")
+			})
+		})
+
+		Convey("when X-Forwarded-For is trusted", func() {
+			a := &API{TrustForwardedFor: true}
+
+			Convey("then the entry appended by the proxy is used", func() {
+				So(a.clientIP(r), ShouldEqual, "172.16.0.1")
+			})
+		})
+	})
+}
+
+func TestAPI_RegisterEndpointsRateLimited(t *testing.T) {
+	Convey("given the endpoints are registered with a rate limiter refusing every request", t, func() {
+		limiter := &apitest.RateLimiterMock{
+			TakeFunc: func(ctx context.Context, key string, limit ratelimit.Limit) (time.Duration, error) {
+				return time.Second, nil
+			},
+		}
+		a, _ := newRateLimitedAPI(limiter)
+
+		router := mux.NewRouter()
+		a.RegisterEndpoints(router)
+		router.Handle("/healthcheck", okHandler()).Methods("GET")
+
+		Convey("then requests to the endpoints are refused", func() {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, createIdentityURL, nil))
+			So(w.Code, ShouldEqual, http.StatusTooManyRequests)
+		})
+
+		Convey("and routes registered afterwards are not limited", func() {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://localhost:23800/healthcheck", nil))
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+	})
+}
@@ -3,18 +3,63 @@ package api
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"github.com/ONSdigital/dp-identity-api/identity"
+	"github.com/ONSdigital/dp-identity-api/persistence"
 	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/ONSdigital/go-ns/common"
 	"github.com/ONSdigital/go-ns/log"
+	"github.com/pkg/errors"
 	"net/http"
+	"strings"
+)
+
+const (
+	codeValidationFailed = "validation_failed"
 )
 
 var (
 	ErrInternalServerError = errors.New("internal server error")
 
+	// errorCodes are the stable error codes returned in error responses. Errors without a code are identified by a
+	// code derived from their HTTP status.
+	errorCodes = map[error]string{
+		ErrInternalServerError:                "internal_server_error",
+		ErrFailedToReadRequestBody:            "invalid_request_body",
+		ErrFailedToUnmarshalRequestBody:       "invalid_request_body",
+		ErrRequestBodyNil:                     "request_body_required",
+		ErrRequestBodyTooLarge:                "request_body_too_large",
+		ErrUnknownRequestField:                "unknown_field",
+		ErrNoTokenProvided:                    "token_required",
+		ErrAuthRequestNil:                     "invalid_request_body",
+		ErrAuthRequestIDNil:                   "email_required",
+		ErrAuthorizationRequired:              "authorization_required",
+		ErrInvalidBootstrapSecret:             "invalid_bootstrap_secret",
+		ErrForbidden:                          "forbidden",
+		ErrImpersonationDisabled:              "impersonation_disabled",
+		ErrRateLimited:                        "rate_limited",
+		identity.ErrImpersonateAdmin:          "impersonate_admin",
+		identity.ErrInvalidPasswordHash:       "invalid_password_hash",
+		identity.ErrAuthenticateFailed:        "authentication_failed",
+		identity.ErrIdentityNotFound:          "identity_not_found",
+		identity.ErrEmailAlreadyExists:        "email_already_exists",
+		identity.ErrIdentityNotVerified:       "identity_not_verified",
+		identity.ErrIdentityDisabled:          "identity_disabled",
+		identity.ErrIdentityErased:            "identity_erased",
+		identity.ErrDisabledReasonRequired:    "reason_required",
+		identity.ErrVerificationTokenNotFound: "verification_token_not_found",
+		ErrNoVerificationToken:                "verification_token_required",
+		ErrNoEmailChangeToken:                 "email_change_token_required",
+		identity.ErrEmailChangeTokenNotFound:  "email_change_token_not_found",
+		schema.ErrTokenExpired:                "token_expired",
+		schema.ErrTokenNotFound:               "token_not_found",
+		persistence.ErrTimeout:                "store_timeout",
+		persistence.ErrUnavailable:            "store_unavailable",
+	}
+
 	createIdentityResponse = JSONResponseWriter{
 		ErrFailedToUnmarshalRequestBody: http.StatusBadRequest,
+		ErrUnknownRequestField:          http.StatusBadRequest,
+		ErrRequestBodyTooLarge:          http.StatusRequestEntityTooLarge,
 		ErrFailedToReadRequestBody:      http.StatusBadRequest,
 		ErrRequestBodyNil:               http.StatusBadRequest,
 		identity.ErrInvalidArguments:    http.StatusInternalServerError,
@@ -22,22 +67,165 @@ var (
 		schema.ErrNameValidation:        http.StatusBadRequest,
 		schema.ErrEmailValidation:       http.StatusBadRequest,
 		schema.ErrPasswordValidation:    http.StatusBadRequest,
+		schema.ErrUserTypeValidation:    http.StatusBadRequest,
 		schema.ErrIdentityNil:           http.StatusBadRequest,
 		identity.ErrEmailAlreadyExists:  http.StatusConflict,
+		ErrAuthorizationRequired:        http.StatusUnauthorized,
+		ErrInvalidBootstrapSecret:       http.StatusForbidden,
+		ErrForbidden:                    http.StatusForbidden,
+		schema.ErrTokenExpired:          http.StatusUnauthorized,
+		schema.ErrTokenNotFound:         http.StatusForbidden,
+		ErrImpersonationDisabled:        http.StatusForbidden,
+		persistence.ErrTimeout:          http.StatusGatewayTimeout,
+		persistence.ErrUnavailable:      http.StatusServiceUnavailable,
 	}
 
 	getIdentityResponse = JSONResponseWriter{
-		ErrNoTokenProvided:      http.StatusUnauthorized,
-		schema.ErrTokenExpired:  http.StatusUnauthorized,
-		schema.ErrTokenNotFound: http.StatusForbidden,
+		ErrNoTokenProvided:         http.StatusUnauthorized,
+		schema.ErrTokenExpired:     http.StatusUnauthorized,
+		schema.ErrTokenNotFound:    http.StatusForbidden,
+		ErrImpersonationDisabled:   http.StatusForbidden,
+		persistence.ErrTimeout:     http.StatusGatewayTimeout,
+		persistence.ErrUnavailable: http.StatusServiceUnavailable,
+	}
+
+	importIdentitiesResponse = JSONResponseWriter{
+		ErrFailedToUnmarshalRequestBody: http.StatusBadRequest,
+		ErrUnknownRequestField:          http.StatusBadRequest,
+		ErrRequestBodyTooLarge:          http.StatusRequestEntityTooLarge,
+		ErrFailedToReadRequestBody:      http.StatusBadRequest,
+		ErrRequestBodyNil:               http.StatusBadRequest,
+		ErrNoTokenProvided:              http.StatusUnauthorized,
+		schema.ErrTokenExpired:          http.StatusUnauthorized,
+		schema.ErrTokenNotFound:         http.StatusForbidden,
+		ErrImpersonationDisabled:        http.StatusForbidden,
+		ErrForbidden:                    http.StatusForbidden,
+		identity.ErrInvalidPasswordHash: http.StatusBadRequest,
+		identity.ErrInvalidArguments:    http.StatusInternalServerError,
+		identity.ErrPersistence:         http.StatusInternalServerError,
+		persistence.ErrTimeout:          http.StatusGatewayTimeout,
+		persistence.ErrUnavailable:      http.StatusServiceUnavailable,
+	}
+
+	verifyIdentityResponse = JSONResponseWriter{
+		ErrNoVerificationToken:                http.StatusBadRequest,
+		identity.ErrVerificationTokenNotFound: http.StatusNotFound,
+		identity.ErrPersistence:               http.StatusInternalServerError,
+		persistence.ErrTimeout:                http.StatusGatewayTimeout,
+		persistence.ErrUnavailable:            http.StatusServiceUnavailable,
+	}
+
+	requestEmailChangeResponse = JSONResponseWriter{
+		ErrFailedToUnmarshalRequestBody: http.StatusBadRequest,
+		ErrUnknownRequestField:          http.StatusBadRequest,
+		ErrRequestBodyTooLarge:          http.StatusRequestEntityTooLarge,
+		ErrFailedToReadRequestBody:      http.StatusBadRequest,
+		ErrRequestBodyNil:               http.StatusBadRequest,
+		schema.ErrEmailValidation:       http.StatusBadRequest,
+		ErrNoTokenProvided:              http.StatusUnauthorized,
+		schema.ErrTokenExpired:          http.StatusUnauthorized,
+		schema.ErrTokenNotFound:         http.StatusForbidden,
+		ErrImpersonationDisabled:        http.StatusForbidden,
+		ErrForbidden:                    http.StatusForbidden,
+		identity.ErrIdentityNotFound:    http.StatusNotFound,
+		identity.ErrEmailAlreadyExists:  http.StatusConflict,
+		identity.ErrPersistence:         http.StatusInternalServerError,
+		persistence.ErrTimeout:          http.StatusGatewayTimeout,
+		persistence.ErrUnavailable:      http.StatusServiceUnavailable,
+	}
+
+	confirmEmailChangeResponse = JSONResponseWriter{
+		ErrNoEmailChangeToken:                http.StatusBadRequest,
+		identity.ErrEmailChangeTokenNotFound: http.StatusNotFound,
+		identity.ErrEmailAlreadyExists:       http.StatusConflict,
+		identity.ErrPersistence:              http.StatusInternalServerError,
+		persistence.ErrTimeout:               http.StatusGatewayTimeout,
+		persistence.ErrUnavailable:           http.StatusServiceUnavailable,
+	}
+
+	disableIdentityResponse = JSONResponseWriter{
+		ErrFailedToUnmarshalRequestBody:    http.StatusBadRequest,
+		ErrUnknownRequestField:             http.StatusBadRequest,
+		ErrRequestBodyTooLarge:             http.StatusRequestEntityTooLarge,
+		ErrFailedToReadRequestBody:         http.StatusBadRequest,
+		ErrRequestBodyNil:                  http.StatusBadRequest,
+		identity.ErrDisabledReasonRequired: http.StatusBadRequest,
+		ErrNoTokenProvided:                 http.StatusUnauthorized,
+		schema.ErrTokenExpired:             http.StatusUnauthorized,
+		schema.ErrTokenNotFound:            http.StatusForbidden,
+		ErrImpersonationDisabled:           http.StatusForbidden,
+		ErrForbidden:                       http.StatusForbidden,
+		identity.ErrIdentityNotFound:       http.StatusNotFound,
+		identity.ErrPersistence:            http.StatusInternalServerError,
+		persistence.ErrTimeout:             http.StatusGatewayTimeout,
+		persistence.ErrUnavailable:         http.StatusServiceUnavailable,
+	}
+
+	enableIdentityResponse = JSONResponseWriter{
+		ErrNoTokenProvided:           http.StatusUnauthorized,
+		schema.ErrTokenExpired:       http.StatusUnauthorized,
+		schema.ErrTokenNotFound:      http.StatusForbidden,
+		ErrImpersonationDisabled:     http.StatusForbidden,
+		ErrForbidden:                 http.StatusForbidden,
+		identity.ErrIdentityNotFound: http.StatusNotFound,
+		identity.ErrPersistence:      http.StatusInternalServerError,
+		persistence.ErrTimeout:       http.StatusGatewayTimeout,
+		persistence.ErrUnavailable:   http.StatusServiceUnavailable,
+	}
+
+	exportIdentityResponse = JSONResponseWriter{
+		ErrNoTokenProvided:           http.StatusUnauthorized,
+		schema.ErrTokenExpired:       http.StatusUnauthorized,
+		schema.ErrTokenNotFound:      http.StatusForbidden,
+		ErrImpersonationDisabled:     http.StatusForbidden,
+		ErrForbidden:                 http.StatusForbidden,
+		identity.ErrIdentityNotFound: http.StatusNotFound,
+		identity.ErrIdentityErased:   http.StatusGone,
+		identity.ErrPersistence:      http.StatusInternalServerError,
+		persistence.ErrTimeout:       http.StatusGatewayTimeout,
+		persistence.ErrUnavailable:   http.StatusServiceUnavailable,
+	}
+
+	eraseIdentityResponse = JSONResponseWriter{
+		ErrNoTokenProvided:           http.StatusUnauthorized,
+		schema.ErrTokenExpired:       http.StatusUnauthorized,
+		schema.ErrTokenNotFound:      http.StatusForbidden,
+		ErrImpersonationDisabled:     http.StatusForbidden,
+		ErrForbidden:                 http.StatusForbidden,
+		identity.ErrIdentityNotFound: http.StatusNotFound,
+		identity.ErrIdentityErased:   http.StatusGone,
+		identity.ErrPersistence:      http.StatusInternalServerError,
+		persistence.ErrTimeout:       http.StatusGatewayTimeout,
+		persistence.ErrUnavailable:   http.StatusServiceUnavailable,
+	}
+
+	impersonateIdentityResponse = JSONResponseWriter{
+		ErrNoTokenProvided:           http.StatusUnauthorized,
+		schema.ErrTokenExpired:       http.StatusUnauthorized,
+		schema.ErrTokenNotFound:      http.StatusForbidden,
+		ErrImpersonationDisabled:     http.StatusForbidden,
+		ErrForbidden:                 http.StatusForbidden,
+		identity.ErrImpersonateAdmin: http.StatusForbidden,
+		identity.ErrIdentityDisabled: http.StatusForbidden,
+		identity.ErrIdentityNotFound: http.StatusNotFound,
+		identity.ErrIdentityErased:   http.StatusGone,
+		identity.ErrPersistence:      http.StatusInternalServerError,
+		persistence.ErrTimeout:       http.StatusGatewayTimeout,
+		persistence.ErrUnavailable:   http.StatusServiceUnavailable,
 	}
 
 	newTokenResponse = JSONResponseWriter{
-		ErrRequestBodyNil:              http.StatusBadRequest,
-		ErrAuthRequestNil:              http.StatusBadRequest,
-		ErrAuthRequestIDNil:            http.StatusBadRequest,
-		identity.ErrAuthenticateFailed: http.StatusForbidden,
-		identity.ErrIdentityNotFound:   http.StatusNotFound,
+		ErrFailedToUnmarshalRequestBody: http.StatusBadRequest,
+		ErrFailedToReadRequestBody:      http.StatusBadRequest,
+		ErrRequestBodyNil:               http.StatusBadRequest,
+		ErrAuthRequestNil:               http.StatusBadRequest,
+		ErrAuthRequestIDNil:             http.StatusBadRequest,
+		identity.ErrAuthenticateFailed:  http.StatusForbidden,
+		identity.ErrIdentityNotFound:    http.StatusNotFound,
+		identity.ErrIdentityNotVerified: http.StatusForbidden,
+		identity.ErrIdentityDisabled:    http.StatusForbidden,
+		persistence.ErrTimeout:          http.StatusGatewayTimeout,
+		persistence.ErrUnavailable:      http.StatusServiceUnavailable,
 	}
 )
 
@@ -55,15 +243,72 @@ func (e JSONResponseWriter) writeEntity(ctx context.Context, w http.ResponseWrit
 	w.Write(b) // TODO handle error
 }
 
+// writeError write a JSON ErrorResponse for the error with the HTTP status it resolves to. Wrapped errors are reported
+// using their cause so internal context is not exposed, internal server errors are always reported as
+// ErrInternalServerError.
 func (e JSONResponseWriter) writeError(ctx context.Context, w http.ResponseWriter, err error) {
 	status := e.resolveError(err)
 
+	cause := errors.Cause(err)
 	if status == http.StatusInternalServerError {
-		err = ErrInternalServerError
+		cause = ErrInternalServerError
+	}
+
+	body := ErrorResponse{
+		Code:      errorCode(cause, status),
+		Message:   cause.Error(),
+		RequestID: common.GetRequestId(ctx),
 	}
 
-	log.ErrorCtx(ctx, errors.New("writing error response"), log.Data{"status": status})
-	http.Error(w, err.Error(), status)
+	if validationErr, ok := cause.(schema.ValidationErr); ok && validationErr.Field() != "" {
+		body.Details = []FieldError{{Field: validationErr.Field(), Message: validationErr.Error()}}
+	}
+
+	if unknownFieldErr, ok := findUnknownFieldErr(err); ok && cause == ErrUnknownRequestField {
+		body.Message = unknownFieldErr.Error()
+		body.Details = []FieldError{{Field: unknownFieldErr.Field(), Message: unknownFieldErr.Error()}}
+	}
+
+	log.ErrorCtx(ctx, errors.New("writing error response"), log.Data{"status": status, "code": body.Code})
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		log.ErrorCtx(ctx, errors.Wrap(err, "failed to marshal error response to JSON"), nil)
+		http.Error(w, ErrInternalServerError.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set(headerContentType, mimeTypeJSON)
+	w.WriteHeader(status)
+	w.Write(b)
+}
+
+// findUnknownFieldErr return the UnknownFieldErr in the chain of causes of err, if there is one.
+func findUnknownFieldErr(err error) (UnknownFieldErr, bool) {
+	for err != nil {
+		if unknownFieldErr, ok := err.(UnknownFieldErr); ok {
+			return unknownFieldErr, true
+		}
+
+		causer, ok := err.(interface{ Cause() error })
+		if !ok {
+			break
+		}
+		err = causer.Cause()
+	}
+	return UnknownFieldErr{}, false
+}
+
+// errorCode return the stable error code for the error, or a code derived from the HTTP status if it has none.
+func errorCode(err error, status int) string {
+	if _, ok := err.(schema.ValidationErr); ok {
+		return codeValidationFailed
+	}
+
+	if code, ok := errorCodes[err]; ok {
+		return code
+	}
+	return strings.ToLower(strings.Replace(http.StatusText(status), " ", "_", -1))
 }
 
 func (e JSONResponseWriter) resolveError(err error) int {
@@ -71,6 +316,9 @@ func (e JSONResponseWriter) resolveError(err error) int {
 
 	if val, ok := e[err]; ok {
 		status = val
+	} else if val, ok := e[errors.Cause(err)]; ok {
+		// errors wrapped with additional context resolve to the status of their cause.
+		status = val
 	}
 	return status
 }
@@ -3,8 +3,11 @@ package api
 import (
 	"context"
 	"encoding/json"
-	"errors"
+	"github.com/ONSdigital/dp-identity-api/identity"
+	"github.com/ONSdigital/dp-identity-api/persistence"
 	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/ONSdigital/go-ns/common"
+	"github.com/pkg/errors"
 	. "github.com/smartystreets/goconvey/convey"
 	"net/http"
 	"net/http/httptest"
@@ -37,11 +40,82 @@ func Test_WriteErrorResolveSuccessful(t *testing.T) {
 
 		createIdentityResponse.writeError(context.Background(), w, schema.ErrNameValidation)
 
-		So(w.Header().Get(headerContentType), ShouldEqual, "text/plain; charset=utf-8")
+		So(w.Header().Get(headerContentType), ShouldEqual, mimeTypeJSON)
 		assertErrorResponse(w.Code, http.StatusBadRequest, w.Body.String(), schema.ErrNameValidation.Error())
 	})
 }
 
+func Test_WriteErrorResponseBody(t *testing.T) {
+	Convey("given a request ID on the context", t, func() {
+		ctx := common.WithRequestId(context.Background(), "123")
+		w := httptest.NewRecorder()
+
+		Convey("when a validation error is written", func() {
+			createIdentityResponse.writeError(ctx, w, errors.Wrap(schema.ErrEmailValidation, "create: failed validation"))
+
+			Convey("then the body contains the code, message, request ID and invalid field", func() {
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+
+				var body ErrorResponse
+				So(json.Unmarshal(w.Body.Bytes(), &body), ShouldBeNil)
+				So(body, ShouldResemble, ErrorResponse{
+					Code:      "validation_failed",
+					Message:   schema.ErrEmailValidation.Error(),
+					RequestID: "123",
+					Details:   []FieldError{{Field: "email", Message: schema.ErrEmailValidation.Error()}},
+				})
+			})
+		})
+
+		Convey("when an unknown field error is written", func() {
+			createIdentityResponse.writeError(ctx, w, errors.Wrap(UnknownFieldErr{field: "deleted"}, "create: invalid request"))
+
+			Convey("then the body contains the unknown field", func() {
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+
+				var body ErrorResponse
+				So(json.Unmarshal(w.Body.Bytes(), &body), ShouldBeNil)
+				So(body, ShouldResemble, ErrorResponse{
+					Code:      "unknown_field",
+					Message:   `request body contains an unknown field "deleted"`,
+					RequestID: "123",
+					Details:   []FieldError{{Field: "deleted", Message: `request body contains an unknown field "deleted"`}},
+				})
+			})
+		})
+
+		Convey("when an error with a code is written", func() {
+			newTokenResponse.writeError(ctx, w, identity.ErrAuthenticateFailed)
+
+			Convey("then the body contains the code and message and no details", func() {
+				So(w.Code, ShouldEqual, http.StatusForbidden)
+
+				var body ErrorResponse
+				So(json.Unmarshal(w.Body.Bytes(), &body), ShouldBeNil)
+				So(body, ShouldResemble, ErrorResponse{
+					Code:      "authentication_failed",
+					Message:   identity.ErrAuthenticateFailed.Error(),
+					RequestID: "123",
+				})
+			})
+		})
+
+		Convey("when an error without a code is written", func() {
+			unknown := errors.New("wibble")
+			JSONResponseWriter{unknown: http.StatusTooManyRequests}.writeError(ctx, w, unknown)
+
+			Convey("then the code is derived from the status", func() {
+				So(w.Code, ShouldEqual, http.StatusTooManyRequests)
+
+				var body ErrorResponse
+				So(json.Unmarshal(w.Body.Bytes(), &body), ShouldBeNil)
+				So(body.Code, ShouldEqual, "too_many_requests")
+				So(body.Message, ShouldEqual, "wibble")
+			})
+		})
+	})
+}
+
 func Test_WriteErrorResolveUnsuccessful(t *testing.T) {
 	Convey("should write expected error status and message to http response", t, func() {
 		w := httptest.NewRecorder()
@@ -49,11 +123,30 @@ func Test_WriteErrorResolveUnsuccessful(t *testing.T) {
 
 		createIdentityResponse.writeError(context.Background(), w, expectedErr)
 
-		So(w.Header().Get(headerContentType), ShouldEqual, "text/plain; charset=utf-8")
+		So(w.Header().Get(headerContentType), ShouldEqual, mimeTypeJSON)
 		assertErrorResponse(w.Code, http.StatusInternalServerError, w.Body.String(), ErrInternalServerError.Error())
 	})
 }
 
+func Test_WriteErrorResolveCause(t *testing.T) {
+	Convey("should write the status of the cause of a wrapped error to http response", t, func() {
+		w := httptest.NewRecorder()
+		err := errors.Wrap(persistence.ErrTimeout, "error getting identity by ID")
+
+		newTokenResponse.writeError(context.Background(), w, err)
+
+		assertErrorResponse(w.Code, http.StatusGatewayTimeout, w.Body.String(), persistence.ErrTimeout.Error())
+	})
+
+	Convey("should write service unavailable status to http response if the store is unavailable", t, func() {
+		w := httptest.NewRecorder()
+
+		getIdentityResponse.writeError(context.Background(), w, persistence.ErrUnavailable)
+
+		assertErrorResponse(w.Code, http.StatusServiceUnavailable, w.Body.String(), persistence.ErrUnavailable.Error())
+	})
+}
+
 func Test_WriteErrorMarshalError(t *testing.T) {
 	Convey("should write expected error status and message to http response", t, func() {
 		w := httptest.NewRecorder()
@@ -62,7 +155,7 @@ func Test_WriteErrorMarshalError(t *testing.T) {
 		c := make(chan int, 1)
 		createIdentityResponse.writeEntity(context.Background(), w, c, http.StatusOK)
 
-		So(w.Header().Get(headerContentType), ShouldEqual, "text/plain; charset=utf-8")
+		So(w.Header().Get(headerContentType), ShouldEqual, mimeTypeJSON)
 		assertErrorResponse(w.Code, http.StatusInternalServerError, w.Body.String(), ErrInternalServerError.Error())
 	})
 }
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"context"
+	"github.com/ONSdigital/go-ns/audit"
+	"github.com/ONSdigital/go-ns/common"
+	"github.com/ONSdigital/go-ns/log"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"net/http"
+)
+
+// VerifyIdentityHandler is a POST HTTP handler confirming the email address of the identity issued the verification
+// token in the request path. A request to this endpoint will create an audit event showing an attempt to verify an
+// identity was made followed by another event - successful or unsuccessful depending on outcome of processing the
+// request. A verification token can only be used once.
+func (api *API) VerifyIdentityHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if auditErr := api.auditor.Record(ctx, verifyIdentityAction, audit.Attempted, nil); auditErr != nil {
+		verifyIdentityResponse.writeError(ctx, w, auditErr)
+		return
+	}
+
+	response, err := api.verifyIdentity(ctx, r)
+	if err != nil {
+		log.ErrorCtx(ctx, errors.Wrap(err, "verifyIdentity: error"), nil)
+		api.auditor.Record(ctx, verifyIdentityAction, audit.Unsuccessful, nil)
+		verifyIdentityResponse.writeError(ctx, w, err)
+		return
+	}
+
+	if err := api.auditor.Record(ctx, verifyIdentityAction, audit.Successful, common.Params{"id": response.ID}); err != nil {
+		verifyIdentityResponse.writeError(ctx, w, err)
+		return
+	}
+
+	verifyIdentityResponse.writeEntity(ctx, w, response, http.StatusOK)
+	log.InfoCtx(ctx, "verifyIdentity: identity verified successfully", log.Data{"id": response.ID})
+}
+
+func (api *API) verifyIdentity(ctx context.Context, r *http.Request) (*IdentityVerified, error) {
+	verificationToken := mux.Vars(r)["token"]
+	if verificationToken == "" {
+		return nil, ErrNoVerificationToken
+	}
+
+	i, err := api.IdentityService.Verify(ctx, verificationToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IdentityVerified{ID: i.ID, Verified: i.Verified}, nil
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/ONSdigital/dp-identity-api/api/apitest"
+	"github.com/ONSdigital/dp-identity-api/identity"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/ONSdigital/go-ns/audit"
+	"github.com/ONSdigital/go-ns/audit/auditortest"
+	"github.com/ONSdigital/go-ns/common"
+	"github.com/gorilla/mux"
+	. "github.com/smartystreets/goconvey/convey"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const verifyIdentityURL = "http://localhost:23800/identity/verify/dpidv_token"
+
+func newVerifyRequest(token string) *http.Request {
+	r := httptest.NewRequest("POST", verifyIdentityURL, nil)
+	return mux.SetURLVars(r, map[string]string{"token": token})
+}
+
+func TestAPI_VerifyIdentityHandler(t *testing.T) {
+	Convey("given a verification token", t, func() {
+		auditMock := auditortest.New()
+		serviceMock := &apitest.IdentityServiceMock{
+			VerifyFunc: func(ctx context.Context, token string) (*schema.Identity, error) {
+				return &schema.Identity{ID: ID, Verified: true}, nil
+			},
+		}
+
+		identityAPI := &API{auditor: auditMock, IdentityService: serviceMock}
+
+		Convey("when the token is valid", func() {
+			w := httptest.NewRecorder()
+			identityAPI.VerifyIdentityHandler(w, newVerifyRequest("dpidv_token"))
+
+			Convey("then the verified identity is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+
+				var body IdentityVerified
+				So(json.Unmarshal(w.Body.Bytes(), &body), ShouldBeNil)
+				So(body, ShouldResemble, IdentityVerified{ID: ID, Verified: true})
+
+				So(serviceMock.VerifyCalls(), ShouldHaveLength, 1)
+				So(serviceMock.VerifyCalls()[0].Token, ShouldEqual, "dpidv_token")
+			})
+
+			Convey("and attempted and successful audit events are recorded", func() {
+				auditMock.AssertRecordCalls(
+					auditortest.Expected{Action: verifyIdentityAction, Result: audit.Attempted, Params: nil},
+					auditortest.Expected{Action: verifyIdentityAction, Result: audit.Successful, Params: common.Params{"id": ID}},
+				)
+			})
+		})
+
+		Convey("when the token is not found or has expired", func() {
+			serviceMock.VerifyFunc = func(ctx context.Context, token string) (*schema.Identity, error) {
+				return nil, identity.ErrVerificationTokenNotFound
+			}
+
+			w := httptest.NewRecorder()
+			identityAPI.VerifyIdentityHandler(w, newVerifyRequest("dpidv_token"))
+
+			Convey("then status 404 is returned", func() {
+				assertErrorResponse(w.Code, http.StatusNotFound, w.Body.String(), identity.ErrVerificationTokenNotFound.Error())
+			})
+
+			Convey("and attempted and unsuccessful audit events are recorded", func() {
+				auditMock.AssertRecordCalls(
+					auditortest.Expected{Action: verifyIdentityAction, Result: audit.Attempted, Params: nil},
+					auditortest.Expected{Action: verifyIdentityAction, Result: audit.Unsuccessful, Params: nil},
+				)
+			})
+		})
+
+		Convey("when the request does not contain a token", func() {
+			w := httptest.NewRecorder()
+			identityAPI.VerifyIdentityHandler(w, newVerifyRequest(""))
+
+			Convey("then status 400 is returned and the identity service is not called", func() {
+				assertErrorResponse(w.Code, http.StatusBadRequest, w.Body.String(), ErrNoVerificationToken.Error())
+				So(serviceMock.VerifyCalls(), ShouldHaveLength, 0)
+			})
+		})
+	})
+
+	Convey("given audit action attempted returns an error", t, func() {
+		auditMock := auditortest.NewErroring(verifyIdentityAction, audit.Attempted)
+		serviceMock := &apitest.IdentityServiceMock{}
+		identityAPI := &API{auditor: auditMock, IdentityService: serviceMock}
+
+		w := httptest.NewRecorder()
+		identityAPI.VerifyIdentityHandler(w, newVerifyRequest("dpidv_token"))
+
+		Convey("then status 500 is returned and the identity is not verified", func() {
+			assertErrorResponse(w.Code, http.StatusInternalServerError, w.Body.String(), ErrInternalServerError.Error())
+			So(serviceMock.VerifyCalls(), ShouldHaveLength, 0)
+		})
+	})
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// v1PathPrefix is the path prefix of the v1 endpoints, matching the swagger basePath.
+	v1PathPrefix = "/v1"
+
+	deprecationHeaderKey = "Deprecation"
+	sunsetHeaderKey      = "Sunset"
+	linkHeaderKey        = "Link"
+)
+
+// unversionedDeprecatedDate is when the unversioned paths were deprecated in favour of v1.
+var unversionedDeprecatedDate = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+// deprecated wraps the handler of an unversioned path, adding the Deprecation (RFC 9745) and Sunset (RFC 8594)
+// headers to the response, and a Link header to the v1 path succeeding it. The Sunset header is omitted if
+// UnversionedSunset is not set.
+func (api *API) deprecated(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(deprecationHeaderKey, "@"+strconv.FormatInt(unversionedDeprecatedDate.Unix(), 10))
+		if !api.UnversionedSunset.IsZero() {
+			w.Header().Set(sunsetHeaderKey, api.UnversionedSunset.UTC().Format(http.TimeFormat))
+		}
+		w.Header().Set(linkHeaderKey, "<"+v1PathPrefix+r.URL.EscapedPath()+`>; rel="successor-version"`)
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"bufio"
+	"github.com/gorilla/mux"
+	. "github.com/smartystreets/goconvey/convey"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	swaggerBasePath  = regexp.MustCompile(`^basePath:\s*"?([^"]+)"?$`)
+	swaggerPath      = regexp.MustCompile(`^  (/\S*):$`)
+	swaggerOperation = regexp.MustCompile(`^    (get|put|post|delete|patch|head|options):$`)
+)
+
+// swaggerRoutes return the method and full path of each operation in the swagger spec. Only the basePath and paths
+// are read, so the spec is scanned line by line rather than parsed.
+func swaggerRoutes(t *testing.T, file string) []string {
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var basePath, path string
+	var inPaths bool
+	routes := []string{}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case swaggerBasePath.MatchString(line):
+			basePath = swaggerBasePath.FindStringSubmatch(line)[1]
+		case line == "paths:":
+			inPaths = true
+		case inPaths && len(line) > 0 && line[0] != ' ':
+			inPaths = false
+		case inPaths && swaggerPath.MatchString(line):
+			path = swaggerPath.FindStringSubmatch(line)[1]
+		case inPaths && swaggerOperation.MatchString(line):
+			method := strings.ToUpper(swaggerOperation.FindStringSubmatch(line)[1])
+			routes = append(routes, method+" "+basePath+path)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(routes)
+	return routes
+}
+
+// registeredRoutes return the method and path template of each route registered on the router, split by whether the
+// path has the prefix.
+func registeredRoutes(t *testing.T, router *mux.Router, prefix string) (prefixed []string, other []string) {
+	prefixed, other = []string{}, []string{}
+	err := router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		methods, err := route.GetMethods()
+		if err != nil {
+			// subrouters have no methods.
+			return nil
+		}
+
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+
+		for _, method := range methods {
+			if strings.HasPrefix(template, prefix+"/") {
+				prefixed = append(prefixed, method+" "+template)
+			} else {
+				other = append(other, method+" "+template)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(prefixed)
+	sort.Strings(other)
+	return prefixed, other
+}
+
+func TestAPI_RegisterEndpointsMatchesSwagger(t *testing.T) {
+	Convey("given the endpoints are registered", t, func() {
+		router := mux.NewRouter()
+		(&API{}).RegisterEndpoints(router)
+
+		v1, unversioned := registeredRoutes(t, router, v1PathPrefix)
+
+		Convey("then the v1 routes match the operations in the swagger spec", func() {
+			So(v1, ShouldResemble, swaggerRoutes(t, "../swagger.yaml"))
+		})
+
+		Convey("and every v1 route has an unversioned alias", func() {
+			aliases := make([]string, 0, len(v1))
+			for _, route := range v1 {
+				aliases = append(aliases, strings.Replace(route, " "+v1PathPrefix, " ", 1))
+			}
+			So(unversioned, ShouldResemble, aliases)
+		})
+	})
+}
+
+func TestAPI_UnversionedRoutesDeprecated(t *testing.T) {
+	Convey("given the endpoints are registered with a sunset date", t, func() {
+		sunset := time.Date(2027, time.April, 19, 0, 0, 0, 0, time.UTC)
+		a := &API{UnversionedSunset: sunset}
+
+		router := mux.NewRouter()
+		a.RegisterEndpoints(router)
+
+		Convey("when a request is made to an unversioned path", func() {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, createIdentityURL, nil))
+
+			Convey("then the deprecation headers are returned", func() {
+				So(w.Header().Get("Deprecation"), ShouldEqual, "@1792368000")
+				So(w.Header().Get("Sunset"), ShouldEqual, "Mon, 19 Apr 2027 00:00:00 GMT")
+				So(w.Header().Get("Link"), ShouldEqual, `</v1/identity>; rel="successor-version"`)
+			})
+		})
+
+		Convey("when a request is made to a v1 path", func() {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "http://localhost:23800/v1/identity", nil))
+
+			Convey("then the deprecation headers are not returned", func() {
+				So(w.Header().Get("Deprecation"), ShouldBeEmpty)
+				So(w.Header().Get("Sunset"), ShouldBeEmpty)
+				So(w.Header().Get("Link"), ShouldBeEmpty)
+			})
+		})
+	})
+
+	Convey("given the endpoints are registered without a sunset date", t, func() {
+		router := mux.NewRouter()
+		(&API{}).RegisterEndpoints(router)
+
+		Convey("when a request is made to an unversioned path", func() {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, createIdentityURL, nil))
+
+			Convey("then the Sunset header is omitted", func() {
+				So(w.Header().Get("Deprecation"), ShouldNotBeEmpty)
+				So(w.Header().Get("Sunset"), ShouldBeEmpty)
+			})
+		})
+	})
+}
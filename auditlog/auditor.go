@@ -0,0 +1,51 @@
+// Package auditlog provides an audit.AuditorService which also stores the audit events about each identity so they
+// can be included in an export of the identity's data.
+package auditlog
+
+import (
+	"context"
+	"github.com/ONSdigital/dp-identity-api/persistence"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/ONSdigital/go-ns/audit"
+	"github.com/ONSdigital/go-ns/common"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// identityIDParam is the audit param identifying the identity an event is about.
+const identityIDParam = "id"
+
+// Auditor records audit events using the wrapped Auditor and stores the events about an identity, identified by the
+// id param, in Store. An event that cannot be stored fails the audited request in the same way as an event that
+// cannot be recorded.
+type Auditor struct {
+	Auditor audit.AuditorService
+	Store   persistence.AuditStore
+}
+
+// Record the audit event, storing it if it is about an identity.
+func (a *Auditor) Record(ctx context.Context, action string, result string, params common.Params) error {
+	if err := a.Auditor.Record(ctx, action, result, params); err != nil {
+		return err
+	}
+
+	id := params[identityIDParam]
+	if id == "" {
+		return nil
+	}
+
+	event := schema.AuditEvent{IdentityID: id, Action: action, Result: result, Created: time.Now()}
+	if len(params) > 1 {
+		event.Params = make(map[string]string, len(params)-1)
+		for k, v := range params {
+			if k != identityIDParam {
+				event.Params[k] = v
+			}
+		}
+	}
+
+	if err := a.Store.StoreEvent(ctx, event); err != nil {
+		return errors.Wrap(err, "error storing audit event")
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package auditlog
+
+import (
+	"context"
+	"errors"
+	"github.com/ONSdigital/dp-identity-api/persistence/persistencetest"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/ONSdigital/go-ns/audit"
+	"github.com/ONSdigital/go-ns/audit/auditortest"
+	"github.com/ONSdigital/go-ns/common"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+var errTest = errors.New("test error")
+
+func TestAuditor_Record(t *testing.T) {
+	Convey("given an auditor storing audit events", t, func() {
+		wrapped := auditortest.New()
+		store := &persistencetest.AuditStoreMock{
+			StoreEventFunc: func(ctx context.Context, event schema.AuditEvent) error {
+				return nil
+			},
+		}
+
+		a := &Auditor{Auditor: wrapped, Store: store}
+
+		Convey("when an event about an identity is recorded", func() {
+			params := common.Params{"id": "666", "requested_by": "999"}
+			err := a.Record(context.Background(), "disableIdentity", audit.Successful, params)
+
+			Convey("then it is recorded by the wrapped auditor", func() {
+				So(err, ShouldBeNil)
+				wrapped.AssertRecordCalls(auditortest.Expected{Action: "disableIdentity", Result: audit.Successful, Params: params})
+			})
+
+			Convey("and it is stored against the identity", func() {
+				So(store.StoreEventCalls(), ShouldHaveLength, 1)
+				event := store.StoreEventCalls()[0].Event
+				So(event.IdentityID, ShouldEqual, "666")
+				So(event.Action, ShouldEqual, "disableIdentity")
+				So(event.Result, ShouldEqual, audit.Successful)
+				So(event.Params, ShouldResemble, map[string]string{"requested_by": "999"})
+				So(event.Created, ShouldHappenWithin, time.Minute, time.Now())
+			})
+		})
+
+		Convey("when an event not about an identity is recorded", func() {
+			err := a.Record(context.Background(), "createIdentity", audit.Attempted, nil)
+
+			Convey("then it is recorded by the wrapped auditor but not stored", func() {
+				So(err, ShouldBeNil)
+				wrapped.AssertRecordCalls(auditortest.Expected{Action: "createIdentity", Result: audit.Attempted, Params: nil})
+				So(store.StoreEventCalls(), ShouldHaveLength, 0)
+			})
+		})
+
+		Convey("when storing the event returns an error", func() {
+			store.StoreEventFunc = func(ctx context.Context, event schema.AuditEvent) error {
+				return errTest
+			}
+
+			err := a.Record(context.Background(), "disableIdentity", audit.Successful, common.Params{"id": "666"})
+
+			Convey("then the error is returned", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+
+	Convey("given the wrapped auditor returns an error", t, func() {
+		store := &persistencetest.AuditStoreMock{}
+		a := &Auditor{Auditor: auditortest.NewErroring("disableIdentity", audit.Successful), Store: store}
+
+		err := a.Record(context.Background(), "disableIdentity", audit.Successful, common.Params{"id": "666"})
+
+		Convey("then the error is returned and the event is not stored", func() {
+			So(err, ShouldNotBeNil)
+			So(store.StoreEventCalls(), ShouldHaveLength, 0)
+		})
+	})
+}
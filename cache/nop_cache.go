@@ -15,4 +15,13 @@ func (c *NOP) StoreToken(ctx context.Context, token string, i schema.Identity, t
 
 func (c *NOP) GetIdentityByToken(ctx context.Context, token string) (*schema.Identity, time.Duration, error) {
 	return nil, 0, nil
-}
\ No newline at end of file
+}
+
+func (c *NOP) DeleteToken(ctx context.Context, token string) error {
+	return nil
+}
+
+// Healthcheck implements healthcheck.Client, the NOP cache is always healthy.
+func (c *NOP) Healthcheck() (string, error) {
+	return "cache", nil
+}
@@ -2,27 +2,170 @@ package config
 
 import (
 	"encoding/json"
+	"net/url"
 	"time"
 
+	"github.com/ONSdigital/dp-identity-api/schema"
 	"github.com/ONSdigital/go-ns/log"
 	"github.com/kelseyhightower/envconfig"
+	"github.com/pkg/errors"
+)
+
+const (
+	// StorageMongo selects MongoDB as the persistence backend.
+	StorageMongo = "mongo"
+
+	// StoragePostgres selects PostgreSQL as the persistence backend.
+	StoragePostgres = "postgres"
+
+	// StorageMemory selects the in-memory persistence backend. Data is lost when the service stops.
+	StorageMemory = "memory"
+
+	// TracingNone disables exporting trace spans.
+	TracingNone = "none"
+
+	// TracingStdout writes trace spans to stdout, for local runs.
+	TracingStdout = "stdout"
+
+	// TracingJaeger sends trace spans to a Jaeger collector.
+	TracingJaeger = "jaeger"
+
+	// NotifierNone does not send verification tokens to new identities.
+	NotifierNone = "none"
+
+	// NotifierLog logs the verification URL of new identities, for local runs only as the token is written to the logs.
+	NotifierLog = "log"
+
+	// RateLimitNone disables rate limiting.
+	RateLimitNone = "none"
+
+	// RateLimitMemory limits requests using buckets held in memory, so each instance has its own limits.
+	RateLimitMemory = "memory"
+
+	// RateLimitShared limits requests using buckets held in the persistence backend, shared by every instance.
+	RateLimitShared = "shared"
+)
+
+var (
+	ErrInvalidAPIHost               = errors.New("api host must be an absolute http(s) URL")
+	ErrInvalidStorageBackend        = errors.New("storage backend must be one of mongo, postgres or memory")
+	ErrInvalidUserType              = errors.New("token lifetime user type must be one of admin, service or user")
+	ErrInvalidTokenLifetime         = errors.New("token lifetime must be greater than zero")
+	ErrInvalidTokenCacheTTL         = errors.New("token cache TTL must be greater than zero")
+	ErrInvalidExpiryTime            = errors.New("token expiry time must be in the format HH:MM or HH:MM:SS")
+	ErrInvalidExpiryZone            = errors.New("token expiry time zone is not a valid IANA time zone")
+	ErrInvalidIdleTimeout           = errors.New("token idle timeout must be more than twice the last used interval")
+	ErrInvalidTraceExporter         = errors.New("tracing exporter must be one of none, stdout or jaeger")
+	ErrInvalidSampleRatio           = errors.New("tracing sample ratio must be between 0 and 1")
+	ErrInvalidNotifier              = errors.New("verification notifier must be one of none or log")
+	ErrInvalidVerificationLifetime  = errors.New("verification token lifetime must be greater than zero")
+	ErrInvalidImpersonationLifetime = errors.New("impersonation token lifetime must be greater than zero")
+	ErrInvalidRateLimitBackend      = errors.New("rate limit backend must be one of none, memory or shared")
+	ErrInvalidRateLimit             = errors.New("rate limit rates and bursts must be greater than zero")
 )
 
 // Configuration structure which hold information for configuring the import API
 type Configuration struct {
 	BindAddr                string        `envconfig:"BIND_ADDR"`
+	APIHost                 string        `envconfig:"API_HOST"`
+	StorageBackend          string        `envconfig:"STORAGE_BACKEND"`
 	GracefulShutdownTimeout time.Duration `envconfig:"GRACEFUL_SHUTDOWN_TIMEOUT"`
 	HealthCheckInterval     time.Duration `envconfig:"HEALTHCHECK_INTERVAL"`
 	HealthCheckTimeout      time.Duration `envconfig:"HEALTHCHECK_TIMEOUT"`
+	TokenHashSecret         string        `envconfig:"TOKEN_HASH_SECRET"           json:"-"`
+	BootstrapSecret         string        `envconfig:"BOOTSTRAP_SECRET"            json:"-"`
+	SelfRegistration        bool          `envconfig:"SELF_REGISTRATION_ENABLED"`
+	UnversionedSunset       time.Time     `envconfig:"UNVERSIONED_ROUTES_SUNSET"`
 	MongoConfig             MongoConfig
+	PostgresConfig          PostgresConfig
+	PasswordConfig          PasswordConfig
+	TokenConfig             TokenConfig
+	TracingConfig           TracingConfig
+	VerificationConfig      VerificationConfig
+	ImpersonationConfig     ImpersonationConfig
+	RateLimitConfig         RateLimitConfig
 }
 
 // MongoConfig contains the config required to connect to MongoDB.
 type MongoConfig struct {
-	BindAddr           string `envconfig:"MONGODB_BIND_ADDR"   json:"-"`
-	IdentityCollection string `envconfig:"MONGODB_IDENTITY_COLLECTION"`
-	TokenCollection    string `envconfig:"MONGODB_TOKEN_COLLECTION"`
-	Database           string `envconfig:"MONGODB_DATABASE"`
+	BindAddr            string        `envconfig:"MONGODB_BIND_ADDR"   json:"-"`
+	IdentityCollection  string        `envconfig:"MONGODB_IDENTITY_COLLECTION"`
+	TokenCollection     string        `envconfig:"MONGODB_TOKEN_COLLECTION"`
+	AuditCollection     string        `envconfig:"MONGODB_AUDIT_COLLECTION"`
+	TombstoneCollection string        `envconfig:"MONGODB_TOMBSTONE_COLLECTION"`
+	RateLimitCollection string        `envconfig:"MONGODB_RATE_LIMIT_COLLECTION"`
+	Database            string        `envconfig:"MONGODB_DATABASE"`
+	QueryTimeout        time.Duration `envconfig:"MONGODB_QUERY_TIMEOUT"`
+}
+
+// PostgresConfig contains the config required to connect to PostgreSQL.
+type PostgresConfig struct {
+	URL          string        `envconfig:"POSTGRES_URL" json:"-"`
+	QueryTimeout time.Duration `envconfig:"POSTGRES_QUERY_TIMEOUT"`
+}
+
+// PasswordConfig contains the config for hashing user passwords.
+type PasswordConfig struct {
+	Algorithm     string `envconfig:"PASSWORD_HASH_ALGORITHM"`
+	BcryptCost    int    `envconfig:"PASSWORD_BCRYPT_COST"`
+	Argon2Time    uint32 `envconfig:"PASSWORD_ARGON2_TIME"`
+	Argon2Memory  uint32 `envconfig:"PASSWORD_ARGON2_MEMORY"`
+	Argon2Threads uint8  `envconfig:"PASSWORD_ARGON2_THREADS"`
+}
+
+// TokenConfig contains the config for the lifetime and caching of identity tokens. UserTypeLifetimes overrides
+// Lifetime for identities with a matching UserType, in the format "admin:30m,service:24h".
+//
+// If ExpiryTime is set tokens without a user type override expire at that time each day in ExpiryTimeZone instead of
+// after Lifetime. Tokens issued less than ExpiryMinLifetime before the cut-off expire at the following one.
+//
+// If IdleTimeout is set tokens unused for longer are rejected, use is recorded at most once every LastUsedInterval.
+type TokenConfig struct {
+	Lifetime          time.Duration            `envconfig:"TOKEN_LIFETIME"`
+	UserTypeLifetimes map[string]time.Duration `envconfig:"TOKEN_USER_TYPE_LIFETIMES"`
+	CacheTTL          time.Duration            `envconfig:"TOKEN_CACHE_TTL"`
+	ExpiryTime        string                   `envconfig:"TOKEN_EXPIRY_TIME"`
+	ExpiryTimeZone    string                   `envconfig:"TOKEN_EXPIRY_TIME_ZONE"`
+	ExpiryMinLifetime time.Duration            `envconfig:"TOKEN_EXPIRY_MIN_LIFETIME"`
+	IdleTimeout       time.Duration            `envconfig:"TOKEN_IDLE_TIMEOUT"`
+	LastUsedInterval  time.Duration            `envconfig:"TOKEN_LAST_USED_INTERVAL"`
+}
+
+// TracingConfig contains the config for exporting OpenTelemetry trace spans. SampleRatio is the fraction of traces
+// started by this service which are sampled, traces started by a caller follow the caller's sampling decision.
+type TracingConfig struct {
+	Exporter       string  `envconfig:"TRACING_EXPORTER"`
+	JaegerEndpoint string  `envconfig:"TRACING_JAEGER_ENDPOINT"`
+	SampleRatio    float64 `envconfig:"TRACING_SAMPLE_RATIO"`
+}
+
+// VerificationConfig contains the config for verifying the email address of new identities. Verification tokens are
+// sent by Notifier and expire after TokenLifetime. If Required unverified identities are refused new tokens.
+type VerificationConfig struct {
+	Notifier      string        `envconfig:"VERIFICATION_NOTIFIER"`
+	TokenLifetime time.Duration `envconfig:"VERIFICATION_TOKEN_LIFETIME"`
+	Required      bool          `envconfig:"VERIFICATION_REQUIRED"`
+}
+
+// ImpersonationConfig contains the config for admin impersonation tokens. If Enabled admins can be issued a token to
+// act as another identity, which expires after TokenLifetime.
+type ImpersonationConfig struct {
+	Enabled       bool          `envconfig:"IMPERSONATION_ENABLED"`
+	TokenLifetime time.Duration `envconfig:"IMPERSONATION_TOKEN_LIFETIME"`
+}
+
+// RateLimitConfig contains the config for limiting requests per client IP and per token. Rates are in requests per
+// second and bursts are the number of requests allowed at once. If TrustForwardedFor the client IP is read from the
+// last X-Forwarded-For entry, which must be set by a trusted proxy. Expired shared buckets are purged at most once
+// every PurgeInterval.
+type RateLimitConfig struct {
+	Backend           string        `envconfig:"RATE_LIMIT_BACKEND"`
+	IPRate            float64       `envconfig:"RATE_LIMIT_IP_RATE"`
+	IPBurst           int           `envconfig:"RATE_LIMIT_IP_BURST"`
+	TokenRate         float64       `envconfig:"RATE_LIMIT_TOKEN_RATE"`
+	TokenBurst        int           `envconfig:"RATE_LIMIT_TOKEN_BURST"`
+	TrustForwardedFor bool          `envconfig:"RATE_LIMIT_TRUST_FORWARDED_FOR"`
+	PurgeInterval     time.Duration `envconfig:"RATE_LIMIT_PURGE_INTERVAL"`
 }
 
 var cfg *Configuration
@@ -35,14 +178,64 @@ func Get() (*Configuration, error) {
 
 	cfg = &Configuration{
 		BindAddr:                ":23800",
+		APIHost:                 "http://localhost:23800",
+		StorageBackend:          StorageMongo,
 		GracefulShutdownTimeout: 5 * time.Second,
 		HealthCheckInterval:     30 * time.Second,
 		HealthCheckTimeout:      2 * time.Second,
+		UnversionedSunset:       time.Date(2027, time.April, 19, 0, 0, 0, 0, time.UTC),
 		MongoConfig: MongoConfig{
-			BindAddr:           "localhost:27017",
-			IdentityCollection: "identities",
-			TokenCollection:    "tokens",
-			Database:           "identities",
+			BindAddr:            "localhost:27017",
+			IdentityCollection:  "identities",
+			TokenCollection:     "tokens",
+			AuditCollection:     "audit_events",
+			TombstoneCollection: "tombstones",
+			RateLimitCollection: "rate_limits",
+			Database:            "identities",
+			QueryTimeout:        5 * time.Second,
+		},
+		PostgresConfig: PostgresConfig{
+			URL:          "postgres://localhost:5432/identities?sslmode=disable",
+			QueryTimeout: 5 * time.Second,
+		},
+		PasswordConfig: PasswordConfig{
+			Algorithm:     "bcrypt",
+			BcryptCost:    10,
+			Argon2Time:    1,
+			Argon2Memory:  64 * 1024,
+			Argon2Threads: 4,
+		},
+		TokenConfig: TokenConfig{
+			Lifetime: time.Hour,
+			UserTypeLifetimes: map[string]time.Duration{
+				"admin":   30 * time.Minute,
+				"service": 24 * time.Hour,
+			},
+			CacheTTL:          15 * time.Minute,
+			ExpiryTimeZone:    "UTC",
+			ExpiryMinLifetime: time.Hour,
+			LastUsedInterval:  time.Minute,
+		},
+		TracingConfig: TracingConfig{
+			Exporter:       TracingNone,
+			JaegerEndpoint: "http://localhost:14268/api/traces",
+			SampleRatio:    1,
+		},
+		VerificationConfig: VerificationConfig{
+			Notifier:      NotifierNone,
+			TokenLifetime: 24 * time.Hour,
+		},
+		ImpersonationConfig: ImpersonationConfig{
+			Enabled:       false,
+			TokenLifetime: 15 * time.Minute,
+		},
+		RateLimitConfig: RateLimitConfig{
+			Backend:       RateLimitMemory,
+			IPRate:        50,
+			IPBurst:       200,
+			TokenRate:     10,
+			TokenBurst:    50,
+			PurgeInterval: time.Minute,
 		},
 	}
 
@@ -50,11 +243,100 @@ func Get() (*Configuration, error) {
 		return nil, err
 	}
 
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
 	// sensitive fields are omitted from config.String().
 	log.Info("loaded service configuration", log.Data{"config": cfg})
 	return cfg, nil
 }
 
+// Validate return an error if any of the configured values are invalid.
+func (config Configuration) Validate() error {
+	host, err := url.Parse(config.APIHost)
+	if err != nil || (host.Scheme != "http" && host.Scheme != "https") || host.Host == "" {
+		return ErrInvalidAPIHost
+	}
+
+	switch config.StorageBackend {
+	case StorageMongo, StoragePostgres, StorageMemory:
+	default:
+		return ErrInvalidStorageBackend
+	}
+
+	if config.TokenConfig.Lifetime <= 0 {
+		return ErrInvalidTokenLifetime
+	}
+
+	for userType, lifetime := range config.TokenConfig.UserTypeLifetimes {
+		switch userType {
+		case schema.UserTypeAdmin, schema.UserTypeService, schema.UserTypeUser:
+		default:
+			return errors.Wrapf(ErrInvalidUserType, "user type %q", userType)
+		}
+		if lifetime <= 0 {
+			return errors.Wrapf(ErrInvalidTokenLifetime, "user type %q", userType)
+		}
+	}
+
+	if config.TokenConfig.CacheTTL <= 0 {
+		return ErrInvalidTokenCacheTTL
+	}
+
+	if idle := config.TokenConfig.IdleTimeout; idle > 0 && (config.TokenConfig.LastUsedInterval < 0 || 2*config.TokenConfig.LastUsedInterval >= idle) {
+		return ErrInvalidIdleTimeout
+	}
+
+	if config.TokenConfig.ExpiryTime != "" {
+		if _, err := time.Parse("15:04:05", config.TokenConfig.ExpiryTime); err != nil {
+			if _, err := time.Parse("15:04", config.TokenConfig.ExpiryTime); err != nil {
+				return ErrInvalidExpiryTime
+			}
+		}
+
+		if _, err := time.LoadLocation(config.TokenConfig.ExpiryTimeZone); err != nil {
+			return ErrInvalidExpiryZone
+		}
+	}
+
+	switch config.TracingConfig.Exporter {
+	case TracingNone, TracingStdout, TracingJaeger:
+	default:
+		return ErrInvalidTraceExporter
+	}
+
+	if config.TracingConfig.SampleRatio < 0 || config.TracingConfig.SampleRatio > 1 {
+		return ErrInvalidSampleRatio
+	}
+
+	switch config.VerificationConfig.Notifier {
+	case NotifierNone, NotifierLog:
+	default:
+		return ErrInvalidNotifier
+	}
+
+	if config.VerificationConfig.TokenLifetime <= 0 {
+		return ErrInvalidVerificationLifetime
+	}
+
+	if config.ImpersonationConfig.Enabled && config.ImpersonationConfig.TokenLifetime <= 0 {
+		return ErrInvalidImpersonationLifetime
+	}
+
+	switch config.RateLimitConfig.Backend {
+	case RateLimitNone:
+	case RateLimitMemory, RateLimitShared:
+		rl := config.RateLimitConfig
+		if rl.IPRate <= 0 || rl.IPBurst <= 0 || rl.TokenRate <= 0 || rl.TokenBurst <= 0 {
+			return ErrInvalidRateLimit
+		}
+	default:
+		return ErrInvalidRateLimitBackend
+	}
+	return nil
+}
+
 // String is implemented to prevent sensitive fields being logged.
 // The config is returned as JSON with sensitive fields omitted.
 func (config Configuration) String() string {
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/pkg/errors"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -19,13 +20,239 @@ func TestSpec(t *testing.T) {
 
 			Convey("The values should be set to the expected defaults", func() {
 				So(cfg.BindAddr, ShouldEqual, ":23800")
+				So(cfg.APIHost, ShouldEqual, "http://localhost:23800")
+				So(cfg.StorageBackend, ShouldEqual, StorageMongo)
 				So(cfg.HealthCheckInterval, ShouldEqual, 30*time.Second)
 				So(cfg.HealthCheckTimeout, ShouldEqual, 2*time.Second)
+				So(cfg.BootstrapSecret, ShouldBeEmpty)
+				So(cfg.SelfRegistration, ShouldBeFalse)
+				So(cfg.UnversionedSunset, ShouldResemble, time.Date(2027, time.April, 19, 0, 0, 0, 0, time.UTC))
 				So(cfg.MongoConfig.Database, ShouldEqual, "identities")
 				So(cfg.MongoConfig.IdentityCollection, ShouldEqual, "identities")
 				So(cfg.MongoConfig.TokenCollection, ShouldEqual, "tokens")
+				So(cfg.MongoConfig.AuditCollection, ShouldEqual, "audit_events")
+				So(cfg.MongoConfig.TombstoneCollection, ShouldEqual, "tombstones")
+				So(cfg.MongoConfig.RateLimitCollection, ShouldEqual, "rate_limits")
 				So(cfg.MongoConfig.BindAddr, ShouldEqual, "localhost:27017")
+				So(cfg.MongoConfig.QueryTimeout, ShouldEqual, 5*time.Second)
+				So(cfg.PostgresConfig.URL, ShouldEqual, "postgres://localhost:5432/identities?sslmode=disable")
+				So(cfg.PostgresConfig.QueryTimeout, ShouldEqual, 5*time.Second)
+				So(cfg.PasswordConfig.Algorithm, ShouldEqual, "bcrypt")
+				So(cfg.PasswordConfig.BcryptCost, ShouldEqual, 10)
+				So(cfg.PasswordConfig.Argon2Time, ShouldEqual, 1)
+				So(cfg.PasswordConfig.Argon2Memory, ShouldEqual, 64*1024)
+				So(cfg.PasswordConfig.Argon2Threads, ShouldEqual, 4)
+				So(cfg.TokenConfig.Lifetime, ShouldEqual, time.Hour)
+				So(cfg.TokenConfig.UserTypeLifetimes, ShouldResemble, map[string]time.Duration{
+					"admin":   30 * time.Minute,
+					"service": 24 * time.Hour,
+				})
+				So(cfg.TokenConfig.CacheTTL, ShouldEqual, 15*time.Minute)
+				So(cfg.TokenConfig.ExpiryTime, ShouldBeEmpty)
+				So(cfg.TokenConfig.ExpiryTimeZone, ShouldEqual, "UTC")
+				So(cfg.TokenConfig.ExpiryMinLifetime, ShouldEqual, time.Hour)
+				So(cfg.TokenConfig.IdleTimeout, ShouldEqual, 0)
+				So(cfg.TokenConfig.LastUsedInterval, ShouldEqual, time.Minute)
+				So(cfg.TracingConfig.Exporter, ShouldEqual, TracingNone)
+				So(cfg.TracingConfig.JaegerEndpoint, ShouldEqual, "http://localhost:14268/api/traces")
+				So(cfg.TracingConfig.SampleRatio, ShouldEqual, 1)
+				So(cfg.VerificationConfig.Notifier, ShouldEqual, NotifierNone)
+				So(cfg.VerificationConfig.TokenLifetime, ShouldEqual, 24*time.Hour)
+				So(cfg.VerificationConfig.Required, ShouldBeFalse)
+				So(cfg.ImpersonationConfig.Enabled, ShouldBeFalse)
+				So(cfg.ImpersonationConfig.TokenLifetime, ShouldEqual, 15*time.Minute)
+				So(cfg.RateLimitConfig.Backend, ShouldEqual, RateLimitMemory)
+				So(cfg.RateLimitConfig.IPRate, ShouldEqual, 50)
+				So(cfg.RateLimitConfig.IPBurst, ShouldEqual, 200)
+				So(cfg.RateLimitConfig.TokenRate, ShouldEqual, 10)
+				So(cfg.RateLimitConfig.TokenBurst, ShouldEqual, 50)
+				So(cfg.RateLimitConfig.TrustForwardedFor, ShouldBeFalse)
+				So(cfg.RateLimitConfig.PurgeInterval, ShouldEqual, time.Minute)
 			})
 		})
 	})
 }
+
+func TestValidate(t *testing.T) {
+	valid := func() Configuration {
+		return Configuration{
+			APIHost:        "https://identity.ons.gov.uk",
+			StorageBackend: StorageMemory,
+			TokenConfig: TokenConfig{
+				Lifetime:          time.Hour,
+				UserTypeLifetimes: map[string]time.Duration{"admin": time.Minute},
+				CacheTTL:          time.Minute,
+			},
+			TracingConfig:      TracingConfig{Exporter: TracingNone},
+			VerificationConfig: VerificationConfig{Notifier: NotifierNone, TokenLifetime: time.Hour},
+			RateLimitConfig:    RateLimitConfig{Backend: RateLimitNone},
+		}
+	}
+
+	Convey("Given a valid configuration", t, func() {
+		So(valid().Validate(), ShouldBeNil)
+	})
+
+	Convey("Given an unsupported storage backend", t, func() {
+		for _, backend := range []string{"", "redis", "Mongo"} {
+			c := valid()
+			c.StorageBackend = backend
+			So(c.Validate(), ShouldEqual, ErrInvalidStorageBackend)
+		}
+	})
+
+	Convey("Given an invalid api host", t, func() {
+		for _, host := range []string{"", "localhost:23800", "ftp://localhost", "http://"} {
+			c := valid()
+			c.APIHost = host
+			So(c.Validate(), ShouldEqual, ErrInvalidAPIHost)
+		}
+	})
+
+	Convey("Given a token lifetime that is not greater than zero", t, func() {
+		c := valid()
+		c.TokenConfig.Lifetime = 0
+		So(c.Validate(), ShouldEqual, ErrInvalidTokenLifetime)
+	})
+
+	Convey("Given a user type token lifetime that is not greater than zero", t, func() {
+		c := valid()
+		c.TokenConfig.UserTypeLifetimes["service"] = -time.Minute
+		err := c.Validate()
+		So(errors.Cause(err), ShouldEqual, ErrInvalidTokenLifetime)
+		So(err.Error(), ShouldContainSubstring, "service")
+	})
+
+	Convey("Given a token lifetime for an unknown user type", t, func() {
+		c := valid()
+		c.TokenConfig.UserTypeLifetimes["viewer"] = time.Minute
+		err := c.Validate()
+		So(errors.Cause(err), ShouldEqual, ErrInvalidUserType)
+		So(err.Error(), ShouldContainSubstring, "viewer")
+	})
+
+	Convey("Given a token cache TTL that is not greater than zero", t, func() {
+		c := valid()
+		c.TokenConfig.CacheTTL = 0
+		So(c.Validate(), ShouldEqual, ErrInvalidTokenCacheTTL)
+	})
+
+	Convey("Given a token idle timeout", t, func() {
+		c := valid()
+		c.TokenConfig.IdleTimeout = 30 * time.Minute
+		c.TokenConfig.LastUsedInterval = time.Minute
+		So(c.Validate(), ShouldBeNil)
+
+		Convey("that is not more than twice the last used interval", func() {
+			c.TokenConfig.LastUsedInterval = 15 * time.Minute
+			So(c.Validate(), ShouldEqual, ErrInvalidIdleTimeout)
+		})
+
+		Convey("that is not more than the last used interval", func() {
+			c.TokenConfig.LastUsedInterval = 30 * time.Minute
+			So(c.Validate(), ShouldEqual, ErrInvalidIdleTimeout)
+
+			c.TokenConfig.LastUsedInterval = time.Hour
+			So(c.Validate(), ShouldEqual, ErrInvalidIdleTimeout)
+		})
+
+		Convey("with a negative last used interval", func() {
+			c.TokenConfig.LastUsedInterval = -time.Minute
+			So(c.Validate(), ShouldEqual, ErrInvalidIdleTimeout)
+		})
+	})
+
+	Convey("Given a daily token expiry time", t, func() {
+		c := valid()
+		c.TokenConfig.ExpiryTime = "02:00"
+		c.TokenConfig.ExpiryTimeZone = "Europe/London"
+		So(c.Validate(), ShouldBeNil)
+
+		Convey("that is not a valid time of day", func() {
+			c.TokenConfig.ExpiryTime = "2am"
+			So(c.Validate(), ShouldEqual, ErrInvalidExpiryTime)
+		})
+
+		Convey("in an unknown time zone", func() {
+			c.TokenConfig.ExpiryTimeZone = "Europe/Atlantis"
+			So(c.Validate(), ShouldEqual, ErrInvalidExpiryZone)
+		})
+	})
+
+	Convey("Given a tracing configuration", t, func() {
+		c := valid()
+		c.TracingConfig.Exporter = TracingJaeger
+		c.TracingConfig.SampleRatio = 0.25
+		So(c.Validate(), ShouldBeNil)
+
+		Convey("with an unsupported exporter", func() {
+			c.TracingConfig.Exporter = "zipkin"
+			So(c.Validate(), ShouldEqual, ErrInvalidTraceExporter)
+		})
+
+		Convey("with a sample ratio greater than 1", func() {
+			c.TracingConfig.SampleRatio = 1.5
+			So(c.Validate(), ShouldEqual, ErrInvalidSampleRatio)
+		})
+	})
+
+	Convey("Given a verification configuration", t, func() {
+		c := valid()
+		c.VerificationConfig.Notifier = NotifierLog
+		c.VerificationConfig.Required = true
+		So(c.Validate(), ShouldBeNil)
+
+		Convey("with an unsupported notifier", func() {
+			c.VerificationConfig.Notifier = "carrier-pigeon"
+			So(c.Validate(), ShouldEqual, ErrInvalidNotifier)
+		})
+
+		Convey("with a token lifetime of zero", func() {
+			c.VerificationConfig.TokenLifetime = 0
+			So(c.Validate(), ShouldEqual, ErrInvalidVerificationLifetime)
+		})
+	})
+
+	Convey("Given an impersonation configuration", t, func() {
+		c := valid()
+		c.ImpersonationConfig.Enabled = true
+		c.ImpersonationConfig.TokenLifetime = time.Minute
+		So(c.Validate(), ShouldBeNil)
+
+		Convey("with a token lifetime of zero", func() {
+			c.ImpersonationConfig.TokenLifetime = 0
+			So(c.Validate(), ShouldEqual, ErrInvalidImpersonationLifetime)
+
+			Convey("and impersonation disabled", func() {
+				c.ImpersonationConfig.Enabled = false
+				So(c.Validate(), ShouldBeNil)
+			})
+		})
+	})
+
+	Convey("Given a rate limit configuration", t, func() {
+		c := valid()
+		c.RateLimitConfig = RateLimitConfig{Backend: RateLimitShared, IPRate: 0.5, IPBurst: 10, TokenRate: 1, TokenBurst: 5}
+		So(c.Validate(), ShouldBeNil)
+
+		Convey("with an unsupported backend", func() {
+			c.RateLimitConfig.Backend = "redis"
+			So(c.Validate(), ShouldEqual, ErrInvalidRateLimitBackend)
+		})
+
+		Convey("with a rate of zero", func() {
+			c.RateLimitConfig.TokenRate = 0
+			So(c.Validate(), ShouldEqual, ErrInvalidRateLimit)
+
+			Convey("and rate limiting disabled", func() {
+				c.RateLimitConfig.Backend = RateLimitNone
+				So(c.Validate(), ShouldBeNil)
+			})
+		})
+
+		Convey("with a burst of zero", func() {
+			c.RateLimitConfig.IPBurst = 0
+			So(c.Validate(), ShouldEqual, ErrInvalidRateLimit)
+		})
+	})
+}
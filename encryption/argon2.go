@@ -0,0 +1,158 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+
+	// argon2MaxTime, argon2MaxMemory (KiB) and the key length bounds limit the work done verifying a password, so a
+	// hash with extreme parameters cannot be used to exhaust the CPU or memory of the service.
+	argon2MaxTime         = 10
+	argon2MaxMemory       = 1024 * 1024
+	argon2MinKeyLen       = 16
+	argon2MaxKeyLen       = 64
+	argon2MinSaltLen      = 8
+	argon2MinMemPerThread = 8
+
+	// argon2Format is the PHC string format for argon2id hashes.
+	argon2Format = "$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s"
+)
+
+var (
+	argon2Prefix = []byte("$argon2id$")
+
+	// ErrInvalidArgon2Params is returned if the configured argon2id parameters are invalid.
+	ErrInvalidArgon2Params = errors.Errorf("argon2id time must be between 1 and %d, threads greater than 0 and memory "+
+		"between %d KiB per thread and %d KiB", argon2MaxTime, argon2MinMemPerThread, argon2MaxMemory)
+
+	// ErrInvalidArgon2Hash is returned if an argon2id hash could not be decoded.
+	ErrInvalidArgon2Hash = errors.New("invalid argon2id hash")
+
+	// ErrMismatchedHashAndPassword is returned if a password does not match an argon2id hash.
+	ErrMismatchedHashAndPassword = errors.New("hashedPassword is not the hash of the given password")
+)
+
+// Argon2idHasher is a Hasher using argon2id with configurable time, memory (KiB) and thread parameters.
+type Argon2idHasher struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+}
+
+type argon2Hash struct {
+	version int
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+// NewArgon2idHasher construct a new Argon2idHasher. Returns ErrInvalidArgon2Params if any parameter is outside of the
+// bounds accepted when verifying a hash.
+func NewArgon2idHasher(time, memory uint32, threads uint8) (*Argon2idHasher, error) {
+	if !validArgon2Params(time, memory, threads) {
+		return nil, ErrInvalidArgon2Params
+	}
+	return &Argon2idHasher{time: time, memory: memory, threads: threads}, nil
+}
+
+// Hash return an argon2id hash of the password encoded in PHC string format.
+func (h *Argon2idHasher) Hash(password []byte) ([]byte, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, errors.Wrap(err, "error generating argon2id salt")
+	}
+
+	key := argon2.IDKey(password, salt, h.time, h.memory, h.threads, argon2KeyLen)
+
+	encoded := fmt.Sprintf(argon2Format, argon2.Version, h.memory, h.time, h.threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+	return []byte(encoded), nil
+}
+
+// Compare return nil if the password matches the argon2id hash, or an error otherwise.
+func (h *Argon2idHasher) Compare(hashedPassword, password []byte) error {
+	decoded, err := decodeArgon2(hashedPassword)
+	if err != nil {
+		return err
+	}
+
+	key := argon2.IDKey(password, decoded.salt, decoded.time, decoded.memory, decoded.threads, uint32(len(decoded.key)))
+	if subtle.ConstantTimeCompare(key, decoded.key) != 1 {
+		return ErrMismatchedHashAndPassword
+	}
+	return nil
+}
+
+// Identifies return true if the hash is an argon2id hash.
+func (h *Argon2idHasher) Identifies(hashedPassword []byte) bool {
+	return bytes.HasPrefix(hashedPassword, argon2Prefix)
+}
+
+// Validate return ErrInvalidArgon2Hash if the hash is not a well formed argon2id hash with parameters within bounds.
+func (h *Argon2idHasher) Validate(hashedPassword []byte) error {
+	_, err := decodeArgon2(hashedPassword)
+	return err
+}
+
+// Outdated return true if the hash was produced with parameters other than the configured parameters.
+func (h *Argon2idHasher) Outdated(hashedPassword []byte) bool {
+	decoded, err := decodeArgon2(hashedPassword)
+	if err != nil {
+		return true
+	}
+	return decoded.version != argon2.Version || decoded.time != h.time || decoded.memory != h.memory ||
+		decoded.threads != h.threads || len(decoded.key) != argon2KeyLen
+}
+
+// decodeArgon2 decode an argon2id hash in PHC string format. Returns ErrInvalidArgon2Hash if the hash is malformed or
+// any of its parameters are outside of the accepted bounds.
+func decodeArgon2(hashedPassword []byte) (*argon2Hash, error) {
+	parts := bytes.Split(hashedPassword, []byte("$"))
+	if len(parts) != 6 {
+		return nil, ErrInvalidArgon2Hash
+	}
+
+	var decoded argon2Hash
+	if _, err := fmt.Sscanf(string(parts[2]), "v=%d", &decoded.version); err != nil {
+		return nil, ErrInvalidArgon2Hash
+	}
+
+	if _, err := fmt.Sscanf(string(parts[3]), "m=%d,t=%d,p=%d", &decoded.memory, &decoded.time, &decoded.threads); err != nil {
+		return nil, ErrInvalidArgon2Hash
+	}
+
+	if !validArgon2Params(decoded.time, decoded.memory, decoded.threads) {
+		return nil, ErrInvalidArgon2Hash
+	}
+
+	var err error
+	if decoded.salt, err = base64.RawStdEncoding.DecodeString(string(parts[4])); err != nil {
+		return nil, ErrInvalidArgon2Hash
+	}
+
+	if decoded.key, err = base64.RawStdEncoding.DecodeString(string(parts[5])); err != nil {
+		return nil, ErrInvalidArgon2Hash
+	}
+
+	if len(decoded.salt) < argon2MinSaltLen || len(decoded.key) < argon2MinKeyLen || len(decoded.key) > argon2MaxKeyLen {
+		return nil, ErrInvalidArgon2Hash
+	}
+	return &decoded, nil
+}
+
+// validArgon2Params return true if the argon2id parameters are within the bounds accepted by the hasher.
+func validArgon2Params(time, memory uint32, threads uint8) bool {
+	return time >= 1 && time <= argon2MaxTime && threads >= 1 &&
+		memory >= argon2MinMemPerThread*uint32(threads) && memory <= argon2MaxMemory
+}
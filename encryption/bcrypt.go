@@ -0,0 +1,63 @@
+package encryption
+
+import (
+	"bytes"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	bcryptPrefixes = [][]byte{[]byte("$2a$"), []byte("$2b$"), []byte("$2y$")}
+
+	// ErrInvalidBcryptCost is returned if the configured bcrypt cost is outside of the range supported by bcrypt.
+	ErrInvalidBcryptCost = errors.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+)
+
+// BcryptHasher is a Hasher using bcrypt with a configurable cost.
+type BcryptHasher struct {
+	cost int
+}
+
+// NewBcryptHasher construct a new BcryptHasher. Returns ErrInvalidBcryptCost if the cost is outside of the range
+// supported by bcrypt.
+func NewBcryptHasher(cost int) (*BcryptHasher, error) {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return nil, ErrInvalidBcryptCost
+	}
+	return &BcryptHasher{cost: cost}, nil
+}
+
+// Hash return a bcrypt hash of the password.
+func (h *BcryptHasher) Hash(password []byte) ([]byte, error) {
+	return bcrypt.GenerateFromPassword(password, h.cost)
+}
+
+// Compare return nil if the password matches the bcrypt hash, or an error otherwise.
+func (h *BcryptHasher) Compare(hashedPassword, password []byte) error {
+	return bcrypt.CompareHashAndPassword(hashedPassword, password)
+}
+
+// Identifies return true if the hash is a bcrypt hash.
+func (h *BcryptHasher) Identifies(hashedPassword []byte) bool {
+	for _, prefix := range bcryptPrefixes {
+		if bytes.HasPrefix(hashedPassword, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate return an error if the hash is not a well formed bcrypt hash with a cost supported by bcrypt.
+func (h *BcryptHasher) Validate(hashedPassword []byte) error {
+	_, err := bcrypt.Cost(hashedPassword)
+	return err
+}
+
+// Outdated return true if the hash was produced with a cost other than the configured cost.
+func (h *BcryptHasher) Outdated(hashedPassword []byte) bool {
+	cost, err := bcrypt.Cost(hashedPassword)
+	if err != nil {
+		return true
+	}
+	return cost != h.cost
+}
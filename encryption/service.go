@@ -1,14 +1,128 @@
 package encryption
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"github.com/ONSdigital/dp-identity-api/config"
+	"github.com/ONSdigital/dp-identity-api/metrics"
+	"github.com/pkg/errors"
+	"time"
+)
 
+const (
+	// AlgorithmBcrypt is the config value selecting bcrypt as the current password hashing algorithm.
+	AlgorithmBcrypt = "bcrypt"
+
+	// AlgorithmArgon2id is the config value selecting argon2id as the current password hashing algorithm.
+	AlgorithmArgon2id = "argon2id"
+)
+
+var (
+	// ErrUnknownHashFormat is returned if a hash was not produced by any of the supported algorithms.
+	ErrUnknownHashFormat = errors.New("password hash format not recognised")
+
+	// ErrUnsupportedAlgorithm is returned if the configured hashing algorithm is not supported.
+	ErrUnsupportedAlgorithm = errors.New("unsupported password hashing algorithm")
+)
+
+// Hasher hashes and verifies passwords using a single algorithm.
+type Hasher interface {
+	// Hash return a hash of the password using the hasher's current parameters.
+	Hash(password []byte) ([]byte, error)
+	// Compare return nil if the password matches the hash, or an error otherwise.
+	Compare(hashedPassword, password []byte) error
+	// Identifies return true if the hash was produced by this hasher's algorithm.
+	Identifies(hashedPassword []byte) bool
+	// Validate return an error if a hash identified by the hasher is malformed or its parameters are out of bounds.
+	Validate(hashedPassword []byte) error
+	// Outdated return true if the hash was produced using parameters other than the hasher's current parameters.
+	Outdated(hashedPassword []byte) bool
+}
+
+// Service hashes new passwords using the current Hasher and verifies existing passwords using whichever supported
+// Hasher produced them, identified by the hash prefix. If Metrics is set the time taken to verify passwords is recorded.
 type Service struct {
+	Metrics metrics.Recorder
+	current Hasher
+	hashers []Hasher
+}
+
+// New construct a new Service using the algorithm selected in the config to hash new passwords. Hashes produced by any
+// supported algorithm can be verified.
+func New(cfg config.PasswordConfig) (*Service, error) {
+	bcryptHasher, err := NewBcryptHasher(cfg.BcryptCost)
+	if err != nil {
+		return nil, err
+	}
+
+	argon2Hasher, err := NewArgon2idHasher(cfg.Argon2Time, cfg.Argon2Memory, cfg.Argon2Threads)
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.Algorithm {
+	case AlgorithmBcrypt:
+		return NewService(bcryptHasher, argon2Hasher), nil
+	case AlgorithmArgon2id:
+		return NewService(argon2Hasher, bcryptHasher), nil
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+}
+
+// NewService construct a new Service hashing new passwords with current. Hashes produced by current or any of the
+// additional hashers can be verified.
+func NewService(current Hasher, additional ...Hasher) *Service {
+	return &Service{
+		current: current,
+		hashers: append([]Hasher{current}, additional...),
+	}
+}
+
+// GenerateFromPassword return a hash of the password using the current Hasher.
+func (s *Service) GenerateFromPassword(password []byte) ([]byte, error) {
+	return s.current.Hash(password)
+}
+
+// CompareHashAndPassword return nil if the password matches the hash, or an error otherwise. Returns
+// ErrUnknownHashFormat if the hash was not produced by a supported Hasher.
+func (s *Service) CompareHashAndPassword(hashedPassword, password []byte) error {
+	h := s.hasherFor(hashedPassword)
+	if h == nil {
+		return ErrUnknownHashFormat
+	}
+
+	if s.Metrics != nil {
+		defer func(start time.Time) {
+			s.Metrics.ObservePasswordCompare(time.Since(start))
+		}(time.Now())
+	}
+	return h.Compare(hashedPassword, password)
+}
+
+// NeedsRehash return true if the hash was not produced by the current Hasher using its current parameters.
+func (s *Service) NeedsRehash(hashedPassword []byte) bool {
+	if !s.current.Identifies(hashedPassword) {
+		return true
+	}
+	return s.current.Outdated(hashedPassword)
+}
+
+// Supports return true if the hash was produced by one of the supported Hashers and is valid.
+func (s *Service) Supports(hashedPassword []byte) bool {
+	h := s.hasherFor(hashedPassword)
+	return h != nil && h.Validate(hashedPassword) == nil
 }
 
-func (s Service) GenerateFromPassword(password []byte, cost int) ([]byte, error) {
-	return bcrypt.GenerateFromPassword(password, cost)
+// Identifies return true if the hash was produced by the algorithm of one of the supported Hashers, whether or not it
+// is valid.
+func (s *Service) Identifies(hashedPassword []byte) bool {
+	return s.hasherFor(hashedPassword) != nil
 }
 
-func (s Service) CompareHashAndPassword(hashedPassword, password []byte) error {
-	return bcrypt.CompareHashAndPassword(hashedPassword, password)
+func (s *Service) hasherFor(hashedPassword []byte) Hasher {
+	for _, h := range s.hashers {
+		if h.Identifies(hashedPassword) {
+			return h
+		}
+	}
+	return nil
 }
@@ -0,0 +1,206 @@
+package encryption
+
+import (
+	"github.com/ONSdigital/dp-identity-api/config"
+	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/crypto/bcrypt"
+	"testing"
+)
+
+var (
+	testPassword = []byte("There is no Dana only zuul!")
+
+	testConfig = config.PasswordConfig{
+		Algorithm:     AlgorithmBcrypt,
+		BcryptCost:    bcrypt.MinCost,
+		Argon2Time:    1,
+		Argon2Memory:  1024,
+		Argon2Threads: 1,
+	}
+
+	// invalidHashes are malformed bcrypt and argon2id hashes, or hashes with parameters outside of the accepted bounds.
+	invalidHashes = []string{
+		"$2a$99$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy",
+		"$argon2id$v=19$m=1024,t=0,p=1$" + testArgon2Salt + "$" + testArgon2Key,
+		"$argon2id$v=19$m=1024,t=1,p=0$" + testArgon2Salt + "$" + testArgon2Key,
+		"$argon2id$v=19$m=4194304,t=1,p=1$" + testArgon2Salt + "$" + testArgon2Key,
+		"$argon2id$v=19$m=1024,t=1000,p=1$" + testArgon2Salt + "$" + testArgon2Key,
+		"$argon2id$v=19$m=1024,t=1,p=1$" + testArgon2Salt + "$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdA$" + testArgon2Key,
+		"$argon2id$v=19$m=1024,t=1,p=1$" + testArgon2Salt,
+	}
+)
+
+const (
+	testArgon2Salt = "c2FsdHNhbHRzYWx0c2FsdA"
+	testArgon2Key  = "a2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2s"
+)
+
+func TestNew(t *testing.T) {
+	Convey("should return expected error if the algorithm is not supported", t, func() {
+		cfg := testConfig
+		cfg.Algorithm = "md5"
+
+		s, err := New(cfg)
+		So(err, ShouldEqual, ErrUnsupportedAlgorithm)
+		So(s, ShouldBeNil)
+	})
+
+	Convey("should return expected error if the bcrypt cost is invalid", t, func() {
+		cfg := testConfig
+		cfg.BcryptCost = bcrypt.MaxCost + 1
+
+		s, err := New(cfg)
+		So(err, ShouldEqual, ErrInvalidBcryptCost)
+		So(s, ShouldBeNil)
+	})
+
+	Convey("should return expected error if the argon2id params are invalid", t, func() {
+		cfg := testConfig
+		cfg.Argon2Threads = 0
+
+		s, err := New(cfg)
+		So(err, ShouldEqual, ErrInvalidArgon2Params)
+		So(s, ShouldBeNil)
+	})
+}
+
+func TestService_HashAndCompare(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmBcrypt, AlgorithmArgon2id} {
+		Convey("given the current algorithm is "+algorithm, t, func() {
+			cfg := testConfig
+			cfg.Algorithm = algorithm
+
+			s, err := New(cfg)
+			So(err, ShouldBeNil)
+
+			hash, err := s.GenerateFromPassword(testPassword)
+			So(err, ShouldBeNil)
+
+			Convey("then the correct password matches the hash", func() {
+				So(s.CompareHashAndPassword(hash, testPassword), ShouldBeNil)
+			})
+
+			Convey("and an incorrect password does not match the hash", func() {
+				So(s.CompareHashAndPassword(hash, []byte("Zuul")), ShouldNotBeNil)
+			})
+
+			Convey("and the hash does not need rehashing", func() {
+				So(s.NeedsRehash(hash), ShouldBeFalse)
+			})
+		})
+	}
+}
+
+func TestService_CompareUnknownFormat(t *testing.T) {
+	Convey("should return expected error if the hash format is not recognised", t, func() {
+		s, err := New(testConfig)
+		So(err, ShouldBeNil)
+
+		err = s.CompareHashAndPassword([]byte("plaintext"), testPassword)
+		So(err, ShouldEqual, ErrUnknownHashFormat)
+	})
+}
+
+func TestService_Supports(t *testing.T) {
+	Convey("should return true for supported hash formats", t, func() {
+		s, err := New(testConfig)
+		So(err, ShouldBeNil)
+
+		So(s.Supports([]byte("$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy")), ShouldBeTrue)
+		So(s.Supports([]byte("$argon2id$v=19$m=1024,t=1,p=1$"+testArgon2Salt+"$"+testArgon2Key)), ShouldBeTrue)
+	})
+
+	Convey("should return false for unsupported hash formats", t, func() {
+		s, err := New(testConfig)
+		So(err, ShouldBeNil)
+
+		So(s.Supports([]byte("5f4dcc3b5aa765d61d8327deb882cf99")), ShouldBeFalse)
+		So(s.Identifies([]byte("5f4dcc3b5aa765d61d8327deb882cf99")), ShouldBeFalse)
+	})
+
+	Convey("should return false for malformed hashes of supported formats", t, func() {
+		s, err := New(testConfig)
+		So(err, ShouldBeNil)
+
+		for _, hash := range invalidHashes {
+			So(s.Identifies([]byte(hash)), ShouldBeTrue)
+			So(s.Supports([]byte(hash)), ShouldBeFalse)
+		}
+	})
+}
+
+func TestService_CompareHashAndPasswordInvalidArgon2Params(t *testing.T) {
+	Convey("should return ErrInvalidArgon2Hash rather than verifying hashes with parameters out of bounds", t, func() {
+		s, err := New(testConfig)
+		So(err, ShouldBeNil)
+
+		for _, hash := range invalidHashes[1:] {
+			So(s.CompareHashAndPassword([]byte(hash), testPassword), ShouldEqual, ErrInvalidArgon2Hash)
+		}
+	})
+}
+
+func TestService_NeedsRehash(t *testing.T) {
+	Convey("given a password hashed with bcrypt", t, func() {
+		bcryptService, err := New(testConfig)
+		So(err, ShouldBeNil)
+
+		hash, err := bcryptService.GenerateFromPassword(testPassword)
+		So(err, ShouldBeNil)
+
+		Convey("when the current algorithm is argon2id", func() {
+			cfg := testConfig
+			cfg.Algorithm = AlgorithmArgon2id
+
+			s, err := New(cfg)
+			So(err, ShouldBeNil)
+
+			Convey("then the existing hash can still be verified", func() {
+				So(s.CompareHashAndPassword(hash, testPassword), ShouldBeNil)
+			})
+
+			Convey("and the hash needs rehashing", func() {
+				So(s.NeedsRehash(hash), ShouldBeTrue)
+			})
+		})
+
+		Convey("when the configured bcrypt cost has increased", func() {
+			cfg := testConfig
+			cfg.BcryptCost = bcrypt.MinCost + 1
+
+			s, err := New(cfg)
+			So(err, ShouldBeNil)
+
+			Convey("then the hash needs rehashing", func() {
+				So(s.NeedsRehash(hash), ShouldBeTrue)
+			})
+		})
+	})
+
+	Convey("given a password hashed with argon2id", t, func() {
+		cfg := testConfig
+		cfg.Algorithm = AlgorithmArgon2id
+
+		argon2Service, err := New(cfg)
+		So(err, ShouldBeNil)
+
+		hash, err := argon2Service.GenerateFromPassword(testPassword)
+		So(err, ShouldBeNil)
+
+		Convey("when the configured argon2id memory has increased", func() {
+			cfg.Argon2Memory = 2048
+
+			s, err := New(cfg)
+			So(err, ShouldBeNil)
+
+			Convey("then the existing hash can still be verified", func() {
+				So(s.CompareHashAndPassword(hash, testPassword), ShouldBeNil)
+			})
+
+			Convey("and the hash needs rehashing", func() {
+				So(s.NeedsRehash(hash), ShouldBeTrue)
+			})
+		})
+	})
+}
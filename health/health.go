@@ -0,0 +1,241 @@
+// Package health checks the service's dependencies at a regular interval and provides HTTP handlers reporting the
+// result of each check, the liveness and the readiness of the service.
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ONSdigital/go-ns/healthcheck"
+	"github.com/ONSdigital/go-ns/log"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+const (
+	// StatusOK is the status of a passing check, or of the service if all checks are passing.
+	StatusOK = "ok"
+
+	// StatusFailing is the status of a check that failed or timed out.
+	StatusFailing = "failing"
+
+	// StatusUnknown is the status of a check that has not yet completed.
+	StatusUnknown = "unknown"
+
+	// StatusDegraded is the status of the service if any non-critical check is failing. The service can still handle
+	// requests, for example a failing cache is bypassed.
+	StatusDegraded = "degraded"
+
+	// StatusUnavailable is the status of the service if any critical check is failing or has not yet completed.
+	StatusUnavailable = "unavailable"
+
+	headerContentType = "content-type"
+	mimeTypeJSON      = "application/json"
+)
+
+// ErrCheckTimeout is returned if a check does not complete within the configured timeout.
+var ErrCheckTimeout = errors.New("health check timed out")
+
+// Check is a dependency checked by the Checker. If a Critical check is failing the service is reported as unavailable
+// and not ready to handle requests.
+type Check struct {
+	Name     string
+	Critical bool
+	Client   healthcheck.Client
+}
+
+// ClientFunc adapts a func to a healthcheck.Client for dependencies without a health check client.
+type ClientFunc func() error
+
+// Healthcheck return the error returned by f.
+func (f ClientFunc) Healthcheck() (string, error) {
+	return "", f()
+}
+
+// CheckResult is the outcome of the last run of a Check.
+type CheckResult struct {
+	Name        string     `json:"name"`
+	Status      string     `json:"status"`
+	Critical    bool       `json:"critical"`
+	Error       string     `json:"error,omitempty"`
+	LastChecked *time.Time `json:"last_checked,omitempty"`
+	Latency     string     `json:"latency,omitempty"`
+}
+
+// Report is the HTTP response entity describing the health of the service and each of its dependencies.
+type Report struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks,omitempty"`
+}
+
+// Checker runs the configured checks concurrently at a regular interval and records the result of each.
+type Checker struct {
+	checks  []Check
+	timeout time.Duration
+	mutex   sync.RWMutex
+	results map[string]CheckResult
+	closing chan struct{}
+	closed  chan struct{}
+}
+
+// New construct a new Checker for the checks. Each check fails if it does not complete within the timeout.
+func New(timeout time.Duration, checks ...Check) *Checker {
+	results := make(map[string]CheckResult, len(checks))
+	for _, c := range checks {
+		results[c.Name] = CheckResult{Name: c.Name, Status: StatusUnknown, Critical: c.Critical}
+	}
+
+	return &Checker{
+		checks:  checks,
+		timeout: timeout,
+		results: results,
+		closing: make(chan struct{}),
+		closed:  make(chan struct{}),
+	}
+}
+
+// Start runs the checks immediately then at the interval until the Checker is closed.
+func (c *Checker) Start(interval time.Duration) {
+	go func() {
+		defer close(c.closed)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			c.Run()
+			select {
+			case <-ticker.C:
+			case <-c.closing:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops the Checker running checks, waiting for any run in progress to complete.
+func (c *Checker) Close() {
+	close(c.closing)
+	<-c.closed
+	log.Info("health checker stopped", nil)
+}
+
+// Run runs each check concurrently and records the results.
+func (c *Checker) Run() {
+	var wg sync.WaitGroup
+	wg.Add(len(c.checks))
+
+	for _, check := range c.checks {
+		go func(check Check) {
+			defer wg.Done()
+			result := c.run(check)
+
+			c.mutex.Lock()
+			c.results[check.Name] = result
+			c.mutex.Unlock()
+		}(check)
+	}
+	wg.Wait()
+}
+
+func (c *Checker) run(check Check) CheckResult {
+	start := time.Now()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := check.Client.Healthcheck()
+		done <- err
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(c.timeout):
+		err = ErrCheckTimeout
+	}
+
+	result := CheckResult{
+		Name:        check.Name,
+		Status:      StatusOK,
+		Critical:    check.Critical,
+		LastChecked: &start,
+		Latency:     time.Since(start).String(),
+	}
+
+	if err != nil {
+		log.ErrorC("health check failed", err, log.Data{"check": check.Name, "critical": check.Critical})
+		result.Status = StatusFailing
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Report return the overall status of the service and the result of each check, in the order the checks were
+// configured.
+func (c *Checker) Report() Report {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	report := Report{Status: StatusOK, Checks: make([]CheckResult, 0, len(c.checks))}
+	for _, check := range c.checks {
+		result := c.results[check.Name]
+		report.Checks = append(report.Checks, result)
+
+		switch {
+		case result.Status == StatusOK:
+		case result.Critical:
+			report.Status = StatusUnavailable
+		case report.Status == StatusOK:
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+// RegisterEndpoints registers the health, liveness and readiness handlers with the router.
+func (c *Checker) RegisterEndpoints(r *mux.Router) {
+	r.Path("/healthcheck").HandlerFunc(c.HealthHandler)
+	r.Path("/health/live").HandlerFunc(c.LivenessHandler)
+	r.Path("/health/ready").HandlerFunc(c.ReadinessHandler)
+}
+
+// HealthHandler writes the Report. Returns status 503 if the service is unavailable, 200 otherwise.
+func (c *Checker) HealthHandler(w http.ResponseWriter, r *http.Request) {
+	report := c.Report()
+	writeReport(w, report, statusCode(report))
+}
+
+// ReadinessHandler writes the overall status without the check results. Returns status 503 if the service is
+// unavailable, indicating it should not be sent requests, or 200 if it is ready. A degraded service is ready.
+func (c *Checker) ReadinessHandler(w http.ResponseWriter, r *http.Request) {
+	report := c.Report()
+	writeReport(w, Report{Status: report.Status}, statusCode(report))
+}
+
+// LivenessHandler writes status 200 while the service is able to handle requests. Dependencies are not checked as a
+// failing dependency is not resolved by restarting the service.
+func (c *Checker) LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, Report{Status: StatusOK}, http.StatusOK)
+}
+
+func statusCode(report Report) int {
+	if report.Status == StatusUnavailable {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusOK
+}
+
+func writeReport(w http.ResponseWriter, report Report, status int) {
+	b, err := json.Marshal(report)
+	if err != nil {
+		log.Error(errors.Wrap(err, "failed to marshal health report to JSON"), nil)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set(headerContentType, mimeTypeJSON)
+	w.WriteHeader(status)
+	w.Write(b)
+}
@@ -0,0 +1,168 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var (
+	errTest = errors.New("explosions")
+
+	healthy = ClientFunc(func() error { return nil })
+	failing = ClientFunc(func() error { return errTest })
+)
+
+func TestChecker_Report(t *testing.T) {
+	Convey("given the checks have not yet run", t, func() {
+		c := New(time.Second, Check{Name: "mongodb", Critical: true, Client: healthy})
+
+		Convey("then the service is unavailable and the check status is unknown", func() {
+			report := c.Report()
+			So(report.Status, ShouldEqual, StatusUnavailable)
+			So(report.Checks, ShouldHaveLength, 1)
+			So(report.Checks[0].Status, ShouldEqual, StatusUnknown)
+			So(report.Checks[0].LastChecked, ShouldBeNil)
+		})
+	})
+
+	Convey("given all checks are passing", t, func() {
+		c := New(time.Second,
+			Check{Name: "mongodb", Critical: true, Client: healthy},
+			Check{Name: "cache", Critical: false, Client: healthy},
+		)
+		c.Run()
+
+		Convey("then the service is ok and each check is reported in order", func() {
+			report := c.Report()
+			So(report.Status, ShouldEqual, StatusOK)
+			So(report.Checks, ShouldHaveLength, 2)
+			So(report.Checks[0].Name, ShouldEqual, "mongodb")
+			So(report.Checks[0].Status, ShouldEqual, StatusOK)
+			So(report.Checks[0].Critical, ShouldBeTrue)
+			So(report.Checks[0].LastChecked, ShouldNotBeNil)
+			So(report.Checks[0].Latency, ShouldNotBeEmpty)
+			So(report.Checks[1].Name, ShouldEqual, "cache")
+		})
+	})
+
+	Convey("given a non-critical check is failing", t, func() {
+		c := New(time.Second,
+			Check{Name: "mongodb", Critical: true, Client: healthy},
+			Check{Name: "cache", Critical: false, Client: failing},
+		)
+		c.Run()
+
+		Convey("then the service is degraded", func() {
+			report := c.Report()
+			So(report.Status, ShouldEqual, StatusDegraded)
+			So(report.Checks[1].Status, ShouldEqual, StatusFailing)
+			So(report.Checks[1].Error, ShouldEqual, errTest.Error())
+		})
+	})
+
+	Convey("given a critical check is failing", t, func() {
+		c := New(time.Second,
+			Check{Name: "mongodb", Critical: true, Client: failing},
+			Check{Name: "cache", Critical: false, Client: failing},
+		)
+		c.Run()
+
+		Convey("then the service is unavailable", func() {
+			So(c.Report().Status, ShouldEqual, StatusUnavailable)
+		})
+	})
+
+	Convey("given a check does not complete within the timeout", t, func() {
+		block := make(chan struct{})
+		defer close(block)
+
+		c := New(time.Millisecond*10, Check{Name: "mongodb", Critical: true, Client: ClientFunc(func() error {
+			<-block
+			return nil
+		})})
+		c.Run()
+
+		Convey("then the check is failing with ErrCheckTimeout", func() {
+			report := c.Report()
+			So(report.Checks[0].Status, ShouldEqual, StatusFailing)
+			So(report.Checks[0].Error, ShouldEqual, ErrCheckTimeout.Error())
+		})
+	})
+}
+
+func TestChecker_Handlers(t *testing.T) {
+	Convey("given a non-critical check is failing", t, func() {
+		c := New(time.Second,
+			Check{Name: "mongodb", Critical: true, Client: healthy},
+			Check{Name: "cache", Critical: false, Client: failing},
+		)
+		c.Run()
+
+		Convey("then the health handler returns 200 with each check", func() {
+			report, status := serve(c.HealthHandler)
+			So(status, ShouldEqual, http.StatusOK)
+			So(report.Status, ShouldEqual, StatusDegraded)
+			So(report.Checks, ShouldHaveLength, 2)
+		})
+
+		Convey("and the readiness handler returns 200 without the checks", func() {
+			report, status := serve(c.ReadinessHandler)
+			So(status, ShouldEqual, http.StatusOK)
+			So(report.Status, ShouldEqual, StatusDegraded)
+			So(report.Checks, ShouldBeEmpty)
+		})
+	})
+
+	Convey("given a critical check is failing", t, func() {
+		c := New(time.Second, Check{Name: "mongodb", Critical: true, Client: failing})
+		c.Run()
+
+		Convey("then the health and readiness handlers return 503", func() {
+			_, status := serve(c.HealthHandler)
+			So(status, ShouldEqual, http.StatusServiceUnavailable)
+
+			report, status := serve(c.ReadinessHandler)
+			So(status, ShouldEqual, http.StatusServiceUnavailable)
+			So(report.Status, ShouldEqual, StatusUnavailable)
+		})
+
+		Convey("and the liveness handler returns 200", func() {
+			report, status := serve(c.LivenessHandler)
+			So(status, ShouldEqual, http.StatusOK)
+			So(report.Status, ShouldEqual, StatusOK)
+		})
+	})
+}
+
+func TestChecker_StartClose(t *testing.T) {
+	Convey("checks should run when the Checker is started and stop when closed", t, func() {
+		runs := make(chan struct{}, 10)
+		c := New(time.Second, Check{Name: "mongodb", Critical: true, Client: ClientFunc(func() error {
+			runs <- struct{}{}
+			return nil
+		})})
+
+		c.Start(time.Millisecond * 5)
+		<-runs
+		<-runs
+		c.Close()
+
+		So(c.Report().Status, ShouldEqual, StatusOK)
+	})
+}
+
+func serve(h http.HandlerFunc) (Report, int) {
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodGet, "/healthcheck", nil))
+
+	var report Report
+	So(json.Unmarshal(w.Body.Bytes(), &report), ShouldBeNil)
+	So(w.Header().Get(headerContentType), ShouldEqual, mimeTypeJSON)
+	return report, w.Code
+}
@@ -0,0 +1,76 @@
+package identity
+
+import (
+	"context"
+	"github.com/ONSdigital/dp-identity-api/persistence"
+	"github.com/ONSdigital/dp-identity-api/tracing"
+	"github.com/ONSdigital/go-ns/log"
+	"github.com/pkg/errors"
+	"time"
+)
+
+var (
+	ErrIdentityDisabled       = errors.New("identity is disabled")
+	ErrDisabledReasonRequired = errors.New("a reason is required to disable an identity")
+)
+
+// Disable suspend the identity with the provided ID, recording the reason and the ID of the identity disabling it, and
+// revoke its tokens. Unlike deletion the identity retains its email, so it cannot be reused while the identity is
+// disabled. Returns ErrIdentityNotFound if no active identity exists.
+func (s *Service) Disable(ctx context.Context, id string, reason string, disabledBy string) (err error) {
+	ctx, span := tracing.Start(ctx, "identity.Service.Disable")
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
+	if reason == "" {
+		return ErrDisabledReasonRequired
+	}
+
+	logD := log.Data{"id": id, "disabled_by": disabledBy}
+
+	if err := s.IdentityStore.DisableIdentity(ctx, id, reason, disabledBy, time.Now()); err != nil {
+		if err == persistence.ErrNotFound {
+			log.ErrorCtx(ctx, errors.New("disable identity: identity not found"), logD)
+			return ErrIdentityNotFound
+		}
+		log.ErrorCtx(ctx, errors.WithMessage(err, "disable identity: failed to write data to store"), logD)
+		return storeErr(err)
+	}
+
+	// tokens issued before the identity was disabled must not remain valid.
+	if s.Tokens != nil {
+		if err := s.Tokens.Revoke(ctx, id); err != nil {
+			log.ErrorCtx(ctx, errors.WithMessage(err, "disable identity: failed to revoke tokens"), logD)
+			return storeErr(err)
+		}
+	}
+
+	log.InfoCtx(ctx, "disable identity: identity disabled successfully", logD)
+	return nil
+}
+
+// Enable reinstate the disabled identity with the provided ID. Tokens revoked when the identity was disabled are not
+// restored. Returns ErrIdentityNotFound if no active identity exists.
+func (s *Service) Enable(ctx context.Context, id string) (err error) {
+	ctx, span := tracing.Start(ctx, "identity.Service.Enable")
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
+	logD := log.Data{"id": id}
+
+	if err := s.IdentityStore.EnableIdentity(ctx, id); err != nil {
+		if err == persistence.ErrNotFound {
+			log.ErrorCtx(ctx, errors.New("enable identity: identity not found"), logD)
+			return ErrIdentityNotFound
+		}
+		log.ErrorCtx(ctx, errors.WithMessage(err, "enable identity: failed to write data to store"), logD)
+		return storeErr(err)
+	}
+
+	log.InfoCtx(ctx, "enable identity: identity enabled successfully", logD)
+	return nil
+}
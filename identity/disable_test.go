@@ -0,0 +1,162 @@
+package identity
+
+import (
+	"context"
+	"github.com/ONSdigital/dp-identity-api/identity/identitytest"
+	"github.com/ONSdigital/dp-identity-api/persistence"
+	"github.com/ONSdigital/dp-identity-api/persistence/persistencetest"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+func TestService_Disable(t *testing.T) {
+	Convey("given an identity", t, func() {
+		p := &persistencetest.IdentityStoreMock{
+			DisableIdentityFunc: func(ctx context.Context, id string, reason string, disabledBy string, date time.Time) error {
+				return nil
+			},
+		}
+		revoker := &identitytest.TokenManagerMock{
+			RevokeFunc: func(ctx context.Context, identityID string) error {
+				return nil
+			},
+		}
+
+		s := &Service{IdentityStore: p, Tokens: revoker}
+
+		Convey("when the identity is disabled", func() {
+			err := s.Disable(context.Background(), "666", "under investigation", "999")
+
+			Convey("then it is disabled with the reason and actor", func() {
+				So(err, ShouldBeNil)
+				So(p.DisableIdentityCalls(), ShouldHaveLength, 1)
+				call := p.DisableIdentityCalls()[0]
+				So(call.ID, ShouldEqual, "666")
+				So(call.Reason, ShouldEqual, "under investigation")
+				So(call.DisabledBy, ShouldEqual, "999")
+				So(call.Date, ShouldHappenWithin, time.Minute, time.Now())
+			})
+
+			Convey("and the identity's tokens are revoked", func() {
+				So(revoker.RevokeCalls(), ShouldHaveLength, 1)
+				So(revoker.RevokeCalls()[0].IdentityID, ShouldEqual, "666")
+			})
+		})
+
+		Convey("when no reason is provided", func() {
+			err := s.Disable(context.Background(), "666", "", "999")
+
+			Convey("then ErrDisabledReasonRequired is returned and the identity is not disabled", func() {
+				So(err, ShouldEqual, ErrDisabledReasonRequired)
+				So(p.DisableIdentityCalls(), ShouldHaveLength, 0)
+				So(revoker.RevokeCalls(), ShouldHaveLength, 0)
+			})
+		})
+
+		Convey("when the identity does not exist", func() {
+			p.DisableIdentityFunc = func(ctx context.Context, id string, reason string, disabledBy string, date time.Time) error {
+				return persistence.ErrNotFound
+			}
+
+			err := s.Disable(context.Background(), "666", "under investigation", "999")
+
+			Convey("then ErrIdentityNotFound is returned", func() {
+				So(err, ShouldEqual, ErrIdentityNotFound)
+				So(revoker.RevokeCalls(), ShouldHaveLength, 0)
+			})
+		})
+
+		Convey("when revoking the identity's tokens returns an error", func() {
+			revoker.RevokeFunc = func(ctx context.Context, identityID string) error {
+				return persistence.ErrTimeout
+			}
+
+			err := s.Disable(context.Background(), "666", "under investigation", "999")
+
+			Convey("then the error is returned", func() {
+				So(err, ShouldEqual, persistence.ErrTimeout)
+			})
+		})
+	})
+}
+
+func TestService_Enable(t *testing.T) {
+	Convey("given a disabled identity", t, func() {
+		p := &persistencetest.IdentityStoreMock{
+			EnableIdentityFunc: func(ctx context.Context, id string) error {
+				return nil
+			},
+		}
+
+		s := &Service{IdentityStore: p}
+
+		Convey("when the identity is enabled", func() {
+			err := s.Enable(context.Background(), "666")
+
+			Convey("then the store is updated", func() {
+				So(err, ShouldBeNil)
+				So(p.EnableIdentityCalls(), ShouldHaveLength, 1)
+				So(p.EnableIdentityCalls()[0].ID, ShouldEqual, "666")
+			})
+		})
+
+		Convey("when the identity does not exist", func() {
+			p.EnableIdentityFunc = func(ctx context.Context, id string) error {
+				return persistence.ErrNotFound
+			}
+
+			err := s.Enable(context.Background(), "666")
+
+			Convey("then ErrIdentityNotFound is returned", func() {
+				So(err, ShouldEqual, ErrIdentityNotFound)
+			})
+		})
+
+		Convey("when the store returns an error", func() {
+			p.EnableIdentityFunc = func(ctx context.Context, id string) error {
+				return errTest
+			}
+
+			err := s.Enable(context.Background(), "666")
+
+			Convey("then ErrPersistence is returned", func() {
+				So(err, ShouldEqual, ErrPersistence)
+			})
+		})
+	})
+}
+
+func TestService_VerifyPasswordDisabled(t *testing.T) {
+	Convey("given a disabled identity", t, func() {
+		stored := schema.Identity{ID: "666", Email: newIdentity.Email, Password: "hash", Verified: true, Disabled: true}
+
+		p := &persistencetest.IdentityStoreMock{
+			GetIdentityFunc: func(ctx context.Context, email string) (schema.Identity, error) {
+				return stored, nil
+			},
+		}
+
+		s := Service{IdentityStore: p, Encryptor: newEncryptorMock(nil, nil, nil)}
+
+		Convey("when the password is verified", func() {
+			i, err := s.VerifyPassword(context.Background(), stored.Email, "WAFFLES")
+
+			Convey("then ErrIdentityDisabled is returned", func() {
+				So(err, ShouldEqual, ErrIdentityDisabled)
+				So(i, ShouldBeNil)
+			})
+		})
+
+		Convey("when the password is incorrect", func() {
+			s.Encryptor = newEncryptorMock(nil, nil, errTest)
+
+			_, err := s.VerifyPassword(context.Background(), stored.Email, "WAFFLES")
+
+			Convey("then ErrAuthenticateFailed is returned so the status is not disclosed", func() {
+				So(err, ShouldEqual, ErrAuthenticateFailed)
+			})
+		})
+	})
+}
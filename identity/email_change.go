@@ -0,0 +1,117 @@
+package identity
+
+import (
+	"context"
+	"github.com/ONSdigital/dp-identity-api/persistence"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/ONSdigital/dp-identity-api/token"
+	"github.com/ONSdigital/dp-identity-api/tracing"
+	"github.com/ONSdigital/go-ns/log"
+	"github.com/pkg/errors"
+	"time"
+)
+
+var ErrEmailChangeTokenNotFound = errors.New("email change token not found or expired")
+
+// RequestEmailChange record a request to change the email of the identity with the provided ID and send a token
+// confirming the change to the new address. The identity's current address is notified of the request. The change is
+// not applied until it is confirmed using ConfirmEmailChange, a new request replaces any previous one. Returns
+// ErrEmailAlreadyExists if the new email is associated with an active identity.
+func (s *Service) RequestEmailChange(ctx context.Context, id string, email string) (err error) {
+	ctx, span := tracing.Start(ctx, "identity.Service.RequestEmailChange")
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
+	if email == "" {
+		return schema.ErrEmailValidation
+	}
+
+	logD := log.Data{"id": id, "email": email}
+
+	i, err := s.IdentityStore.GetIdentityByID(ctx, id)
+	if err != nil {
+		if err == persistence.ErrNotFound {
+			log.ErrorCtx(ctx, errors.New("request email change: identity not found"), logD)
+			return ErrIdentityNotFound
+		}
+		return storeErr(err)
+	}
+
+	if _, err := s.IdentityStore.GetIdentity(ctx, email); err != persistence.ErrNotFound {
+		if err == nil {
+			log.ErrorCtx(ctx, errors.New("request email change: an active identity with this email already exists"), logD)
+			return ErrEmailAlreadyExists
+		}
+		return storeErr(err)
+	}
+
+	changeToken, err := s.generator(token.EmailChangeTokenPrefix).Generate()
+	if err != nil {
+		return errors.Wrap(err, "request email change: error generating email change token")
+	}
+
+	expiry := time.Now().Add(s.verificationLifetime())
+	if err := s.IdentityStore.SetPendingEmail(ctx, id, email, s.digester().Digest(changeToken), expiry); err != nil {
+		if err == persistence.ErrNotFound {
+			return ErrIdentityNotFound
+		}
+		log.ErrorCtx(ctx, errors.WithMessage(err, "request email change: failed to write data to store"), logD)
+		return storeErr(err)
+	}
+
+	if s.Notifier != nil {
+		// the request can be repeated if the confirmation can't be sent, so unlike verification of a new identity
+		// this is considered critical.
+		if err := s.Notifier.SendEmailChange(ctx, *i, email, changeToken); err != nil {
+			return errors.Wrap(err, "request email change: failed to send email change token")
+		}
+
+		if err := s.Notifier.SendEmailChangeNotice(ctx, *i, email); err != nil {
+			log.ErrorCtx(ctx, errors.Wrap(err, "request email change: failed to notify current email"), logD)
+		}
+	}
+
+	log.InfoCtx(ctx, "request email change: email change requested successfully", logD)
+	return nil
+}
+
+// ConfirmEmailChange apply the email change the token was issued for and revoke the identity's tokens. The new email
+// is treated as verified. Returns ErrEmailChangeTokenNotFound if the token does not exist, has expired or has already
+// been used, or ErrEmailAlreadyExists if the new email has since been associated with another active identity.
+func (s *Service) ConfirmEmailChange(ctx context.Context, changeToken string) (i *schema.Identity, err error) {
+	ctx, span := tracing.Start(ctx, "identity.Service.ConfirmEmailChange")
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
+	i, err = s.IdentityStore.ConfirmEmailChange(ctx, s.digester().Digest(changeToken))
+	if err != nil {
+		switch err {
+		case persistence.ErrNotFound:
+			log.ErrorCtx(ctx, errors.New("confirm email change: email change token not found or expired"), nil)
+			return nil, ErrEmailChangeTokenNotFound
+		case persistence.ErrNonUnique:
+			log.ErrorCtx(ctx, errors.New("confirm email change: an active identity with this email already exists"), nil)
+			return nil, ErrEmailAlreadyExists
+		default:
+			log.ErrorCtx(ctx, errors.WithMessage(err, "confirm email change: failed to write data to store"), nil)
+			return nil, storeErr(err)
+		}
+	}
+
+	logD := log.Data{"id": i.ID, "email": i.Email}
+
+	// tokens issued before the change must not remain valid.
+	if s.Tokens != nil {
+		if err := s.Tokens.Revoke(ctx, i.ID); err != nil {
+			log.ErrorCtx(ctx, errors.WithMessage(err, "confirm email change: failed to revoke tokens"), logD)
+			return nil, storeErr(err)
+		}
+	}
+
+	log.InfoCtx(ctx, "confirm email change: email changed successfully", logD)
+	return i, nil
+}
@@ -0,0 +1,209 @@
+package identity
+
+import (
+	"context"
+	"github.com/ONSdigital/dp-identity-api/identity/identitytest"
+	"github.com/ONSdigital/dp-identity-api/persistence"
+	"github.com/ONSdigital/dp-identity-api/persistence/persistencetest"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/ONSdigital/dp-identity-api/token"
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+	"strings"
+	"testing"
+	"time"
+)
+
+const newEmail = "eleven@hawkins.com"
+
+func newEmailChangeStoreMock() *persistencetest.IdentityStoreMock {
+	return &persistencetest.IdentityStoreMock{
+		GetIdentityByIDFunc: func(ctx context.Context, id string) (*schema.Identity, error) {
+			return &schema.Identity{ID: id, Email: "11@StrangerThings.com"}, nil
+		},
+		GetIdentityFunc: func(ctx context.Context, email string) (schema.Identity, error) {
+			return schema.NilIdentity, persistence.ErrNotFound
+		},
+		SetPendingEmailFunc: func(ctx context.Context, id string, email string, token string, expiry time.Time) error {
+			return nil
+		},
+	}
+}
+
+func newEmailChangeNotifierMock() *identitytest.NotifierMock {
+	return &identitytest.NotifierMock{
+		SendEmailChangeFunc: func(ctx context.Context, i schema.Identity, email string, token string) error {
+			return nil
+		},
+		SendEmailChangeNoticeFunc: func(ctx context.Context, i schema.Identity, email string) error {
+			return nil
+		},
+	}
+}
+
+func TestService_RequestEmailChange(t *testing.T) {
+	Convey("given an identity", t, func() {
+		p := newEmailChangeStoreMock()
+		n := newEmailChangeNotifierMock()
+		s := &Service{IdentityStore: p, Notifier: n, VerificationLifetime: time.Hour}
+
+		Convey("when an email change is requested", func() {
+			err := s.RequestEmailChange(context.Background(), "666", newEmail)
+
+			Convey("then the pending email is recorded with the digest of a change token", func() {
+				So(err, ShouldBeNil)
+				So(p.SetPendingEmailCalls(), ShouldHaveLength, 1)
+				call := p.SetPendingEmailCalls()[0]
+				So(call.ID, ShouldEqual, "666")
+				So(call.Email, ShouldEqual, newEmail)
+				So(call.Expiry, ShouldHappenWithin, time.Minute, time.Now().Add(time.Hour))
+			})
+
+			Convey("and the plain text token is sent to the new email", func() {
+				So(n.SendEmailChangeCalls(), ShouldHaveLength, 1)
+				sent := n.SendEmailChangeCalls()[0]
+				So(sent.Email, ShouldEqual, newEmail)
+				So(strings.HasPrefix(sent.Token, token.EmailChangeTokenPrefix), ShouldBeTrue)
+				So(token.SHA256Digester{}.Digest(sent.Token), ShouldEqual, p.SetPendingEmailCalls()[0].Token)
+			})
+
+			Convey("and the current email is notified", func() {
+				So(n.SendEmailChangeNoticeCalls(), ShouldHaveLength, 1)
+				So(n.SendEmailChangeNoticeCalls()[0].I.Email, ShouldEqual, "11@StrangerThings.com")
+				So(n.SendEmailChangeNoticeCalls()[0].Email, ShouldEqual, newEmail)
+			})
+		})
+
+		Convey("when the new email is used by an active identity", func() {
+			p.GetIdentityFunc = func(ctx context.Context, email string) (schema.Identity, error) {
+				return schema.Identity{ID: "999", Email: email}, nil
+			}
+
+			err := s.RequestEmailChange(context.Background(), "666", newEmail)
+
+			Convey("then ErrEmailAlreadyExists is returned and no change is recorded", func() {
+				So(err, ShouldEqual, ErrEmailAlreadyExists)
+				So(p.SetPendingEmailCalls(), ShouldHaveLength, 0)
+				So(n.SendEmailChangeCalls(), ShouldHaveLength, 0)
+			})
+		})
+
+		Convey("when the identity does not exist", func() {
+			p.GetIdentityByIDFunc = func(ctx context.Context, id string) (*schema.Identity, error) {
+				return nil, persistence.ErrNotFound
+			}
+
+			err := s.RequestEmailChange(context.Background(), "666", newEmail)
+
+			Convey("then ErrIdentityNotFound is returned", func() {
+				So(err, ShouldEqual, ErrIdentityNotFound)
+			})
+		})
+
+		Convey("when the new email is empty", func() {
+			err := s.RequestEmailChange(context.Background(), "666", "")
+
+			Convey("then ErrEmailValidation is returned", func() {
+				So(err, ShouldResemble, schema.ErrEmailValidation)
+				So(p.GetIdentityByIDCalls(), ShouldHaveLength, 0)
+			})
+		})
+
+		Convey("when sending the change token returns an error", func() {
+			n.SendEmailChangeFunc = func(ctx context.Context, i schema.Identity, email string, token string) error {
+				return errTest
+			}
+
+			err := s.RequestEmailChange(context.Background(), "666", newEmail)
+
+			Convey("then the error is returned", func() {
+				So(errors.Cause(err), ShouldEqual, errTest)
+				So(n.SendEmailChangeNoticeCalls(), ShouldHaveLength, 0)
+			})
+		})
+
+		Convey("when notifying the current email returns an error", func() {
+			n.SendEmailChangeNoticeFunc = func(ctx context.Context, i schema.Identity, email string) error {
+				return errTest
+			}
+
+			err := s.RequestEmailChange(context.Background(), "666", newEmail)
+
+			Convey("then the change is still requested", func() {
+				So(err, ShouldBeNil)
+				So(p.SetPendingEmailCalls(), ShouldHaveLength, 1)
+			})
+		})
+	})
+}
+
+func TestService_ConfirmEmailChange(t *testing.T) {
+	Convey("given an email change token", t, func() {
+		p := &persistencetest.IdentityStoreMock{
+			ConfirmEmailChangeFunc: func(ctx context.Context, token string) (*schema.Identity, error) {
+				return &schema.Identity{ID: "666", Email: newEmail, Verified: true}, nil
+			},
+		}
+		revoker := &identitytest.TokenManagerMock{
+			RevokeFunc: func(ctx context.Context, identityID string) error {
+				return nil
+			},
+		}
+
+		s := &Service{IdentityStore: p, Tokens: revoker}
+
+		Convey("when the change is confirmed", func() {
+			i, err := s.ConfirmEmailChange(context.Background(), "dpide_token")
+
+			Convey("then the change is applied using the digest of the token", func() {
+				So(err, ShouldBeNil)
+				So(i.Email, ShouldEqual, newEmail)
+				So(p.ConfirmEmailChangeCalls(), ShouldHaveLength, 1)
+				So(p.ConfirmEmailChangeCalls()[0].Token, ShouldEqual, token.SHA256Digester{}.Digest("dpide_token"))
+			})
+
+			Convey("and the identity's tokens are revoked", func() {
+				So(revoker.RevokeCalls(), ShouldHaveLength, 1)
+				So(revoker.RevokeCalls()[0].IdentityID, ShouldEqual, "666")
+			})
+		})
+
+		Convey("when the token is not found", func() {
+			p.ConfirmEmailChangeFunc = func(ctx context.Context, token string) (*schema.Identity, error) {
+				return nil, persistence.ErrNotFound
+			}
+
+			_, err := s.ConfirmEmailChange(context.Background(), "dpide_token")
+
+			Convey("then ErrEmailChangeTokenNotFound is returned", func() {
+				So(err, ShouldEqual, ErrEmailChangeTokenNotFound)
+				So(revoker.RevokeCalls(), ShouldHaveLength, 0)
+			})
+		})
+
+		Convey("when the new email has since been used by another identity", func() {
+			p.ConfirmEmailChangeFunc = func(ctx context.Context, token string) (*schema.Identity, error) {
+				return nil, persistence.ErrNonUnique
+			}
+
+			_, err := s.ConfirmEmailChange(context.Background(), "dpide_token")
+
+			Convey("then ErrEmailAlreadyExists is returned", func() {
+				So(err, ShouldEqual, ErrEmailAlreadyExists)
+				So(revoker.RevokeCalls(), ShouldHaveLength, 0)
+			})
+		})
+
+		Convey("when revoking the identity's tokens returns an error", func() {
+			revoker.RevokeFunc = func(ctx context.Context, identityID string) error {
+				return persistence.ErrTimeout
+			}
+
+			_, err := s.ConfirmEmailChange(context.Background(), "dpide_token")
+
+			Convey("then the error is returned", func() {
+				So(err, ShouldEqual, persistence.ErrTimeout)
+			})
+		})
+	})
+}
@@ -4,12 +4,17 @@
 package identitytest
 
 import (
+	"context"
+	"github.com/ONSdigital/dp-identity-api/schema"
 	"sync"
 )
 
 var (
 	lockEncryptorMockCompareHashAndPassword sync.RWMutex
 	lockEncryptorMockGenerateFromPassword   sync.RWMutex
+	lockEncryptorMockIdentifies             sync.RWMutex
+	lockEncryptorMockNeedsRehash            sync.RWMutex
+	lockEncryptorMockSupports               sync.RWMutex
 )
 
 // EncryptorMock is a mock implementation of Encryptor.
@@ -21,9 +26,18 @@ var (
 //             CompareHashAndPasswordFunc: func(hashedPassword []byte, password []byte) error {
 // 	               panic("TODO: mock out the CompareHashAndPassword method")
 //             },
-//             GenerateFromPasswordFunc: func(password []byte, cost int) ([]byte, error) {
+//             GenerateFromPasswordFunc: func(password []byte) ([]byte, error) {
 // 	               panic("TODO: mock out the GenerateFromPassword method")
 //             },
+//             IdentifiesFunc: func(hashedPassword []byte) bool {
+// 	               panic("TODO: mock out the Identifies method")
+//             },
+//             NeedsRehashFunc: func(hashedPassword []byte) bool {
+// 	               panic("TODO: mock out the NeedsRehash method")
+//             },
+//             SupportsFunc: func(hashedPassword []byte) bool {
+// 	               panic("TODO: mock out the Supports method")
+//             },
 //         }
 //
 //         // TODO: use mockedEncryptor in code that requires Encryptor
@@ -35,7 +49,16 @@ type EncryptorMock struct {
 	CompareHashAndPasswordFunc func(hashedPassword []byte, password []byte) error
 
 	// GenerateFromPasswordFunc mocks the GenerateFromPassword method.
-	GenerateFromPasswordFunc func(password []byte, cost int) ([]byte, error)
+	GenerateFromPasswordFunc func(password []byte) ([]byte, error)
+
+	// IdentifiesFunc mocks the Identifies method.
+	IdentifiesFunc func(hashedPassword []byte) bool
+
+	// NeedsRehashFunc mocks the NeedsRehash method.
+	NeedsRehashFunc func(hashedPassword []byte) bool
+
+	// SupportsFunc mocks the Supports method.
+	SupportsFunc func(hashedPassword []byte) bool
 
 	// calls tracks calls to the methods.
 	calls struct {
@@ -50,8 +73,21 @@ type EncryptorMock struct {
 		GenerateFromPassword []struct {
 			// Password is the password argument value.
 			Password []byte
-			// Cost is the cost argument value.
-			Cost int
+		}
+		// Identifies holds details about calls to the Identifies method.
+		Identifies []struct {
+			// HashedPassword is the hashedPassword argument value.
+			HashedPassword []byte
+		}
+		// NeedsRehash holds details about calls to the NeedsRehash method.
+		NeedsRehash []struct {
+			// HashedPassword is the hashedPassword argument value.
+			HashedPassword []byte
+		}
+		// Supports holds details about calls to the Supports method.
+		Supports []struct {
+			// HashedPassword is the hashedPassword argument value.
+			HashedPassword []byte
 		}
 	}
 }
@@ -92,21 +128,19 @@ func (mock *EncryptorMock) CompareHashAndPasswordCalls() []struct {
 }
 
 // GenerateFromPassword calls GenerateFromPasswordFunc.
-func (mock *EncryptorMock) GenerateFromPassword(password []byte, cost int) ([]byte, error) {
+func (mock *EncryptorMock) GenerateFromPassword(password []byte) ([]byte, error) {
 	if mock.GenerateFromPasswordFunc == nil {
 		panic("moq: EncryptorMock.GenerateFromPasswordFunc is nil but Encryptor.GenerateFromPassword was just called")
 	}
 	callInfo := struct {
 		Password []byte
-		Cost     int
 	}{
 		Password: password,
-		Cost:     cost,
 	}
 	lockEncryptorMockGenerateFromPassword.Lock()
 	mock.calls.GenerateFromPassword = append(mock.calls.GenerateFromPassword, callInfo)
 	lockEncryptorMockGenerateFromPassword.Unlock()
-	return mock.GenerateFromPasswordFunc(password, cost)
+	return mock.GenerateFromPasswordFunc(password)
 }
 
 // GenerateFromPasswordCalls gets all the calls that were made to GenerateFromPassword.
@@ -114,14 +148,465 @@ func (mock *EncryptorMock) GenerateFromPassword(password []byte, cost int) ([]by
 //     len(mockedEncryptor.GenerateFromPasswordCalls())
 func (mock *EncryptorMock) GenerateFromPasswordCalls() []struct {
 	Password []byte
-	Cost     int
 } {
 	var calls []struct {
 		Password []byte
-		Cost     int
 	}
 	lockEncryptorMockGenerateFromPassword.RLock()
 	calls = mock.calls.GenerateFromPassword
 	lockEncryptorMockGenerateFromPassword.RUnlock()
 	return calls
 }
+
+// Identifies calls IdentifiesFunc.
+func (mock *EncryptorMock) Identifies(hashedPassword []byte) bool {
+	if mock.IdentifiesFunc == nil {
+		panic("moq: EncryptorMock.IdentifiesFunc is nil but Encryptor.Identifies was just called")
+	}
+	callInfo := struct {
+		HashedPassword []byte
+	}{
+		HashedPassword: hashedPassword,
+	}
+	lockEncryptorMockIdentifies.Lock()
+	mock.calls.Identifies = append(mock.calls.Identifies, callInfo)
+	lockEncryptorMockIdentifies.Unlock()
+	return mock.IdentifiesFunc(hashedPassword)
+}
+
+// IdentifiesCalls gets all the calls that were made to Identifies.
+// Check the length with:
+//     len(mockedEncryptor.IdentifiesCalls())
+func (mock *EncryptorMock) IdentifiesCalls() []struct {
+	HashedPassword []byte
+} {
+	var calls []struct {
+		HashedPassword []byte
+	}
+	lockEncryptorMockIdentifies.RLock()
+	calls = mock.calls.Identifies
+	lockEncryptorMockIdentifies.RUnlock()
+	return calls
+}
+
+// NeedsRehash calls NeedsRehashFunc.
+func (mock *EncryptorMock) NeedsRehash(hashedPassword []byte) bool {
+	if mock.NeedsRehashFunc == nil {
+		panic("moq: EncryptorMock.NeedsRehashFunc is nil but Encryptor.NeedsRehash was just called")
+	}
+	callInfo := struct {
+		HashedPassword []byte
+	}{
+		HashedPassword: hashedPassword,
+	}
+	lockEncryptorMockNeedsRehash.Lock()
+	mock.calls.NeedsRehash = append(mock.calls.NeedsRehash, callInfo)
+	lockEncryptorMockNeedsRehash.Unlock()
+	return mock.NeedsRehashFunc(hashedPassword)
+}
+
+// NeedsRehashCalls gets all the calls that were made to NeedsRehash.
+// Check the length with:
+//     len(mockedEncryptor.NeedsRehashCalls())
+func (mock *EncryptorMock) NeedsRehashCalls() []struct {
+	HashedPassword []byte
+} {
+	var calls []struct {
+		HashedPassword []byte
+	}
+	lockEncryptorMockNeedsRehash.RLock()
+	calls = mock.calls.NeedsRehash
+	lockEncryptorMockNeedsRehash.RUnlock()
+	return calls
+}
+
+// Supports calls SupportsFunc.
+func (mock *EncryptorMock) Supports(hashedPassword []byte) bool {
+	if mock.SupportsFunc == nil {
+		panic("moq: EncryptorMock.SupportsFunc is nil but Encryptor.Supports was just called")
+	}
+	callInfo := struct {
+		HashedPassword []byte
+	}{
+		HashedPassword: hashedPassword,
+	}
+	lockEncryptorMockSupports.Lock()
+	mock.calls.Supports = append(mock.calls.Supports, callInfo)
+	lockEncryptorMockSupports.Unlock()
+	return mock.SupportsFunc(hashedPassword)
+}
+
+// SupportsCalls gets all the calls that were made to Supports.
+// Check the length with:
+//     len(mockedEncryptor.SupportsCalls())
+func (mock *EncryptorMock) SupportsCalls() []struct {
+	HashedPassword []byte
+} {
+	var calls []struct {
+		HashedPassword []byte
+	}
+	lockEncryptorMockSupports.RLock()
+	calls = mock.calls.Supports
+	lockEncryptorMockSupports.RUnlock()
+	return calls
+}
+
+var (
+	lockNotifierMockSendEmailChange       sync.RWMutex
+	lockNotifierMockSendEmailChangeNotice sync.RWMutex
+	lockNotifierMockSendVerification      sync.RWMutex
+)
+
+// NotifierMock is a mock implementation of Notifier.
+//
+//     func TestSomethingThatUsesNotifier(t *testing.T) {
+//
+//         // make and configure a mocked Notifier
+//         mockedNotifier := &NotifierMock{
+//             SendEmailChangeFunc: func(ctx context.Context, i schema.Identity, email string, token string) error {
+// 	               panic("TODO: mock out the SendEmailChange method")
+//             },
+//             SendEmailChangeNoticeFunc: func(ctx context.Context, i schema.Identity, email string) error {
+// 	               panic("TODO: mock out the SendEmailChangeNotice method")
+//             },
+//             SendVerificationFunc: func(ctx context.Context, i schema.Identity, token string) error {
+// 	               panic("TODO: mock out the SendVerification method")
+//             },
+//         }
+//
+//         // TODO: use mockedNotifier in code that requires Notifier
+//         //       and then make assertions.
+//
+//     }
+type NotifierMock struct {
+	// SendEmailChangeFunc mocks the SendEmailChange method.
+	SendEmailChangeFunc func(ctx context.Context, i schema.Identity, email string, token string) error
+
+	// SendEmailChangeNoticeFunc mocks the SendEmailChangeNotice method.
+	SendEmailChangeNoticeFunc func(ctx context.Context, i schema.Identity, email string) error
+
+	// SendVerificationFunc mocks the SendVerification method.
+	SendVerificationFunc func(ctx context.Context, i schema.Identity, token string) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// SendEmailChange holds details about calls to the SendEmailChange method.
+		SendEmailChange []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// I is the i argument value.
+			I schema.Identity
+			// Email is the email argument value.
+			Email string
+			// Token is the token argument value.
+			Token string
+		}
+		// SendEmailChangeNotice holds details about calls to the SendEmailChangeNotice method.
+		SendEmailChangeNotice []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// I is the i argument value.
+			I schema.Identity
+			// Email is the email argument value.
+			Email string
+		}
+		// SendVerification holds details about calls to the SendVerification method.
+		SendVerification []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// I is the i argument value.
+			I schema.Identity
+			// Token is the token argument value.
+			Token string
+		}
+	}
+}
+
+// SendEmailChange calls SendEmailChangeFunc.
+func (mock *NotifierMock) SendEmailChange(ctx context.Context, i schema.Identity, email string, token string) error {
+	if mock.SendEmailChangeFunc == nil {
+		panic("moq: NotifierMock.SendEmailChangeFunc is nil but Notifier.SendEmailChange was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		I     schema.Identity
+		Email string
+		Token string
+	}{
+		Ctx:   ctx,
+		I:     i,
+		Email: email,
+		Token: token,
+	}
+	lockNotifierMockSendEmailChange.Lock()
+	mock.calls.SendEmailChange = append(mock.calls.SendEmailChange, callInfo)
+	lockNotifierMockSendEmailChange.Unlock()
+	return mock.SendEmailChangeFunc(ctx, i, email, token)
+}
+
+// SendEmailChangeCalls gets all the calls that were made to SendEmailChange.
+// Check the length with:
+//     len(mockedNotifier.SendEmailChangeCalls())
+func (mock *NotifierMock) SendEmailChangeCalls() []struct {
+	Ctx   context.Context
+	I     schema.Identity
+	Email string
+	Token string
+} {
+	var calls []struct {
+		Ctx   context.Context
+		I     schema.Identity
+		Email string
+		Token string
+	}
+	lockNotifierMockSendEmailChange.RLock()
+	calls = mock.calls.SendEmailChange
+	lockNotifierMockSendEmailChange.RUnlock()
+	return calls
+}
+
+// SendEmailChangeNotice calls SendEmailChangeNoticeFunc.
+func (mock *NotifierMock) SendEmailChangeNotice(ctx context.Context, i schema.Identity, email string) error {
+	if mock.SendEmailChangeNoticeFunc == nil {
+		panic("moq: NotifierMock.SendEmailChangeNoticeFunc is nil but Notifier.SendEmailChangeNotice was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		I     schema.Identity
+		Email string
+	}{
+		Ctx:   ctx,
+		I:     i,
+		Email: email,
+	}
+	lockNotifierMockSendEmailChangeNotice.Lock()
+	mock.calls.SendEmailChangeNotice = append(mock.calls.SendEmailChangeNotice, callInfo)
+	lockNotifierMockSendEmailChangeNotice.Unlock()
+	return mock.SendEmailChangeNoticeFunc(ctx, i, email)
+}
+
+// SendEmailChangeNoticeCalls gets all the calls that were made to SendEmailChangeNotice.
+// Check the length with:
+//     len(mockedNotifier.SendEmailChangeNoticeCalls())
+func (mock *NotifierMock) SendEmailChangeNoticeCalls() []struct {
+	Ctx   context.Context
+	I     schema.Identity
+	Email string
+} {
+	var calls []struct {
+		Ctx   context.Context
+		I     schema.Identity
+		Email string
+	}
+	lockNotifierMockSendEmailChangeNotice.RLock()
+	calls = mock.calls.SendEmailChangeNotice
+	lockNotifierMockSendEmailChangeNotice.RUnlock()
+	return calls
+}
+
+// SendVerification calls SendVerificationFunc.
+func (mock *NotifierMock) SendVerification(ctx context.Context, i schema.Identity, token string) error {
+	if mock.SendVerificationFunc == nil {
+		panic("moq: NotifierMock.SendVerificationFunc is nil but Notifier.SendVerification was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		I     schema.Identity
+		Token string
+	}{
+		Ctx:   ctx,
+		I:     i,
+		Token: token,
+	}
+	lockNotifierMockSendVerification.Lock()
+	mock.calls.SendVerification = append(mock.calls.SendVerification, callInfo)
+	lockNotifierMockSendVerification.Unlock()
+	return mock.SendVerificationFunc(ctx, i, token)
+}
+
+// SendVerificationCalls gets all the calls that were made to SendVerification.
+// Check the length with:
+//     len(mockedNotifier.SendVerificationCalls())
+func (mock *NotifierMock) SendVerificationCalls() []struct {
+	Ctx   context.Context
+	I     schema.Identity
+	Token string
+} {
+	var calls []struct {
+		Ctx   context.Context
+		I     schema.Identity
+		Token string
+	}
+	lockNotifierMockSendVerification.RLock()
+	calls = mock.calls.SendVerification
+	lockNotifierMockSendVerification.RUnlock()
+	return calls
+}
+
+var (
+	lockTokenManagerMockErase  sync.RWMutex
+	lockTokenManagerMockList   sync.RWMutex
+	lockTokenManagerMockRevoke sync.RWMutex
+)
+
+// TokenManagerMock is a mock implementation of TokenManager.
+//
+//     func TestSomethingThatUsesTokenManager(t *testing.T) {
+//
+//         // make and configure a mocked TokenManager
+//         mockedTokenManager := &TokenManagerMock{
+//             EraseFunc: func(ctx context.Context, identityID string) error {
+// 	               panic("TODO: mock out the Erase method")
+//             },
+//             ListFunc: func(ctx context.Context, identityID string) ([]schema.Token, error) {
+// 	               panic("TODO: mock out the List method")
+//             },
+//             RevokeFunc: func(ctx context.Context, identityID string) error {
+// 	               panic("TODO: mock out the Revoke method")
+//             },
+//         }
+//
+//         // TODO: use mockedTokenManager in code that requires TokenManager
+//         //       and then make assertions.
+//
+//     }
+type TokenManagerMock struct {
+	// EraseFunc mocks the Erase method.
+	EraseFunc func(ctx context.Context, identityID string) error
+
+	// ListFunc mocks the List method.
+	ListFunc func(ctx context.Context, identityID string) ([]schema.Token, error)
+
+	// RevokeFunc mocks the Revoke method.
+	RevokeFunc func(ctx context.Context, identityID string) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Erase holds details about calls to the Erase method.
+		Erase []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// IdentityID is the identityID argument value.
+			IdentityID string
+		}
+		// List holds details about calls to the List method.
+		List []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// IdentityID is the identityID argument value.
+			IdentityID string
+		}
+		// Revoke holds details about calls to the Revoke method.
+		Revoke []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// IdentityID is the identityID argument value.
+			IdentityID string
+		}
+	}
+}
+
+// Erase calls EraseFunc.
+func (mock *TokenManagerMock) Erase(ctx context.Context, identityID string) error {
+	if mock.EraseFunc == nil {
+		panic("moq: TokenManagerMock.EraseFunc is nil but TokenManager.Erase was just called")
+	}
+	callInfo := struct {
+		Ctx        context.Context
+		IdentityID string
+	}{
+		Ctx:        ctx,
+		IdentityID: identityID,
+	}
+	lockTokenManagerMockErase.Lock()
+	mock.calls.Erase = append(mock.calls.Erase, callInfo)
+	lockTokenManagerMockErase.Unlock()
+	return mock.EraseFunc(ctx, identityID)
+}
+
+// EraseCalls gets all the calls that were made to Erase.
+// Check the length with:
+//     len(mockedTokenManager.EraseCalls())
+func (mock *TokenManagerMock) EraseCalls() []struct {
+	Ctx        context.Context
+	IdentityID string
+} {
+	var calls []struct {
+		Ctx        context.Context
+		IdentityID string
+	}
+	lockTokenManagerMockErase.RLock()
+	calls = mock.calls.Erase
+	lockTokenManagerMockErase.RUnlock()
+	return calls
+}
+
+// List calls ListFunc.
+func (mock *TokenManagerMock) List(ctx context.Context, identityID string) ([]schema.Token, error) {
+	if mock.ListFunc == nil {
+		panic("moq: TokenManagerMock.ListFunc is nil but TokenManager.List was just called")
+	}
+	callInfo := struct {
+		Ctx        context.Context
+		IdentityID string
+	}{
+		Ctx:        ctx,
+		IdentityID: identityID,
+	}
+	lockTokenManagerMockList.Lock()
+	mock.calls.List = append(mock.calls.List, callInfo)
+	lockTokenManagerMockList.Unlock()
+	return mock.ListFunc(ctx, identityID)
+}
+
+// ListCalls gets all the calls that were made to List.
+// Check the length with:
+//     len(mockedTokenManager.ListCalls())
+func (mock *TokenManagerMock) ListCalls() []struct {
+	Ctx        context.Context
+	IdentityID string
+} {
+	var calls []struct {
+		Ctx        context.Context
+		IdentityID string
+	}
+	lockTokenManagerMockList.RLock()
+	calls = mock.calls.List
+	lockTokenManagerMockList.RUnlock()
+	return calls
+}
+
+// Revoke calls RevokeFunc.
+func (mock *TokenManagerMock) Revoke(ctx context.Context, identityID string) error {
+	if mock.RevokeFunc == nil {
+		panic("moq: TokenManagerMock.RevokeFunc is nil but TokenManager.Revoke was just called")
+	}
+	callInfo := struct {
+		Ctx        context.Context
+		IdentityID string
+	}{
+		Ctx:        ctx,
+		IdentityID: identityID,
+	}
+	lockTokenManagerMockRevoke.Lock()
+	mock.calls.Revoke = append(mock.calls.Revoke, callInfo)
+	lockTokenManagerMockRevoke.Unlock()
+	return mock.RevokeFunc(ctx, identityID)
+}
+
+// RevokeCalls gets all the calls that were made to Revoke.
+// Check the length with:
+//     len(mockedTokenManager.RevokeCalls())
+func (mock *TokenManagerMock) RevokeCalls() []struct {
+	Ctx        context.Context
+	IdentityID string
+} {
+	var calls []struct {
+		Ctx        context.Context
+		IdentityID string
+	}
+	lockTokenManagerMockRevoke.RLock()
+	calls = mock.calls.Revoke
+	lockTokenManagerMockRevoke.RUnlock()
+	return calls
+}
@@ -0,0 +1,49 @@
+package identity
+
+import (
+	"context"
+	"github.com/ONSdigital/dp-identity-api/persistence"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/ONSdigital/dp-identity-api/tracing"
+	"github.com/ONSdigital/go-ns/log"
+	"github.com/pkg/errors"
+)
+
+var ErrImpersonateAdmin = errors.New("admin identities cannot be impersonated")
+
+// Impersonate return the identity with the provided ID with the admin identity impersonating it as its Impersonator,
+// ready for an impersonation token to be issued. Admin identities cannot be impersonated, so an impersonation token
+// never grants admin privileges. Returns ErrIdentityDisabled if the identity is disabled, ErrIdentityErased if it has
+// been erased or ErrIdentityNotFound if no active identity exists.
+func (s *Service) Impersonate(ctx context.Context, id string, admin schema.Identity) (i *schema.Identity, err error) {
+	ctx, span := tracing.Start(ctx, "identity.Service.Impersonate")
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
+	logD := log.Data{"id": id, "impersonated_by": admin.ID}
+
+	i, err = s.IdentityStore.GetIdentityByID(ctx, id)
+	if err != nil {
+		if err == persistence.ErrNotFound {
+			return nil, s.notFoundOrErased(ctx, id)
+		}
+		log.ErrorCtx(ctx, errors.WithMessage(err, "impersonate: failed to read identity from store"), logD)
+		return nil, storeErr(err)
+	}
+
+	if i.UserType == schema.UserTypeAdmin {
+		log.ErrorCtx(ctx, errors.New("impersonate: identity is an admin"), logD)
+		return nil, ErrImpersonateAdmin
+	}
+
+	if i.Disabled {
+		log.ErrorCtx(ctx, errors.New("impersonate: identity is disabled"), logD)
+		return nil, ErrIdentityDisabled
+	}
+
+	i.Impersonator = &admin
+	log.InfoCtx(ctx, "impersonate: identity impersonated successfully", logD)
+	return i, nil
+}
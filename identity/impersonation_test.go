@@ -0,0 +1,99 @@
+package identity
+
+import (
+	"context"
+	"github.com/ONSdigital/dp-identity-api/persistence"
+	"github.com/ONSdigital/dp-identity-api/persistence/persistencetest"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestService_Impersonate(t *testing.T) {
+	Convey("given an identity", t, func() {
+		admin := schema.Identity{ID: "999", UserType: schema.UserTypeAdmin}
+		stored := &schema.Identity{ID: "666", Email: newIdentity.Email, UserType: schema.UserTypeUser}
+
+		p := &persistencetest.IdentityStoreMock{
+			GetIdentityByIDFunc: func(ctx context.Context, id string) (*schema.Identity, error) {
+				return stored, nil
+			},
+			GetTombstoneFunc: func(ctx context.Context, id string) (*schema.Tombstone, error) {
+				return nil, persistence.ErrNotFound
+			},
+		}
+
+		s := &Service{IdentityStore: p}
+
+		Convey("when the identity is impersonated by an admin", func() {
+			i, err := s.Impersonate(context.Background(), "666", admin)
+
+			Convey("then the identity is returned with the admin as its impersonator", func() {
+				So(err, ShouldBeNil)
+				So(i.ID, ShouldEqual, "666")
+				So(i.Impersonator, ShouldResemble, &admin)
+				So(p.GetIdentityByIDCalls()[0].ID, ShouldEqual, "666")
+			})
+		})
+
+		Convey("when the identity is an admin", func() {
+			stored.UserType = schema.UserTypeAdmin
+
+			i, err := s.Impersonate(context.Background(), "666", admin)
+
+			Convey("then ErrImpersonateAdmin is returned", func() {
+				So(err, ShouldEqual, ErrImpersonateAdmin)
+				So(i, ShouldBeNil)
+			})
+		})
+
+		Convey("when the identity is disabled", func() {
+			stored.Disabled = true
+
+			_, err := s.Impersonate(context.Background(), "666", admin)
+
+			Convey("then ErrIdentityDisabled is returned", func() {
+				So(err, ShouldEqual, ErrIdentityDisabled)
+			})
+		})
+
+		Convey("when the identity does not exist", func() {
+			p.GetIdentityByIDFunc = func(ctx context.Context, id string) (*schema.Identity, error) {
+				return nil, persistence.ErrNotFound
+			}
+
+			_, err := s.Impersonate(context.Background(), "666", admin)
+
+			Convey("then ErrIdentityNotFound is returned", func() {
+				So(err, ShouldEqual, ErrIdentityNotFound)
+			})
+		})
+
+		Convey("when the identity has been erased", func() {
+			p.GetIdentityByIDFunc = func(ctx context.Context, id string) (*schema.Identity, error) {
+				return nil, persistence.ErrNotFound
+			}
+			p.GetTombstoneFunc = func(ctx context.Context, id string) (*schema.Tombstone, error) {
+				return &schema.Tombstone{ID: id}, nil
+			}
+
+			_, err := s.Impersonate(context.Background(), "666", admin)
+
+			Convey("then ErrIdentityErased is returned", func() {
+				So(err, ShouldEqual, ErrIdentityErased)
+			})
+		})
+
+		Convey("when the store returns an error", func() {
+			p.GetIdentityByIDFunc = func(ctx context.Context, id string) (*schema.Identity, error) {
+				return nil, persistence.ErrTimeout
+			}
+
+			_, err := s.Impersonate(context.Background(), "666", admin)
+
+			Convey("then the error is returned", func() {
+				So(err, ShouldEqual, persistence.ErrTimeout)
+			})
+		})
+	})
+}
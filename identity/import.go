@@ -0,0 +1,106 @@
+package identity
+
+import (
+	"context"
+	"github.com/ONSdigital/dp-identity-api/persistence"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/ONSdigital/dp-identity-api/tracing"
+	"github.com/ONSdigital/go-ns/log"
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidPasswordHash is returned if an identity being imported has a password hash in a supported format that is
+// malformed or has parameters outside of the accepted bounds.
+var ErrInvalidPasswordHash = errors.New("legacy password hash is malformed or its parameters are out of bounds")
+
+// ImportReport summarises the outcome of importing legacy identities.
+type ImportReport struct {
+	Imported    []string `json:"imported"`
+	Skipped     []string `json:"skipped"`
+	Conflicting []string `json:"conflicting"`
+}
+
+// Import creates identities migrated from Zebedee. The legacy password hash of each identity is stored as is and the
+// identity is flagged as migrated so the hash is upgraded using the current Encryptor on first login. Migrated
+// identities are already in use so are imported as verified. Identities that
+// fail validation, including those with an unknown user type, or whose password hash is not in a supported format
+// are skipped. Identities whose email is already
+// associated with an active identity, or appears more than once in the import, are reported as conflicting.
+//
+// If any identity has a password hash in a supported format that is not valid no identities are imported and
+// ErrInvalidPasswordHash is returned.
+func (s *Service) Import(ctx context.Context, identities []schema.Identity) (*ImportReport, error) {
+	if ctx == nil {
+		log.Error(errors.New("import: failed mandatory context parameter was nil"), nil)
+		return nil, ErrInvalidArguments
+	}
+
+	ctx, span := tracing.Start(ctx, "identity.Service.Import")
+	defer span.End()
+
+	for _, i := range identities {
+		hash := []byte(i.Password)
+		if s.Encryptor.Identifies(hash) && !s.Encryptor.Supports(hash) {
+			log.ErrorCtx(ctx, errors.WithMessage(ErrInvalidPasswordHash, "import: rejecting import"), log.Data{"email": i.Email})
+			return nil, ErrInvalidPasswordHash
+		}
+	}
+
+	report := &ImportReport{
+		Imported:    []string{},
+		Skipped:     []string{},
+		Conflicting: []string{},
+	}
+
+	seen := make(map[string]bool)
+
+	for _, i := range identities {
+		logD := log.Data{"email": i.Email}
+
+		if seen[i.Email] {
+			log.InfoCtx(ctx, "import: email appears more than once in import", logD)
+			report.Conflicting = append(report.Conflicting, i.Email)
+			continue
+		}
+		seen[i.Email] = true
+
+		if err := i.Validate(); err != nil {
+			log.ErrorCtx(ctx, errors.Wrap(err, "import: skipping identity failed validation"), logD)
+			report.Skipped = append(report.Skipped, i.Email)
+			continue
+		}
+
+		if !s.Encryptor.Supports([]byte(i.Password)) {
+			log.ErrorCtx(ctx, errors.New("import: skipping identity legacy password hash format not supported"), logD)
+			report.Skipped = append(report.Skipped, i.Email)
+			continue
+		}
+
+		i.Migrated = true
+		i.Deleted = false
+		i.Verified = true
+
+		id, err := s.IdentityStore.SaveIdentity(ctx, i)
+		if err != nil && err == persistence.ErrNonUnique {
+			log.InfoCtx(ctx, "import: an active identity with this email already exists", logD)
+			report.Conflicting = append(report.Conflicting, i.Email)
+			continue
+		}
+
+		if err != nil {
+			log.ErrorCtx(ctx, errors.WithMessage(err, "import: failed to write data to mongo"), logD)
+			return report, storeErr(err)
+		}
+
+		logD["id"] = id
+		log.InfoCtx(ctx, "import: legacy identity imported successfully", logD)
+		report.Imported = append(report.Imported, i.Email)
+	}
+
+	log.InfoCtx(ctx, "import: completed", log.Data{
+		"imported":    len(report.Imported),
+		"skipped":     len(report.Skipped),
+		"conflicting": len(report.Conflicting),
+	})
+	return report, nil
+}
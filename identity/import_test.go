@@ -0,0 +1,156 @@
+package identity
+
+import (
+	"context"
+	"github.com/ONSdigital/dp-identity-api/identity/identitytest"
+	"github.com/ONSdigital/dp-identity-api/persistence"
+	"github.com/ONSdigital/dp-identity-api/persistence/persistencetest"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+var (
+	legacyIdentities = []schema.Identity{
+		{Name: "Peter Venkman", Email: "venkman@whoyougunnacall.com", Password: "$2a$10$legacy"},
+		{Name: "Ray Stantz", Email: "stantz@whoyougunnacall.com", Password: "$2a$10$legacy"},
+		{Name: "Egon Spengler", Email: "spengler@whoyougunnacall.com", Password: "unsupported"},
+		{Name: "Winston Zeddemore", Email: "", Password: "$2a$10$legacy"},
+		{Name: "Peter Venkman", Email: "venkman@whoyougunnacall.com", Password: "$2a$10$legacy"},
+		{Name: "Janine Melnitz", Email: "melnitz@whoyougunnacall.com", Password: "$2a$10$legacy", UserType: "superuser"},
+	}
+)
+
+func TestService_Import(t *testing.T) {
+	Convey("given a batch of legacy identities", t, func() {
+		p := &persistencetest.IdentityStoreMock{
+			SaveIdentityFunc: func(ctx context.Context, i schema.Identity) (string, error) {
+				if i.Email == "stantz@whoyougunnacall.com" {
+					return "", persistence.ErrNonUnique
+				}
+				return "666", nil
+			},
+		}
+
+		e := &identitytest.EncryptorMock{
+			SupportsFunc: func(hashedPassword []byte) bool {
+				return string(hashedPassword) != "unsupported"
+			},
+			IdentifiesFunc: func(hashedPassword []byte) bool {
+				return string(hashedPassword) != "unsupported"
+			},
+		}
+
+		s := &Service{IdentityStore: p, Encryptor: e}
+
+		Convey("when import is called", func() {
+			report, err := s.Import(context.Background(), legacyIdentities)
+
+			Convey("then the expected report is returned", func() {
+				So(err, ShouldBeNil)
+				So(report.Imported, ShouldResemble, []string{"venkman@whoyougunnacall.com"})
+				So(report.Skipped, ShouldResemble, []string{"spengler@whoyougunnacall.com", "", "melnitz@whoyougunnacall.com"})
+				So(report.Conflicting, ShouldResemble, []string{"stantz@whoyougunnacall.com", "venkman@whoyougunnacall.com"})
+			})
+
+			Convey("and the legacy hash is stored as is with the migrated flag set", func() {
+				So(p.SaveIdentityCalls(), ShouldHaveLength, 2)
+				So(p.SaveIdentityCalls()[0].NewIdentity.Password, ShouldEqual, "$2a$10$legacy")
+				So(p.SaveIdentityCalls()[0].NewIdentity.Migrated, ShouldBeTrue)
+				So(e.GenerateFromPasswordCalls(), ShouldHaveLength, 0)
+			})
+
+			Convey("and the identity is imported as verified", func() {
+				So(p.SaveIdentityCalls()[0].NewIdentity.Verified, ShouldBeTrue)
+			})
+		})
+	})
+
+	Convey("should return expected error if the store returns an error", t, func() {
+		p := &persistencetest.IdentityStoreMock{
+			SaveIdentityFunc: func(ctx context.Context, i schema.Identity) (string, error) {
+				return "", errTest
+			},
+		}
+
+		e := &identitytest.EncryptorMock{
+			SupportsFunc: func(hashedPassword []byte) bool {
+				return true
+			},
+			IdentifiesFunc: func(hashedPassword []byte) bool {
+				return true
+			},
+		}
+
+		s := &Service{IdentityStore: p, Encryptor: e}
+
+		report, err := s.Import(context.Background(), legacyIdentities[:1])
+
+		So(err, ShouldEqual, ErrPersistence)
+		So(report.Imported, ShouldBeEmpty)
+		So(p.SaveIdentityCalls(), ShouldHaveLength, 1)
+	})
+
+	Convey("should return ErrInvalidPasswordHash and import nothing if a hash in a supported format is invalid", t, func() {
+		p := &persistencetest.IdentityStoreMock{
+			SaveIdentityFunc: func(ctx context.Context, i schema.Identity) (string, error) {
+				return "666", nil
+			},
+		}
+
+		e := &identitytest.EncryptorMock{
+			SupportsFunc: func(hashedPassword []byte) bool {
+				return string(hashedPassword) != "$argon2id$v=19$m=1024,t=0,p=1$c2FsdA$a2V5"
+			},
+			IdentifiesFunc: func(hashedPassword []byte) bool {
+				return true
+			},
+		}
+
+		s := &Service{IdentityStore: p, Encryptor: e}
+
+		identities := append([]schema.Identity{}, legacyIdentities[0], schema.Identity{
+			Name:     "Winston Zeddemore",
+			Email:    "zeddemore@whoyougunnacall.com",
+			Password: "$argon2id$v=19$m=1024,t=0,p=1$c2FsdA$a2V5",
+		})
+
+		report, err := s.Import(context.Background(), identities)
+
+		So(err, ShouldEqual, ErrInvalidPasswordHash)
+		So(report, ShouldBeNil)
+		So(p.SaveIdentityCalls(), ShouldHaveLength, 0)
+	})
+}
+
+func TestService_VerifyPasswordMigrated(t *testing.T) {
+	Convey("given the identity was migrated from Zebedee", t, func() {
+		stored := schema.Identity{ID: "666", Email: "venkman@whoyougunnacall.com", Password: "$2a$10$legacy", Migrated: true}
+
+		p := &persistencetest.IdentityStoreMock{
+			GetIdentityFunc: func(ctx context.Context, email string) (schema.Identity, error) {
+				return stored, nil
+			},
+			UpdatePasswordFunc: func(ctx context.Context, id string, password string) error {
+				return nil
+			},
+		}
+
+		e := newEncryptorMock([]byte("upgraded"), nil, nil)
+
+		s := Service{IdentityStore: p, Encryptor: e}
+
+		Convey("when the password is verified successfully on first login", func() {
+			identity, err := s.VerifyPassword(context.Background(), stored.Email, "Zuul")
+
+			Convey("then the legacy hash is replaced with a hash from the current encryptor", func() {
+				So(err, ShouldBeNil)
+				So(identity.Password, ShouldEqual, "upgraded")
+				So(identity.Migrated, ShouldBeFalse)
+				So(e.CompareHashAndPasswordCalls()[0].HashedPassword, ShouldResemble, []byte("$2a$10$legacy"))
+				So(p.UpdatePasswordCalls(), ShouldHaveLength, 1)
+				So(p.UpdatePasswordCalls()[0].Password, ShouldEqual, "upgraded")
+			})
+		})
+	})
+}
@@ -1,24 +1,69 @@
 package identity
 
 import (
+	"context"
 	"errors"
 	"github.com/ONSdigital/dp-identity-api/persistence"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/ONSdigital/dp-identity-api/token"
+	"time"
 )
 
-//go:generate moq -out identitytest/generate_mocks.go -pkg identitytest . Encryptor
+//go:generate moq -out identitytest/generate_mocks.go -pkg identitytest . Encryptor Notifier TokenManager
 
 var (
 	ErrInvalidArguments = errors.New("error while attempting create new identity")
 	ErrPersistence      = errors.New("error while attempting to write data to mongo")
 )
 
+// Encryptor hashes and verifies passwords.
 type Encryptor interface {
-	GenerateFromPassword(password []byte, cost int) ([]byte, error)
+	GenerateFromPassword(password []byte) ([]byte, error)
 	CompareHashAndPassword(hashedPassword, password []byte) error
+	NeedsRehash(hashedPassword []byte) bool
+	Supports(hashedPassword []byte) bool
+	Identifies(hashedPassword []byte) bool
+}
+
+// Notifier sends the token issued to verify the email address of a new identity to that address.
+//
+// SendEmailChange sends the token confirming an email change to the new address, SendEmailChangeNotice notifies the
+// identity's current address that a change has been requested.
+type Notifier interface {
+	SendVerification(ctx context.Context, i schema.Identity, token string) error
+	SendEmailChange(ctx context.Context, i schema.Identity, email string, token string) error
+	SendEmailChangeNotice(ctx context.Context, i schema.Identity, email string) error
+}
+
+// TokenManager manages the tokens of an identity. Revoke revokes the active tokens, Erase permanently removes every
+// token and List returns every token ordered by created date.
+type TokenManager interface {
+	Revoke(ctx context.Context, identityID string) error
+	Erase(ctx context.Context, identityID string) error
+	List(ctx context.Context, identityID string) ([]schema.Token, error)
 }
 
 //Service encapsulates the logic for creating, updating and deleting identities
+//
+// New identities are unverified and issued a verification token which is sent by the Notifier, if there is one, and
+// expires after VerificationLifetime. Verification and email change tokens are generated by the Generator and stored
+// as the digest computed by the Digester, defaulting to the same random generator and SHA-256 digest used for identity
+// tokens. If RequireVerified is true password verification of an unverified identity fails with
+// ErrIdentityNotVerified.
+//
+// Changing an identity's email or disabling it revokes its tokens using Tokens. Password verification of a disabled
+// identity fails with ErrIdentityDisabled.
+//
+// Exports include the audit events about the identity stored in AuditEvents, if there is one, which are
+// pseudonymised when the identity is erased.
 type Service struct {
-	IdentityStore persistence.IdentityStore
-	Encryptor     Encryptor
+	IdentityStore        persistence.IdentityStore
+	Encryptor            Encryptor
+	Notifier             Notifier
+	Tokens               TokenManager
+	AuditEvents          persistence.AuditStore
+	Generator            token.Generator
+	Digester             token.Digester
+	VerificationLifetime time.Duration
+	RequireVerified      bool
 }
@@ -0,0 +1,121 @@
+package identity
+
+import (
+	"context"
+	"github.com/ONSdigital/dp-identity-api/persistence"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/ONSdigital/dp-identity-api/tracing"
+	"github.com/ONSdigital/go-ns/log"
+	"github.com/pkg/errors"
+	"time"
+)
+
+var ErrIdentityErased = errors.New("identity has been erased")
+
+// Export is the data stored about an identity.
+type Export struct {
+	Identity schema.Identity
+	Tokens   []schema.Token
+	Events   []schema.AuditEvent
+}
+
+// Export return the data stored about the identity with the provided ID, whether or not it has been deleted: the
+// identity, every token issued to it and the audit events about it. Returns ErrIdentityErased if the identity has been
+// erased or ErrIdentityNotFound if no identity exists.
+func (s *Service) Export(ctx context.Context, id string) (e *Export, err error) {
+	ctx, span := tracing.Start(ctx, "identity.Service.Export")
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
+	logD := log.Data{"id": id}
+
+	i, err := s.IdentityStore.GetIdentityByIDIncludingDeleted(ctx, id)
+	if err != nil {
+		if err == persistence.ErrNotFound {
+			return nil, s.notFoundOrErased(ctx, id)
+		}
+		log.ErrorCtx(ctx, errors.WithMessage(err, "export: failed to read identity from store"), logD)
+		return nil, storeErr(err)
+	}
+
+	e = &Export{Identity: *i, Tokens: []schema.Token{}, Events: []schema.AuditEvent{}}
+
+	if s.Tokens != nil {
+		if e.Tokens, err = s.Tokens.List(ctx, id); err != nil {
+			log.ErrorCtx(ctx, errors.WithMessage(err, "export: failed to read tokens from store"), logD)
+			return nil, storeErr(err)
+		}
+	}
+
+	if s.AuditEvents != nil {
+		if e.Events, err = s.AuditEvents.GetEvents(ctx, id); err != nil {
+			log.ErrorCtx(ctx, errors.WithMessage(err, "export: failed to read audit events from store"), logD)
+			return nil, storeErr(err)
+		}
+	}
+
+	log.InfoCtx(ctx, "export: identity exported successfully", logD)
+	return e, nil
+}
+
+// Erase permanently remove the identity with the provided ID, whether or not it has been deleted, and every token
+// issued to it, including from the token cache, and pseudonymise the audit events about it. A tombstone recording
+// the erasure and the ID of the identity requesting it is stored in its place and returned. The identity is removed
+// last so a failed erasure can be retried. Returns ErrIdentityErased if the identity has already been erased or
+// ErrIdentityNotFound if no identity exists.
+func (s *Service) Erase(ctx context.Context, id string, erasedBy string) (t *schema.Tombstone, err error) {
+	ctx, span := tracing.Start(ctx, "identity.Service.Erase")
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
+	logD := log.Data{"id": id, "erased_by": erasedBy}
+
+	if err := s.notFoundOrErased(ctx, id); err != ErrIdentityNotFound {
+		return nil, err
+	}
+
+	if s.Tokens != nil {
+		if err := s.Tokens.Erase(ctx, id); err != nil {
+			log.ErrorCtx(ctx, errors.WithMessage(err, "erase: failed to erase tokens"), logD)
+			return nil, storeErr(err)
+		}
+	}
+
+	if s.AuditEvents != nil {
+		if err := s.AuditEvents.PseudonymiseEvents(ctx, id); err != nil {
+			log.ErrorCtx(ctx, errors.WithMessage(err, "erase: failed to pseudonymise audit events"), logD)
+			return nil, storeErr(err)
+		}
+	}
+
+	t = &schema.Tombstone{ID: id, ErasedBy: erasedBy, ErasedDate: time.Now()}
+	if err := s.IdentityStore.EraseIdentity(ctx, *t); err != nil {
+		if err == persistence.ErrNotFound {
+			log.ErrorCtx(ctx, errors.New("erase: identity not found"), logD)
+			return nil, ErrIdentityNotFound
+		}
+		log.ErrorCtx(ctx, errors.WithMessage(err, "erase: failed to erase identity"), logD)
+		return nil, storeErr(err)
+	}
+
+	log.InfoCtx(ctx, "erase: identity erased successfully", logD)
+	return t, nil
+}
+
+// notFoundOrErased return ErrIdentityErased if the identity with the provided ID has been erased, otherwise
+// ErrIdentityNotFound, or the store error if the tombstone cannot be read.
+func (s *Service) notFoundOrErased(ctx context.Context, id string) error {
+	_, err := s.IdentityStore.GetTombstone(ctx, id)
+	switch err {
+	case nil:
+		return ErrIdentityErased
+	case persistence.ErrNotFound:
+		return ErrIdentityNotFound
+	default:
+		return storeErr(err)
+	}
+}
@@ -0,0 +1,200 @@
+package identity
+
+import (
+	"context"
+	"github.com/ONSdigital/dp-identity-api/identity/identitytest"
+	"github.com/ONSdigital/dp-identity-api/persistence"
+	"github.com/ONSdigital/dp-identity-api/persistence/persistencetest"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+func TestService_Export(t *testing.T) {
+	Convey("given an identity with tokens and audit events", t, func() {
+		stored := schema.Identity{ID: "666", Name: "Eleven", Email: "11@StrangerThings.com", Password: "hash"}
+		tokens := []schema.Token{{ID: "digest", IdentityID: "666"}}
+		events := []schema.AuditEvent{{IdentityID: "666", Action: "createToken", Result: "successful"}}
+
+		p := &persistencetest.IdentityStoreMock{
+			GetIdentityByIDIncludingDeletedFunc: func(ctx context.Context, id string) (*schema.Identity, error) {
+				i := stored
+				return &i, nil
+			},
+			GetTombstoneFunc: func(ctx context.Context, id string) (*schema.Tombstone, error) {
+				return nil, persistence.ErrNotFound
+			},
+		}
+		tokenManager := &identitytest.TokenManagerMock{
+			ListFunc: func(ctx context.Context, identityID string) ([]schema.Token, error) {
+				return tokens, nil
+			},
+		}
+		auditStore := &persistencetest.AuditStoreMock{
+			GetEventsFunc: func(ctx context.Context, identityID string) ([]schema.AuditEvent, error) {
+				return events, nil
+			},
+		}
+
+		s := &Service{IdentityStore: p, Tokens: tokenManager, AuditEvents: auditStore}
+
+		Convey("when the identity is exported", func() {
+			e, err := s.Export(context.Background(), "666")
+
+			Convey("then the identity, its tokens and the audit events about it are returned", func() {
+				So(err, ShouldBeNil)
+				So(e.Identity, ShouldResemble, stored)
+				So(e.Tokens, ShouldResemble, tokens)
+				So(e.Events, ShouldResemble, events)
+				So(tokenManager.ListCalls()[0].IdentityID, ShouldEqual, "666")
+				So(auditStore.GetEventsCalls()[0].IdentityID, ShouldEqual, "666")
+			})
+		})
+
+		Convey("when the identity has been soft deleted", func() {
+			stored.Deleted = true
+
+			e, err := s.Export(context.Background(), "666")
+
+			Convey("then the identity and the data about it are still returned", func() {
+				So(err, ShouldBeNil)
+				So(e.Identity.Deleted, ShouldBeTrue)
+				So(e.Identity, ShouldResemble, stored)
+				So(e.Tokens, ShouldResemble, tokens)
+				So(e.Events, ShouldResemble, events)
+			})
+		})
+
+		Convey("when there is no audit store", func() {
+			s.AuditEvents = nil
+
+			e, err := s.Export(context.Background(), "666")
+
+			Convey("then no audit events are returned", func() {
+				So(err, ShouldBeNil)
+				So(e.Events, ShouldBeEmpty)
+			})
+		})
+
+		Convey("when the identity does not exist", func() {
+			p.GetIdentityByIDIncludingDeletedFunc = func(ctx context.Context, id string) (*schema.Identity, error) {
+				return nil, persistence.ErrNotFound
+			}
+
+			_, err := s.Export(context.Background(), "666")
+
+			Convey("then ErrIdentityNotFound is returned", func() {
+				So(err, ShouldEqual, ErrIdentityNotFound)
+			})
+
+			Convey("and ErrIdentityErased is returned if it has been erased", func() {
+				p.GetTombstoneFunc = func(ctx context.Context, id string) (*schema.Tombstone, error) {
+					return &schema.Tombstone{ID: id}, nil
+				}
+
+				_, err := s.Export(context.Background(), "666")
+				So(err, ShouldEqual, ErrIdentityErased)
+			})
+		})
+
+		Convey("when reading the audit events times out", func() {
+			auditStore.GetEventsFunc = func(ctx context.Context, identityID string) ([]schema.AuditEvent, error) {
+				return nil, persistence.ErrTimeout
+			}
+
+			_, err := s.Export(context.Background(), "666")
+
+			Convey("then persistence.ErrTimeout is returned", func() {
+				So(err, ShouldEqual, persistence.ErrTimeout)
+			})
+		})
+	})
+}
+
+func TestService_Erase(t *testing.T) {
+	Convey("given an identity", t, func() {
+		p := &persistencetest.IdentityStoreMock{
+			GetTombstoneFunc: func(ctx context.Context, id string) (*schema.Tombstone, error) {
+				return nil, persistence.ErrNotFound
+			},
+			EraseIdentityFunc: func(ctx context.Context, tombstone schema.Tombstone) error {
+				return nil
+			},
+		}
+		tokenManager := &identitytest.TokenManagerMock{
+			EraseFunc: func(ctx context.Context, identityID string) error {
+				return nil
+			},
+		}
+		auditStore := &persistencetest.AuditStoreMock{
+			PseudonymiseEventsFunc: func(ctx context.Context, identityID string) error {
+				return nil
+			},
+		}
+
+		s := &Service{IdentityStore: p, Tokens: tokenManager, AuditEvents: auditStore}
+
+		Convey("when the identity is erased", func() {
+			tombstone, err := s.Erase(context.Background(), "666", "999")
+
+			Convey("then a tombstone recording the erasure is returned", func() {
+				So(err, ShouldBeNil)
+				So(tombstone.ID, ShouldEqual, "666")
+				So(tombstone.ErasedBy, ShouldEqual, "999")
+				So(tombstone.ErasedDate, ShouldHappenWithin, time.Minute, time.Now())
+			})
+
+			Convey("and the identity's tokens are erased and audit events pseudonymised", func() {
+				So(tokenManager.EraseCalls(), ShouldHaveLength, 1)
+				So(tokenManager.EraseCalls()[0].IdentityID, ShouldEqual, "666")
+				So(auditStore.PseudonymiseEventsCalls(), ShouldHaveLength, 1)
+				So(auditStore.PseudonymiseEventsCalls()[0].IdentityID, ShouldEqual, "666")
+			})
+
+			Convey("and the identity is replaced by the tombstone", func() {
+				So(p.EraseIdentityCalls(), ShouldHaveLength, 1)
+				So(p.EraseIdentityCalls()[0].Tombstone, ShouldResemble, *tombstone)
+			})
+		})
+
+		Convey("when the identity has already been erased", func() {
+			p.GetTombstoneFunc = func(ctx context.Context, id string) (*schema.Tombstone, error) {
+				return &schema.Tombstone{ID: id}, nil
+			}
+
+			_, err := s.Erase(context.Background(), "666", "999")
+
+			Convey("then ErrIdentityErased is returned and nothing is erased", func() {
+				So(err, ShouldEqual, ErrIdentityErased)
+				So(tokenManager.EraseCalls(), ShouldHaveLength, 0)
+				So(p.EraseIdentityCalls(), ShouldHaveLength, 0)
+			})
+		})
+
+		Convey("when the identity does not exist", func() {
+			p.EraseIdentityFunc = func(ctx context.Context, tombstone schema.Tombstone) error {
+				return persistence.ErrNotFound
+			}
+
+			_, err := s.Erase(context.Background(), "666", "999")
+
+			Convey("then ErrIdentityNotFound is returned", func() {
+				So(err, ShouldEqual, ErrIdentityNotFound)
+			})
+		})
+
+		Convey("when erasing the identity's tokens returns an error", func() {
+			tokenManager.EraseFunc = func(ctx context.Context, identityID string) error {
+				return errTest
+			}
+
+			_, err := s.Erase(context.Background(), "666", "999")
+
+			Convey("then ErrPersistence is returned and the identity is not erased", func() {
+				So(err, ShouldEqual, ErrPersistence)
+				So(p.EraseIdentityCalls(), ShouldHaveLength, 0)
+			})
+		})
+	})
+}
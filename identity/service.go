@@ -4,9 +4,9 @@ import (
 	"context"
 	"github.com/ONSdigital/dp-identity-api/persistence"
 	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/ONSdigital/dp-identity-api/tracing"
 	"github.com/ONSdigital/go-ns/log"
 	"github.com/pkg/errors"
-	"golang.org/x/crypto/bcrypt"
 )
 
 var (
@@ -22,6 +22,9 @@ func (s *Service) Create(ctx context.Context, i *schema.Identity) (string, error
 		return "", ErrInvalidArguments
 	}
 
+	ctx, span := tracing.Start(ctx, "identity.Service.Create")
+	defer span.End()
+
 	if err := i.Validate(); err != nil {
 		log.ErrorCtx(ctx, errors.Wrap(err, "create: failed validation"), nil)
 		return "", err
@@ -32,14 +35,23 @@ func (s *Service) Create(ctx context.Context, i *schema.Identity) (string, error
 		"email": i.Email,
 	}
 
+	_, hashSpan := tracing.Start(ctx, "encryption.GenerateFromPassword")
 	pwd, err := s.encryptPassword(i)
+	hashSpan.End()
 	if err != nil {
+		tracing.RecordError(span, err)
 		return "", errors.Wrap(err, "create: error encrypting password")
 	}
 
 	i.Password = pwd
 
-	id, err := s.IdentityStore.SaveIdentity(*i)
+	verificationToken, err := s.issueVerification(i)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return "", errors.Wrap(err, "create: error generating verification token")
+	}
+
+	id, err := s.IdentityStore.SaveIdentity(ctx, *i)
 	if err != nil && err == persistence.ErrNonUnique {
 		log.ErrorCtx(ctx, errors.New("create: failed to create identity - an active identity with this email already exists"), logD)
 		return "", ErrEmailAlreadyExists
@@ -47,15 +59,23 @@ func (s *Service) Create(ctx context.Context, i *schema.Identity) (string, error
 
 	if err != nil {
 		log.ErrorCtx(ctx, errors.WithMessage(err, "create: failed to write data to mongo"), logD)
-		return "", ErrPersistence
+		tracing.RecordError(span, err)
+		return "", storeErr(err)
 	}
 
 	logD["id"] = id
 	log.InfoCtx(ctx, "create: new identity created successfully", logD)
+
+	created := *i
+	created.ID = id
+	s.sendVerification(ctx, created, verificationToken)
 	return id, nil
 }
 
 func (s *Service) VerifyPassword(ctx context.Context, email string, password string) (*schema.Identity, error) {
+	ctx, span := tracing.Start(ctx, "identity.Service.VerifyPassword")
+	defer span.End()
+
 	i, err := s.getIdentity(ctx, email)
 	if err != nil {
 		return nil, err
@@ -63,20 +83,59 @@ func (s *Service) VerifyPassword(ctx context.Context, email string, password str
 
 	logD := log.Data{"email": email}
 
+	_, compareSpan := tracing.Start(ctx, "encryption.CompareHashAndPassword")
 	err = s.Encryptor.CompareHashAndPassword([]byte(i.Password), []byte(password))
+	compareSpan.End()
 	if err != nil {
 		log.ErrorCtx(ctx, errors.Wrap(err, "password did not match stored value"), logD)
 		return nil, ErrAuthenticateFailed
 	}
 
+	if i.Disabled {
+		log.ErrorCtx(ctx, errors.New("identity is disabled"), logD)
+		return nil, ErrIdentityDisabled
+	}
+
+	if s.RequireVerified && !i.Verified {
+		log.ErrorCtx(ctx, errors.New("identity email address has not been verified"), logD)
+		return nil, ErrIdentityNotVerified
+	}
+
+	// identities migrated from Zebedee are always rehashed on first login to replace the legacy hash.
+	if i.Migrated || s.Encryptor.NeedsRehash([]byte(i.Password)) {
+		s.rehashPassword(ctx, i, password)
+	}
+
 	log.InfoCtx(ctx, "user authentication successful", logD)
 	return i, nil
 }
 
+// rehashPassword upgrades a stored password hash produced using an outdated algorithm or parameters. A failure is
+// considered non critical as the user has been successfully authenticated, the hash will be upgraded on a subsequent
+// login.
+func (s *Service) rehashPassword(ctx context.Context, i *schema.Identity, password string) {
+	logD := log.Data{"id": i.ID}
+
+	pwd, err := s.Encryptor.GenerateFromPassword([]byte(password))
+	if err != nil {
+		log.ErrorCtx(ctx, errors.Wrap(err, "rehash: error encrypting password"), logD)
+		return
+	}
+
+	if err := s.IdentityStore.UpdatePassword(ctx, i.ID, string(pwd)); err != nil {
+		log.ErrorCtx(ctx, errors.Wrap(err, "rehash: error updating stored password"), logD)
+		return
+	}
+
+	i.Password = string(pwd)
+	i.Migrated = false
+	log.InfoCtx(ctx, "rehash: outdated password hash upgraded", logD)
+}
+
 func (s *Service) getIdentity(ctx context.Context, email string) (*schema.Identity, error) {
 	logD := log.Data{"email": email}
 
-	i, err := s.IdentityStore.GetIdentity(email)
+	i, err := s.IdentityStore.GetIdentity(ctx, email)
 	if err != nil {
 		if err == persistence.ErrNotFound {
 			log.ErrorCtx(ctx, errors.New("user not found"), logD)
@@ -90,8 +149,19 @@ func (s *Service) getIdentity(ctx context.Context, email string) (*schema.Identi
 
 }
 
+// storeErr return the error to report for a failed store write. Timeouts and an unavailable store are returned as is
+// so the caller can distinguish them from other persistence failures.
+func storeErr(err error) error {
+	switch cause := errors.Cause(err); cause {
+	case persistence.ErrTimeout, persistence.ErrUnavailable:
+		return cause
+	default:
+		return ErrPersistence
+	}
+}
+
 func (s *Service) encryptPassword(i *schema.Identity) (string, error) {
-	pwd, err := s.Encryptor.GenerateFromPassword([]byte(i.Password), bcrypt.DefaultCost)
+	pwd, err := s.Encryptor.GenerateFromPassword([]byte(i.Password))
 	if err != nil {
 		return "", err
 	}
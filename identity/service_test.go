@@ -8,7 +8,6 @@ import (
 	"github.com/ONSdigital/dp-identity-api/schema"
 	"github.com/pkg/errors"
 	. "github.com/smartystreets/goconvey/convey"
-	"golang.org/x/crypto/bcrypt"
 	"strings"
 	"testing"
 )
@@ -27,7 +26,7 @@ var (
 
 func newPersistenceMock(email string, err error) *persistencetest.IdentityStoreMock {
 	return &persistencetest.IdentityStoreMock{
-		SaveIdentityFunc: func(identity schema.Identity) (string, error) {
+		SaveIdentityFunc: func(ctx context.Context, identity schema.Identity) (string, error) {
 			return email, err
 		},
 	}
@@ -35,13 +34,17 @@ func newPersistenceMock(email string, err error) *persistencetest.IdentityStoreM
 
 func newEncryptorMock(pwd []byte, generate, compare error) *identitytest.EncryptorMock {
 	return &identitytest.EncryptorMock{
-		GenerateFromPasswordFunc: func(password []byte, cost int) ([]byte, error) {
+		GenerateFromPasswordFunc: func(password []byte) ([]byte, error) {
 			return pwd, generate
 		},
 
 		CompareHashAndPasswordFunc: func(hashedPassword []byte, password []byte) error {
 			return compare
 		},
+
+		NeedsRehashFunc: func(hashedPassword []byte) bool {
+			return false
+		},
 	}
 }
 
@@ -59,7 +62,6 @@ func TestCreate_Success(t *testing.T) {
 
 		So(encryptorMock.GenerateFromPasswordCalls(), ShouldHaveLength, 1)
 		So(encryptorMock.GenerateFromPasswordCalls()[0].Password, ShouldResemble, []byte(newIdentity.Password))
-		So(encryptorMock.GenerateFromPasswordCalls()[0].Cost, ShouldEqual, bcrypt.DefaultCost)
 
 		So(persistenceMock.SaveIdentityCalls(), ShouldHaveLength, 1)
 		So(persistenceMock.SaveIdentityCalls()[0].NewIdentity, ShouldResemble, *newIdentity)
@@ -79,13 +81,28 @@ func TestCreate_DataStoreError(t *testing.T) {
 
 		So(encryptorMock.GenerateFromPasswordCalls(), ShouldHaveLength, 1)
 		So(encryptorMock.GenerateFromPasswordCalls()[0].Password, ShouldResemble, []byte(newIdentity.Password))
-		So(encryptorMock.GenerateFromPasswordCalls()[0].Cost, ShouldEqual, bcrypt.DefaultCost)
 
 		So(persistenceMock.SaveIdentityCalls(), ShouldHaveLength, 1)
 		So(persistenceMock.SaveIdentityCalls()[0].NewIdentity, ShouldResemble, *newIdentity)
 	})
 }
 
+func TestCreate_DataStoreTimeout(t *testing.T) {
+	Convey("should return the store error if the store times out or is unavailable", t, func() {
+		for _, storeErr := range []error{persistence.ErrTimeout, persistence.ErrUnavailable} {
+			persistenceMock := newPersistenceMock("", errors.Wrap(storeErr, "expected"))
+			encryptorMock := newEncryptorMock([]byte(newIdentity.Password), nil, nil)
+
+			s := &Service{IdentityStore: persistenceMock, Encryptor: encryptorMock}
+			id, err := s.Create(context.Background(), newIdentity)
+
+			So(err, ShouldEqual, storeErr)
+			So(id, ShouldBeEmpty)
+			So(persistenceMock.SaveIdentityCalls(), ShouldHaveLength, 1)
+		}
+	})
+}
+
 func TestCreate_ValidationError(t *testing.T) {
 	Convey("should return expected error if validate returns an error", t, func() {
 		persistenceMock := &persistencetest.IdentityStoreMock{}
@@ -116,7 +133,6 @@ func TestService_CreateEncryptPasswordError(t *testing.T) {
 		So(id, ShouldBeEmpty)
 		So(encryptorMock.GenerateFromPasswordCalls(), ShouldHaveLength, 1)
 		So(encryptorMock.GenerateFromPasswordCalls()[0].Password, ShouldResemble, []byte(newIdentity.Password))
-		So(encryptorMock.GenerateFromPasswordCalls()[0].Cost, ShouldEqual, bcrypt.DefaultCost)
 		So(persistenceMock.SaveIdentityCalls(), ShouldHaveLength, 0)
 	})
 }
@@ -164,7 +180,6 @@ func TestService_EncryptPassword(t *testing.T) {
 		So(pwd, ShouldResemble, expectedPWD)
 		So(encryptorMock.GenerateFromPasswordCalls(), ShouldHaveLength, 1)
 		So(encryptorMock.GenerateFromPasswordCalls()[0].Password, ShouldResemble, []byte(newIdentity.Password))
-		So(encryptorMock.GenerateFromPasswordCalls()[0].Cost, ShouldEqual, bcrypt.DefaultCost)
 	})
 
 	Convey("should return expected error if encryption is unsuccessful", t, func() {
@@ -180,20 +195,19 @@ func TestService_EncryptPassword(t *testing.T) {
 		So(pwd, ShouldBeEmpty)
 		So(encryptorMock.GenerateFromPasswordCalls(), ShouldHaveLength, 1)
 		So(encryptorMock.GenerateFromPasswordCalls()[0].Password, ShouldResemble, []byte(newIdentity.Password))
-		So(encryptorMock.GenerateFromPasswordCalls()[0].Cost, ShouldEqual, bcrypt.DefaultCost)
 	})
 }
 
 func TestService_CreateEmailAlreadyInUse(t *testing.T) {
 	Convey("todo", t, func() {
 		persistenceMock := &persistencetest.IdentityStoreMock{
-			SaveIdentityFunc: func(newIdentity schema.Identity) (string, error) {
+			SaveIdentityFunc: func(ctx context.Context, newIdentity schema.Identity) (string, error) {
 				return "", persistence.ErrNonUnique
 			},
 		}
 
 		enc := &identitytest.EncryptorMock{
-			GenerateFromPasswordFunc: func(password []byte, cost int) ([]byte, error) {
+			GenerateFromPasswordFunc: func(password []byte) ([]byte, error) {
 				return []byte("WAFFLES"), nil
 			},
 		}
@@ -206,7 +220,6 @@ func TestService_CreateEmailAlreadyInUse(t *testing.T) {
 		So(persistenceMock.SaveIdentityCalls()[0].NewIdentity, ShouldResemble, *newIdentity)
 		So(enc.GenerateFromPasswordCalls(), ShouldHaveLength, 1)
 		So(enc.GenerateFromPasswordCalls()[0].Password, ShouldResemble, []byte(newIdentity.Password))
-		So(enc.GenerateFromPasswordCalls()[0].Cost, ShouldEqual, bcrypt.DefaultCost)
 
 	})
 }
@@ -214,7 +227,7 @@ func TestService_CreateEmailAlreadyInUse(t *testing.T) {
 func TestService_VerifyPassword(t *testing.T) {
 	Convey("should not return error is password is correct", t, func() {
 		p := &persistencetest.IdentityStoreMock{
-			GetIdentityFunc: func(email string) (schema.Identity, error) {
+			GetIdentityFunc: func(ctx context.Context, email string) (schema.Identity, error) {
 				return *newIdentity, nil
 			},
 		}
@@ -232,13 +245,66 @@ func TestService_VerifyPassword(t *testing.T) {
 		So(e.CompareHashAndPasswordCalls(), ShouldHaveLength, 1)
 		So(e.CompareHashAndPasswordCalls()[0].Password, ShouldResemble, []byte(newIdentity.Password))
 		So(e.CompareHashAndPasswordCalls()[0].HashedPassword, ShouldResemble, []byte(newIdentity.Password))
+		So(e.NeedsRehashCalls(), ShouldHaveLength, 1)
+		So(e.GenerateFromPasswordCalls(), ShouldHaveLength, 0)
+	})
+}
+
+func TestService_VerifyPasswordRehash(t *testing.T) {
+	Convey("given the stored password hash is outdated", t, func() {
+		stored := schema.Identity{ID: "666", Email: newIdentity.Email, Password: "outdated"}
+
+		p := &persistencetest.IdentityStoreMock{
+			GetIdentityFunc: func(ctx context.Context, email string) (schema.Identity, error) {
+				return stored, nil
+			},
+			UpdatePasswordFunc: func(ctx context.Context, id string, password string) error {
+				return nil
+			},
+		}
+
+		e := newEncryptorMock([]byte("upgraded"), nil, nil)
+		e.NeedsRehashFunc = func(hashedPassword []byte) bool {
+			return true
+		}
+
+		s := Service{IdentityStore: p, Encryptor: e}
+
+		Convey("when the password is verified successfully", func() {
+			identity, err := s.VerifyPassword(context.Background(), stored.Email, "WAFFLES")
+
+			Convey("then the password is rehashed and the stored hash is updated", func() {
+				So(err, ShouldBeNil)
+				So(identity.Password, ShouldEqual, "upgraded")
+				So(e.GenerateFromPasswordCalls(), ShouldHaveLength, 1)
+				So(e.GenerateFromPasswordCalls()[0].Password, ShouldResemble, []byte("WAFFLES"))
+				So(p.UpdatePasswordCalls(), ShouldHaveLength, 1)
+				So(p.UpdatePasswordCalls()[0].ID, ShouldEqual, stored.ID)
+				So(p.UpdatePasswordCalls()[0].Password, ShouldEqual, "upgraded")
+			})
+		})
+
+		Convey("when updating the stored hash returns an error", func() {
+			p.UpdatePasswordFunc = func(ctx context.Context, id string, password string) error {
+				return errTest
+			}
+
+			identity, err := s.VerifyPassword(context.Background(), stored.Email, "WAFFLES")
+
+			Convey("then the user is still authenticated", func() {
+				So(err, ShouldBeNil)
+				So(identity.ID, ShouldEqual, stored.ID)
+				So(identity.Password, ShouldEqual, "outdated")
+				So(p.UpdatePasswordCalls(), ShouldHaveLength, 1)
+			})
+		})
 	})
 }
 
 func TestService_VerifyPasswordIdentityNotFound(t *testing.T) {
 	Convey("should return error if identity is not found", t, func() {
 		p := &persistencetest.IdentityStoreMock{
-			GetIdentityFunc: func(email string) (schema.Identity, error) {
+			GetIdentityFunc: func(ctx context.Context, email string) (schema.Identity, error) {
 				return schema.Identity{}, persistence.ErrNotFound
 			},
 		}
@@ -260,7 +326,7 @@ func TestService_VerifyPasswordIdentityNotFound(t *testing.T) {
 func TestService_VerifyPasswordPersistenceErr(t *testing.T) {
 	Convey("should return error if identity is not found", t, func() {
 		p := &persistencetest.IdentityStoreMock{
-			GetIdentityFunc: func(email string) (schema.Identity, error) {
+			GetIdentityFunc: func(ctx context.Context, email string) (schema.Identity, error) {
 				return schema.Identity{}, errTest
 			},
 		}
@@ -282,7 +348,7 @@ func TestService_VerifyPasswordPersistenceErr(t *testing.T) {
 func TestService_VerifyPasswordPasswordIncorrect(t *testing.T) {
 	Convey("should return error if provided password is incorrect", t, func() {
 		p := &persistencetest.IdentityStoreMock{
-			GetIdentityFunc: func(email string) (schema.Identity, error) {
+			GetIdentityFunc: func(ctx context.Context, email string) (schema.Identity, error) {
 				return *newIdentity, nil
 			},
 		}
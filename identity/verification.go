@@ -0,0 +1,91 @@
+package identity
+
+import (
+	"context"
+	"github.com/ONSdigital/dp-identity-api/persistence"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/ONSdigital/dp-identity-api/token"
+	"github.com/ONSdigital/dp-identity-api/tracing"
+	"github.com/ONSdigital/go-ns/log"
+	"github.com/pkg/errors"
+	"time"
+)
+
+const defaultVerificationLifetime = 24 * time.Hour
+
+var (
+	ErrVerificationTokenNotFound = errors.New("verification token not found or expired")
+	ErrIdentityNotVerified       = errors.New("identity email address has not been verified")
+
+	defaultVerificationDigester = token.SHA256Digester{}
+)
+
+// Verify mark the identity issued the verification token as verified. Returns ErrVerificationTokenNotFound if the token
+// does not exist, has expired or has already been used.
+func (s *Service) Verify(ctx context.Context, verificationToken string) (*schema.Identity, error) {
+	ctx, span := tracing.Start(ctx, "identity.Service.Verify")
+	defer span.End()
+
+	i, err := s.IdentityStore.VerifyIdentity(ctx, s.digester().Digest(verificationToken))
+	if err != nil {
+		if err == persistence.ErrNotFound {
+			log.ErrorCtx(ctx, errors.New("verify: verification token not found or expired"), nil)
+			return nil, ErrVerificationTokenNotFound
+		}
+
+		log.ErrorCtx(ctx, errors.WithMessage(err, "verify: failed to verify identity"), nil)
+		tracing.RecordError(span, err)
+		return nil, storeErr(err)
+	}
+
+	log.InfoCtx(ctx, "verify: identity verified successfully", log.Data{"id": i.ID})
+	return i, nil
+}
+
+// issueVerification set a new verification token on the unverified identity. Returns the plain text token, only its
+// digest is stored.
+func (s *Service) issueVerification(i *schema.Identity) (string, error) {
+	verificationToken, err := s.generator(token.VerificationTokenPrefix).Generate()
+	if err != nil {
+		return "", err
+	}
+
+	i.Verified = false
+	i.VerificationToken = s.digester().Digest(verificationToken)
+	i.VerificationExpiry = time.Now().Add(s.verificationLifetime())
+	return verificationToken, nil
+}
+
+// sendVerification send the verification token to the new identity. A failure is considered non critical as the
+// identity has been created, the error is logged so monitoring is aware notifications are failing.
+func (s *Service) sendVerification(ctx context.Context, i schema.Identity, verificationToken string) {
+	if s.Notifier == nil {
+		return
+	}
+
+	if err := s.Notifier.SendVerification(ctx, i, verificationToken); err != nil {
+		log.ErrorCtx(ctx, errors.Wrap(err, "create: failed to send verification token"), log.Data{"id": i.ID})
+	}
+}
+
+// generator return the Generator, or a RandomGenerator with the prefix if there is none.
+func (s *Service) generator(prefix string) token.Generator {
+	if s.Generator == nil {
+		return token.RandomGenerator{Prefix: prefix}
+	}
+	return s.Generator
+}
+
+func (s *Service) verificationLifetime() time.Duration {
+	if s.VerificationLifetime <= 0 {
+		return defaultVerificationLifetime
+	}
+	return s.VerificationLifetime
+}
+
+func (s *Service) digester() token.Digester {
+	if s.Digester == nil {
+		return defaultVerificationDigester
+	}
+	return s.Digester
+}
@@ -0,0 +1,181 @@
+package identity
+
+import (
+	"context"
+	"github.com/ONSdigital/dp-identity-api/identity/identitytest"
+	"github.com/ONSdigital/dp-identity-api/persistence"
+	"github.com/ONSdigital/dp-identity-api/persistence/persistencetest"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/ONSdigital/dp-identity-api/token"
+	. "github.com/smartystreets/goconvey/convey"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newNotifierMock(err error) *identitytest.NotifierMock {
+	return &identitytest.NotifierMock{
+		SendVerificationFunc: func(ctx context.Context, i schema.Identity, token string) error {
+			return err
+		},
+	}
+}
+
+func TestCreate_IssuesVerificationToken(t *testing.T) {
+	Convey("given a new identity", t, func() {
+		persistenceMock := newPersistenceMock("666", nil)
+		notifierMock := newNotifierMock(nil)
+
+		s := &Service{
+			IdentityStore:        persistenceMock,
+			Encryptor:            newEncryptorMock([]byte("hash"), nil, nil),
+			Notifier:             notifierMock,
+			VerificationLifetime: time.Hour,
+		}
+
+		i := &schema.Identity{Name: "Eleven", Email: "11@StrangerThings.com", Password: "WAFFLES", Verified: true}
+
+		Convey("when the identity is created", func() {
+			id, err := s.Create(context.Background(), i)
+			So(err, ShouldBeNil)
+			So(id, ShouldEqual, "666")
+
+			Convey("then it is stored unverified with the digest of a verification token", func() {
+				So(persistenceMock.SaveIdentityCalls(), ShouldHaveLength, 1)
+				stored := persistenceMock.SaveIdentityCalls()[0].NewIdentity
+				So(stored.Verified, ShouldBeFalse)
+				So(stored.VerificationToken, ShouldNotBeEmpty)
+				So(stored.VerificationExpiry, ShouldHappenWithin, time.Minute, time.Now().Add(time.Hour))
+			})
+
+			Convey("and the plain text token is sent to the identity", func() {
+				So(notifierMock.SendVerificationCalls(), ShouldHaveLength, 1)
+				sent := notifierMock.SendVerificationCalls()[0]
+				So(sent.I.ID, ShouldEqual, "666")
+				So(sent.I.Email, ShouldEqual, "11@StrangerThings.com")
+				So(strings.HasPrefix(sent.Token, token.VerificationTokenPrefix), ShouldBeTrue)
+
+				stored := persistenceMock.SaveIdentityCalls()[0].NewIdentity
+				So(token.SHA256Digester{}.Digest(sent.Token), ShouldEqual, stored.VerificationToken)
+			})
+		})
+
+		Convey("when sending the verification token returns an error", func() {
+			notifierMock.SendVerificationFunc = func(ctx context.Context, i schema.Identity, token string) error {
+				return errTest
+			}
+
+			id, err := s.Create(context.Background(), i)
+
+			Convey("then the identity is still created", func() {
+				So(err, ShouldBeNil)
+				So(id, ShouldEqual, "666")
+				So(persistenceMock.SaveIdentityCalls(), ShouldHaveLength, 1)
+			})
+		})
+	})
+}
+
+func TestService_Verify(t *testing.T) {
+	Convey("given a verification token", t, func() {
+		p := &persistencetest.IdentityStoreMock{
+			VerifyIdentityFunc: func(ctx context.Context, token string) (*schema.Identity, error) {
+				return &schema.Identity{ID: "666", Verified: true}, nil
+			},
+		}
+
+		s := &Service{IdentityStore: p}
+
+		Convey("when the token is valid", func() {
+			i, err := s.Verify(context.Background(), "dpidv_token")
+
+			Convey("then the identity is verified using the digest of the token", func() {
+				So(err, ShouldBeNil)
+				So(i.ID, ShouldEqual, "666")
+				So(p.VerifyIdentityCalls(), ShouldHaveLength, 1)
+				So(p.VerifyIdentityCalls()[0].Token, ShouldEqual, token.SHA256Digester{}.Digest("dpidv_token"))
+			})
+		})
+
+		Convey("when the token is not found", func() {
+			p.VerifyIdentityFunc = func(ctx context.Context, token string) (*schema.Identity, error) {
+				return nil, persistence.ErrNotFound
+			}
+
+			i, err := s.Verify(context.Background(), "dpidv_token")
+
+			Convey("then ErrVerificationTokenNotFound is returned", func() {
+				So(err, ShouldEqual, ErrVerificationTokenNotFound)
+				So(i, ShouldBeNil)
+			})
+		})
+
+		Convey("when the store times out", func() {
+			p.VerifyIdentityFunc = func(ctx context.Context, token string) (*schema.Identity, error) {
+				return nil, persistence.ErrTimeout
+			}
+
+			_, err := s.Verify(context.Background(), "dpidv_token")
+
+			Convey("then persistence.ErrTimeout is returned", func() {
+				So(err, ShouldEqual, persistence.ErrTimeout)
+			})
+		})
+
+		Convey("when the store returns an error", func() {
+			p.VerifyIdentityFunc = func(ctx context.Context, token string) (*schema.Identity, error) {
+				return nil, errTest
+			}
+
+			_, err := s.Verify(context.Background(), "dpidv_token")
+
+			Convey("then ErrPersistence is returned", func() {
+				So(err, ShouldEqual, ErrPersistence)
+			})
+		})
+	})
+}
+
+func TestService_VerifyPasswordRequireVerified(t *testing.T) {
+	Convey("given verified identities are required", t, func() {
+		stored := schema.Identity{ID: "666", Email: newIdentity.Email, Password: "hash"}
+
+		p := &persistencetest.IdentityStoreMock{
+			GetIdentityFunc: func(ctx context.Context, email string) (schema.Identity, error) {
+				return stored, nil
+			},
+		}
+
+		s := Service{IdentityStore: p, Encryptor: newEncryptorMock(nil, nil, nil), RequireVerified: true}
+
+		Convey("when the password of an unverified identity is verified", func() {
+			i, err := s.VerifyPassword(context.Background(), stored.Email, "WAFFLES")
+
+			Convey("then ErrIdentityNotVerified is returned", func() {
+				So(err, ShouldEqual, ErrIdentityNotVerified)
+				So(i, ShouldBeNil)
+			})
+		})
+
+		Convey("when the password of an unverified identity is incorrect", func() {
+			s.Encryptor = newEncryptorMock(nil, nil, errTest)
+
+			_, err := s.VerifyPassword(context.Background(), stored.Email, "WAFFLES")
+
+			Convey("then ErrAuthenticateFailed is returned", func() {
+				So(err, ShouldEqual, ErrAuthenticateFailed)
+			})
+		})
+
+		Convey("when the password of a verified identity is verified", func() {
+			stored.Verified = true
+
+			i, err := s.VerifyPassword(context.Background(), stored.Email, "WAFFLES")
+
+			Convey("then the identity is returned", func() {
+				So(err, ShouldBeNil)
+				So(i.ID, ShouldEqual, stored.ID)
+			})
+		})
+	})
+}
@@ -4,19 +4,29 @@ import (
 	"context"
 	"fmt"
 	"github.com/ONSdigital/dp-identity-api/api"
+	"github.com/ONSdigital/dp-identity-api/auditlog"
 	"github.com/ONSdigital/dp-identity-api/cache"
 	"github.com/ONSdigital/dp-identity-api/config"
 	"github.com/ONSdigital/dp-identity-api/encryption"
+	"github.com/ONSdigital/dp-identity-api/health"
 	"github.com/ONSdigital/dp-identity-api/identity"
+	"github.com/ONSdigital/dp-identity-api/metrics"
+	"github.com/ONSdigital/dp-identity-api/metrics/prometheus"
 	"github.com/ONSdigital/dp-identity-api/mongo"
+	"github.com/ONSdigital/dp-identity-api/notify"
+	"github.com/ONSdigital/dp-identity-api/persistence"
+	"github.com/ONSdigital/dp-identity-api/persistence/memory"
+	"github.com/ONSdigital/dp-identity-api/postgres"
+	"github.com/ONSdigital/dp-identity-api/ratelimit"
 	"github.com/ONSdigital/dp-identity-api/token"
+	"github.com/ONSdigital/dp-identity-api/tracing"
 	"github.com/ONSdigital/go-ns/audit"
-	"github.com/ONSdigital/go-ns/healthcheck"
 	"github.com/ONSdigital/go-ns/log"
 	mongolib "github.com/ONSdigital/go-ns/mongo"
 	"github.com/ONSdigital/go-ns/server"
-	"github.com/globalsign/mgo"
 	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
 	"os"
 	"os/signal"
 	"syscall"
@@ -37,45 +47,96 @@ func main() {
 		os.Exit(1)
 	}
 
-	mongodb, err := mongo.New(cfg.MongoConfig)
+	tracerProvider, err := tracing.New(cfg.TracingConfig)
 	if err != nil {
-		log.ErrorC("failed to initialise mongo, exiting app", err, nil)
+		log.ErrorC("failed to initialise tracing, exiting app", err, nil)
 		os.Exit(1)
 	}
+	otel.SetTracerProvider(tracerProvider)
 
-	healthTicker := healthcheck.NewTicker(
-		cfg.HealthCheckInterval,
-		cfg.HealthCheckTimeout,
-		mongolib.NewHealthCheckClient(mongodb.Session),
-	)
+	digester := token.NewDigester(cfg.TokenHashSecret)
+	recorder := prometheus.New()
+
+	store, healthChecks, closeStore, err := newStore(cfg, digester, recorder)
+	if err != nil {
+		log.ErrorC("failed to initialise persistence backend, exiting app", err, nil)
+		os.Exit(1)
+	}
+
+	tokenCache := &cache.NOP{}
 
 	// use Nop until kafka is added to environment
-	auditor := &audit.NopAuditor{}
+	// audit events about an identity are also stored so they can be included in its data export. There is no producer
+	// to check, the store audit events are written to is checked by the persistence backend health checks.
+	auditor := &auditlog.Auditor{Auditor: &audit.NopAuditor{}, Store: store}
+
+	// a failing cache is bypassed so is non-critical.
+	healthChecks = append(healthChecks, health.Check{Name: "cache", Critical: false, Client: tokenCache})
+
+	healthChecker := health.New(cfg.HealthCheckTimeout, healthChecks...)
+	healthChecker.Start(cfg.HealthCheckInterval)
 
 	apiErrors := make(chan error, 1)
 
+	encryptor, err := encryption.New(cfg.PasswordConfig)
+	if err != nil {
+		log.ErrorC("invalid password hashing configuration, exiting app", err, nil)
+		os.Exit(1)
+	}
+	encryptor.Metrics = recorder
+
 	identityService := &identity.Service{
-		IdentityStore: mongodb,
-		Encryptor:     encryption.Service{},
+		IdentityStore:        store,
+		Encryptor:            encryptor,
+		Notifier:             newNotifier(cfg),
+		VerificationLifetime: cfg.VerificationConfig.TokenLifetime,
+		RequireVerified:      cfg.VerificationConfig.Required,
+		AuditEvents:          store,
+		Digester:             digester,
 	}
 
-	// TODO get from config
-	timeHelper := token.NewExpiryHelper(1, 0, 0)
-	// TODO get from config
-	tokenTTL := time.Minute * 15
+	userTypeTimeHelpers := make(map[string]token.ExpiryTimeHelper)
+	for userType, lifetime := range cfg.TokenConfig.UserTypeLifetimes {
+		userTypeTimeHelpers[userType] = token.NewLifetimeExpiryHelper(lifetime)
+	}
+
+	timeHelper, err := newTimeHelper(cfg.TokenConfig)
+	if err != nil {
+		log.ErrorC("invalid token expiry configuration, exiting app", err, nil)
+		os.Exit(1)
+	}
 
 	tokens := &token.Tokens{
-		TimeHelper: timeHelper,
-		MaxTTL:     tokenTTL,
-		Store:      mongodb,
-		Cache:      &cache.NOP{},
+		TimeHelper:              timeHelper,
+		UserTypeTimeHelpers:     userTypeTimeHelpers,
+		ImpersonationTimeHelper: token.NewLifetimeExpiryHelper(cfg.ImpersonationConfig.TokenLifetime),
+		MaxTTL:                  cfg.TokenConfig.CacheTTL,
+		IdleTimeout:             cfg.TokenConfig.IdleTimeout,
+		LastUsedInterval:        cfg.TokenConfig.LastUsedInterval,
+		Store:                   store,
+		Identities:              store,
+		Cache:                   tokenCache,
+		Digester:                digester,
+		Metrics:                 recorder,
 	}
+	identityService.Tokens = tokens
 
-	// TODO make Host config
-	identityAPI := api.New("http://localhost"+cfg.BindAddr, identityService, tokens, auditor)
+	identityAPI := api.New(cfg.APIHost, identityService, tokens, auditor)
+	identityAPI.Metrics = recorder
+	identityAPI.BootstrapSecret = cfg.BootstrapSecret
+	identityAPI.Impersonation = cfg.ImpersonationConfig.Enabled
+	identityAPI.SelfRegistration = cfg.SelfRegistration
+	identityAPI.UnversionedSunset = cfg.UnversionedSunset
+	identityAPI.Digester = digester
+	identityAPI.RateLimiter = newRateLimiter(cfg.RateLimitConfig, store)
+	identityAPI.IPRateLimit = ratelimit.Limit{Rate: cfg.RateLimitConfig.IPRate, Burst: cfg.RateLimitConfig.IPBurst}
+	identityAPI.TokenRateLimit = ratelimit.Limit{Rate: cfg.RateLimitConfig.TokenRate, Burst: cfg.RateLimitConfig.TokenBurst}
+	identityAPI.TrustForwardedFor = cfg.RateLimitConfig.TrustForwardedFor
 
 	router := mux.NewRouter()
 	identityAPI.RegisterEndpoints(router)
+	router.Handle("/metrics", recorder.Handler()).Methods("GET")
+	healthChecker.RegisterEndpoints(router)
 
 	httpServer := startHTTPServer(cfg.BindAddr, router, apiErrors)
 
@@ -83,11 +144,97 @@ func main() {
 		select {
 		case err := <-apiErrors:
 			log.ErrorC("api error received shutting down service", err, nil)
-			gracefulShutdown(cfg.GracefulShutdownTimeout, httpServer, healthTicker, mongodb.Session)
+			gracefulShutdown(cfg.GracefulShutdownTimeout, httpServer, healthChecker, closeStore, tracerProvider)
 		case s := <-signals:
 			log.Debug("os signal received shutting down service", log.Data{"signal": s.String()})
-			gracefulShutdown(cfg.GracefulShutdownTimeout, httpServer, healthTicker, mongodb.Session)
+			gracefulShutdown(cfg.GracefulShutdownTimeout, httpServer, healthChecker, closeStore, tracerProvider)
+		}
+	}
+}
+
+// newTimeHelper return the ExpiryHelper for tokens without a user type lifetime, expiring daily if an expiry time is
+// configured or after the configured lifetime otherwise.
+func newTimeHelper(cfg config.TokenConfig) (*token.ExpiryHelper, error) {
+	if cfg.ExpiryTime == "" {
+		return token.NewLifetimeExpiryHelper(cfg.Lifetime), nil
+	}
+
+	location, err := time.LoadLocation(cfg.ExpiryTimeZone)
+	if err != nil {
+		return nil, err
+	}
+	return token.NewDailyExpiryHelper(cfg.ExpiryTime, location, cfg.ExpiryMinLifetime)
+}
+
+// newNotifier return the identity.Notifier selected in the config, or nil if verification tokens are not sent.
+func newNotifier(cfg *config.Configuration) identity.Notifier {
+	if cfg.VerificationConfig.Notifier == config.NotifierLog {
+		log.Info("verification tokens will be written to the logs, this must not be used in production", nil)
+		return notify.Log{Host: cfg.APIHost}
+	}
+	return nil
+}
+
+// newRateLimiter return the api.RateLimiter selected in the config, or nil if requests are not rate limited. Shared
+// buckets are held in the persistence backend so every instance using it shares the limits.
+func newRateLimiter(cfg config.RateLimitConfig, store persistence.Store) api.RateLimiter {
+	switch cfg.Backend {
+	case config.RateLimitMemory:
+		return &ratelimit.Limiter{Store: memory.New(), PurgeInterval: cfg.PurgeInterval}
+	case config.RateLimitShared:
+		return &ratelimit.Limiter{Store: store, PurgeInterval: cfg.PurgeInterval}
+	default:
+		return nil
+	}
+}
+
+//newStore initialises the persistence backend selected in the config. Returns the store, the health checks for the
+// backend and a func closing its connections on shutdown, which is nil if the backend has no connections to close.
+func newStore(cfg *config.Configuration, digester token.Digester, recorder metrics.Recorder) (persistence.Store, []health.Check, func(ctx context.Context) error, error) {
+	switch cfg.StorageBackend {
+	case config.StorageMemory:
+		log.Info("using in-memory persistence backend, data will be lost when the service stops", nil)
+		return memory.New(), nil, nil, nil
+	case config.StoragePostgres:
+		pg, err := postgres.New(cfg.PostgresConfig)
+		if err != nil {
+			return nil, nil, nil, errors.Wrap(err, "failed to initialise postgres")
 		}
+		// indexes are created by the schema migrations applied in postgres.New.
+		closePostgres := func(ctx context.Context) error {
+			return pg.Close()
+		}
+		return pg, []health.Check{{Name: "postgres", Critical: true, Client: postgres.NewHealthCheckClient(pg.DB)}}, closePostgres, nil
+	case config.StorageMongo:
+		mongodb, err := mongo.New(cfg.MongoConfig)
+		if err != nil {
+			return nil, nil, nil, errors.Wrap(err, "failed to initialise mongo")
+		}
+		mongodb.Metrics = recorder
+
+		if _, err := mongodb.MigrateTokenDigests(context.Background(), digester.Digest); err != nil {
+			return nil, nil, nil, errors.Wrap(err, "failed to migrate plain text tokens")
+		}
+
+		if _, err := mongodb.MigrateVerified(context.Background()); err != nil {
+			return nil, nil, nil, errors.Wrap(err, "failed to mark existing identities verified")
+		}
+
+		if err := mongodb.EnsureRateLimitIndexes(context.Background()); err != nil {
+			return nil, nil, nil, errors.Wrap(err, "failed to create rate limit indexes")
+		}
+
+		// missing indexes make queries slow rather than failing them.
+		healthChecks := []health.Check{
+			{Name: "mongodb", Critical: true, Client: mongolib.NewHealthCheckClient(mongodb.Session)},
+			{Name: "mongodb indexes", Critical: false, Client: mongo.NewIndexHealthCheckClient(mongodb)},
+		}
+		closeMongo := func(ctx context.Context) error {
+			return mongolib.Close(ctx, mongodb.Session)
+		}
+		return mongodb, healthChecks, closeMongo, nil
+	default:
+		return nil, nil, nil, errors.Errorf("unsupported storage backend %q", cfg.StorageBackend)
 	}
 }
 
@@ -106,7 +253,7 @@ func startHTTPServer(bindAddr string, router *mux.Router, errorChan chan error)
 }
 
 //gracefulShutdown attempts to gracefully shutdown the service resources before existing.
-func gracefulShutdown(timeout time.Duration, httpServer *server.Server, healthTicker *healthcheck.Ticker, mongoSess *mgo.Session) {
+func gracefulShutdown(timeout time.Duration, httpServer *server.Server, healthChecker *health.Checker, closeStore func(ctx context.Context) error, tracerProvider tracing.Provider) {
 	log.Info(fmt.Sprintf("shutdown with timeout: %s", timeout), nil)
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 
@@ -115,9 +262,16 @@ func gracefulShutdown(timeout time.Duration, httpServer *server.Server, healthTi
 		log.Error(err, nil)
 	}
 
-	healthTicker.Close()
+	healthChecker.Close()
+
+	if closeStore != nil {
+		if err := closeStore(ctx); err != nil {
+			log.Error(err, nil)
+		}
+	}
 
-	if err := mongolib.Close(ctx, mongoSess); err != nil {
+	// flush any spans still waiting to be exported
+	if err := tracerProvider.Shutdown(ctx); err != nil {
 		log.Error(err, nil)
 	}
 
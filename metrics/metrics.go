@@ -0,0 +1,50 @@
+// Package metrics defines the application metrics recorded by the identity API. Packages record metrics through the
+// Recorder interface so they are not coupled to a particular metrics library.
+package metrics
+
+import "time"
+
+//go:generate moq -out metricstest/generate_mocks.go -pkg metricstest . Recorder
+
+// Recorder records application metrics. Implementations must be safe for concurrent use.
+type Recorder interface {
+	// TokenIssued records that a new token was issued.
+	TokenIssued()
+
+	// LoginFailed records an unsuccessful login attempt and the reason it failed.
+	LoginFailed(reason string)
+
+	// TokenLookup records a token lookup and whether it was served from the cache.
+	TokenLookup(cacheHit bool)
+
+	// IdentityCreated records that a new identity was created.
+	IdentityCreated()
+
+	// ObserveHandler records the time taken by a HTTP handler and the status of its response.
+	ObserveHandler(handler string, status int, duration time.Duration)
+
+	// ObservePasswordCompare records the time taken to compare a password with its hash.
+	ObservePasswordCompare(duration time.Duration)
+
+	// ObserveStoreOperation records the time taken by a persistence store method.
+	ObserveStoreOperation(method string, duration time.Duration)
+
+	// RequestRateLimited records a request refused by a rate limit, by the limit exceeded.
+	RequestRateLimited(limit string)
+
+	// RateLimitFailed records a request allowed because its rate limit could not be taken, by the limit.
+	RateLimitFailed(limit string)
+}
+
+// Nop is a Recorder that discards all metrics.
+type Nop struct{}
+
+func (Nop) TokenIssued()                                                      {}
+func (Nop) LoginFailed(reason string)                                         {}
+func (Nop) TokenLookup(cacheHit bool)                                         {}
+func (Nop) IdentityCreated()                                                  {}
+func (Nop) ObserveHandler(handler string, status int, duration time.Duration) {}
+func (Nop) ObservePasswordCompare(duration time.Duration)                     {}
+func (Nop) ObserveStoreOperation(method string, duration time.Duration)       {}
+func (Nop) RequestRateLimited(limit string)                                   {}
+func (Nop) RateLimitFailed(limit string)                                      {}
@@ -0,0 +1,142 @@
+// Package prometheus provides a metrics.Recorder exposing metrics in the Prometheus exposition format.
+package prometheus
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "dp_identity_api"
+
+// Recorder is a metrics.Recorder backed by a Prometheus registry. Go runtime and process metrics are also registered.
+type Recorder struct {
+	registry                *prom.Registry
+	tokensIssued            prom.Counter
+	loginFailures           *prom.CounterVec
+	tokenLookups            *prom.CounterVec
+	identitiesCreated       prom.Counter
+	handlerDuration         *prom.HistogramVec
+	passwordCompareDuration prom.Histogram
+	storeDuration           *prom.HistogramVec
+	rateLimited             *prom.CounterVec
+	rateLimitFailures       *prom.CounterVec
+}
+
+// New construct a new Recorder with its own registry.
+func New() *Recorder {
+	r := &Recorder{
+		registry: prom.NewRegistry(),
+		tokensIssued: prom.NewCounter(prom.CounterOpts{
+			Namespace: namespace,
+			Name:      "tokens_issued_total",
+			Help:      "Number of tokens issued.",
+		}),
+		loginFailures: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: namespace,
+			Name:      "login_failures_total",
+			Help:      "Number of unsuccessful login attempts by reason.",
+		}, []string{"reason"}),
+		tokenLookups: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: namespace,
+			Name:      "token_lookups_total",
+			Help:      "Number of token lookups by cache result.",
+		}, []string{"cache"}),
+		identitiesCreated: prom.NewCounter(prom.CounterOpts{
+			Namespace: namespace,
+			Name:      "identities_created_total",
+			Help:      "Number of identities created.",
+		}),
+		handlerDuration: prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time taken to handle HTTP requests by handler and response status.",
+			Buckets:   prom.DefBuckets,
+		}, []string{"handler", "status"}),
+		passwordCompareDuration: prom.NewHistogram(prom.HistogramOpts{
+			Namespace: namespace,
+			Name:      "password_compare_duration_seconds",
+			Help:      "Time taken to compare a password with its hash.",
+			Buckets:   prom.ExponentialBuckets(0.005, 2, 10),
+		}),
+		storeDuration: prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace: namespace,
+			Name:      "store_operation_duration_seconds",
+			Help:      "Time taken by persistence store methods by method.",
+			Buckets:   prom.DefBuckets,
+		}, []string{"method"}),
+		rateLimited: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: namespace,
+			Name:      "rate_limited_requests_total",
+			Help:      "Number of requests refused by a rate limit by limit.",
+		}, []string{"limit"}),
+		rateLimitFailures: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: namespace,
+			Name:      "rate_limit_failures_total",
+			Help:      "Number of requests allowed because the rate limiter failed by limit.",
+		}, []string{"limit"}),
+	}
+
+	r.registry.MustRegister(
+		prom.NewGoCollector(),
+		prom.NewProcessCollector(prom.ProcessCollectorOpts{}),
+		r.tokensIssued,
+		r.loginFailures,
+		r.tokenLookups,
+		r.identitiesCreated,
+		r.handlerDuration,
+		r.passwordCompareDuration,
+		r.storeDuration,
+		r.rateLimited,
+		r.rateLimitFailures,
+	)
+	return r
+}
+
+// Handler return a http.Handler serving the registered metrics.
+func (r *Recorder) Handler() http.Handler {
+	return promhttp.HandlerFor(r.registry, promhttp.HandlerOpts{})
+}
+
+func (r *Recorder) TokenIssued() {
+	r.tokensIssued.Inc()
+}
+
+func (r *Recorder) LoginFailed(reason string) {
+	r.loginFailures.WithLabelValues(reason).Inc()
+}
+
+func (r *Recorder) TokenLookup(cacheHit bool) {
+	result := "miss"
+	if cacheHit {
+		result = "hit"
+	}
+	r.tokenLookups.WithLabelValues(result).Inc()
+}
+
+func (r *Recorder) IdentityCreated() {
+	r.identitiesCreated.Inc()
+}
+
+func (r *Recorder) ObserveHandler(handler string, status int, duration time.Duration) {
+	r.handlerDuration.WithLabelValues(handler, strconv.Itoa(status)).Observe(duration.Seconds())
+}
+
+func (r *Recorder) ObservePasswordCompare(duration time.Duration) {
+	r.passwordCompareDuration.Observe(duration.Seconds())
+}
+
+func (r *Recorder) ObserveStoreOperation(method string, duration time.Duration) {
+	r.storeDuration.WithLabelValues(method).Observe(duration.Seconds())
+}
+
+func (r *Recorder) RequestRateLimited(limit string) {
+	r.rateLimited.WithLabelValues(limit).Inc()
+}
+
+func (r *Recorder) RateLimitFailed(limit string) {
+	r.rateLimitFailures.WithLabelValues(limit).Inc()
+}
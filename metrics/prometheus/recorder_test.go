@@ -0,0 +1,57 @@
+package prometheus
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-identity-api/metrics"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var _ metrics.Recorder = &Recorder{}
+
+func TestRecorder_Handler(t *testing.T) {
+	Convey("given metrics have been recorded", t, func() {
+		r := New()
+		r.TokenIssued()
+		r.LoginFailed("authentication_failed")
+		r.LoginFailed("authentication_failed")
+		r.TokenLookup(true)
+		r.TokenLookup(false)
+		r.IdentityCreated()
+		r.ObserveHandler("createToken", 200, time.Millisecond*20)
+		r.ObservePasswordCompare(time.Millisecond * 60)
+		r.ObserveStoreOperation("GetIdentity", time.Millisecond*3)
+		r.RequestRateLimited("ip")
+		r.RateLimitFailed("token")
+
+		Convey("when the metrics are scraped", func() {
+			w := httptest.NewRecorder()
+			r.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+			b, err := ioutil.ReadAll(w.Body)
+			So(err, ShouldBeNil)
+			body := string(b)
+
+			Convey("then the recorded metrics are exposed", func() {
+				So(w.Code, ShouldEqual, 200)
+				So(body, ShouldContainSubstring, "dp_identity_api_tokens_issued_total 1")
+				So(body, ShouldContainSubstring, `dp_identity_api_login_failures_total{reason="authentication_failed"} 2`)
+				So(body, ShouldContainSubstring, `dp_identity_api_token_lookups_total{cache="hit"} 1`)
+				So(body, ShouldContainSubstring, `dp_identity_api_token_lookups_total{cache="miss"} 1`)
+				So(body, ShouldContainSubstring, "dp_identity_api_identities_created_total 1")
+				So(body, ShouldContainSubstring, `dp_identity_api_http_request_duration_seconds_count{handler="createToken",status="200"} 1`)
+				So(body, ShouldContainSubstring, "dp_identity_api_password_compare_duration_seconds_count 1")
+				So(body, ShouldContainSubstring, `dp_identity_api_store_operation_duration_seconds_count{method="GetIdentity"} 1`)
+				So(body, ShouldContainSubstring, `dp_identity_api_rate_limited_requests_total{limit="ip"} 1`)
+				So(body, ShouldContainSubstring, `dp_identity_api_rate_limit_failures_total{limit="token"} 1`)
+			})
+
+			Convey("and go runtime metrics are exposed", func() {
+				So(body, ShouldContainSubstring, "go_goroutines")
+			})
+		})
+	})
+}
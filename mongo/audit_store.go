@@ -0,0 +1,69 @@
+package mongo
+
+import (
+	"context"
+	"github.com/ONSdigital/dp-identity-api/persistence"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/pkg/errors"
+)
+
+// StoreEvent store an audit event document in the audit collection.
+func (m *Mongo) StoreEvent(ctx context.Context, event schema.AuditEvent) error {
+	ctx, end := m.start(ctx, "StoreEvent")
+	defer end()
+
+	err := m.run(ctx, func(s *mgo.Session) error {
+		return s.DB(m.Database).C(m.AuditCollection).Insert(event)
+	})
+
+	if err != nil {
+		if err == persistence.ErrTimeout || err == persistence.ErrUnavailable {
+			return err
+		}
+		return errors.Wrap(err, "auditStore: error storing audit event")
+	}
+	return nil
+}
+
+// GetEvents return the audit events about the identity with the provided ID ordered by created date.
+func (m *Mongo) GetEvents(ctx context.Context, identityID string) ([]schema.AuditEvent, error) {
+	ctx, end := m.start(ctx, "GetEvents")
+	defer end()
+
+	events := []schema.AuditEvent{}
+	err := m.run(ctx, func(s *mgo.Session) error {
+		return s.DB(m.Database).C(m.AuditCollection).Find(bson.M{identityIDKey: identityID}).Sort("created").All(&events)
+	})
+
+	if err != nil {
+		if err == persistence.ErrTimeout || err == persistence.ErrUnavailable {
+			return nil, err
+		}
+		return nil, errors.Wrap(err, "auditStore: error querying for audit events")
+	}
+	return events, nil
+}
+
+// PseudonymiseEvents remove the params of the audit events about the identity with the provided ID.
+func (m *Mongo) PseudonymiseEvents(ctx context.Context, identityID string) error {
+	ctx, end := m.start(ctx, "PseudonymiseEvents")
+	defer end()
+
+	err := m.run(ctx, func(s *mgo.Session) error {
+		_, err := s.DB(m.Database).C(m.AuditCollection).UpdateAll(
+			bson.M{identityIDKey: identityID},
+			bson.M{"$unset": bson.M{"params": ""}},
+		)
+		return err
+	})
+
+	if err != nil {
+		if err == persistence.ErrTimeout || err == persistence.ErrUnavailable {
+			return err
+		}
+		return errors.Wrap(err, "auditStore: error pseudonymising audit events")
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package mongo
+
+import (
+	"context"
+	"github.com/ONSdigital/dp-identity-api/persistence"
+	"github.com/ONSdigital/dp-identity-api/tracing"
+	"github.com/globalsign/mgo"
+	"go.opentelemetry.io/otel/trace"
+	"net"
+	"time"
+)
+
+// copySession return a copy of the session with its socket timeout set to the configured query timeout, or the time
+// remaining until the ctx deadline if sooner. Returns an error if the ctx is already done.
+func (m *Mongo) copySession(ctx context.Context) (*mgo.Session, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, contextErr(err)
+	}
+
+	s := m.Session.Copy()
+	if timeout := m.operationTimeout(ctx); timeout > 0 {
+		s.SetSocketTimeout(timeout)
+	}
+	return s, nil
+}
+
+// run executes op with a copy of the session, returning early if the ctx is done before op completes. Timeouts are
+// returned as persistence.ErrTimeout and cancellation as persistence.ErrUnavailable.
+func (m *Mongo) run(ctx context.Context, op func(s *mgo.Session) error) error {
+	s, err := m.copySession(ctx)
+	if err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		defer s.Close()
+		done <- op(s)
+	}()
+
+	select {
+	case err = <-done:
+		if isTimeout(err) {
+			err = persistence.ErrTimeout
+		}
+	case <-ctx.Done():
+		// op will be abandoned once the socket timeout is reached.
+		err = contextErr(ctx.Err())
+	}
+
+	if err != mgo.ErrNotFound {
+		tracing.RecordError(trace.SpanFromContext(ctx), err)
+	}
+	return err
+}
+
+func (m *Mongo) operationTimeout(ctx context.Context) time.Duration {
+	timeout := m.QueryTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline); timeout == 0 || remaining < timeout {
+			timeout = remaining
+		}
+	}
+	return timeout
+}
+
+func contextErr(err error) error {
+	if err == context.DeadlineExceeded {
+		return persistence.ErrTimeout
+	}
+	return persistence.ErrUnavailable
+}
+
+func isTimeout(err error) bool {
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
@@ -0,0 +1,71 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/pkg/errors"
+)
+
+// ErrMissingIndex is returned by the index health check if a required index does not exist.
+var ErrMissingIndex = errors.New("required index missing")
+
+// requiredIndexes are the indexes that must exist in each collection for queries to perform acceptably, or in the
+// case of the rate limit indexes to behave correctly, by collection name.
+func (m *Mongo) requiredIndexes() map[string][]mgo.Index {
+	return map[string][]mgo.Index{
+		m.IdentityCollection:  {{Key: []string{"email"}}, {Key: []string{"id"}}},
+		m.TokenCollection:     {{Key: []string{"token_id"}}, {Key: []string{"identity_id"}}},
+		m.AuditCollection:     {{Key: []string{"identity_id"}}},
+		m.TombstoneCollection: {{Key: []string{"id"}}},
+		m.RateLimitCollection: rateLimitIndexes,
+	}
+}
+
+// IndexHealthCheckClient provides a healthcheck.Client implementation checking the indexes required by the store
+// exist.
+type IndexHealthCheckClient struct {
+	mongo       *Mongo
+	serviceName string
+}
+
+// NewIndexHealthCheckClient returns a new health check client for the indexes of the provided store.
+func NewIndexHealthCheckClient(m *Mongo) *IndexHealthCheckClient {
+	return &IndexHealthCheckClient{
+		mongo:       m,
+		serviceName: "mongodb indexes",
+	}
+}
+
+// Healthcheck return ErrMissingIndex if any index required by the store does not exist.
+func (c *IndexHealthCheckClient) Healthcheck() (string, error) {
+	s := c.mongo.Session.Copy()
+	defer s.Close()
+
+	for collection, required := range c.mongo.requiredIndexes() {
+		indexes, err := s.DB(c.mongo.Database).C(collection).Indexes()
+		if err != nil {
+			return c.serviceName, err
+		}
+
+		for _, required := range required {
+			if !hasIndex(indexes, required) {
+				return c.serviceName, errors.Wrapf(ErrMissingIndex, "%s.%s", collection, required.Key[0])
+			}
+		}
+	}
+	return c.serviceName, nil
+}
+
+// hasIndex return true if an index has the first key of the required index as its first field, so can be used by
+// queries on the key, and is unique and expires documents if the required index does.
+func hasIndex(indexes []mgo.Index, required mgo.Index) bool {
+	for _, i := range indexes {
+		if len(i.Key) == 0 || i.Key[0] != required.Key[0] {
+			continue
+		}
+		if (required.Unique && !i.Unique) || (required.ExpireAfter > 0 && i.ExpireAfter <= 0) {
+			continue
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package mongo
+
+import (
+	"context"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/globalsign/mgo"
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+func TestHasIndex(t *testing.T) {
+	Convey("hasIndex should only match indexes with the key as their first field", t, func() {
+		indexes := []mgo.Index{{Key: []string{"_id"}}, {Key: []string{"identity_id", "deleted"}}}
+
+		So(hasIndex(indexes, mgo.Index{Key: []string{"identity_id"}}), ShouldBeTrue)
+		So(hasIndex(indexes, mgo.Index{Key: []string{"deleted"}}), ShouldBeFalse)
+		So(hasIndex(nil, mgo.Index{Key: []string{"identity_id"}}), ShouldBeFalse)
+	})
+
+	Convey("hasIndex should only match unique and TTL indexes if they are required", t, func() {
+		indexes := []mgo.Index{{Key: []string{"key"}}, {Key: []string{"tat"}}}
+
+		So(hasIndex(indexes, mgo.Index{Key: []string{"key"}, Unique: true}), ShouldBeFalse)
+		So(hasIndex(indexes, mgo.Index{Key: []string{"tat"}, ExpireAfter: time.Second}), ShouldBeFalse)
+		So(hasIndex(rateLimitIndexes, mgo.Index{Key: []string{"key"}, Unique: true}), ShouldBeTrue)
+		So(hasIndex(rateLimitIndexes, mgo.Index{Key: []string{"tat"}, ExpireAfter: time.Second}), ShouldBeTrue)
+	})
+}
+
+// TestIndexHealthCheckClient_Healthcheck is skipped unless MONGODB_TEST_BIND_ADDR is set.
+func TestIndexHealthCheckClient_Healthcheck(t *testing.T) {
+	m := newTestMongo(t)
+	defer m.Session.Close()
+
+	Convey("given the collections exist without the required indexes", t, func() {
+		dropTestDatabase(t, m)
+		_, err := m.SaveIdentity(context.Background(), schema.Identity{Name: "Egon Spengler", Email: "spengler@whoyougunnacall.com"})
+		So(err, ShouldBeNil)
+		So(m.Session.DB(m.Database).C(m.TokenCollection).Insert(schema.Token{ID: "digest"}), ShouldBeNil)
+
+		c := NewIndexHealthCheckClient(m)
+
+		Convey("then the health check returns ErrMissingIndex", func() {
+			_, err := c.Healthcheck()
+			So(errors.Cause(err), ShouldEqual, ErrMissingIndex)
+		})
+
+		Convey("when the required indexes are created", func() {
+			for collection, indexes := range m.requiredIndexes() {
+				for _, index := range indexes {
+					So(m.Session.DB(m.Database).C(collection).EnsureIndex(index), ShouldBeNil)
+				}
+			}
+
+			Convey("then the health check is successful", func() {
+				name, err := c.Healthcheck()
+				So(name, ShouldEqual, "mongodb indexes")
+				So(err, ShouldBeNil)
+			})
+		})
+	})
+}
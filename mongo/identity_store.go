@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/ONSdigital/dp-identity-api/persistence"
 	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/ONSdigital/go-ns/log"
 	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
 	"github.com/pkg/errors"
@@ -11,32 +12,35 @@ import (
 	"time"
 )
 
-func (m *Mongo) SaveIdentity(identity schema.Identity) (string, error) {
-	s := m.Session.Copy()
-	defer s.Close()
+func (m *Mongo) SaveIdentity(ctx context.Context, identity schema.Identity) (string, error) {
+	ctx, end := m.start(ctx, "SaveIdentity")
+	defer end()
 
-	available, err := m.identityAvailable(s, identity.Email)
-	if err != nil {
-		return "", err
-	}
+	err := m.run(ctx, func(s *mgo.Session) error {
+		available, err := m.identityAvailable(s, identity.Email)
+		if err != nil {
+			return err
+		}
 
-	if !available {
-		return "", persistence.ErrNonUnique
-	}
+		if !available {
+			return persistence.ErrNonUnique
+		}
 
-	// NOTE - Upsert may be more appropriate than Insert. Consider "already exists" scenarios?
-	id, err := uuid.NewV4()
-	if err != nil {
-		return "", errors.Wrap(err, "error generating uuid")
-	}
+		// NOTE - Upsert may be more appropriate than Insert. Consider "already exists" scenarios?
+		id, err := uuid.NewV4()
+		if err != nil {
+			return errors.Wrap(err, "error generating uuid")
+		}
 
-	identity.ID = id.String()
-	identity.CreatedDate = time.Now()
+		identity.ID = id.String()
+		identity.CreatedDate = time.Now()
 
-	err = s.DB(m.Database).C(m.IdentityCollection).Insert(identity)
-	if err == mgo.ErrNotFound {
-		return "", errors.New("failed to post new identity document to mongo")
-	}
+		err = s.DB(m.Database).C(m.IdentityCollection).Insert(identity)
+		if err == mgo.ErrNotFound {
+			return errors.New("failed to post new identity document to mongo")
+		}
+		return err
+	})
 
 	if err != nil {
 		return "", err
@@ -56,41 +60,326 @@ func (m *Mongo) identityAvailable(s *mgo.Session, email string) (bool, error) {
 	return count == 0, nil
 }
 
-func (m *Mongo) GetIdentity(email string) (schema.Identity, error) {
-	s := m.Session.Copy()
-	defer s.Close()
+func (m *Mongo) GetIdentity(ctx context.Context, email string) (schema.Identity, error) {
+	ctx, end := m.start(ctx, "GetIdentity")
+	defer end()
 
 	query := bson.M{"email": email, "deleted": false}
 
-	count, err := s.DB(m.Database).C(m.IdentityCollection).Find(query).Count()
+	var i schema.Identity
+	err := m.run(ctx, func(s *mgo.Session) error {
+		return s.DB(m.Database).C(m.IdentityCollection).Find(query).One(&i)
+	})
+
 	if err != nil {
+		if err == mgo.ErrNotFound {
+			err = persistence.ErrNotFound
+		}
 		return schema.NilIdentity, err
 	}
+	return i, nil
+}
+
+func (m *Mongo) GetIdentityByID(ctx context.Context, id string) (*schema.Identity, error) {
+	ctx, end := m.start(ctx, "GetIdentityByID")
+	defer end()
+
+	query := bson.M{"id": id, "deleted": false}
+
+	var i schema.Identity
+	err := m.run(ctx, func(s *mgo.Session) error {
+		return s.DB(m.Database).C(m.IdentityCollection).Find(query).One(&i)
+	})
 
-	if count == 0 {
-		return schema.NilIdentity, persistence.ErrNotFound
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			err = persistence.ErrNotFound
+		}
+		return nil, err
 	}
+	return &i, nil
+}
+
+// GetIdentityByIDIncludingDeleted return the identity with the provided ID whether or not it has been deleted. Returns
+// persistence.ErrNotFound if no identity exists.
+func (m *Mongo) GetIdentityByIDIncludingDeleted(ctx context.Context, id string) (*schema.Identity, error) {
+	ctx, end := m.start(ctx, "GetIdentityByIDIncludingDeleted")
+	defer end()
 
 	var i schema.Identity
-	if err := s.DB(m.Database).C(m.IdentityCollection).Find(query).One(&i); err != nil {
-		return schema.NilIdentity, err
+	err := m.run(ctx, func(s *mgo.Session) error {
+		return s.DB(m.Database).C(m.IdentityCollection).Find(bson.M{"id": id}).One(&i)
+	})
+
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			err = persistence.ErrNotFound
+		}
+		return nil, err
 	}
-	return i, nil
+	return &i, nil
 }
 
-func (m *Mongo) GetIdentityByID(ctx context.Context, id string) (*schema.Identity, error) {
-	s := m.Session.Copy()
-	defer s.Close()
+// UpdatePassword replace the stored password hash of the active identity with the provided ID and clear its migrated
+// flag.
+func (m *Mongo) UpdatePassword(ctx context.Context, id string, password string) error {
+	ctx, end := m.start(ctx, "UpdatePassword")
+	defer end()
+
+	query := bson.M{"id": id, "deleted": false}
+	update := bson.M{"$set": bson.M{"password": password, "migrated": false}}
+
+	err := m.run(ctx, func(s *mgo.Session) error {
+		return s.DB(m.Database).C(m.IdentityCollection).Update(query, update)
+	})
+
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return persistence.ErrNotFound
+		}
+		if err == persistence.ErrTimeout || err == persistence.ErrUnavailable {
+			return err
+		}
+		return errors.Wrap(err, "error updating identity password")
+	}
+	return nil
+}
+
+// VerifyIdentity mark the active identity issued the unexpired verification token with the provided digest as verified
+// and clear the token. Returns persistence.ErrNotFound if no such identity exists.
+func (m *Mongo) VerifyIdentity(ctx context.Context, token string) (*schema.Identity, error) {
+	ctx, end := m.start(ctx, "VerifyIdentity")
+	defer end()
+
+	query := bson.M{
+		"verification_token":  token,
+		"verification_expiry": bson.M{"$gt": time.Now()},
+		"deleted":             false,
+	}
+	change := mgo.Change{
+		Update: bson.M{
+			"$set":   bson.M{"verified": true},
+			"$unset": bson.M{"verification_token": "", "verification_expiry": ""},
+		},
+		ReturnNew: true,
+	}
 
 	var i schema.Identity
+	err := m.run(ctx, func(s *mgo.Session) error {
+		_, err := s.DB(m.Database).C(m.IdentityCollection).Find(query).Apply(change, &i)
+		return err
+	})
+
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, persistence.ErrNotFound
+		}
+		if err == persistence.ErrTimeout || err == persistence.ErrUnavailable {
+			return nil, err
+		}
+		return nil, errors.Wrap(err, "error verifying identity")
+	}
+	return &i, nil
+}
+
+// SetPendingEmail record a requested email change for the active identity with the provided ID. Returns
+// persistence.ErrNotFound if no active identity exists.
+func (m *Mongo) SetPendingEmail(ctx context.Context, id string, email string, token string, expiry time.Time) error {
+	ctx, end := m.start(ctx, "SetPendingEmail")
+	defer end()
 
 	query := bson.M{"id": id, "deleted": false}
+	update := bson.M{"$set": bson.M{"pending_email": email, "email_change_token": token, "email_change_expiry": expiry}}
+
+	err := m.run(ctx, func(s *mgo.Session) error {
+		return s.DB(m.Database).C(m.IdentityCollection).Update(query, update)
+	})
+
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return persistence.ErrNotFound
+		}
+		if err == persistence.ErrTimeout || err == persistence.ErrUnavailable {
+			return err
+		}
+		return errors.Wrap(err, "error setting identity pending email")
+	}
+	return nil
+}
+
+// ConfirmEmailChange apply the pending email of the active identity issued the unexpired email change token with the
+// provided digest. Returns persistence.ErrNonUnique if the pending email is used by another active identity or
+// persistence.ErrNotFound if no such identity exists.
+func (m *Mongo) ConfirmEmailChange(ctx context.Context, token string) (*schema.Identity, error) {
+	ctx, end := m.start(ctx, "ConfirmEmailChange")
+	defer end()
+
+	query := bson.M{
+		"email_change_token":  token,
+		"email_change_expiry": bson.M{"$gt": time.Now()},
+		"deleted":             false,
+	}
+
+	var i schema.Identity
+	err := m.run(ctx, func(s *mgo.Session) error {
+		c := s.DB(m.Database).C(m.IdentityCollection)
+
+		var pending schema.Identity
+		if err := c.Find(query).One(&pending); err != nil {
+			return err
+		}
+
+		available, err := m.identityAvailable(s, pending.PendingEmail)
+		if err != nil {
+			return err
+		}
+
+		if !available {
+			return persistence.ErrNonUnique
+		}
+
+		change := mgo.Change{
+			Update: bson.M{
+				"$set":   bson.M{"email": pending.PendingEmail, "verified": true},
+				"$unset": bson.M{"pending_email": "", "email_change_token": "", "email_change_expiry": ""},
+			},
+			ReturnNew: true,
+		}
+		_, err = c.Find(bson.M{"id": pending.ID, "email_change_token": token, "deleted": false}).Apply(change, &i)
+		return err
+	})
 
-	if err := s.DB(m.Database).C(m.IdentityCollection).Find(query).One(&i); err != nil {
+	if err != nil {
 		if err == mgo.ErrNotFound {
-			err = persistence.ErrNotFound
+			return nil, persistence.ErrNotFound
 		}
-		return nil, err
+		if err == persistence.ErrNonUnique || err == persistence.ErrTimeout || err == persistence.ErrUnavailable {
+			return nil, err
+		}
+		return nil, errors.Wrap(err, "error confirming identity email change")
 	}
 	return &i, nil
 }
+
+// DisableIdentity mark the active identity with the provided ID as disabled. Returns persistence.ErrNotFound if no
+// active identity exists.
+func (m *Mongo) DisableIdentity(ctx context.Context, id string, reason string, disabledBy string, date time.Time) error {
+	ctx, end := m.start(ctx, "DisableIdentity")
+	defer end()
+
+	update := bson.M{"$set": bson.M{"disabled": true, "disabled_reason": reason, "disabled_by": disabledBy, "disabled_date": date}}
+	return m.updateActiveIdentity(ctx, id, update, "error disabling identity")
+}
+
+// EnableIdentity clear the disabled flag of the active identity with the provided ID. Returns persistence.ErrNotFound
+// if no active identity exists.
+func (m *Mongo) EnableIdentity(ctx context.Context, id string) error {
+	ctx, end := m.start(ctx, "EnableIdentity")
+	defer end()
+
+	update := bson.M{
+		"$set":   bson.M{"disabled": false},
+		"$unset": bson.M{"disabled_reason": "", "disabled_by": "", "disabled_date": ""},
+	}
+	return m.updateActiveIdentity(ctx, id, update, "error enabling identity")
+}
+
+func (m *Mongo) updateActiveIdentity(ctx context.Context, id string, update bson.M, msg string) error {
+	err := m.run(ctx, func(s *mgo.Session) error {
+		return s.DB(m.Database).C(m.IdentityCollection).Update(bson.M{"id": id, "deleted": false}, update)
+	})
+
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return persistence.ErrNotFound
+		}
+		if err == persistence.ErrTimeout || err == persistence.ErrUnavailable {
+			return err
+		}
+		return errors.Wrap(err, msg)
+	}
+	return nil
+}
+
+// EraseIdentity remove the identity with the ID of the tombstone and store the tombstone. The tombstone is stored
+// first so an identity is never removed without one, erasing again after a failure is safe. Returns
+// persistence.ErrNotFound if no identity exists.
+func (m *Mongo) EraseIdentity(ctx context.Context, tombstone schema.Tombstone) error {
+	ctx, end := m.start(ctx, "EraseIdentity")
+	defer end()
+
+	err := m.run(ctx, func(s *mgo.Session) error {
+		db := s.DB(m.Database)
+
+		count, err := db.C(m.IdentityCollection).Find(bson.M{"id": tombstone.ID}).Count()
+		if err != nil {
+			return err
+		}
+
+		if count == 0 {
+			return mgo.ErrNotFound
+		}
+
+		if _, err := db.C(m.TombstoneCollection).Upsert(bson.M{"id": tombstone.ID}, tombstone); err != nil {
+			return err
+		}
+
+		_, err = db.C(m.IdentityCollection).RemoveAll(bson.M{"id": tombstone.ID})
+		return err
+	})
+
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return persistence.ErrNotFound
+		}
+		if err == persistence.ErrTimeout || err == persistence.ErrUnavailable {
+			return err
+		}
+		return errors.Wrap(err, "error erasing identity")
+	}
+	return nil
+}
+
+// GetTombstone return the tombstone of the erased identity with the provided ID. Returns persistence.ErrNotFound if
+// the identity has not been erased.
+func (m *Mongo) GetTombstone(ctx context.Context, id string) (*schema.Tombstone, error) {
+	ctx, end := m.start(ctx, "GetTombstone")
+	defer end()
+
+	var t schema.Tombstone
+	err := m.run(ctx, func(s *mgo.Session) error {
+		return s.DB(m.Database).C(m.TombstoneCollection).Find(bson.M{"id": id}).One(&t)
+	})
+
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, persistence.ErrNotFound
+		}
+		if err == persistence.ErrTimeout || err == persistence.ErrUnavailable {
+			return nil, err
+		}
+		return nil, errors.Wrap(err, "error querying for tombstone")
+	}
+	return &t, nil
+}
+
+// MigrateVerified marks identities stored before email verification was introduced as verified, so enabling
+// verification does not lock out existing users. Returns the number of identities migrated.
+func (m *Mongo) MigrateVerified(ctx context.Context) (int, error) {
+	s, err := m.copySession(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer s.Close()
+
+	info, err := s.DB(m.Database).C(m.IdentityCollection).UpdateAll(
+		bson.M{"verified": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"verified": true}},
+	)
+	if err != nil {
+		return 0, errors.Wrap(err, "identityStore: error marking existing identities verified")
+	}
+
+	log.InfoCtx(ctx, "identityStore: migrate verified completed without error", log.Data{"migrated": info.Updated})
+	return info.Updated, nil
+}
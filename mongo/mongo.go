@@ -1,8 +1,13 @@
 package mongo
 
 import (
+	"context"
 	"github.com/ONSdigital/dp-identity-api/config"
+	"github.com/ONSdigital/dp-identity-api/metrics"
+	"github.com/ONSdigital/dp-identity-api/tracing"
 	"github.com/globalsign/mgo"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
 	"time"
 
 	"github.com/pkg/errors"
@@ -10,23 +15,32 @@ import (
 
 // Mongo represents a simplistic MongoDB configuration.
 type Mongo struct {
-	IdentityCollection string // TODO need to make this identityCollection and tokenCollection
-	TokenCollection    string // TODO need to make this identityCollection and tokenCollection
-	Database           string
-	Session            *mgo.Session
-	URI                string
-	lastPingTime       time.Time
-	lastPingResult     error
+	IdentityCollection  string // TODO need to make this identityCollection and tokenCollection
+	TokenCollection     string // TODO need to make this identityCollection and tokenCollection
+	AuditCollection     string
+	TombstoneCollection string
+	RateLimitCollection string
+	Database            string
+	Session             *mgo.Session
+	URI                 string
+	QueryTimeout        time.Duration
+	Metrics             metrics.Recorder
+	lastPingTime        time.Time
+	lastPingResult      error
 }
 
 type changeInfo map[string]interface{}
 
 func New(cfg config.MongoConfig) (*Mongo, error) {
 	mongodb := &Mongo{
-		IdentityCollection: cfg.IdentityCollection,
-		TokenCollection:    cfg.TokenCollection,
-		Database:           cfg.Database,
-		URI:                cfg.BindAddr,
+		IdentityCollection:  cfg.IdentityCollection,
+		TokenCollection:     cfg.TokenCollection,
+		AuditCollection:     cfg.AuditCollection,
+		TombstoneCollection: cfg.TombstoneCollection,
+		RateLimitCollection: cfg.RateLimitCollection,
+		Database:            cfg.Database,
+		URI:                 cfg.BindAddr,
+		QueryTimeout:        cfg.QueryTimeout,
 	}
 
 	session, err := mongodb.createSession()
@@ -52,3 +66,18 @@ func (m *Mongo) createSession() (session *mgo.Session, err error) {
 	session.SetMode(mgo.Strong, true)
 	return session, nil
 }
+
+// start creates a span for the store method, returning a ctx containing the span and a func which must be called when
+// the method returns to end the span and record the time taken if a metrics.Recorder is configured.
+func (m *Mongo) start(ctx context.Context, method string) (context.Context, func()) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "mongo."+method, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemMongoDB, semconv.DBNameKey.String(m.Database)))
+
+	return ctx, func() {
+		span.End()
+		if m.Metrics != nil {
+			m.Metrics.ObserveStoreOperation(method, time.Since(start))
+		}
+	}
+}
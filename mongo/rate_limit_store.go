@@ -0,0 +1,116 @@
+package mongo
+
+import (
+	"context"
+	"github.com/ONSdigital/dp-identity-api/persistence"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// rateLimitIndexes are the indexes rate limiting relies on: a unique index on key, so SwapRateLimit can detect a bucket
+// inserted concurrently by another instance, and a TTL index on tat, so buckets are removed once they are full again.
+var rateLimitIndexes = []mgo.Index{
+	{Key: []string{"key"}, Unique: true},
+	{Key: []string{"tat"}, ExpireAfter: time.Second},
+}
+
+// rateLimit is the document storing the TAT of a rate limit bucket.
+type rateLimit struct {
+	Key string    `bson:"key"`
+	TAT time.Time `bson:"tat"`
+}
+
+// EnsureRateLimitIndexes create the indexes rate limiting relies on if they do not already exist.
+func (m *Mongo) EnsureRateLimitIndexes(ctx context.Context) error {
+	s, err := m.copySession(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	c := s.DB(m.Database).C(m.RateLimitCollection)
+	for _, index := range rateLimitIndexes {
+		if err := c.EnsureIndex(index); err != nil {
+			return errors.Wrapf(err, "rateLimitStore: error creating index on %s", index.Key[0])
+		}
+	}
+	return nil
+}
+
+// GetRateLimit return the TAT of the rate limit bucket with the provided key, or the zero time if no bucket exists.
+func (m *Mongo) GetRateLimit(ctx context.Context, key string) (time.Time, error) {
+	ctx, end := m.start(ctx, "GetRateLimit")
+	defer end()
+
+	var bucket rateLimit
+	err := m.run(ctx, func(s *mgo.Session) error {
+		return s.DB(m.Database).C(m.RateLimitCollection).Find(bson.M{"key": key}).One(&bucket)
+	})
+
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return time.Time{}, nil
+		}
+		if err == persistence.ErrTimeout || err == persistence.ErrUnavailable {
+			return time.Time{}, err
+		}
+		return time.Time{}, errors.Wrap(err, "rateLimitStore: error querying for rate limit")
+	}
+	return bucket.TAT, nil
+}
+
+// SwapRateLimit set the TAT of the rate limit bucket with the provided key if its TAT equals old, or insert the bucket
+// if old is zero. Returns false if the bucket has changed. Relies on the unique index on key to detect a bucket
+// inserted concurrently.
+func (m *Mongo) SwapRateLimit(ctx context.Context, key string, old time.Time, tat time.Time) (bool, error) {
+	ctx, end := m.start(ctx, "SwapRateLimit")
+	defer end()
+
+	swapped := true
+	err := m.run(ctx, func(s *mgo.Session) error {
+		c := s.DB(m.Database).C(m.RateLimitCollection)
+
+		var err error
+		if old.IsZero() {
+			err = c.Insert(rateLimit{Key: key, TAT: tat})
+		} else {
+			err = c.Update(bson.M{"key": key, "tat": old}, bson.M{"$set": bson.M{"tat": tat}})
+		}
+
+		if err == mgo.ErrNotFound || mgo.IsDup(err) {
+			swapped = false
+			return nil
+		}
+		return err
+	})
+
+	if err != nil {
+		if err == persistence.ErrTimeout || err == persistence.ErrUnavailable {
+			return false, err
+		}
+		return false, errors.Wrap(err, "rateLimitStore: error updating rate limit")
+	}
+	return swapped, nil
+}
+
+// PurgeRateLimits remove the rate limit buckets with a TAT before the provided time. The TTL index on tat also removes
+// these, but only once a minute.
+func (m *Mongo) PurgeRateLimits(ctx context.Context, before time.Time) error {
+	ctx, end := m.start(ctx, "PurgeRateLimits")
+	defer end()
+
+	err := m.run(ctx, func(s *mgo.Session) error {
+		_, err := s.DB(m.Database).C(m.RateLimitCollection).RemoveAll(bson.M{"tat": bson.M{"$lt": before}})
+		return err
+	})
+
+	if err != nil {
+		if err == persistence.ErrTimeout || err == persistence.ErrUnavailable {
+			return err
+		}
+		return errors.Wrap(err, "rateLimitStore: error purging rate limits")
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package mongo
+
+import (
+	"context"
+	"github.com/ONSdigital/dp-identity-api/config"
+	"github.com/ONSdigital/dp-identity-api/persistence"
+	"github.com/ONSdigital/dp-identity-api/persistence/persistencetest"
+	"os"
+	"testing"
+)
+
+const testBindAddrEnv = "MONGODB_TEST_BIND_ADDR"
+
+// TestMongo_Contract runs the persistence contract tests against a local Mongo instance. Skipped unless
+// MONGODB_TEST_BIND_ADDR is set. The test database is dropped before each test case, then the rate limit indexes
+// created as they are at startup.
+func TestMongo_Contract(t *testing.T) {
+	m := newTestMongo(t)
+	defer m.Session.Close()
+
+	persistencetest.RunContractTests(t, func() persistence.Store {
+		dropTestDatabase(t, m)
+		if err := m.EnsureRateLimitIndexes(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		return m
+	})
+}
+
+// newTestMongo connect to the Mongo instance at MONGODB_TEST_BIND_ADDR, skipping the test if it is not set.
+func newTestMongo(tb testing.TB) *Mongo {
+	bindAddr := os.Getenv(testBindAddrEnv)
+	if bindAddr == "" {
+		tb.Skipf("%s not set skipping mongo tests", testBindAddrEnv)
+	}
+
+	m, err := New(config.MongoConfig{
+		BindAddr:            bindAddr,
+		Database:            "dp-identity-api-test",
+		IdentityCollection:  "identities",
+		TokenCollection:     "tokens",
+		AuditCollection:     "audit_events",
+		TombstoneCollection: "tombstones",
+		RateLimitCollection: "rate_limits",
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return m
+}
+
+func dropTestDatabase(tb testing.TB, m *Mongo) {
+	if err := m.Session.DB(m.Database).DropDatabase(); err != nil {
+		tb.Fatal(err)
+	}
+}
@@ -13,62 +13,223 @@ import (
 
 const (
 	identityIDKey = "identity_id"
+
+	// legacyTokenIDPattern matches token IDs stored in plain text before token digests were introduced.
+	legacyTokenIDPattern = "^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$"
 )
 
 // StoreToken store a new token document in mongodb tokens collection. Any active token associated with the identity
-// will be marked as deleted. Sets the last modified date on all documents updated.
+// and issued to the same impersonator will be marked as deleted. Sets the last modified date on all documents updated.
 func (m *Mongo) StoreToken(ctx context.Context, tkn schema.Token, i schema.Identity) error {
+	ctx, end := m.start(ctx, "StoreToken")
+	defer end()
+
 	logD := log.Data{identityIDKey: i.ID}
 	log.InfoCtx(ctx, "tokenStore: storing identity token", logD)
 
-	_, err := m.deleteTokens(ctx, i.ID)
+	_, err := m.deleteTokens(ctx, i.ID, replacedTokens(i.ID, tkn.ImpersonatedBy))
 	if err != nil {
+		if err == persistence.ErrTimeout || err == persistence.ErrUnavailable {
+			return err
+		}
 		return errors.Wrap(err, "error deleting tokens")
 	}
 
 	err = m.storeNewActiveToken(ctx, tkn)
 	if err != nil {
+		if err == persistence.ErrTimeout || err == persistence.ErrUnavailable {
+			return err
+		}
 		return errors.Wrap(err, "error storing new active token")
 	}
 	return nil
 }
 
+// GetIdentityByToken return the identity and active token for the provided token digest. The token and its identity
+// are retrieved in a single round trip by joining the identities collection onto the matching token.
 func (m *Mongo) GetIdentityByToken(ctx context.Context, token string) (*schema.Identity, *schema.Token, error) {
-	s := m.Session.Copy()
-	defer s.Close()
+	ctx, end := m.start(ctx, "GetIdentityByToken")
+	defer end()
+
+	pipeline := []bson.M{
+		{"$match": bson.M{"token_id": token, "deleted": false}},
+		{"$limit": 1},
+		{"$lookup": bson.M{
+			"from":         m.IdentityCollection,
+			"localField":   "identity_id",
+			"foreignField": "id",
+			"as":           "identities",
+		}},
+	}
 
-	t, err := m.getTokenByID(ctx, token)
-	if err != nil && err == persistence.ErrNotFound { // token does not exit
+	var result tokenWithIdentities
+	err := m.run(ctx, func(s *mgo.Session) error {
+		return s.DB(m.Database).C(m.TokenCollection).Pipe(pipeline).One(&result)
+	})
+
+	if err == mgo.ErrNotFound {
+		log.InfoCtx(ctx, "active token for this values does not exist", nil)
+		return nil, nil, persistence.ErrNotFound
+	}
+
+	if err == persistence.ErrTimeout || err == persistence.ErrUnavailable {
 		return nil, nil, err
 	}
 
 	// some other error querying fot token.
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "error getting token by ID")
+		return nil, nil, errors.Wrap(err, "error querying for active token and identity")
+	}
+
+	i := result.activeIdentity()
+	if i == nil {
+		return nil, nil, errors.Wrap(persistence.ErrNotFound, "error getting identity by ID")
+	}
+	return i, &result.Token, nil
+}
+
+// UpdateLastUsed set the last used time of the active token with the provided digest. Returns persistence.ErrNotFound
+// if no active token exists.
+func (m *Mongo) UpdateLastUsed(ctx context.Context, token string, lastUsed time.Time) error {
+	ctx, end := m.start(ctx, "UpdateLastUsed")
+	defer end()
+
+	query := bson.M{"token_id": token, "deleted": false}
+	update := bson.M{"$set": bson.M{"last_used": lastUsed}}
+
+	err := m.run(ctx, func(s *mgo.Session) error {
+		return s.DB(m.Database).C(m.TokenCollection).Update(query, update)
+	})
+
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return persistence.ErrNotFound
+		}
+		if err == persistence.ErrTimeout || err == persistence.ErrUnavailable {
+			return err
+		}
+		return errors.Wrap(err, "tokenStore: error updating token last used time")
+	}
+	return nil
+}
+
+// RevokeTokens soft delete the active tokens of the identity with the provided ID, returning their digests.
+func (m *Mongo) RevokeTokens(ctx context.Context, identityID string) ([]string, error) {
+	ctx, end := m.start(ctx, "RevokeTokens")
+	defer end()
+
+	active, err := m.getActiveTokensByIdentity(ctx, identityID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := m.deleteTokens(ctx, identityID, bson.M{"identity_id": identityID, "deleted": false}); err != nil {
+		return nil, err
 	}
 
-	i, err := m.GetIdentityByID(ctx, t.IdentityID)
+	revoked := make([]string, 0, len(active))
+	for _, t := range active {
+		revoked = append(revoked, t.ID)
+	}
+	return revoked, nil
+}
+
+// GetTokens return every token of the identity with the provided ID ordered by created date.
+func (m *Mongo) GetTokens(ctx context.Context, identityID string) ([]schema.Token, error) {
+	ctx, end := m.start(ctx, "GetTokens")
+	defer end()
+
+	tokens := []schema.Token{}
+	err := m.run(ctx, func(s *mgo.Session) error {
+		return s.DB(m.Database).C(m.TokenCollection).Find(bson.M{"identity_id": identityID}).Sort("created_date").All(&tokens)
+	})
+
+	if err != nil {
+		if err == persistence.ErrTimeout || err == persistence.ErrUnavailable {
+			return nil, err
+		}
+		return nil, errors.Wrap(err, "tokenStore: error querying for identity tokens")
+	}
+	return tokens, nil
+}
+
+// DeleteTokens remove every token of the identity with the provided ID, returning their digests.
+func (m *Mongo) DeleteTokens(ctx context.Context, identityID string) ([]string, error) {
+	ctx, end := m.start(ctx, "DeleteTokens")
+	defer end()
+
+	query := bson.M{"identity_id": identityID}
+
+	var deleted []string
+	err := m.run(ctx, func(s *mgo.Session) error {
+		c := s.DB(m.Database).C(m.TokenCollection)
+
+		var tokens []schema.Token
+		if err := c.Find(query).All(&tokens); err != nil {
+			return err
+		}
+
+		for _, t := range tokens {
+			deleted = append(deleted, t.ID)
+		}
+
+		_, err := c.RemoveAll(query)
+		return err
+	})
+
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "error getting identity by ID")
+		if err == persistence.ErrTimeout || err == persistence.ErrUnavailable {
+			return nil, err
+		}
+		return nil, errors.Wrap(err, "tokenStore: error removing identity tokens")
+	}
+	return deleted, nil
+}
+
+// tokenWithIdentities is a token document with the identity documents sharing its identity ID joined on.
+type tokenWithIdentities struct {
+	schema.Token `bson:",inline"`
+	Identities   []schema.Identity `bson:"identities"`
+}
+
+// activeIdentity return the identity that has not been deleted, or nil if there is none.
+func (t tokenWithIdentities) activeIdentity() *schema.Identity {
+	for idx := range t.Identities {
+		if !t.Identities[idx].Deleted {
+			return &t.Identities[idx]
+		}
+	}
+	return nil
+}
+
+// replacedTokens return the selector of the active tokens of the identity issued to the provided impersonator. Tokens
+// stored before impersonation was introduced have no impersonated_by field and were issued to the identity itself.
+func replacedTokens(identityID string, impersonatedBy string) bson.M {
+	selector := bson.M{"identity_id": identityID, "deleted": false, "impersonated_by": impersonatedBy}
+	if impersonatedBy == "" {
+		selector["impersonated_by"] = bson.M{"$in": []interface{}{"", nil}}
 	}
-	return i, t, nil
+	return selector
 }
 
-// deleteTokens soft delete any active token associated with the provided identity ID. Sets token.deleted = true and
-// updates token.last_modified to the current time. Returns the number of documents updated or any error encountered
-// while executing.
-func (m *Mongo) deleteTokens(ctx context.Context, identityID string) (int, error) {
+// deleteTokens soft delete the active tokens of the provided identity ID matching the selector. Sets token.deleted =
+// true and updates token.last_modified to the current time. Returns the number of documents updated or any error
+// encountered while executing.
+func (m *Mongo) deleteTokens(ctx context.Context, identityID string, selector bson.M) (int, error) {
 	logD := log.Data{identityIDKey: identityID}
 	log.InfoCtx(ctx, "tokenStore: deleting active token(s) for identity", logD)
 
-	s := m.Session.Copy()
-	defer s.Close()
-
-	selector := bson.M{"identity_id": identityID, "deleted": false}
 	update := bson.M{"$set": bson.M{"deleted": true, "last_modified": time.Now()}}
 
-	info, err := s.DB(m.Database).C(m.TokenCollection).UpdateAll(selector, update)
+	var info *mgo.ChangeInfo
+	err := m.run(ctx, func(s *mgo.Session) (err error) {
+		info, err = s.DB(m.Database).C(m.TokenCollection).UpdateAll(selector, update)
+		return err
+	})
 	if err != nil {
+		if err == persistence.ErrTimeout || err == persistence.ErrUnavailable {
+			return 0, err
+		}
 		return 0, errors.Wrap(err, "tokenStore: error deleting active token(s) for identity")
 	}
 
@@ -85,17 +246,19 @@ func (m *Mongo) deleteTokens(ctx context.Context, identityID string) (int, error
 // storeNewActiveToken store the provided token in the Tokens collection. Token will become the active token for this
 // identity.
 func (m *Mongo) storeNewActiveToken(ctx context.Context, tkn schema.Token) error {
-	s := m.Session.Copy()
-	defer s.Close()
-
 	logD := log.Data{identityIDKey: tkn.IdentityID}
 	log.InfoCtx(ctx, "tokenStore: storing new active identity token", logD)
 
 	tkn.LastModified = time.Now()
 	tkn.Deleted = false // always set to false to ensure this is now the active token.
 
-	err := s.DB(m.Database).C(m.TokenCollection).Insert(tkn)
+	err := m.run(ctx, func(s *mgo.Session) error {
+		return s.DB(m.Database).C(m.TokenCollection).Insert(tkn)
+	})
 	if err != nil {
+		if err == persistence.ErrTimeout || err == persistence.ErrUnavailable {
+			return err
+		}
 		return errors.Wrap(err, "tokenStore: error while storing new active identity token")
 	}
 
@@ -103,36 +266,61 @@ func (m *Mongo) storeNewActiveToken(ctx context.Context, tkn schema.Token) error
 	return nil
 }
 
-func (m *Mongo) getTokenByID(ctx context.Context, tokenID string) (*schema.Token, error) {
-	s := m.Session.Copy()
-	defer s.Close()
-
-	queryForToken := bson.M{"token_id": tokenID, "deleted": false}
-
-	var t schema.Token
-	if err := s.DB(m.Database).C(m.TokenCollection).Find(queryForToken).One(&t); err != nil {
-		if err == mgo.ErrNotFound {
-			log.InfoCtx(ctx, "active token for this values does not exist", nil)
-			return nil, persistence.ErrNotFound
-		}
-		return nil, errors.Wrap(err, "error querying for active token")
-	}
-	return &t, nil
-}
-
 // activeTokens return a list of tokens associated with the provided identity with "deleted = false".
 func (m *Mongo) getActiveTokensByIdentity(ctx context.Context, identityID string) ([]schema.Token, error) {
 	log.InfoCtx(ctx, "tokenStore: querying for active tokens", log.Data{identityIDKey: identityID})
-	s := m.Session.Copy()
-	defer s.Close()
-
 	query := bson.M{"identity_id": identityID, "deleted": false}
 
 	var active []schema.Token
-	err := s.DB(m.Database).C(m.TokenCollection).Find(query).All(&active)
+	err := m.run(ctx, func(s *mgo.Session) error {
+		return s.DB(m.Database).C(m.TokenCollection).Find(query).All(&active)
+	})
 	if err != nil {
+		if err == persistence.ErrTimeout || err == persistence.ErrUnavailable {
+			return nil, err
+		}
 		return nil, errors.Wrap(err, "tokenStore: query for active tokens returned an error")
 	}
 
 	return active, nil
 }
+
+// MigrateTokenDigests replaces the token ID of any token stored in plain text with its digest. Tokens stored before
+// digests were introduced will continue to work after the migration. Tokens are read in batches and tokens migrated
+// concurrently by another instance are skipped, so every instance can run the migration on startup. Returns the number
+// of tokens migrated.
+func (m *Mongo) MigrateTokenDigests(ctx context.Context, digest func(token string) string) (int, error) {
+	s, err := m.copySession(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer s.Close()
+
+	c := s.DB(m.Database).C(m.TokenCollection)
+	query := bson.M{"token_id": bson.M{"$regex": legacyTokenIDPattern}}
+
+	migrated := 0
+	iter := c.Find(query).Iter()
+
+	var t schema.Token
+	for iter.Next(&t) {
+		update := bson.M{"$set": bson.M{"token_id": digest(t.ID), "last_modified": time.Now()}}
+		err := c.Update(bson.M{"token_id": t.ID}, update)
+		if err == mgo.ErrNotFound {
+			// another instance starting at the same time has already migrated the token.
+			continue
+		}
+		if err != nil {
+			iter.Close()
+			return migrated, errors.Wrap(err, "tokenStore: error replacing plain text token with digest")
+		}
+		migrated++
+	}
+
+	if err := iter.Close(); err != nil {
+		return migrated, errors.Wrap(err, "tokenStore: error querying for plain text tokens")
+	}
+
+	log.InfoCtx(ctx, "tokenStore: migrate token digests completed without error", log.Data{"migrated": migrated})
+	return migrated, nil
+}
@@ -0,0 +1,159 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	. "github.com/smartystreets/goconvey/convey"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestMongo_MigrateTokenDigests is skipped unless MONGODB_TEST_BIND_ADDR is set.
+func TestMongo_MigrateTokenDigests(t *testing.T) {
+	m := newTestMongo(t)
+	defer m.Session.Close()
+
+	Convey("given tokens stored in plain text and as digests", t, func() {
+		dropTestDatabase(t, m)
+		ctx := context.Background()
+
+		digest := func(token string) string {
+			return "digest:" + token
+		}
+
+		const (
+			plainText = "0f8fad5b-d9cb-469f-a165-70867728950e"
+			digested  = "5e884898da28047151d0e56f8dc6292773603d0d6aabbdd62a11ef721d1542d8"
+		)
+
+		now := time.Now()
+		c := m.Session.DB(m.Database).C(m.TokenCollection)
+		for _, id := range []string{plainText, digested} {
+			tkn := schema.Token{ID: id, IdentityID: "666", CreatedDate: now, ExpiryDate: now.Add(time.Hour)}
+			So(c.Insert(tkn), ShouldBeNil)
+		}
+
+		tokenIDs := func() []string {
+			var tokens []schema.Token
+			So(c.Find(nil).Sort("created_date", "token_id").All(&tokens), ShouldBeNil)
+
+			ids := make([]string, 0, len(tokens))
+			for _, tkn := range tokens {
+				ids = append(ids, tkn.ID)
+			}
+			return ids
+		}
+
+		Convey("when the token digests are migrated", func() {
+			migrated, err := m.MigrateTokenDigests(ctx, digest)
+
+			Convey("then only the plain text token is replaced with its digest", func() {
+				So(err, ShouldBeNil)
+				So(migrated, ShouldEqual, 1)
+				So(tokenIDs(), ShouldResemble, []string{digested, digest(plainText)})
+			})
+
+			Convey("and migrating again makes no changes", func() {
+				migrated, err := m.MigrateTokenDigests(ctx, digest)
+
+				So(err, ShouldBeNil)
+				So(migrated, ShouldEqual, 0)
+				So(tokenIDs(), ShouldResemble, []string{digested, digest(plainText)})
+			})
+		})
+
+		Convey("when several instances migrate the token digests at the same time", func() {
+			for n := 0; n < 500; n++ {
+				id := fmt.Sprintf("%08x-0000-4000-8000-%012x", n, n)
+				tkn := schema.Token{ID: id, IdentityID: "666", CreatedDate: now, ExpiryDate: now.Add(time.Hour)}
+				So(c.Insert(tkn), ShouldBeNil)
+			}
+
+			var wg sync.WaitGroup
+			results := make([]int, 3)
+			errs := make([]error, 3)
+			for n := range results {
+				wg.Add(1)
+				go func(n int) {
+					defer wg.Done()
+					results[n], errs[n] = m.MigrateTokenDigests(ctx, digest)
+				}(n)
+			}
+			wg.Wait()
+
+			Convey("then every plain text token is migrated exactly once without error", func() {
+				So(errs, ShouldResemble, []error{nil, nil, nil})
+				So(results[0]+results[1]+results[2], ShouldEqual, 501)
+
+				remaining, err := c.Find(bson.M{"token_id": bson.M{"$regex": legacyTokenIDPattern}}).Count()
+				So(err, ShouldBeNil)
+				So(remaining, ShouldEqual, 0)
+			})
+		})
+	})
+}
+
+// BenchmarkGetIdentityByToken compares the single aggregation used by GetIdentityByToken against querying for the
+// token and then its identity. Reports the number of operations sent to Mongo per lookup alongside the latency.
+// Skipped unless MONGODB_TEST_BIND_ADDR is set.
+func BenchmarkGetIdentityByToken(b *testing.B) {
+	m := newTestMongo(b)
+	defer m.Session.Close()
+
+	dropTestDatabase(b, m)
+	ctx := context.Background()
+
+	id, err := m.SaveIdentity(ctx, schema.Identity{Name: "Peter Venkman", Email: "venkman@whoyougunnacall.com"})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	now := time.Now()
+	tkn := schema.Token{ID: "digest", IdentityID: id, CreatedDate: now, ExpiryDate: now.Add(time.Hour)}
+	if err := m.StoreToken(ctx, tkn, schema.Identity{ID: id}); err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("aggregation", func(b *testing.B) {
+		benchmarkLookup(b, func() error {
+			_, _, err := m.GetIdentityByToken(ctx, tkn.ID)
+			return err
+		})
+	})
+
+	b.Run("token then identity", func(b *testing.B) {
+		benchmarkLookup(b, func() error {
+			s := m.Session.Copy()
+			defer s.Close()
+
+			var t schema.Token
+			query := bson.M{"token_id": tkn.ID, "deleted": false}
+			if err := s.DB(m.Database).C(m.TokenCollection).Find(query).One(&t); err != nil {
+				return err
+			}
+
+			_, err := m.GetIdentityByID(ctx, t.IdentityID)
+			return err
+		})
+	})
+}
+
+func benchmarkLookup(b *testing.B, lookup func() error) {
+	mgo.SetStats(true)
+	defer mgo.SetStats(false)
+	mgo.ResetStats()
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if err := lookup(); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+
+	b.ReportMetric(float64(mgo.GetStats().SentOps)/float64(b.N), "ops/lookup")
+}
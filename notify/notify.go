@@ -0,0 +1,47 @@
+// Package notify provides implementations of identity.Notifier for sending verification and email change
+// tokens to identities.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/ONSdigital/go-ns/log"
+)
+
+const (
+	verifyURIFormat      = "%s/v1/identity/verify/%s"
+	emailChangeURIFormat = "%s/v1/identity/email-change/%s"
+)
+
+// Log is an identity.Notifier which logs the URL to verify a new identity instead of sending it. Intended for local
+// development only as the verification token is written to the logs.
+type Log struct {
+	Host string
+}
+
+// SendVerification log the URL to verify the identity.
+func (n Log) SendVerification(ctx context.Context, i schema.Identity, token string) error {
+	log.InfoCtx(ctx, "notify: verification token issued", log.Data{
+		"id":         i.ID,
+		"email":      i.Email,
+		"verify_uri": fmt.Sprintf(verifyURIFormat, n.Host, token),
+	})
+	return nil
+}
+
+// SendEmailChange log the URL to confirm the email change.
+func (n Log) SendEmailChange(ctx context.Context, i schema.Identity, email string, token string) error {
+	log.InfoCtx(ctx, "notify: email change token issued", log.Data{
+		"id":          i.ID,
+		"email":       email,
+		"confirm_uri": fmt.Sprintf(emailChangeURIFormat, n.Host, token),
+	})
+	return nil
+}
+
+// SendEmailChangeNotice log the notice sent to the identity's current email.
+func (n Log) SendEmailChangeNotice(ctx context.Context, i schema.Identity, email string) error {
+	log.InfoCtx(ctx, "notify: email change requested", log.Data{"id": i.ID, "email": i.Email, "new_email": email})
+	return nil
+}
@@ -0,0 +1,573 @@
+// Package memory provides an in-memory implementation of persistence.Store with the same semantics as the mongo
+// implementation. Intended for local development without Mongo and for tests, and as the rate limit store of a single
+// instance.
+package memory
+
+import (
+	"context"
+	"github.com/ONSdigital/dp-identity-api/persistence"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/pkg/errors"
+	"github.com/satori/go.uuid"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Store is an in-memory identity, token, audit event and rate limit store. All operations are atomic.
+type Store struct {
+	mutex      sync.RWMutex
+	identities []schema.Identity
+	tokens     []schema.Token
+	events     []schema.AuditEvent
+	tombstones []schema.Tombstone
+	rateLimits map[string]time.Time
+}
+
+// New construct a new empty in-memory Store.
+func New() *Store {
+	return &Store{
+		identities: []schema.Identity{},
+		tokens:     []schema.Token{},
+		events:     []schema.AuditEvent{},
+		tombstones: []schema.Tombstone{},
+		rateLimits: map[string]time.Time{},
+	}
+}
+
+// SaveIdentity store a new identity, returning the generated identity ID. Returns persistence.ErrNonUnique if an
+// active identity already exists with the same email.
+func (s *Store) SaveIdentity(ctx context.Context, identity schema.Identity) (string, error) {
+	if err := contextErr(ctx); err != nil {
+		return "", err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.activeIdentityByEmail(identity.Email) != nil {
+		return "", persistence.ErrNonUnique
+	}
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		return "", errors.Wrap(err, "error generating uuid")
+	}
+
+	identity.ID = id.String()
+	identity.CreatedDate = time.Now()
+
+	s.identities = append(s.identities, identity)
+	return identity.ID, nil
+}
+
+// GetIdentity return the active identity with the provided email. Returns persistence.ErrNotFound if no active
+// identity exists.
+func (s *Store) GetIdentity(ctx context.Context, email string) (schema.Identity, error) {
+	if err := contextErr(ctx); err != nil {
+		return schema.NilIdentity, err
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	i := s.activeIdentityByEmail(email)
+	if i == nil {
+		return schema.NilIdentity, persistence.ErrNotFound
+	}
+	return *i, nil
+}
+
+// GetIdentityByID return the active identity with the provided ID. Returns persistence.ErrNotFound if no active
+// identity exists.
+func (s *Store) GetIdentityByID(ctx context.Context, id string) (*schema.Identity, error) {
+	if err := contextErr(ctx); err != nil {
+		return nil, err
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	i := s.activeIdentityByID(id)
+	if i == nil {
+		return nil, persistence.ErrNotFound
+	}
+
+	result := *i
+	return &result, nil
+}
+
+// GetIdentityByIDIncludingDeleted return the identity with the provided ID whether or not it has been deleted. Returns
+// persistence.ErrNotFound if no identity exists.
+func (s *Store) GetIdentityByIDIncludingDeleted(ctx context.Context, id string) (*schema.Identity, error) {
+	if err := contextErr(ctx); err != nil {
+		return nil, err
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, i := range s.identities {
+		if i.ID == id {
+			result := i
+			return &result, nil
+		}
+	}
+	return nil, persistence.ErrNotFound
+}
+
+// UpdatePassword replace the stored password hash of the active identity with the provided ID and clear its migrated
+// flag.
+func (s *Store) UpdatePassword(ctx context.Context, id string, password string) error {
+	if err := contextErr(ctx); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	i := s.activeIdentityByID(id)
+	if i == nil {
+		return persistence.ErrNotFound
+	}
+
+	i.Password = password
+	i.Migrated = false
+	return nil
+}
+
+// VerifyIdentity mark the active identity issued the unexpired verification token with the provided digest as verified
+// and clear the token. Returns persistence.ErrNotFound if no such identity exists.
+func (s *Store) VerifyIdentity(ctx context.Context, token string) (*schema.Identity, error) {
+	if err := contextErr(ctx); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	for idx := range s.identities {
+		i := &s.identities[idx]
+		if i.VerificationToken == token && token != "" && !i.Deleted && i.VerificationExpiry.After(now) {
+			i.Verified = true
+			i.VerificationToken = ""
+			i.VerificationExpiry = time.Time{}
+
+			result := *i
+			return &result, nil
+		}
+	}
+	return nil, persistence.ErrNotFound
+}
+
+// SetPendingEmail record a requested email change for the active identity with the provided ID. Returns
+// persistence.ErrNotFound if no active identity exists.
+func (s *Store) SetPendingEmail(ctx context.Context, id string, email string, token string, expiry time.Time) error {
+	if err := contextErr(ctx); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	i := s.activeIdentityByID(id)
+	if i == nil {
+		return persistence.ErrNotFound
+	}
+
+	i.PendingEmail = email
+	i.EmailChangeToken = token
+	i.EmailChangeExpiry = expiry
+	return nil
+}
+
+// ConfirmEmailChange apply the pending email of the active identity issued the unexpired email change token with the
+// provided digest. Returns persistence.ErrNonUnique if the pending email is used by another active identity or
+// persistence.ErrNotFound if no such identity exists.
+func (s *Store) ConfirmEmailChange(ctx context.Context, token string) (*schema.Identity, error) {
+	if err := contextErr(ctx); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	for idx := range s.identities {
+		i := &s.identities[idx]
+		if i.EmailChangeToken != token || token == "" || i.Deleted || !i.EmailChangeExpiry.After(now) {
+			continue
+		}
+
+		if s.activeIdentityByEmail(i.PendingEmail) != nil {
+			return nil, persistence.ErrNonUnique
+		}
+
+		i.Email = i.PendingEmail
+		i.Verified = true
+		i.PendingEmail = ""
+		i.EmailChangeToken = ""
+		i.EmailChangeExpiry = time.Time{}
+
+		result := *i
+		return &result, nil
+	}
+	return nil, persistence.ErrNotFound
+}
+
+// DisableIdentity mark the active identity with the provided ID as disabled. Returns persistence.ErrNotFound if no
+// active identity exists.
+func (s *Store) DisableIdentity(ctx context.Context, id string, reason string, disabledBy string, date time.Time) error {
+	if err := contextErr(ctx); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	i := s.activeIdentityByID(id)
+	if i == nil {
+		return persistence.ErrNotFound
+	}
+
+	i.Disabled = true
+	i.DisabledReason = reason
+	i.DisabledBy = disabledBy
+	i.DisabledDate = &date
+	return nil
+}
+
+// EnableIdentity clear the disabled flag of the active identity with the provided ID. Returns persistence.ErrNotFound
+// if no active identity exists.
+func (s *Store) EnableIdentity(ctx context.Context, id string) error {
+	if err := contextErr(ctx); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	i := s.activeIdentityByID(id)
+	if i == nil {
+		return persistence.ErrNotFound
+	}
+
+	i.Disabled = false
+	i.DisabledReason = ""
+	i.DisabledBy = ""
+	i.DisabledDate = nil
+	return nil
+}
+
+// EraseIdentity remove the identity with the ID of the tombstone and store the tombstone. Returns
+// persistence.ErrNotFound if no identity exists.
+func (s *Store) EraseIdentity(ctx context.Context, tombstone schema.Tombstone) error {
+	if err := contextErr(ctx); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	remaining := make([]schema.Identity, 0, len(s.identities))
+	for _, i := range s.identities {
+		if i.ID != tombstone.ID {
+			remaining = append(remaining, i)
+		}
+	}
+
+	if len(remaining) == len(s.identities) {
+		return persistence.ErrNotFound
+	}
+
+	s.identities = remaining
+	s.tombstones = append(s.tombstones, tombstone)
+	return nil
+}
+
+// GetTombstone return the tombstone of the erased identity with the provided ID. Returns persistence.ErrNotFound if
+// the identity has not been erased.
+func (s *Store) GetTombstone(ctx context.Context, id string) (*schema.Tombstone, error) {
+	if err := contextErr(ctx); err != nil {
+		return nil, err
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, t := range s.tombstones {
+		if t.ID == id {
+			result := t
+			return &result, nil
+		}
+	}
+	return nil, persistence.ErrNotFound
+}
+
+// StoreToken store a new token. Any active token associated with the identity and issued to the same impersonator will
+// be marked as deleted. Sets the last modified date on all tokens updated.
+func (s *Store) StoreToken(ctx context.Context, tkn schema.Token, i schema.Identity) error {
+	if err := contextErr(ctx); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	for idx := range s.tokens {
+		if s.tokens[idx].IdentityID == i.ID && s.tokens[idx].ImpersonatedBy == tkn.ImpersonatedBy && !s.tokens[idx].Deleted {
+			s.tokens[idx].Deleted = true
+			s.tokens[idx].LastModified = now
+		}
+	}
+
+	tkn.LastModified = now
+	tkn.Deleted = false
+	s.tokens = append(s.tokens, tkn)
+	return nil
+}
+
+// UpdateLastUsed set the last used time of the active token with the provided digest. Returns persistence.ErrNotFound
+// if no active token exists.
+func (s *Store) UpdateLastUsed(ctx context.Context, token string, lastUsed time.Time) error {
+	if err := contextErr(ctx); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for idx := range s.tokens {
+		if s.tokens[idx].ID == token && !s.tokens[idx].Deleted {
+			s.tokens[idx].LastUsed = lastUsed
+			return nil
+		}
+	}
+	return persistence.ErrNotFound
+}
+
+// RevokeTokens mark the active tokens of the identity with the provided ID as deleted, returning their digests.
+func (s *Store) RevokeTokens(ctx context.Context, identityID string) ([]string, error) {
+	if err := contextErr(ctx); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	revoked := []string{}
+	for idx := range s.tokens {
+		if s.tokens[idx].IdentityID == identityID && !s.tokens[idx].Deleted {
+			s.tokens[idx].Deleted = true
+			s.tokens[idx].LastModified = now
+			revoked = append(revoked, s.tokens[idx].ID)
+		}
+	}
+	return revoked, nil
+}
+
+// GetTokens return every token of the identity with the provided ID ordered by created date.
+func (s *Store) GetTokens(ctx context.Context, identityID string) ([]schema.Token, error) {
+	if err := contextErr(ctx); err != nil {
+		return nil, err
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	tokens := []schema.Token{}
+	for _, t := range s.tokens {
+		if t.IdentityID == identityID {
+			tokens = append(tokens, t)
+		}
+	}
+
+	sort.SliceStable(tokens, func(i, j int) bool { return tokens[i].CreatedDate.Before(tokens[j].CreatedDate) })
+	return tokens, nil
+}
+
+// DeleteTokens remove every token of the identity with the provided ID, returning their digests.
+func (s *Store) DeleteTokens(ctx context.Context, identityID string) ([]string, error) {
+	if err := contextErr(ctx); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	deleted := []string{}
+	remaining := make([]schema.Token, 0, len(s.tokens))
+	for _, t := range s.tokens {
+		if t.IdentityID == identityID {
+			deleted = append(deleted, t.ID)
+			continue
+		}
+		remaining = append(remaining, t)
+	}
+
+	s.tokens = remaining
+	return deleted, nil
+}
+
+// StoreEvent store an audit event.
+func (s *Store) StoreEvent(ctx context.Context, event schema.AuditEvent) error {
+	if err := contextErr(ctx); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.events = append(s.events, event)
+	return nil
+}
+
+// GetEvents return the audit events about the identity with the provided ID ordered by created date.
+func (s *Store) GetEvents(ctx context.Context, identityID string) ([]schema.AuditEvent, error) {
+	if err := contextErr(ctx); err != nil {
+		return nil, err
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	events := []schema.AuditEvent{}
+	for _, e := range s.events {
+		if e.IdentityID == identityID {
+			events = append(events, e)
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Created.Before(events[j].Created) })
+	return events, nil
+}
+
+// PseudonymiseEvents remove the params of the audit events about the identity with the provided ID.
+func (s *Store) PseudonymiseEvents(ctx context.Context, identityID string) error {
+	if err := contextErr(ctx); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for idx := range s.events {
+		if s.events[idx].IdentityID == identityID {
+			s.events[idx].Params = nil
+		}
+	}
+	return nil
+}
+
+// GetIdentityByToken return the identity and active token for the provided token digest. Returns
+// persistence.ErrNotFound if no active token exists.
+func (s *Store) GetIdentityByToken(ctx context.Context, token string) (*schema.Identity, *schema.Token, error) {
+	if err := contextErr(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var t *schema.Token
+	for idx := range s.tokens {
+		if s.tokens[idx].ID == token && !s.tokens[idx].Deleted {
+			t = &s.tokens[idx]
+			break
+		}
+	}
+
+	if t == nil {
+		return nil, nil, persistence.ErrNotFound
+	}
+
+	i := s.activeIdentityByID(t.IdentityID)
+	if i == nil {
+		return nil, nil, errors.Wrap(persistence.ErrNotFound, "error getting identity by ID")
+	}
+
+	identity := *i
+	tkn := *t
+	return &identity, &tkn, nil
+}
+
+// GetRateLimit return the TAT of the rate limit bucket with the provided key, or the zero time if no bucket exists.
+func (s *Store) GetRateLimit(ctx context.Context, key string) (time.Time, error) {
+	if err := contextErr(ctx); err != nil {
+		return time.Time{}, err
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.rateLimits[key], nil
+}
+
+// SwapRateLimit set the TAT of the rate limit bucket with the provided key if its TAT equals old, or create the bucket
+// if old is zero and no bucket exists. Returns false if the bucket has changed.
+func (s *Store) SwapRateLimit(ctx context.Context, key string, old time.Time, tat time.Time) (bool, error) {
+	if err := contextErr(ctx); err != nil {
+		return false, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	current, ok := s.rateLimits[key]
+	if old.IsZero() == ok || (ok && !current.Equal(old)) {
+		return false, nil
+	}
+
+	s.rateLimits[key] = tat
+	return true, nil
+}
+
+// PurgeRateLimits remove the rate limit buckets with a TAT before the provided time.
+func (s *Store) PurgeRateLimits(ctx context.Context, before time.Time) error {
+	if err := contextErr(ctx); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for key, tat := range s.rateLimits {
+		if tat.Before(before) {
+			delete(s.rateLimits, key)
+		}
+	}
+	return nil
+}
+
+// contextErr return the persistence error matching the state of a done ctx, or nil if the ctx is not done.
+func contextErr(ctx context.Context) error {
+	switch ctx.Err() {
+	case nil:
+		return nil
+	case context.DeadlineExceeded:
+		return persistence.ErrTimeout
+	default:
+		return persistence.ErrUnavailable
+	}
+}
+
+func (s *Store) activeIdentityByEmail(email string) *schema.Identity {
+	for idx := range s.identities {
+		if s.identities[idx].Email == email && !s.identities[idx].Deleted {
+			return &s.identities[idx]
+		}
+	}
+	return nil
+}
+
+func (s *Store) activeIdentityByID(id string) *schema.Identity {
+	for idx := range s.identities {
+		if s.identities[idx].ID == id && !s.identities[idx].Deleted {
+			return &s.identities[idx]
+		}
+	}
+	return nil
+}
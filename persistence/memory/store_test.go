@@ -0,0 +1,13 @@
+package memory
+
+import (
+	"github.com/ONSdigital/dp-identity-api/persistence"
+	"github.com/ONSdigital/dp-identity-api/persistence/persistencetest"
+	"testing"
+)
+
+func TestStore_Contract(t *testing.T) {
+	persistencetest.RunContractTests(t, func() persistence.Store {
+		return New()
+	})
+}
@@ -4,22 +4,114 @@ import (
 	"context"
 	"errors"
 	"github.com/ONSdigital/dp-identity-api/schema"
+	"time"
 )
 
-//go:generate moq -out persistencetest/generate_mocks.go -pkg persistencetest . IdentityStore TokenStore
+//go:generate moq -out persistencetest/generate_mocks.go -pkg persistencetest . IdentityStore TokenStore AuditStore RateLimitStore
 
 var (
 	ErrNotFound  = errors.New("not found")
 	ErrNonUnique = errors.New("non unique")
+
+	// ErrTimeout is returned when a store operation does not complete before its deadline.
+	ErrTimeout = errors.New("store operation timed out")
+
+	// ErrUnavailable is returned when a store operation is cancelled or the store cannot be reached.
+	ErrUnavailable = errors.New("store unavailable")
 )
 
 // IdentityStore...
+//
+// GetIdentityByIDIncludingDeleted returns the identity with the provided ID whether or not it has been deleted, for
+// operations on all the data held about an identity. Returns ErrNotFound if there is no such identity.
+//
+// UpdatePassword replaces the password hash of an identity and clears its migrated flag, as the new hash is no longer
+// a legacy hash.
+//
+// VerifyIdentity marks the active identity issued the verification token with the provided digest as verified and
+// clears the token, so it can only be used once. Returns ErrNotFound if there is no such identity or the token has
+// expired.
+//
+// SetPendingEmail records a requested email change for the active identity with the provided ID, replacing any
+// previous request. ConfirmEmailChange applies the pending email of the active identity issued the unexpired email
+// change token with the provided digest, marking it verified. Returns ErrNonUnique if the pending email is now used by
+// another active identity, the same check as SaveIdentity, or ErrNotFound if there is no such identity.
+//
+// DisableIdentity marks the active identity with the provided ID as disabled, recording the reason, the ID of the
+// identity disabling it and the date. EnableIdentity clears the disabled flag and these details. Both return
+// ErrNotFound if there is no such identity.
+//
+// EraseIdentity permanently removes the identity with the ID of the tombstone, whether or not it has been deleted, and
+// stores the tombstone in its place. Returns ErrNotFound if there is no such identity. GetTombstone returns the
+// tombstone of an erased identity or ErrNotFound if the identity has not been erased.
+//
+// Implementations must abandon an operation once the ctx is done, returning ErrTimeout if its deadline was exceeded or
+// ErrUnavailable if it was cancelled.
 type IdentityStore interface {
-	SaveIdentity(newIdentity schema.Identity) (string, error)
-	GetIdentity(email string) (schema.Identity, error)
+	SaveIdentity(ctx context.Context, newIdentity schema.Identity) (string, error)
+	GetIdentity(ctx context.Context, email string) (schema.Identity, error)
+	UpdatePassword(ctx context.Context, id string, password string) error
+	VerifyIdentity(ctx context.Context, token string) (*schema.Identity, error)
+	GetIdentityByID(ctx context.Context, id string) (*schema.Identity, error)
+	GetIdentityByIDIncludingDeleted(ctx context.Context, id string) (*schema.Identity, error)
+	SetPendingEmail(ctx context.Context, id string, email string, token string, expiry time.Time) error
+	ConfirmEmailChange(ctx context.Context, token string) (*schema.Identity, error)
+	DisableIdentity(ctx context.Context, id string, reason string, disabledBy string, date time.Time) error
+	EnableIdentity(ctx context.Context, id string) error
+	EraseIdentity(ctx context.Context, tombstone schema.Tombstone) error
+	GetTombstone(ctx context.Context, id string) (*schema.Tombstone, error)
+}
+
+// Store is a persistence backend providing identity, token, audit event and rate limit storage.
+type Store interface {
+	IdentityStore
+	TokenStore
+	AuditStore
+	RateLimitStore
 }
 
+// TokenStore stores tokens against the digest of the token, the plain text token is never provided to the store.
+//
+// StoreToken replaces the active token of the identity with the same ImpersonatedBy, so storing an impersonation token
+// does not replace the token issued to the identity itself.
+//
+// UpdateLastUsed records the time the active token with the provided digest was last used. Returns ErrNotFound if
+// there is no active token.
+//
+// RevokeTokens soft deletes the active tokens of an identity, returning the digest of each token revoked.
+//
+// GetTokens returns every token of an identity, including revoked and replaced tokens, ordered by created date.
+// DeleteTokens permanently removes every token of an identity, returning the digest of each token removed.
 type TokenStore interface {
 	StoreToken(ctx context.Context, token schema.Token, i schema.Identity) error
 	GetIdentityByToken(ctx context.Context, token string) (*schema.Identity, *schema.Token, error)
+	UpdateLastUsed(ctx context.Context, token string, lastUsed time.Time) error
+	RevokeTokens(ctx context.Context, identityID string) ([]string, error)
+	GetTokens(ctx context.Context, identityID string) ([]schema.Token, error)
+	DeleteTokens(ctx context.Context, identityID string) ([]string, error)
+}
+
+// AuditStore stores the audit events about each identity.
+//
+// GetEvents returns the events about an identity ordered by created date. PseudonymiseEvents removes the params of
+// the events about an identity, which may contain personal data, retaining the action, result and created date.
+type AuditStore interface {
+	StoreEvent(ctx context.Context, event schema.AuditEvent) error
+	GetEvents(ctx context.Context, identityID string) ([]schema.AuditEvent, error)
+	PseudonymiseEvents(ctx context.Context, identityID string) error
+}
+
+// RateLimitStore stores the theoretical arrival time (TAT) of each rate limit bucket, shared by every instance using the
+// store.
+//
+// GetRateLimit returns the TAT of the bucket with the provided key, or the zero time if there is no such bucket.
+//
+// SwapRateLimit replaces the TAT of a bucket only if it still equals old, returning false if another caller changed it
+// first. A zero old creates the bucket, returning false if it already exists.
+//
+// PurgeRateLimits removes the buckets with a TAT before the provided time, as these no longer limit any request.
+type RateLimitStore interface {
+	GetRateLimit(ctx context.Context, key string) (time.Time, error)
+	SwapRateLimit(ctx context.Context, key string, old time.Time, tat time.Time) (bool, error)
+	PurgeRateLimits(ctx context.Context, before time.Time) error
 }
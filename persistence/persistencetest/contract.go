@@ -0,0 +1,653 @@
+package persistencetest
+
+import (
+	"context"
+	"github.com/ONSdigital/dp-identity-api/persistence"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+// RunContractTests runs the behavioural tests every persistence.Store implementation must pass. newStore is called at
+// the start of each test case and must return an empty store.
+func RunContractTests(t *testing.T, newStore func() persistence.Store) {
+	ctx := context.Background()
+
+	Convey("given an empty store", t, func() {
+		store := newStore()
+		venkman := schema.Identity{Name: "Peter Venkman", Email: "venkman@whoyougunnacall.com", Password: "hash"}
+
+		Convey("when an identity is saved", func() {
+			id, err := store.SaveIdentity(ctx, venkman)
+			So(err, ShouldBeNil)
+			So(id, ShouldNotBeEmpty)
+
+			Convey("then it can be retrieved by email with a generated ID and created date", func() {
+				i, err := store.GetIdentity(ctx, venkman.Email)
+				So(err, ShouldBeNil)
+				So(i.ID, ShouldEqual, id)
+				So(i.Name, ShouldEqual, venkman.Name)
+				So(i.Password, ShouldEqual, venkman.Password)
+				So(i.CreatedDate.IsZero(), ShouldBeFalse)
+			})
+
+			Convey("and another active identity with the same email cannot be saved", func() {
+				_, err := store.SaveIdentity(ctx, venkman)
+				So(err, ShouldEqual, persistence.ErrNonUnique)
+			})
+
+			Convey("and its password can be updated, clearing the migrated flag", func() {
+				So(store.UpdatePassword(ctx, id, "new hash"), ShouldBeNil)
+
+				i, err := store.GetIdentity(ctx, venkman.Email)
+				So(err, ShouldBeNil)
+				So(i.Password, ShouldEqual, "new hash")
+				So(i.Migrated, ShouldBeFalse)
+			})
+		})
+
+		Convey("when getting an identity that does not exist", func() {
+			_, err := store.GetIdentity(ctx, venkman.Email)
+
+			Convey("then persistence.ErrNotFound is returned", func() {
+				So(err, ShouldEqual, persistence.ErrNotFound)
+			})
+		})
+
+		Convey("when updating the password of an identity that does not exist", func() {
+			err := store.UpdatePassword(ctx, "666", "hash")
+
+			Convey("then persistence.ErrNotFound is returned", func() {
+				So(err, ShouldEqual, persistence.ErrNotFound)
+			})
+		})
+
+		Convey("when a soft deleted identity is saved", func() {
+			deleted := venkman
+			deleted.Deleted = true
+
+			id, err := store.SaveIdentity(ctx, deleted)
+			So(err, ShouldBeNil)
+
+			Convey("then it cannot be retrieved", func() {
+				_, err := store.GetIdentity(ctx, venkman.Email)
+				So(err, ShouldEqual, persistence.ErrNotFound)
+
+				_, err = store.GetIdentityByID(ctx, id)
+				So(err, ShouldEqual, persistence.ErrNotFound)
+			})
+
+			Convey("and it can be retrieved by ID including deleted identities", func() {
+				i, err := store.GetIdentityByIDIncludingDeleted(ctx, id)
+				So(err, ShouldBeNil)
+				So(i.ID, ShouldEqual, id)
+				So(i.Email, ShouldEqual, venkman.Email)
+				So(i.Deleted, ShouldBeTrue)
+			})
+
+			Convey("and its email is available for reuse", func() {
+				_, err := store.SaveIdentity(ctx, venkman)
+				So(err, ShouldBeNil)
+			})
+		})
+
+		Convey("when an identity is saved with a verification token", func() {
+			unverified := venkman
+			unverified.VerificationToken = "verify"
+			unverified.VerificationExpiry = time.Now().Add(time.Hour)
+
+			id, err := store.SaveIdentity(ctx, unverified)
+			So(err, ShouldBeNil)
+
+			Convey("then it is not verified", func() {
+				i, err := store.GetIdentity(ctx, venkman.Email)
+				So(err, ShouldBeNil)
+				So(i.Verified, ShouldBeFalse)
+			})
+
+			Convey("and it can be verified using the token", func() {
+				i, err := store.VerifyIdentity(ctx, "verify")
+				So(err, ShouldBeNil)
+				So(i.ID, ShouldEqual, id)
+				So(i.Verified, ShouldBeTrue)
+
+				stored, err := store.GetIdentity(ctx, venkman.Email)
+				So(err, ShouldBeNil)
+				So(stored.Verified, ShouldBeTrue)
+			})
+
+			Convey("and the token can only be used once", func() {
+				_, err := store.VerifyIdentity(ctx, "verify")
+				So(err, ShouldBeNil)
+
+				_, err = store.VerifyIdentity(ctx, "verify")
+				So(err, ShouldEqual, persistence.ErrNotFound)
+			})
+
+			Convey("and a different token does not verify it", func() {
+				_, err := store.VerifyIdentity(ctx, "666")
+				So(err, ShouldEqual, persistence.ErrNotFound)
+			})
+		})
+
+		Convey("when an identity is saved with an expired verification token", func() {
+			expired := venkman
+			expired.VerificationToken = "expired"
+			expired.VerificationExpiry = time.Now().Add(-time.Minute)
+
+			_, err := store.SaveIdentity(ctx, expired)
+			So(err, ShouldBeNil)
+
+			Convey("then it cannot be verified", func() {
+				_, err := store.VerifyIdentity(ctx, "expired")
+				So(err, ShouldEqual, persistence.ErrNotFound)
+
+				i, err := store.GetIdentity(ctx, venkman.Email)
+				So(err, ShouldBeNil)
+				So(i.Verified, ShouldBeFalse)
+			})
+		})
+
+		Convey("when an email change is requested for an identity", func() {
+			id, err := store.SaveIdentity(ctx, venkman)
+			So(err, ShouldBeNil)
+
+			newEmail := "peter@whoyougunnacall.com"
+			So(store.SetPendingEmail(ctx, id, newEmail, "change", time.Now().Add(time.Hour)), ShouldBeNil)
+
+			Convey("then the email is not changed until it is confirmed", func() {
+				i, err := store.GetIdentity(ctx, venkman.Email)
+				So(err, ShouldBeNil)
+				So(i.ID, ShouldEqual, id)
+			})
+
+			Convey("and confirming the change applies the pending email and marks it verified", func() {
+				i, err := store.ConfirmEmailChange(ctx, "change")
+				So(err, ShouldBeNil)
+				So(i.ID, ShouldEqual, id)
+				So(i.Email, ShouldEqual, newEmail)
+				So(i.Verified, ShouldBeTrue)
+
+				_, err = store.GetIdentity(ctx, venkman.Email)
+				So(err, ShouldEqual, persistence.ErrNotFound)
+
+				stored, err := store.GetIdentityByID(ctx, id)
+				So(err, ShouldBeNil)
+				So(stored.Email, ShouldEqual, newEmail)
+			})
+
+			Convey("and the change can only be confirmed once", func() {
+				_, err := store.ConfirmEmailChange(ctx, "change")
+				So(err, ShouldBeNil)
+
+				_, err = store.ConfirmEmailChange(ctx, "change")
+				So(err, ShouldEqual, persistence.ErrNotFound)
+			})
+
+			Convey("and the change cannot be confirmed if another active identity now uses the email", func() {
+				_, err := store.SaveIdentity(ctx, schema.Identity{Name: "Peter", Email: newEmail, Password: "hash"})
+				So(err, ShouldBeNil)
+
+				_, err = store.ConfirmEmailChange(ctx, "change")
+				So(err, ShouldEqual, persistence.ErrNonUnique)
+
+				i, err := store.GetIdentityByID(ctx, id)
+				So(err, ShouldBeNil)
+				So(i.Email, ShouldEqual, venkman.Email)
+			})
+		})
+
+		Convey("when an expired email change is confirmed", func() {
+			id, err := store.SaveIdentity(ctx, venkman)
+			So(err, ShouldBeNil)
+			So(store.SetPendingEmail(ctx, id, "peter@whoyougunnacall.com", "expired", time.Now().Add(-time.Minute)), ShouldBeNil)
+
+			_, err = store.ConfirmEmailChange(ctx, "expired")
+
+			Convey("then persistence.ErrNotFound is returned", func() {
+				So(err, ShouldEqual, persistence.ErrNotFound)
+			})
+		})
+
+		Convey("when an email change is requested for an identity that does not exist", func() {
+			err := store.SetPendingEmail(ctx, "666", "peter@whoyougunnacall.com", "change", time.Now().Add(time.Hour))
+
+			Convey("then persistence.ErrNotFound is returned", func() {
+				So(err, ShouldEqual, persistence.ErrNotFound)
+			})
+		})
+
+		Convey("when an identity is disabled", func() {
+			id, err := store.SaveIdentity(ctx, venkman)
+			So(err, ShouldBeNil)
+
+			disabledDate := time.Now()
+			So(store.DisableIdentity(ctx, id, "under investigation", "admin", disabledDate), ShouldBeNil)
+
+			Convey("then it is disabled with the reason, actor and date but still active", func() {
+				i, err := store.GetIdentity(ctx, venkman.Email)
+				So(err, ShouldBeNil)
+				So(i.ID, ShouldEqual, id)
+				So(i.Disabled, ShouldBeTrue)
+				So(i.DisabledReason, ShouldEqual, "under investigation")
+				So(i.DisabledBy, ShouldEqual, "admin")
+				So(i.DisabledDate, ShouldNotBeNil)
+				So(*i.DisabledDate, ShouldHappenWithin, time.Millisecond, disabledDate)
+			})
+
+			Convey("and its email is not available to a new identity", func() {
+				_, err := store.SaveIdentity(ctx, venkman)
+				So(err, ShouldEqual, persistence.ErrNonUnique)
+			})
+
+			Convey("and enabling it clears the disabled flag and details", func() {
+				So(store.EnableIdentity(ctx, id), ShouldBeNil)
+
+				i, err := store.GetIdentityByID(ctx, id)
+				So(err, ShouldBeNil)
+				So(i.Disabled, ShouldBeFalse)
+				So(i.DisabledReason, ShouldBeEmpty)
+				So(i.DisabledBy, ShouldBeEmpty)
+				So(i.DisabledDate, ShouldBeNil)
+			})
+		})
+
+		Convey("when an identity that does not exist is disabled or enabled", func() {
+			Convey("then persistence.ErrNotFound is returned", func() {
+				So(store.DisableIdentity(ctx, "666", "under investigation", "admin", time.Now()), ShouldEqual, persistence.ErrNotFound)
+				So(store.EnableIdentity(ctx, "666"), ShouldEqual, persistence.ErrNotFound)
+			})
+		})
+
+		Convey("when a token is stored for an identity", func() {
+			id, err := store.SaveIdentity(ctx, venkman)
+			So(err, ShouldBeNil)
+			venkman.ID = id
+
+			first := newContractToken("first", id)
+			So(store.StoreToken(ctx, first, venkman), ShouldBeNil)
+
+			Convey("then the identity and token can be retrieved by the token", func() {
+				i, tkn, err := store.GetIdentityByToken(ctx, first.ID)
+				So(err, ShouldBeNil)
+				So(i.ID, ShouldEqual, id)
+				So(i.Email, ShouldEqual, venkman.Email)
+				So(tkn.ID, ShouldEqual, first.ID)
+				So(tkn.IdentityID, ShouldEqual, id)
+				So(tkn.Deleted, ShouldBeFalse)
+			})
+
+			Convey("and storing a new token for the identity replaces the active token", func() {
+				second := newContractToken("second", id)
+				So(store.StoreToken(ctx, second, venkman), ShouldBeNil)
+
+				_, _, err := store.GetIdentityByToken(ctx, first.ID)
+				So(err, ShouldEqual, persistence.ErrNotFound)
+
+				_, tkn, err := store.GetIdentityByToken(ctx, second.ID)
+				So(err, ShouldBeNil)
+				So(tkn.ID, ShouldEqual, second.ID)
+			})
+
+			Convey("and the last used time of the active token can be updated", func() {
+				lastUsed := time.Now().Add(time.Minute)
+				So(store.UpdateLastUsed(ctx, first.ID, lastUsed), ShouldBeNil)
+
+				_, tkn, err := store.GetIdentityByToken(ctx, first.ID)
+				So(err, ShouldBeNil)
+				So(tkn.LastUsed, ShouldHappenWithin, time.Millisecond, lastUsed)
+			})
+
+			Convey("and the last used time of a replaced token cannot be updated", func() {
+				So(store.StoreToken(ctx, newContractToken("second", id), venkman), ShouldBeNil)
+				So(store.UpdateLastUsed(ctx, first.ID, time.Now()), ShouldEqual, persistence.ErrNotFound)
+			})
+
+			Convey("and revoking the identity's tokens returns their digests and deletes them", func() {
+				revoked, err := store.RevokeTokens(ctx, id)
+				So(err, ShouldBeNil)
+				So(revoked, ShouldResemble, []string{first.ID})
+
+				_, _, err = store.GetIdentityByToken(ctx, first.ID)
+				So(err, ShouldEqual, persistence.ErrNotFound)
+
+				revoked, err = store.RevokeTokens(ctx, id)
+				So(err, ShouldBeNil)
+				So(revoked, ShouldBeEmpty)
+			})
+
+			Convey("and every token of the identity can be retrieved including replaced tokens", func() {
+				second := newContractToken("second", id)
+				second.CreatedDate = first.CreatedDate.Add(time.Minute)
+				So(store.StoreToken(ctx, second, venkman), ShouldBeNil)
+
+				tokens, err := store.GetTokens(ctx, id)
+				So(err, ShouldBeNil)
+				So(tokens, ShouldHaveLength, 2)
+				So(tokens[0].ID, ShouldEqual, first.ID)
+				So(tokens[0].Deleted, ShouldBeTrue)
+				So(tokens[1].ID, ShouldEqual, second.ID)
+				So(tokens[1].Deleted, ShouldBeFalse)
+			})
+
+			Convey("and deleting the identity's tokens returns their digests and removes them", func() {
+				So(store.StoreToken(ctx, newContractToken("second", id), venkman), ShouldBeNil)
+
+				deleted, err := store.DeleteTokens(ctx, id)
+				So(err, ShouldBeNil)
+				So(deleted, ShouldHaveLength, 2)
+				So(deleted, ShouldContain, first.ID)
+				So(deleted, ShouldContain, "second")
+
+				tokens, err := store.GetTokens(ctx, id)
+				So(err, ShouldBeNil)
+				So(tokens, ShouldBeEmpty)
+			})
+
+			Convey("and tokens for other identities are unaffected", func() {
+				stantz := schema.Identity{Name: "Ray Stantz", Email: "stantz@whoyougunnacall.com", Password: "hash"}
+				stantz.ID, err = store.SaveIdentity(ctx, stantz)
+				So(err, ShouldBeNil)
+
+				So(store.StoreToken(ctx, newContractToken("other", stantz.ID), stantz), ShouldBeNil)
+
+				_, tkn, err := store.GetIdentityByToken(ctx, first.ID)
+				So(err, ShouldBeNil)
+				So(tkn.ID, ShouldEqual, first.ID)
+			})
+
+			Convey("and storing an impersonation token does not replace the identity's own token", func() {
+				impersonation := newContractToken("impersonation", id)
+				impersonation.ImpersonatedBy = "999"
+				So(store.StoreToken(ctx, impersonation, venkman), ShouldBeNil)
+
+				i, tkn, err := store.GetIdentityByToken(ctx, impersonation.ID)
+				So(err, ShouldBeNil)
+				So(i.ID, ShouldEqual, id)
+				So(tkn.ImpersonatedBy, ShouldEqual, "999")
+
+				_, tkn, err = store.GetIdentityByToken(ctx, first.ID)
+				So(err, ShouldBeNil)
+				So(tkn.ImpersonatedBy, ShouldBeEmpty)
+
+				Convey("and a new impersonation token by the same admin replaces it", func() {
+					second := newContractToken("second-impersonation", id)
+					second.ImpersonatedBy = "999"
+					So(store.StoreToken(ctx, second, venkman), ShouldBeNil)
+
+					_, _, err := store.GetIdentityByToken(ctx, impersonation.ID)
+					So(err, ShouldEqual, persistence.ErrNotFound)
+
+					_, _, err = store.GetIdentityByToken(ctx, first.ID)
+					So(err, ShouldBeNil)
+				})
+
+				Convey("and revoking the identity's tokens revokes both", func() {
+					revoked, err := store.RevokeTokens(ctx, id)
+					So(err, ShouldBeNil)
+					So(revoked, ShouldHaveLength, 2)
+				})
+			})
+		})
+
+		Convey("when audit events are stored for identities", func() {
+			created := time.Now().UTC().Truncate(time.Millisecond)
+			first := schema.AuditEvent{IdentityID: "666", Action: "createToken", Result: "successful", Params: map[string]string{"email": venkman.Email}, Created: created}
+			second := schema.AuditEvent{IdentityID: "666", Action: "disableIdentity", Result: "successful", Created: created.Add(time.Minute)}
+			other := schema.AuditEvent{IdentityID: "999", Action: "createToken", Result: "successful", Params: map[string]string{"email": "stantz@whoyougunnacall.com"}, Created: created}
+
+			So(store.StoreEvent(ctx, second), ShouldBeNil)
+			So(store.StoreEvent(ctx, first), ShouldBeNil)
+			So(store.StoreEvent(ctx, other), ShouldBeNil)
+
+			Convey("then the events about an identity are returned ordered by created date", func() {
+				events, err := store.GetEvents(ctx, "666")
+				So(err, ShouldBeNil)
+				So(events, ShouldHaveLength, 2)
+				So(events[0].Action, ShouldEqual, first.Action)
+				So(events[0].Params, ShouldResemble, first.Params)
+				So(events[0].Created, ShouldHappenWithin, time.Millisecond, first.Created)
+				So(events[1].Action, ShouldEqual, second.Action)
+				So(events[1].Params, ShouldBeEmpty)
+			})
+
+			Convey("and pseudonymising the events of an identity removes their params", func() {
+				So(store.PseudonymiseEvents(ctx, "666"), ShouldBeNil)
+
+				events, err := store.GetEvents(ctx, "666")
+				So(err, ShouldBeNil)
+				So(events, ShouldHaveLength, 2)
+				So(events[0].Action, ShouldEqual, first.Action)
+				So(events[0].Params, ShouldBeEmpty)
+
+				events, err = store.GetEvents(ctx, "999")
+				So(err, ShouldBeNil)
+				So(events[0].Params, ShouldResemble, other.Params)
+			})
+		})
+
+		Convey("when an identity is erased", func() {
+			id, err := store.SaveIdentity(ctx, venkman)
+			So(err, ShouldBeNil)
+
+			tombstone := schema.Tombstone{ID: id, ErasedBy: "admin", ErasedDate: time.Now()}
+			So(store.EraseIdentity(ctx, tombstone), ShouldBeNil)
+
+			Convey("then the identity is removed", func() {
+				_, err := store.GetIdentityByID(ctx, id)
+				So(err, ShouldEqual, persistence.ErrNotFound)
+
+				_, err = store.GetIdentity(ctx, venkman.Email)
+				So(err, ShouldEqual, persistence.ErrNotFound)
+
+				So(store.EraseIdentity(ctx, tombstone), ShouldEqual, persistence.ErrNotFound)
+			})
+
+			Convey("and the tombstone is stored", func() {
+				t, err := store.GetTombstone(ctx, id)
+				So(err, ShouldBeNil)
+				So(t.ID, ShouldEqual, id)
+				So(t.ErasedBy, ShouldEqual, "admin")
+				So(t.ErasedDate, ShouldHappenWithin, time.Millisecond, tombstone.ErasedDate)
+			})
+		})
+
+		Convey("when an identity that does not exist is erased", func() {
+			err := store.EraseIdentity(ctx, schema.Tombstone{ID: "666", ErasedBy: "admin", ErasedDate: time.Now()})
+
+			Convey("then persistence.ErrNotFound is returned and no tombstone is stored", func() {
+				So(err, ShouldEqual, persistence.ErrNotFound)
+
+				_, err := store.GetTombstone(ctx, "666")
+				So(err, ShouldEqual, persistence.ErrNotFound)
+			})
+		})
+
+		Convey("when a rate limit bucket is created", func() {
+			tat := time.Now().Truncate(time.Millisecond)
+			swapped, err := store.SwapRateLimit(ctx, "ip:127.0.0.1", time.Time{}, tat)
+			So(err, ShouldBeNil)
+			So(swapped, ShouldBeTrue)
+
+			Convey("then its TAT can be retrieved", func() {
+				stored, err := store.GetRateLimit(ctx, "ip:127.0.0.1")
+				So(err, ShouldBeNil)
+				So(stored.Equal(tat), ShouldBeTrue)
+			})
+
+			Convey("and creating it again is not swapped", func() {
+				swapped, err := store.SwapRateLimit(ctx, "ip:127.0.0.1", time.Time{}, tat.Add(time.Second))
+				So(err, ShouldBeNil)
+				So(swapped, ShouldBeFalse)
+			})
+
+			Convey("and its TAT can be swapped if unchanged", func() {
+				next := tat.Add(time.Second)
+				swapped, err := store.SwapRateLimit(ctx, "ip:127.0.0.1", tat, next)
+				So(err, ShouldBeNil)
+				So(swapped, ShouldBeTrue)
+
+				stored, err := store.GetRateLimit(ctx, "ip:127.0.0.1")
+				So(err, ShouldBeNil)
+				So(stored.Equal(next), ShouldBeTrue)
+
+				Convey("and swapping the previous TAT again is not swapped", func() {
+					swapped, err := store.SwapRateLimit(ctx, "ip:127.0.0.1", tat, next.Add(time.Second))
+					So(err, ShouldBeNil)
+					So(swapped, ShouldBeFalse)
+				})
+			})
+
+			Convey("and purging buckets before its TAT does not remove it", func() {
+				So(store.PurgeRateLimits(ctx, tat.Add(-time.Second)), ShouldBeNil)
+
+				stored, err := store.GetRateLimit(ctx, "ip:127.0.0.1")
+				So(err, ShouldBeNil)
+				So(stored.Equal(tat), ShouldBeTrue)
+			})
+
+			Convey("and purging buckets after its TAT removes it", func() {
+				So(store.PurgeRateLimits(ctx, tat.Add(time.Second)), ShouldBeNil)
+
+				stored, err := store.GetRateLimit(ctx, "ip:127.0.0.1")
+				So(err, ShouldBeNil)
+				So(stored.IsZero(), ShouldBeTrue)
+			})
+		})
+
+		Convey("when getting a rate limit bucket that does not exist", func() {
+			tat, err := store.GetRateLimit(ctx, "ip:127.0.0.1")
+
+			Convey("then the zero time is returned", func() {
+				So(err, ShouldBeNil)
+				So(tat.IsZero(), ShouldBeTrue)
+			})
+		})
+
+		Convey("when swapping a rate limit bucket that does not exist", func() {
+			swapped, err := store.SwapRateLimit(ctx, "ip:127.0.0.1", time.Now(), time.Now())
+
+			Convey("then it is not swapped", func() {
+				So(err, ShouldBeNil)
+				So(swapped, ShouldBeFalse)
+			})
+		})
+
+		Convey("when getting an identity by a token that does not exist", func() {
+			_, _, err := store.GetIdentityByToken(ctx, "666")
+
+			Convey("then persistence.ErrNotFound is returned", func() {
+				So(err, ShouldEqual, persistence.ErrNotFound)
+			})
+		})
+
+		Convey("when getting an identity by a token whose identity does not exist", func() {
+			So(store.StoreToken(ctx, newContractToken("orphan", "666"), schema.Identity{ID: "666"}), ShouldBeNil)
+			_, _, err := store.GetIdentityByToken(ctx, "orphan")
+
+			Convey("then an error caused by persistence.ErrNotFound is returned", func() {
+				So(errors.Cause(err), ShouldEqual, persistence.ErrNotFound)
+			})
+		})
+
+		Convey("when the context is cancelled before an operation", func() {
+			cancelled, cancel := context.WithCancel(ctx)
+			cancel()
+
+			Convey("then every operation returns persistence.ErrUnavailable", func() {
+				_, err := store.SaveIdentity(cancelled, venkman)
+				So(errors.Cause(err), ShouldEqual, persistence.ErrUnavailable)
+
+				_, err = store.GetIdentity(cancelled, venkman.Email)
+				So(errors.Cause(err), ShouldEqual, persistence.ErrUnavailable)
+
+				_, err = store.GetIdentityByIDIncludingDeleted(cancelled, "666")
+				So(errors.Cause(err), ShouldEqual, persistence.ErrUnavailable)
+
+				err = store.UpdatePassword(cancelled, "666", "hash")
+				So(errors.Cause(err), ShouldEqual, persistence.ErrUnavailable)
+
+				_, err = store.VerifyIdentity(cancelled, "verify")
+				So(errors.Cause(err), ShouldEqual, persistence.ErrUnavailable)
+
+				err = store.SetPendingEmail(cancelled, "666", "peter@whoyougunnacall.com", "change", time.Now())
+				So(errors.Cause(err), ShouldEqual, persistence.ErrUnavailable)
+
+				_, err = store.ConfirmEmailChange(cancelled, "change")
+				So(errors.Cause(err), ShouldEqual, persistence.ErrUnavailable)
+
+				err = store.DisableIdentity(cancelled, "666", "under investigation", "admin", time.Now())
+				So(errors.Cause(err), ShouldEqual, persistence.ErrUnavailable)
+
+				err = store.EnableIdentity(cancelled, "666")
+				So(errors.Cause(err), ShouldEqual, persistence.ErrUnavailable)
+
+				err = store.EraseIdentity(cancelled, schema.Tombstone{ID: "666", ErasedBy: "admin", ErasedDate: time.Now()})
+				So(errors.Cause(err), ShouldEqual, persistence.ErrUnavailable)
+
+				_, err = store.GetTombstone(cancelled, "666")
+				So(errors.Cause(err), ShouldEqual, persistence.ErrUnavailable)
+
+				_, err = store.GetTokens(cancelled, "666")
+				So(errors.Cause(err), ShouldEqual, persistence.ErrUnavailable)
+
+				_, err = store.DeleteTokens(cancelled, "666")
+				So(errors.Cause(err), ShouldEqual, persistence.ErrUnavailable)
+
+				err = store.StoreEvent(cancelled, schema.AuditEvent{IdentityID: "666", Created: time.Now()})
+				So(errors.Cause(err), ShouldEqual, persistence.ErrUnavailable)
+
+				_, err = store.GetEvents(cancelled, "666")
+				So(errors.Cause(err), ShouldEqual, persistence.ErrUnavailable)
+
+				err = store.PseudonymiseEvents(cancelled, "666")
+				So(errors.Cause(err), ShouldEqual, persistence.ErrUnavailable)
+
+				_, err = store.RevokeTokens(cancelled, "666")
+				So(errors.Cause(err), ShouldEqual, persistence.ErrUnavailable)
+
+				err = store.StoreToken(cancelled, newContractToken("cancelled", "666"), schema.Identity{ID: "666"})
+				So(errors.Cause(err), ShouldEqual, persistence.ErrUnavailable)
+
+				_, _, err = store.GetIdentityByToken(cancelled, "cancelled")
+				So(errors.Cause(err), ShouldEqual, persistence.ErrUnavailable)
+
+				err = store.UpdateLastUsed(cancelled, "cancelled", time.Now())
+				So(errors.Cause(err), ShouldEqual, persistence.ErrUnavailable)
+
+				_, err = store.GetRateLimit(cancelled, "ip:127.0.0.1")
+				So(errors.Cause(err), ShouldEqual, persistence.ErrUnavailable)
+
+				_, err = store.SwapRateLimit(cancelled, "ip:127.0.0.1", time.Time{}, time.Now())
+				So(errors.Cause(err), ShouldEqual, persistence.ErrUnavailable)
+
+				err = store.PurgeRateLimits(cancelled, time.Now())
+				So(errors.Cause(err), ShouldEqual, persistence.ErrUnavailable)
+			})
+		})
+
+		Convey("when the context deadline has passed before an operation", func() {
+			expired, cancel := context.WithDeadline(ctx, time.Now().Add(-time.Second))
+			defer cancel()
+
+			_, err := store.GetIdentity(expired, venkman.Email)
+
+			Convey("then persistence.ErrTimeout is returned", func() {
+				So(errors.Cause(err), ShouldEqual, persistence.ErrTimeout)
+			})
+		})
+	})
+}
+
+func newContractToken(id string, identityID string) schema.Token {
+	now := time.Now()
+	return schema.Token{
+		ID:          id,
+		IdentityID:  identityID,
+		CreatedDate: now,
+		ExpiryDate:  now.Add(time.Hour),
+		LastUsed:    now,
+	}
+}
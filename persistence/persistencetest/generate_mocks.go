@@ -7,11 +7,22 @@ import (
 	"context"
 	"github.com/ONSdigital/dp-identity-api/schema"
 	"sync"
+	"time"
 )
 
 var (
-	lockIdentityStoreMockGetIdentity  sync.RWMutex
-	lockIdentityStoreMockSaveIdentity sync.RWMutex
+	lockIdentityStoreMockConfirmEmailChange              sync.RWMutex
+	lockIdentityStoreMockDisableIdentity                 sync.RWMutex
+	lockIdentityStoreMockEnableIdentity                  sync.RWMutex
+	lockIdentityStoreMockEraseIdentity                   sync.RWMutex
+	lockIdentityStoreMockGetIdentity                     sync.RWMutex
+	lockIdentityStoreMockGetIdentityByID                 sync.RWMutex
+	lockIdentityStoreMockGetIdentityByIDIncludingDeleted sync.RWMutex
+	lockIdentityStoreMockGetTombstone                    sync.RWMutex
+	lockIdentityStoreMockSaveIdentity                    sync.RWMutex
+	lockIdentityStoreMockSetPendingEmail                 sync.RWMutex
+	lockIdentityStoreMockUpdatePassword                  sync.RWMutex
+	lockIdentityStoreMockVerifyIdentity                  sync.RWMutex
 )
 
 // IdentityStoreMock is a mock implementation of IdentityStore.
@@ -20,12 +31,42 @@ var (
 //
 //         // make and configure a mocked IdentityStore
 //         mockedIdentityStore := &IdentityStoreMock{
-//             GetIdentityFunc: func(email string) (schema.Identity, error) {
+//             ConfirmEmailChangeFunc: func(ctx context.Context, token string) (*schema.Identity, error) {
+// 	               panic("TODO: mock out the ConfirmEmailChange method")
+//             },
+//             DisableIdentityFunc: func(ctx context.Context, id string, reason string, disabledBy string, date time.Time) error {
+// 	               panic("TODO: mock out the DisableIdentity method")
+//             },
+//             EnableIdentityFunc: func(ctx context.Context, id string) error {
+// 	               panic("TODO: mock out the EnableIdentity method")
+//             },
+//             EraseIdentityFunc: func(ctx context.Context, tombstone schema.Tombstone) error {
+// 	               panic("TODO: mock out the EraseIdentity method")
+//             },
+//             GetIdentityFunc: func(ctx context.Context, email string) (schema.Identity, error) {
 // 	               panic("TODO: mock out the GetIdentity method")
 //             },
-//             SaveIdentityFunc: func(newIdentity schema.Identity) (string, error) {
+//             GetIdentityByIDFunc: func(ctx context.Context, id string) (*schema.Identity, error) {
+// 	               panic("TODO: mock out the GetIdentityByID method")
+//             },
+//             GetIdentityByIDIncludingDeletedFunc: func(ctx context.Context, id string) (*schema.Identity, error) {
+// 	               panic("TODO: mock out the GetIdentityByIDIncludingDeleted method")
+//             },
+//             GetTombstoneFunc: func(ctx context.Context, id string) (*schema.Tombstone, error) {
+// 	               panic("TODO: mock out the GetTombstone method")
+//             },
+//             SaveIdentityFunc: func(ctx context.Context, newIdentity schema.Identity) (string, error) {
 // 	               panic("TODO: mock out the SaveIdentity method")
 //             },
+//             SetPendingEmailFunc: func(ctx context.Context, id string, email string, token string, expiry time.Time) error {
+// 	               panic("TODO: mock out the SetPendingEmail method")
+//             },
+//             UpdatePasswordFunc: func(ctx context.Context, id string, password string) error {
+// 	               panic("TODO: mock out the UpdatePassword method")
+//             },
+//             VerifyIdentityFunc: func(ctx context.Context, token string) (*schema.Identity, error) {
+// 	               panic("TODO: mock out the VerifyIdentity method")
+//             },
 //         }
 //
 //         // TODO: use mockedIdentityStore in code that requires IdentityStore
@@ -33,50 +74,324 @@ var (
 //
 //     }
 type IdentityStoreMock struct {
+	// ConfirmEmailChangeFunc mocks the ConfirmEmailChange method.
+	ConfirmEmailChangeFunc func(ctx context.Context, token string) (*schema.Identity, error)
+
+	// DisableIdentityFunc mocks the DisableIdentity method.
+	DisableIdentityFunc func(ctx context.Context, id string, reason string, disabledBy string, date time.Time) error
+
+	// EnableIdentityFunc mocks the EnableIdentity method.
+	EnableIdentityFunc func(ctx context.Context, id string) error
+
+	// EraseIdentityFunc mocks the EraseIdentity method.
+	EraseIdentityFunc func(ctx context.Context, tombstone schema.Tombstone) error
+
 	// GetIdentityFunc mocks the GetIdentity method.
-	GetIdentityFunc func(email string) (schema.Identity, error)
+	GetIdentityFunc func(ctx context.Context, email string) (schema.Identity, error)
+
+	// GetIdentityByIDFunc mocks the GetIdentityByID method.
+	GetIdentityByIDFunc func(ctx context.Context, id string) (*schema.Identity, error)
+
+	// GetIdentityByIDIncludingDeletedFunc mocks the GetIdentityByIDIncludingDeleted method.
+	GetIdentityByIDIncludingDeletedFunc func(ctx context.Context, id string) (*schema.Identity, error)
+
+	// GetTombstoneFunc mocks the GetTombstone method.
+	GetTombstoneFunc func(ctx context.Context, id string) (*schema.Tombstone, error)
 
 	// SaveIdentityFunc mocks the SaveIdentity method.
-	SaveIdentityFunc func(newIdentity schema.Identity) (string, error)
+	SaveIdentityFunc func(ctx context.Context, newIdentity schema.Identity) (string, error)
+
+	// SetPendingEmailFunc mocks the SetPendingEmail method.
+	SetPendingEmailFunc func(ctx context.Context, id string, email string, token string, expiry time.Time) error
+
+	// UpdatePasswordFunc mocks the UpdatePassword method.
+	UpdatePasswordFunc func(ctx context.Context, id string, password string) error
+
+	// VerifyIdentityFunc mocks the VerifyIdentity method.
+	VerifyIdentityFunc func(ctx context.Context, token string) (*schema.Identity, error)
 
 	// calls tracks calls to the methods.
 	calls struct {
+		// ConfirmEmailChange holds details about calls to the ConfirmEmailChange method.
+		ConfirmEmailChange []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Token is the token argument value.
+			Token string
+		}
+		// DisableIdentity holds details about calls to the DisableIdentity method.
+		DisableIdentity []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID string
+			// Reason is the reason argument value.
+			Reason string
+			// DisabledBy is the disabledBy argument value.
+			DisabledBy string
+			// Date is the date argument value.
+			Date time.Time
+		}
+		// EnableIdentity holds details about calls to the EnableIdentity method.
+		EnableIdentity []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID string
+		}
+		// EraseIdentity holds details about calls to the EraseIdentity method.
+		EraseIdentity []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Tombstone is the tombstone argument value.
+			Tombstone schema.Tombstone
+		}
 		// GetIdentity holds details about calls to the GetIdentity method.
 		GetIdentity []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
 			// Email is the email argument value.
 			Email string
 		}
+		// GetIdentityByID holds details about calls to the GetIdentityByID method.
+		GetIdentityByID []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID string
+		}
+		// GetIdentityByIDIncludingDeleted holds details about calls to the GetIdentityByIDIncludingDeleted method.
+		GetIdentityByIDIncludingDeleted []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID string
+		}
+		// GetTombstone holds details about calls to the GetTombstone method.
+		GetTombstone []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID string
+		}
 		// SaveIdentity holds details about calls to the SaveIdentity method.
 		SaveIdentity []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
 			// NewIdentity is the newIdentity argument value.
 			NewIdentity schema.Identity
 		}
+		// SetPendingEmail holds details about calls to the SetPendingEmail method.
+		SetPendingEmail []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID string
+			// Email is the email argument value.
+			Email string
+			// Token is the token argument value.
+			Token string
+			// Expiry is the expiry argument value.
+			Expiry time.Time
+		}
+		// UpdatePassword holds details about calls to the UpdatePassword method.
+		UpdatePassword []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID string
+			// Password is the password argument value.
+			Password string
+		}
+		// VerifyIdentity holds details about calls to the VerifyIdentity method.
+		VerifyIdentity []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Token is the token argument value.
+			Token string
+		}
+	}
+}
+
+// ConfirmEmailChange calls ConfirmEmailChangeFunc.
+func (mock *IdentityStoreMock) ConfirmEmailChange(ctx context.Context, token string) (*schema.Identity, error) {
+	if mock.ConfirmEmailChangeFunc == nil {
+		panic("moq: IdentityStoreMock.ConfirmEmailChangeFunc is nil but IdentityStore.ConfirmEmailChange was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Token string
+	}{
+		Ctx:   ctx,
+		Token: token,
+	}
+	lockIdentityStoreMockConfirmEmailChange.Lock()
+	mock.calls.ConfirmEmailChange = append(mock.calls.ConfirmEmailChange, callInfo)
+	lockIdentityStoreMockConfirmEmailChange.Unlock()
+	return mock.ConfirmEmailChangeFunc(ctx, token)
+}
+
+// ConfirmEmailChangeCalls gets all the calls that were made to ConfirmEmailChange.
+// Check the length with:
+//     len(mockedIdentityStore.ConfirmEmailChangeCalls())
+func (mock *IdentityStoreMock) ConfirmEmailChangeCalls() []struct {
+	Ctx   context.Context
+	Token string
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Token string
+	}
+	lockIdentityStoreMockConfirmEmailChange.RLock()
+	calls = mock.calls.ConfirmEmailChange
+	lockIdentityStoreMockConfirmEmailChange.RUnlock()
+	return calls
+}
+
+// DisableIdentity calls DisableIdentityFunc.
+func (mock *IdentityStoreMock) DisableIdentity(ctx context.Context, id string, reason string, disabledBy string, date time.Time) error {
+	if mock.DisableIdentityFunc == nil {
+		panic("moq: IdentityStoreMock.DisableIdentityFunc is nil but IdentityStore.DisableIdentity was just called")
+	}
+	callInfo := struct {
+		Ctx        context.Context
+		ID         string
+		Reason     string
+		DisabledBy string
+		Date       time.Time
+	}{
+		Ctx:        ctx,
+		ID:         id,
+		Reason:     reason,
+		DisabledBy: disabledBy,
+		Date:       date,
+	}
+	lockIdentityStoreMockDisableIdentity.Lock()
+	mock.calls.DisableIdentity = append(mock.calls.DisableIdentity, callInfo)
+	lockIdentityStoreMockDisableIdentity.Unlock()
+	return mock.DisableIdentityFunc(ctx, id, reason, disabledBy, date)
+}
+
+// DisableIdentityCalls gets all the calls that were made to DisableIdentity.
+// Check the length with:
+//     len(mockedIdentityStore.DisableIdentityCalls())
+func (mock *IdentityStoreMock) DisableIdentityCalls() []struct {
+	Ctx        context.Context
+	ID         string
+	Reason     string
+	DisabledBy string
+	Date       time.Time
+} {
+	var calls []struct {
+		Ctx        context.Context
+		ID         string
+		Reason     string
+		DisabledBy string
+		Date       time.Time
+	}
+	lockIdentityStoreMockDisableIdentity.RLock()
+	calls = mock.calls.DisableIdentity
+	lockIdentityStoreMockDisableIdentity.RUnlock()
+	return calls
+}
+
+// EnableIdentity calls EnableIdentityFunc.
+func (mock *IdentityStoreMock) EnableIdentity(ctx context.Context, id string) error {
+	if mock.EnableIdentityFunc == nil {
+		panic("moq: IdentityStoreMock.EnableIdentityFunc is nil but IdentityStore.EnableIdentity was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  string
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	lockIdentityStoreMockEnableIdentity.Lock()
+	mock.calls.EnableIdentity = append(mock.calls.EnableIdentity, callInfo)
+	lockIdentityStoreMockEnableIdentity.Unlock()
+	return mock.EnableIdentityFunc(ctx, id)
+}
+
+// EnableIdentityCalls gets all the calls that were made to EnableIdentity.
+// Check the length with:
+//     len(mockedIdentityStore.EnableIdentityCalls())
+func (mock *IdentityStoreMock) EnableIdentityCalls() []struct {
+	Ctx context.Context
+	ID  string
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  string
+	}
+	lockIdentityStoreMockEnableIdentity.RLock()
+	calls = mock.calls.EnableIdentity
+	lockIdentityStoreMockEnableIdentity.RUnlock()
+	return calls
+}
+
+// EraseIdentity calls EraseIdentityFunc.
+func (mock *IdentityStoreMock) EraseIdentity(ctx context.Context, tombstone schema.Tombstone) error {
+	if mock.EraseIdentityFunc == nil {
+		panic("moq: IdentityStoreMock.EraseIdentityFunc is nil but IdentityStore.EraseIdentity was just called")
+	}
+	callInfo := struct {
+		Ctx       context.Context
+		Tombstone schema.Tombstone
+	}{
+		Ctx:       ctx,
+		Tombstone: tombstone,
 	}
+	lockIdentityStoreMockEraseIdentity.Lock()
+	mock.calls.EraseIdentity = append(mock.calls.EraseIdentity, callInfo)
+	lockIdentityStoreMockEraseIdentity.Unlock()
+	return mock.EraseIdentityFunc(ctx, tombstone)
+}
+
+// EraseIdentityCalls gets all the calls that were made to EraseIdentity.
+// Check the length with:
+//     len(mockedIdentityStore.EraseIdentityCalls())
+func (mock *IdentityStoreMock) EraseIdentityCalls() []struct {
+	Ctx       context.Context
+	Tombstone schema.Tombstone
+} {
+	var calls []struct {
+		Ctx       context.Context
+		Tombstone schema.Tombstone
+	}
+	lockIdentityStoreMockEraseIdentity.RLock()
+	calls = mock.calls.EraseIdentity
+	lockIdentityStoreMockEraseIdentity.RUnlock()
+	return calls
 }
 
 // GetIdentity calls GetIdentityFunc.
-func (mock *IdentityStoreMock) GetIdentity(email string) (schema.Identity, error) {
+func (mock *IdentityStoreMock) GetIdentity(ctx context.Context, email string) (schema.Identity, error) {
 	if mock.GetIdentityFunc == nil {
 		panic("moq: IdentityStoreMock.GetIdentityFunc is nil but IdentityStore.GetIdentity was just called")
 	}
 	callInfo := struct {
+		Ctx   context.Context
 		Email string
 	}{
+		Ctx:   ctx,
 		Email: email,
 	}
 	lockIdentityStoreMockGetIdentity.Lock()
 	mock.calls.GetIdentity = append(mock.calls.GetIdentity, callInfo)
 	lockIdentityStoreMockGetIdentity.Unlock()
-	return mock.GetIdentityFunc(email)
+	return mock.GetIdentityFunc(ctx, email)
 }
 
 // GetIdentityCalls gets all the calls that were made to GetIdentity.
 // Check the length with:
 //     len(mockedIdentityStore.GetIdentityCalls())
 func (mock *IdentityStoreMock) GetIdentityCalls() []struct {
+	Ctx   context.Context
 	Email string
 } {
 	var calls []struct {
+		Ctx   context.Context
 		Email string
 	}
 	lockIdentityStoreMockGetIdentity.RLock()
@@ -85,29 +400,138 @@ func (mock *IdentityStoreMock) GetIdentityCalls() []struct {
 	return calls
 }
 
+// GetIdentityByID calls GetIdentityByIDFunc.
+func (mock *IdentityStoreMock) GetIdentityByID(ctx context.Context, id string) (*schema.Identity, error) {
+	if mock.GetIdentityByIDFunc == nil {
+		panic("moq: IdentityStoreMock.GetIdentityByIDFunc is nil but IdentityStore.GetIdentityByID was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  string
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	lockIdentityStoreMockGetIdentityByID.Lock()
+	mock.calls.GetIdentityByID = append(mock.calls.GetIdentityByID, callInfo)
+	lockIdentityStoreMockGetIdentityByID.Unlock()
+	return mock.GetIdentityByIDFunc(ctx, id)
+}
+
+// GetIdentityByIDCalls gets all the calls that were made to GetIdentityByID.
+// Check the length with:
+//     len(mockedIdentityStore.GetIdentityByIDCalls())
+func (mock *IdentityStoreMock) GetIdentityByIDCalls() []struct {
+	Ctx context.Context
+	ID  string
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  string
+	}
+	lockIdentityStoreMockGetIdentityByID.RLock()
+	calls = mock.calls.GetIdentityByID
+	lockIdentityStoreMockGetIdentityByID.RUnlock()
+	return calls
+}
+
+// GetIdentityByIDIncludingDeleted calls GetIdentityByIDIncludingDeletedFunc.
+func (mock *IdentityStoreMock) GetIdentityByIDIncludingDeleted(ctx context.Context, id string) (*schema.Identity, error) {
+	if mock.GetIdentityByIDIncludingDeletedFunc == nil {
+		panic("moq: IdentityStoreMock.GetIdentityByIDIncludingDeletedFunc is nil but IdentityStore.GetIdentityByIDIncludingDeleted was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  string
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	lockIdentityStoreMockGetIdentityByIDIncludingDeleted.Lock()
+	mock.calls.GetIdentityByIDIncludingDeleted = append(mock.calls.GetIdentityByIDIncludingDeleted, callInfo)
+	lockIdentityStoreMockGetIdentityByIDIncludingDeleted.Unlock()
+	return mock.GetIdentityByIDIncludingDeletedFunc(ctx, id)
+}
+
+// GetIdentityByIDIncludingDeletedCalls gets all the calls that were made to GetIdentityByIDIncludingDeleted.
+// Check the length with:
+//     len(mockedIdentityStore.GetIdentityByIDIncludingDeletedCalls())
+func (mock *IdentityStoreMock) GetIdentityByIDIncludingDeletedCalls() []struct {
+	Ctx context.Context
+	ID  string
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  string
+	}
+	lockIdentityStoreMockGetIdentityByIDIncludingDeleted.RLock()
+	calls = mock.calls.GetIdentityByIDIncludingDeleted
+	lockIdentityStoreMockGetIdentityByIDIncludingDeleted.RUnlock()
+	return calls
+}
+
+// GetTombstone calls GetTombstoneFunc.
+func (mock *IdentityStoreMock) GetTombstone(ctx context.Context, id string) (*schema.Tombstone, error) {
+	if mock.GetTombstoneFunc == nil {
+		panic("moq: IdentityStoreMock.GetTombstoneFunc is nil but IdentityStore.GetTombstone was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  string
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	lockIdentityStoreMockGetTombstone.Lock()
+	mock.calls.GetTombstone = append(mock.calls.GetTombstone, callInfo)
+	lockIdentityStoreMockGetTombstone.Unlock()
+	return mock.GetTombstoneFunc(ctx, id)
+}
+
+// GetTombstoneCalls gets all the calls that were made to GetTombstone.
+// Check the length with:
+//     len(mockedIdentityStore.GetTombstoneCalls())
+func (mock *IdentityStoreMock) GetTombstoneCalls() []struct {
+	Ctx context.Context
+	ID  string
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  string
+	}
+	lockIdentityStoreMockGetTombstone.RLock()
+	calls = mock.calls.GetTombstone
+	lockIdentityStoreMockGetTombstone.RUnlock()
+	return calls
+}
+
 // SaveIdentity calls SaveIdentityFunc.
-func (mock *IdentityStoreMock) SaveIdentity(newIdentity schema.Identity) (string, error) {
+func (mock *IdentityStoreMock) SaveIdentity(ctx context.Context, newIdentity schema.Identity) (string, error) {
 	if mock.SaveIdentityFunc == nil {
 		panic("moq: IdentityStoreMock.SaveIdentityFunc is nil but IdentityStore.SaveIdentity was just called")
 	}
 	callInfo := struct {
+		Ctx         context.Context
 		NewIdentity schema.Identity
 	}{
+		Ctx:         ctx,
 		NewIdentity: newIdentity,
 	}
 	lockIdentityStoreMockSaveIdentity.Lock()
 	mock.calls.SaveIdentity = append(mock.calls.SaveIdentity, callInfo)
 	lockIdentityStoreMockSaveIdentity.Unlock()
-	return mock.SaveIdentityFunc(newIdentity)
+	return mock.SaveIdentityFunc(ctx, newIdentity)
 }
 
 // SaveIdentityCalls gets all the calls that were made to SaveIdentity.
 // Check the length with:
 //     len(mockedIdentityStore.SaveIdentityCalls())
 func (mock *IdentityStoreMock) SaveIdentityCalls() []struct {
+	Ctx         context.Context
 	NewIdentity schema.Identity
 } {
 	var calls []struct {
+		Ctx         context.Context
 		NewIdentity schema.Identity
 	}
 	lockIdentityStoreMockSaveIdentity.RLock()
@@ -116,9 +540,134 @@ func (mock *IdentityStoreMock) SaveIdentityCalls() []struct {
 	return calls
 }
 
+// SetPendingEmail calls SetPendingEmailFunc.
+func (mock *IdentityStoreMock) SetPendingEmail(ctx context.Context, id string, email string, token string, expiry time.Time) error {
+	if mock.SetPendingEmailFunc == nil {
+		panic("moq: IdentityStoreMock.SetPendingEmailFunc is nil but IdentityStore.SetPendingEmail was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		ID     string
+		Email  string
+		Token  string
+		Expiry time.Time
+	}{
+		Ctx:    ctx,
+		ID:     id,
+		Email:  email,
+		Token:  token,
+		Expiry: expiry,
+	}
+	lockIdentityStoreMockSetPendingEmail.Lock()
+	mock.calls.SetPendingEmail = append(mock.calls.SetPendingEmail, callInfo)
+	lockIdentityStoreMockSetPendingEmail.Unlock()
+	return mock.SetPendingEmailFunc(ctx, id, email, token, expiry)
+}
+
+// SetPendingEmailCalls gets all the calls that were made to SetPendingEmail.
+// Check the length with:
+//     len(mockedIdentityStore.SetPendingEmailCalls())
+func (mock *IdentityStoreMock) SetPendingEmailCalls() []struct {
+	Ctx    context.Context
+	ID     string
+	Email  string
+	Token  string
+	Expiry time.Time
+} {
+	var calls []struct {
+		Ctx    context.Context
+		ID     string
+		Email  string
+		Token  string
+		Expiry time.Time
+	}
+	lockIdentityStoreMockSetPendingEmail.RLock()
+	calls = mock.calls.SetPendingEmail
+	lockIdentityStoreMockSetPendingEmail.RUnlock()
+	return calls
+}
+
+// UpdatePassword calls UpdatePasswordFunc.
+func (mock *IdentityStoreMock) UpdatePassword(ctx context.Context, id string, password string) error {
+	if mock.UpdatePasswordFunc == nil {
+		panic("moq: IdentityStoreMock.UpdatePasswordFunc is nil but IdentityStore.UpdatePassword was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		ID       string
+		Password string
+	}{
+		Ctx:      ctx,
+		ID:       id,
+		Password: password,
+	}
+	lockIdentityStoreMockUpdatePassword.Lock()
+	mock.calls.UpdatePassword = append(mock.calls.UpdatePassword, callInfo)
+	lockIdentityStoreMockUpdatePassword.Unlock()
+	return mock.UpdatePasswordFunc(ctx, id, password)
+}
+
+// UpdatePasswordCalls gets all the calls that were made to UpdatePassword.
+// Check the length with:
+//     len(mockedIdentityStore.UpdatePasswordCalls())
+func (mock *IdentityStoreMock) UpdatePasswordCalls() []struct {
+	Ctx      context.Context
+	ID       string
+	Password string
+} {
+	var calls []struct {
+		Ctx      context.Context
+		ID       string
+		Password string
+	}
+	lockIdentityStoreMockUpdatePassword.RLock()
+	calls = mock.calls.UpdatePassword
+	lockIdentityStoreMockUpdatePassword.RUnlock()
+	return calls
+}
+
+// VerifyIdentity calls VerifyIdentityFunc.
+func (mock *IdentityStoreMock) VerifyIdentity(ctx context.Context, token string) (*schema.Identity, error) {
+	if mock.VerifyIdentityFunc == nil {
+		panic("moq: IdentityStoreMock.VerifyIdentityFunc is nil but IdentityStore.VerifyIdentity was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Token string
+	}{
+		Ctx:   ctx,
+		Token: token,
+	}
+	lockIdentityStoreMockVerifyIdentity.Lock()
+	mock.calls.VerifyIdentity = append(mock.calls.VerifyIdentity, callInfo)
+	lockIdentityStoreMockVerifyIdentity.Unlock()
+	return mock.VerifyIdentityFunc(ctx, token)
+}
+
+// VerifyIdentityCalls gets all the calls that were made to VerifyIdentity.
+// Check the length with:
+//     len(mockedIdentityStore.VerifyIdentityCalls())
+func (mock *IdentityStoreMock) VerifyIdentityCalls() []struct {
+	Ctx   context.Context
+	Token string
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Token string
+	}
+	lockIdentityStoreMockVerifyIdentity.RLock()
+	calls = mock.calls.VerifyIdentity
+	lockIdentityStoreMockVerifyIdentity.RUnlock()
+	return calls
+}
+
 var (
+	lockTokenStoreMockDeleteTokens       sync.RWMutex
 	lockTokenStoreMockGetIdentityByToken sync.RWMutex
+	lockTokenStoreMockGetTokens          sync.RWMutex
+	lockTokenStoreMockRevokeTokens       sync.RWMutex
 	lockTokenStoreMockStoreToken         sync.RWMutex
+	lockTokenStoreMockUpdateLastUsed     sync.RWMutex
 )
 
 // TokenStoreMock is a mock implementation of TokenStore.
@@ -127,12 +676,24 @@ var (
 //
 //         // make and configure a mocked TokenStore
 //         mockedTokenStore := &TokenStoreMock{
+//             DeleteTokensFunc: func(ctx context.Context, identityID string) ([]string, error) {
+// 	               panic("TODO: mock out the DeleteTokens method")
+//             },
 //             GetIdentityByTokenFunc: func(ctx context.Context, token string) (*schema.Identity, *schema.Token, error) {
 // 	               panic("TODO: mock out the GetIdentityByToken method")
 //             },
+//             GetTokensFunc: func(ctx context.Context, identityID string) ([]schema.Token, error) {
+// 	               panic("TODO: mock out the GetTokens method")
+//             },
+//             RevokeTokensFunc: func(ctx context.Context, identityID string) ([]string, error) {
+// 	               panic("TODO: mock out the RevokeTokens method")
+//             },
 //             StoreTokenFunc: func(ctx context.Context, token schema.Token, i schema.Identity) error {
 // 	               panic("TODO: mock out the StoreToken method")
 //             },
+//             UpdateLastUsedFunc: func(ctx context.Context, token string, lastUsed time.Time) error {
+// 	               panic("TODO: mock out the UpdateLastUsed method")
+//             },
 //         }
 //
 //         // TODO: use mockedTokenStore in code that requires TokenStore
@@ -140,14 +701,33 @@ var (
 //
 //     }
 type TokenStoreMock struct {
+	// DeleteTokensFunc mocks the DeleteTokens method.
+	DeleteTokensFunc func(ctx context.Context, identityID string) ([]string, error)
+
 	// GetIdentityByTokenFunc mocks the GetIdentityByToken method.
 	GetIdentityByTokenFunc func(ctx context.Context, token string) (*schema.Identity, *schema.Token, error)
 
+	// GetTokensFunc mocks the GetTokens method.
+	GetTokensFunc func(ctx context.Context, identityID string) ([]schema.Token, error)
+
+	// RevokeTokensFunc mocks the RevokeTokens method.
+	RevokeTokensFunc func(ctx context.Context, identityID string) ([]string, error)
+
 	// StoreTokenFunc mocks the StoreToken method.
 	StoreTokenFunc func(ctx context.Context, token schema.Token, i schema.Identity) error
 
+	// UpdateLastUsedFunc mocks the UpdateLastUsed method.
+	UpdateLastUsedFunc func(ctx context.Context, token string, lastUsed time.Time) error
+
 	// calls tracks calls to the methods.
 	calls struct {
+		// DeleteTokens holds details about calls to the DeleteTokens method.
+		DeleteTokens []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// IdentityID is the identityID argument value.
+			IdentityID string
+		}
 		// GetIdentityByToken holds details about calls to the GetIdentityByToken method.
 		GetIdentityByToken []struct {
 			// Ctx is the ctx argument value.
@@ -155,6 +735,20 @@ type TokenStoreMock struct {
 			// Token is the token argument value.
 			Token string
 		}
+		// GetTokens holds details about calls to the GetTokens method.
+		GetTokens []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// IdentityID is the identityID argument value.
+			IdentityID string
+		}
+		// RevokeTokens holds details about calls to the RevokeTokens method.
+		RevokeTokens []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// IdentityID is the identityID argument value.
+			IdentityID string
+		}
 		// StoreToken holds details about calls to the StoreToken method.
 		StoreToken []struct {
 			// Ctx is the ctx argument value.
@@ -164,7 +758,51 @@ type TokenStoreMock struct {
 			// I is the i argument value.
 			I schema.Identity
 		}
+		// UpdateLastUsed holds details about calls to the UpdateLastUsed method.
+		UpdateLastUsed []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Token is the token argument value.
+			Token string
+			// LastUsed is the lastUsed argument value.
+			LastUsed time.Time
+		}
+	}
+}
+
+// DeleteTokens calls DeleteTokensFunc.
+func (mock *TokenStoreMock) DeleteTokens(ctx context.Context, identityID string) ([]string, error) {
+	if mock.DeleteTokensFunc == nil {
+		panic("moq: TokenStoreMock.DeleteTokensFunc is nil but TokenStore.DeleteTokens was just called")
+	}
+	callInfo := struct {
+		Ctx        context.Context
+		IdentityID string
+	}{
+		Ctx:        ctx,
+		IdentityID: identityID,
+	}
+	lockTokenStoreMockDeleteTokens.Lock()
+	mock.calls.DeleteTokens = append(mock.calls.DeleteTokens, callInfo)
+	lockTokenStoreMockDeleteTokens.Unlock()
+	return mock.DeleteTokensFunc(ctx, identityID)
+}
+
+// DeleteTokensCalls gets all the calls that were made to DeleteTokens.
+// Check the length with:
+//     len(mockedTokenStore.DeleteTokensCalls())
+func (mock *TokenStoreMock) DeleteTokensCalls() []struct {
+	Ctx        context.Context
+	IdentityID string
+} {
+	var calls []struct {
+		Ctx        context.Context
+		IdentityID string
 	}
+	lockTokenStoreMockDeleteTokens.RLock()
+	calls = mock.calls.DeleteTokens
+	lockTokenStoreMockDeleteTokens.RUnlock()
+	return calls
 }
 
 // GetIdentityByToken calls GetIdentityByTokenFunc.
@@ -202,6 +840,76 @@ func (mock *TokenStoreMock) GetIdentityByTokenCalls() []struct {
 	return calls
 }
 
+// GetTokens calls GetTokensFunc.
+func (mock *TokenStoreMock) GetTokens(ctx context.Context, identityID string) ([]schema.Token, error) {
+	if mock.GetTokensFunc == nil {
+		panic("moq: TokenStoreMock.GetTokensFunc is nil but TokenStore.GetTokens was just called")
+	}
+	callInfo := struct {
+		Ctx        context.Context
+		IdentityID string
+	}{
+		Ctx:        ctx,
+		IdentityID: identityID,
+	}
+	lockTokenStoreMockGetTokens.Lock()
+	mock.calls.GetTokens = append(mock.calls.GetTokens, callInfo)
+	lockTokenStoreMockGetTokens.Unlock()
+	return mock.GetTokensFunc(ctx, identityID)
+}
+
+// GetTokensCalls gets all the calls that were made to GetTokens.
+// Check the length with:
+//     len(mockedTokenStore.GetTokensCalls())
+func (mock *TokenStoreMock) GetTokensCalls() []struct {
+	Ctx        context.Context
+	IdentityID string
+} {
+	var calls []struct {
+		Ctx        context.Context
+		IdentityID string
+	}
+	lockTokenStoreMockGetTokens.RLock()
+	calls = mock.calls.GetTokens
+	lockTokenStoreMockGetTokens.RUnlock()
+	return calls
+}
+
+// RevokeTokens calls RevokeTokensFunc.
+func (mock *TokenStoreMock) RevokeTokens(ctx context.Context, identityID string) ([]string, error) {
+	if mock.RevokeTokensFunc == nil {
+		panic("moq: TokenStoreMock.RevokeTokensFunc is nil but TokenStore.RevokeTokens was just called")
+	}
+	callInfo := struct {
+		Ctx        context.Context
+		IdentityID string
+	}{
+		Ctx:        ctx,
+		IdentityID: identityID,
+	}
+	lockTokenStoreMockRevokeTokens.Lock()
+	mock.calls.RevokeTokens = append(mock.calls.RevokeTokens, callInfo)
+	lockTokenStoreMockRevokeTokens.Unlock()
+	return mock.RevokeTokensFunc(ctx, identityID)
+}
+
+// RevokeTokensCalls gets all the calls that were made to RevokeTokens.
+// Check the length with:
+//     len(mockedTokenStore.RevokeTokensCalls())
+func (mock *TokenStoreMock) RevokeTokensCalls() []struct {
+	Ctx        context.Context
+	IdentityID string
+} {
+	var calls []struct {
+		Ctx        context.Context
+		IdentityID string
+	}
+	lockTokenStoreMockRevokeTokens.RLock()
+	calls = mock.calls.RevokeTokens
+	lockTokenStoreMockRevokeTokens.RUnlock()
+	return calls
+}
+
 // StoreToken calls StoreTokenFunc.
 func (mock *TokenStoreMock) StoreToken(ctx context.Context, token schema.Token, i schema.Identity) error {
 	if mock.StoreTokenFunc == nil {
@@ -240,3 +948,390 @@ func (mock *TokenStoreMock) StoreTokenCalls() []struct {
 	lockTokenStoreMockStoreToken.RUnlock()
 	return calls
 }
+
+// UpdateLastUsed calls UpdateLastUsedFunc.
+func (mock *TokenStoreMock) UpdateLastUsed(ctx context.Context, token string, lastUsed time.Time) error {
+	if mock.UpdateLastUsedFunc == nil {
+		panic("moq: TokenStoreMock.UpdateLastUsedFunc is nil but TokenStore.UpdateLastUsed was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		Token    string
+		LastUsed time.Time
+	}{
+		Ctx:      ctx,
+		Token:    token,
+		LastUsed: lastUsed,
+	}
+	lockTokenStoreMockUpdateLastUsed.Lock()
+	mock.calls.UpdateLastUsed = append(mock.calls.UpdateLastUsed, callInfo)
+	lockTokenStoreMockUpdateLastUsed.Unlock()
+	return mock.UpdateLastUsedFunc(ctx, token, lastUsed)
+}
+
+// UpdateLastUsedCalls gets all the calls that were made to UpdateLastUsed.
+// Check the length with:
+//     len(mockedTokenStore.UpdateLastUsedCalls())
+func (mock *TokenStoreMock) UpdateLastUsedCalls() []struct {
+	Ctx      context.Context
+	Token    string
+	LastUsed time.Time
+} {
+	var calls []struct {
+		Ctx      context.Context
+		Token    string
+		LastUsed time.Time
+	}
+	lockTokenStoreMockUpdateLastUsed.RLock()
+	calls = mock.calls.UpdateLastUsed
+	lockTokenStoreMockUpdateLastUsed.RUnlock()
+	return calls
+}
+
+var (
+	lockAuditStoreMockGetEvents          sync.RWMutex
+	lockAuditStoreMockPseudonymiseEvents sync.RWMutex
+	lockAuditStoreMockStoreEvent         sync.RWMutex
+)
+
+// AuditStoreMock is a mock implementation of AuditStore.
+//
+//     func TestSomethingThatUsesAuditStore(t *testing.T) {
+//
+//         // make and configure a mocked AuditStore
+//         mockedAuditStore := &AuditStoreMock{
+//             GetEventsFunc: func(ctx context.Context, identityID string) ([]schema.AuditEvent, error) {
+// 	               panic("TODO: mock out the GetEvents method")
+//             },
+//             PseudonymiseEventsFunc: func(ctx context.Context, identityID string) error {
+// 	               panic("TODO: mock out the PseudonymiseEvents method")
+//             },
+//             StoreEventFunc: func(ctx context.Context, event schema.AuditEvent) error {
+// 	               panic("TODO: mock out the StoreEvent method")
+//             },
+//         }
+//
+//         // TODO: use mockedAuditStore in code that requires AuditStore
+//         //       and then make assertions.
+//
+//     }
+type AuditStoreMock struct {
+	// GetEventsFunc mocks the GetEvents method.
+	GetEventsFunc func(ctx context.Context, identityID string) ([]schema.AuditEvent, error)
+
+	// PseudonymiseEventsFunc mocks the PseudonymiseEvents method.
+	PseudonymiseEventsFunc func(ctx context.Context, identityID string) error
+
+	// StoreEventFunc mocks the StoreEvent method.
+	StoreEventFunc func(ctx context.Context, event schema.AuditEvent) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// GetEvents holds details about calls to the GetEvents method.
+		GetEvents []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// IdentityID is the identityID argument value.
+			IdentityID string
+		}
+		// PseudonymiseEvents holds details about calls to the PseudonymiseEvents method.
+		PseudonymiseEvents []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// IdentityID is the identityID argument value.
+			IdentityID string
+		}
+		// StoreEvent holds details about calls to the StoreEvent method.
+		StoreEvent []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Event is the event argument value.
+			Event schema.AuditEvent
+		}
+	}
+}
+
+// GetEvents calls GetEventsFunc.
+func (mock *AuditStoreMock) GetEvents(ctx context.Context, identityID string) ([]schema.AuditEvent, error) {
+	if mock.GetEventsFunc == nil {
+		panic("moq: AuditStoreMock.GetEventsFunc is nil but AuditStore.GetEvents was just called")
+	}
+	callInfo := struct {
+		Ctx        context.Context
+		IdentityID string
+	}{
+		Ctx:        ctx,
+		IdentityID: identityID,
+	}
+	lockAuditStoreMockGetEvents.Lock()
+	mock.calls.GetEvents = append(mock.calls.GetEvents, callInfo)
+	lockAuditStoreMockGetEvents.Unlock()
+	return mock.GetEventsFunc(ctx, identityID)
+}
+
+// GetEventsCalls gets all the calls that were made to GetEvents.
+// Check the length with:
+//     len(mockedAuditStore.GetEventsCalls())
+func (mock *AuditStoreMock) GetEventsCalls() []struct {
+	Ctx        context.Context
+	IdentityID string
+} {
+	var calls []struct {
+		Ctx        context.Context
+		IdentityID string
+	}
+	lockAuditStoreMockGetEvents.RLock()
+	calls = mock.calls.GetEvents
+	lockAuditStoreMockGetEvents.RUnlock()
+	return calls
+}
+
+// PseudonymiseEvents calls PseudonymiseEventsFunc.
+func (mock *AuditStoreMock) PseudonymiseEvents(ctx context.Context, identityID string) error {
+	if mock.PseudonymiseEventsFunc == nil {
+		panic("moq: AuditStoreMock.PseudonymiseEventsFunc is nil but AuditStore.PseudonymiseEvents was just called")
+	}
+	callInfo := struct {
+		Ctx        context.Context
+		IdentityID string
+	}{
+		Ctx:        ctx,
+		IdentityID: identityID,
+	}
+	lockAuditStoreMockPseudonymiseEvents.Lock()
+	mock.calls.PseudonymiseEvents = append(mock.calls.PseudonymiseEvents, callInfo)
+	lockAuditStoreMockPseudonymiseEvents.Unlock()
+	return mock.PseudonymiseEventsFunc(ctx, identityID)
+}
+
+// PseudonymiseEventsCalls gets all the calls that were made to PseudonymiseEvents.
+// Check the length with:
+//     len(mockedAuditStore.PseudonymiseEventsCalls())
+func (mock *AuditStoreMock) PseudonymiseEventsCalls() []struct {
+	Ctx        context.Context
+	IdentityID string
+} {
+	var calls []struct {
+		Ctx        context.Context
+		IdentityID string
+	}
+	lockAuditStoreMockPseudonymiseEvents.RLock()
+	calls = mock.calls.PseudonymiseEvents
+	lockAuditStoreMockPseudonymiseEvents.RUnlock()
+	return calls
+}
+
+// StoreEvent calls StoreEventFunc.
+func (mock *AuditStoreMock) StoreEvent(ctx context.Context, event schema.AuditEvent) error {
+	if mock.StoreEventFunc == nil {
+		panic("moq: AuditStoreMock.StoreEventFunc is nil but AuditStore.StoreEvent was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Event schema.AuditEvent
+	}{
+		Ctx:   ctx,
+		Event: event,
+	}
+	lockAuditStoreMockStoreEvent.Lock()
+	mock.calls.StoreEvent = append(mock.calls.StoreEvent, callInfo)
+	lockAuditStoreMockStoreEvent.Unlock()
+	return mock.StoreEventFunc(ctx, event)
+}
+
+// StoreEventCalls gets all the calls that were made to StoreEvent.
+// Check the length with:
+//     len(mockedAuditStore.StoreEventCalls())
+func (mock *AuditStoreMock) StoreEventCalls() []struct {
+	Ctx   context.Context
+	Event schema.AuditEvent
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Event schema.AuditEvent
+	}
+	lockAuditStoreMockStoreEvent.RLock()
+	calls = mock.calls.StoreEvent
+	lockAuditStoreMockStoreEvent.RUnlock()
+	return calls
+}
+
+var (
+	lockRateLimitStoreMockGetRateLimit    sync.RWMutex
+	lockRateLimitStoreMockPurgeRateLimits sync.RWMutex
+	lockRateLimitStoreMockSwapRateLimit   sync.RWMutex
+)
+
+// RateLimitStoreMock is a mock implementation of RateLimitStore.
+//
+//     func TestSomethingThatUsesRateLimitStore(t *testing.T) {
+//
+//         // make and configure a mocked RateLimitStore
+//         mockedRateLimitStore := &RateLimitStoreMock{
+//             GetRateLimitFunc: func(ctx context.Context, key string) (time.Time, error) {
+// 	               panic("TODO: mock out the GetRateLimit method")
+//             },
+//             PurgeRateLimitsFunc: func(ctx context.Context, before time.Time) error {
+// 	               panic("TODO: mock out the PurgeRateLimits method")
+//             },
+//             SwapRateLimitFunc: func(ctx context.Context, key string, old time.Time, tat time.Time) (bool, error) {
+// 	               panic("TODO: mock out the SwapRateLimit method")
+//             },
+//         }
+//
+//         // TODO: use mockedRateLimitStore in code that requires RateLimitStore
+//         //       and then make assertions.
+//
+//     }
+type RateLimitStoreMock struct {
+	// GetRateLimitFunc mocks the GetRateLimit method.
+	GetRateLimitFunc func(ctx context.Context, key string) (time.Time, error)
+
+	// PurgeRateLimitsFunc mocks the PurgeRateLimits method.
+	PurgeRateLimitsFunc func(ctx context.Context, before time.Time) error
+
+	// SwapRateLimitFunc mocks the SwapRateLimit method.
+	SwapRateLimitFunc func(ctx context.Context, key string, old time.Time, tat time.Time) (bool, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// GetRateLimit holds details about calls to the GetRateLimit method.
+		GetRateLimit []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Key is the key argument value.
+			Key string
+		}
+		// PurgeRateLimits holds details about calls to the PurgeRateLimits method.
+		PurgeRateLimits []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Before is the before argument value.
+			Before time.Time
+		}
+		// SwapRateLimit holds details about calls to the SwapRateLimit method.
+		SwapRateLimit []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Key is the key argument value.
+			Key string
+			// Old is the old argument value.
+			Old time.Time
+			// Tat is the tat argument value.
+			Tat time.Time
+		}
+	}
+}
+
+// GetRateLimit calls GetRateLimitFunc.
+func (mock *RateLimitStoreMock) GetRateLimit(ctx context.Context, key string) (time.Time, error) {
+	if mock.GetRateLimitFunc == nil {
+		panic("moq: RateLimitStoreMock.GetRateLimitFunc is nil but RateLimitStore.GetRateLimit was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Key string
+	}{
+		Ctx: ctx,
+		Key: key,
+	}
+	lockRateLimitStoreMockGetRateLimit.Lock()
+	mock.calls.GetRateLimit = append(mock.calls.GetRateLimit, callInfo)
+	lockRateLimitStoreMockGetRateLimit.Unlock()
+	return mock.GetRateLimitFunc(ctx, key)
+}
+
+// GetRateLimitCalls gets all the calls that were made to GetRateLimit.
+// Check the length with:
+//     len(mockedRateLimitStore.GetRateLimitCalls())
+func (mock *RateLimitStoreMock) GetRateLimitCalls() []struct {
+	Ctx context.Context
+	Key string
+} {
+	var calls []struct {
+		Ctx context.Context
+		Key string
+	}
+	lockRateLimitStoreMockGetRateLimit.RLock()
+	calls = mock.calls.GetRateLimit
+	lockRateLimitStoreMockGetRateLimit.RUnlock()
+	return calls
+}
+
+// PurgeRateLimits calls PurgeRateLimitsFunc.
+func (mock *RateLimitStoreMock) PurgeRateLimits(ctx context.Context, before time.Time) error {
+	if mock.PurgeRateLimitsFunc == nil {
+		panic("moq: RateLimitStoreMock.PurgeRateLimitsFunc is nil but RateLimitStore.PurgeRateLimits was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Before time.Time
+	}{
+		Ctx:    ctx,
+		Before: before,
+	}
+	lockRateLimitStoreMockPurgeRateLimits.Lock()
+	mock.calls.PurgeRateLimits = append(mock.calls.PurgeRateLimits, callInfo)
+	lockRateLimitStoreMockPurgeRateLimits.Unlock()
+	return mock.PurgeRateLimitsFunc(ctx, before)
+}
+
+// PurgeRateLimitsCalls gets all the calls that were made to PurgeRateLimits.
+// Check the length with:
+//     len(mockedRateLimitStore.PurgeRateLimitsCalls())
+func (mock *RateLimitStoreMock) PurgeRateLimitsCalls() []struct {
+	Ctx    context.Context
+	Before time.Time
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Before time.Time
+	}
+	lockRateLimitStoreMockPurgeRateLimits.RLock()
+	calls = mock.calls.PurgeRateLimits
+	lockRateLimitStoreMockPurgeRateLimits.RUnlock()
+	return calls
+}
+
+// SwapRateLimit calls SwapRateLimitFunc.
+func (mock *RateLimitStoreMock) SwapRateLimit(ctx context.Context, key string, old time.Time, tat time.Time) (bool, error) {
+	if mock.SwapRateLimitFunc == nil {
+		panic("moq: RateLimitStoreMock.SwapRateLimitFunc is nil but RateLimitStore.SwapRateLimit was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Key string
+		Old time.Time
+		Tat time.Time
+	}{
+		Ctx: ctx,
+		Key: key,
+		Old: old,
+		Tat: tat,
+	}
+	lockRateLimitStoreMockSwapRateLimit.Lock()
+	mock.calls.SwapRateLimit = append(mock.calls.SwapRateLimit, callInfo)
+	lockRateLimitStoreMockSwapRateLimit.Unlock()
+	return mock.SwapRateLimitFunc(ctx, key, old, tat)
+}
+
+// SwapRateLimitCalls gets all the calls that were made to SwapRateLimit.
+// Check the length with:
+//     len(mockedRateLimitStore.SwapRateLimitCalls())
+func (mock *RateLimitStoreMock) SwapRateLimitCalls() []struct {
+	Ctx context.Context
+	Key string
+	Old time.Time
+	Tat time.Time
+} {
+	var calls []struct {
+		Ctx context.Context
+		Key string
+		Old time.Time
+		Tat time.Time
+	}
+	lockRateLimitStoreMockSwapRateLimit.RLock()
+	calls = mock.calls.SwapRateLimit
+	lockRateLimitStoreMockSwapRateLimit.RUnlock()
+	return calls
+}
@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/pkg/errors"
+)
+
+// StoreEvent store an audit event in the audit_events table. Params are stored as JSON.
+func (p *Postgres) StoreEvent(ctx context.Context, event schema.AuditEvent) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	var params []byte
+	if len(event.Params) > 0 {
+		var err error
+		if params, err = json.Marshal(event.Params); err != nil {
+			return errors.Wrap(err, "auditStore: error marshalling audit event params")
+		}
+	}
+
+	_, err := p.DB.ExecContext(ctx,
+		"INSERT INTO audit_events (identity_id, action, result, params, created) VALUES ($1, $2, $3, $4, $5)",
+		event.IdentityID, event.Action, event.Result, params, event.Created)
+	if err != nil {
+		if ctxErr := contextErr(ctx); ctxErr != nil {
+			return ctxErr
+		}
+		return errors.Wrap(err, "auditStore: error storing audit event")
+	}
+	return nil
+}
+
+// GetEvents return the audit events about the identity with the provided ID ordered by created date.
+func (p *Postgres) GetEvents(ctx context.Context, identityID string) ([]schema.AuditEvent, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.DB.QueryContext(ctx,
+		"SELECT identity_id, action, result, params, created FROM audit_events WHERE identity_id = $1 ORDER BY created",
+		identityID)
+	if err != nil {
+		if ctxErr := contextErr(ctx); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, errors.Wrap(err, "auditStore: error querying for audit events")
+	}
+	defer rows.Close()
+
+	events := []schema.AuditEvent{}
+	for rows.Next() {
+		var e schema.AuditEvent
+		var params []byte
+		if err := rows.Scan(&e.IdentityID, &e.Action, &e.Result, &params, &e.Created); err != nil {
+			return nil, errors.Wrap(err, "auditStore: error scanning audit event")
+		}
+
+		if params != nil {
+			if err := json.Unmarshal(params, &e.Params); err != nil {
+				return nil, errors.Wrap(err, "auditStore: error unmarshalling audit event params")
+			}
+		}
+		events = append(events, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		if ctxErr := contextErr(ctx); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, errors.Wrap(err, "auditStore: error querying for audit events")
+	}
+	return events, nil
+}
+
+// PseudonymiseEvents remove the params of the audit events about the identity with the provided ID.
+func (p *Postgres) PseudonymiseEvents(ctx context.Context, identityID string) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	_, err := p.DB.ExecContext(ctx, "UPDATE audit_events SET params = NULL WHERE identity_id = $1", identityID)
+	if err != nil {
+		if ctxErr := contextErr(ctx); ctxErr != nil {
+			return ctxErr
+		}
+		return errors.Wrap(err, "auditStore: error pseudonymising audit events")
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package postgres
+
+import (
+	"context"
+	"github.com/ONSdigital/dp-identity-api/persistence"
+)
+
+// withTimeout return a ctx bounded by the configured query timeout. The ctx deadline is kept if it is sooner.
+func (p *Postgres) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.QueryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, p.QueryTimeout)
+}
+
+// contextErr return persistence.ErrTimeout if the ctx deadline was exceeded, persistence.ErrUnavailable if the ctx was
+// cancelled, or nil if the ctx is not done.
+func contextErr(ctx context.Context) error {
+	switch ctx.Err() {
+	case nil:
+		return nil
+	case context.DeadlineExceeded:
+		return persistence.ErrTimeout
+	default:
+		return persistence.ErrUnavailable
+	}
+}
@@ -0,0 +1,29 @@
+package postgres
+
+import (
+	"database/sql"
+	"github.com/ONSdigital/go-ns/log"
+)
+
+// HealthCheckClient provides a healthcheck.Client implementation for health checking PostgreSQL.
+type HealthCheckClient struct {
+	db          *sql.DB
+	serviceName string
+}
+
+// NewHealthCheckClient returns a new health check client for the provided database.
+func NewHealthCheckClient(db *sql.DB) *HealthCheckClient {
+	return &HealthCheckClient{
+		db:          db,
+		serviceName: "postgres",
+	}
+}
+
+// Healthcheck pings the database to check its health status.
+func (c *HealthCheckClient) Healthcheck() (string, error) {
+	if err := c.db.Ping(); err != nil {
+		log.ErrorC("Ping postgres", err, nil)
+		return c.serviceName, err
+	}
+	return c.serviceName, nil
+}
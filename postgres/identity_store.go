@@ -0,0 +1,346 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/ONSdigital/dp-identity-api/persistence"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/satori/go.uuid"
+	"strings"
+	"time"
+)
+
+// identityColumns are the columns scanned into a schema.Identity. The verification token is never read, identities are
+// verified using VerifyIdentity.
+const identityColumns = "id, name, email, password, user_type, temporary_password, migrated, deleted, created_date, " +
+	"verified, disabled, disabled_reason, disabled_by, disabled_date"
+
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// prefixedScanner is a rowScanner scanning the leading columns of the row into prefix, so the remaining columns of a
+// joined row can be scanned by another function.
+type prefixedScanner struct {
+	row    rowScanner
+	prefix []interface{}
+}
+
+func (s prefixedScanner) Scan(dest ...interface{}) error {
+	return s.row.Scan(append(s.prefix, dest...)...)
+}
+
+// qualifiedColumns return the comma separated columns each qualified with the provided table alias.
+func qualifiedColumns(alias string, columns string) string {
+	qualified := strings.Split(columns, ", ")
+	for idx, c := range qualified {
+		qualified[idx] = alias + "." + c
+	}
+	return strings.Join(qualified, ", ")
+}
+
+// SaveIdentity store a new identity, returning the generated identity ID. Returns persistence.ErrNonUnique if an
+// active identity already exists with the same email.
+func (p *Postgres) SaveIdentity(ctx context.Context, identity schema.Identity) (string, error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return "", errors.Wrap(err, "error generating uuid")
+	}
+
+	identity.ID = id.String()
+	identity.CreatedDate = time.Now()
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	var disabledDate pq.NullTime
+	if identity.DisabledDate != nil {
+		disabledDate = pq.NullTime{Time: *identity.DisabledDate, Valid: true}
+	}
+
+	_, err = p.DB.ExecContext(ctx,
+		"INSERT INTO identities ("+identityColumns+", verification_token, verification_expiry) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)",
+		identity.ID, identity.Name, identity.Email, identity.Password, identity.UserType, identity.TemporaryPassword,
+		identity.Migrated, identity.Deleted, identity.CreatedDate, identity.Verified, identity.Disabled,
+		sql.NullString{String: identity.DisabledReason, Valid: identity.DisabledReason != ""},
+		sql.NullString{String: identity.DisabledBy, Valid: identity.DisabledBy != ""},
+		disabledDate,
+		sql.NullString{String: identity.VerificationToken, Valid: identity.VerificationToken != ""},
+		pq.NullTime{Time: identity.VerificationExpiry, Valid: !identity.VerificationExpiry.IsZero()},
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return "", persistence.ErrNonUnique
+		}
+		if ctxErr := contextErr(ctx); ctxErr != nil {
+			return "", ctxErr
+		}
+		return "", errors.Wrap(err, "error inserting identity")
+	}
+
+	return identity.ID, nil
+}
+
+// GetIdentity return the active identity with the provided email. Returns persistence.ErrNotFound if no active
+// identity exists.
+func (p *Postgres) GetIdentity(ctx context.Context, email string) (schema.Identity, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	row := p.DB.QueryRowContext(ctx, "SELECT "+identityColumns+" FROM identities WHERE email = $1 AND NOT deleted", email)
+
+	i, err := scanIdentity(ctx, row)
+	if err != nil {
+		return schema.NilIdentity, err
+	}
+	return *i, nil
+}
+
+// GetIdentityByID return the active identity with the provided ID. Returns persistence.ErrNotFound if no active
+// identity exists.
+func (p *Postgres) GetIdentityByID(ctx context.Context, id string) (*schema.Identity, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	row := p.DB.QueryRowContext(ctx, "SELECT "+identityColumns+" FROM identities WHERE id = $1 AND NOT deleted", id)
+	return scanIdentity(ctx, row)
+}
+
+// GetIdentityByIDIncludingDeleted return the identity with the provided ID whether or not it has been deleted. Returns
+// persistence.ErrNotFound if no identity exists.
+func (p *Postgres) GetIdentityByIDIncludingDeleted(ctx context.Context, id string) (*schema.Identity, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	row := p.DB.QueryRowContext(ctx, "SELECT "+identityColumns+" FROM identities WHERE id = $1", id)
+	return scanIdentity(ctx, row)
+}
+
+// UpdatePassword replace the stored password hash of the active identity with the provided ID and clear its migrated
+// flag.
+func (p *Postgres) UpdatePassword(ctx context.Context, id string, password string) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	res, err := p.DB.ExecContext(ctx,
+		"UPDATE identities SET password = $1, migrated = false WHERE id = $2 AND NOT deleted", password, id)
+	if err != nil {
+		if ctxErr := contextErr(ctx); ctxErr != nil {
+			return ctxErr
+		}
+		return errors.Wrap(err, "error updating identity password")
+	}
+
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "error getting updated identity count")
+	}
+
+	if updated == 0 {
+		return persistence.ErrNotFound
+	}
+	return nil
+}
+
+// VerifyIdentity mark the active identity issued the unexpired verification token with the provided digest as verified
+// and clear the token. Returns persistence.ErrNotFound if no such identity exists.
+func (p *Postgres) VerifyIdentity(ctx context.Context, token string) (*schema.Identity, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	row := p.DB.QueryRowContext(ctx,
+		"UPDATE identities SET verified = true, verification_token = NULL, verification_expiry = NULL "+
+			"WHERE verification_token = $1 AND verification_expiry > $2 AND NOT deleted RETURNING "+identityColumns,
+		token, time.Now())
+	return scanIdentity(ctx, row)
+}
+
+// SetPendingEmail record a requested email change for the active identity with the provided ID. Returns
+// persistence.ErrNotFound if no active identity exists.
+func (p *Postgres) SetPendingEmail(ctx context.Context, id string, email string, token string, expiry time.Time) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	res, err := p.DB.ExecContext(ctx,
+		"UPDATE identities SET pending_email = $1, email_change_token = $2, email_change_expiry = $3 "+
+			"WHERE id = $4 AND NOT deleted", email, token, expiry, id)
+	if err != nil {
+		if ctxErr := contextErr(ctx); ctxErr != nil {
+			return ctxErr
+		}
+		return errors.Wrap(err, "error setting identity pending email")
+	}
+
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "error getting updated identity count")
+	}
+
+	if updated == 0 {
+		return persistence.ErrNotFound
+	}
+	return nil
+}
+
+// ConfirmEmailChange apply the pending email of the active identity issued the unexpired email change token with the
+// provided digest. Returns persistence.ErrNonUnique if the pending email is used by another active identity, enforced
+// by the unique index on active emails, or persistence.ErrNotFound if no such identity exists.
+func (p *Postgres) ConfirmEmailChange(ctx context.Context, token string) (*schema.Identity, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	row := p.DB.QueryRowContext(ctx,
+		"UPDATE identities SET email = pending_email, verified = true, pending_email = NULL, "+
+			"email_change_token = NULL, email_change_expiry = NULL "+
+			"WHERE email_change_token = $1 AND email_change_expiry > $2 AND NOT deleted RETURNING "+identityColumns,
+		token, time.Now())
+
+	i, err := scanIdentity(ctx, row)
+	if err != nil && isUniqueViolation(errors.Cause(err)) {
+		return nil, persistence.ErrNonUnique
+	}
+	return i, err
+}
+
+// DisableIdentity mark the active identity with the provided ID as disabled. Returns persistence.ErrNotFound if no
+// active identity exists.
+func (p *Postgres) DisableIdentity(ctx context.Context, id string, reason string, disabledBy string, date time.Time) error {
+	return p.updateActiveIdentity(ctx, "error disabling identity",
+		"UPDATE identities SET disabled = true, disabled_reason = $1, disabled_by = $2, disabled_date = $3 "+
+			"WHERE id = $4 AND NOT deleted", reason, disabledBy, date, id)
+}
+
+// EnableIdentity clear the disabled flag of the active identity with the provided ID. Returns persistence.ErrNotFound
+// if no active identity exists.
+func (p *Postgres) EnableIdentity(ctx context.Context, id string) error {
+	return p.updateActiveIdentity(ctx, "error enabling identity",
+		"UPDATE identities SET disabled = false, disabled_reason = NULL, disabled_by = NULL, disabled_date = NULL "+
+			"WHERE id = $1 AND NOT deleted", id)
+}
+
+// updateActiveIdentity execute an update of a single active identity. Returns persistence.ErrNotFound if no row was
+// updated.
+func (p *Postgres) updateActiveIdentity(ctx context.Context, msg string, query string, args ...interface{}) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	res, err := p.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		if ctxErr := contextErr(ctx); ctxErr != nil {
+			return ctxErr
+		}
+		return errors.Wrap(err, msg)
+	}
+
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "error getting updated identity count")
+	}
+
+	if updated == 0 {
+		return persistence.ErrNotFound
+	}
+	return nil
+}
+
+// EraseIdentity remove the identity with the ID of the tombstone and store the tombstone in the same transaction.
+// Returns persistence.ErrNotFound if no identity exists.
+func (p *Postgres) EraseIdentity(ctx context.Context, tombstone schema.Tombstone) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := p.DB.BeginTx(ctx, nil)
+	if err != nil {
+		if ctxErr := contextErr(ctx); ctxErr != nil {
+			return ctxErr
+		}
+		return errors.Wrap(err, "error beginning transaction")
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM identities WHERE id = $1", tombstone.ID)
+	if err != nil {
+		if ctxErr := contextErr(ctx); ctxErr != nil {
+			return ctxErr
+		}
+		return errors.Wrap(err, "error erasing identity")
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "error getting erased identity count")
+	}
+
+	if deleted == 0 {
+		return persistence.ErrNotFound
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO tombstones (id, erased_by, erased_date) VALUES ($1, $2, $3)",
+		tombstone.ID, tombstone.ErasedBy, tombstone.ErasedDate)
+	if err != nil {
+		if ctxErr := contextErr(ctx); ctxErr != nil {
+			return ctxErr
+		}
+		return errors.Wrap(err, "error storing tombstone")
+	}
+
+	if err := tx.Commit(); err != nil {
+		if ctxErr := contextErr(ctx); ctxErr != nil {
+			return ctxErr
+		}
+		return errors.Wrap(err, "error committing identity erasure")
+	}
+	return nil
+}
+
+// GetTombstone return the tombstone of the erased identity with the provided ID. Returns persistence.ErrNotFound if
+// the identity has not been erased.
+func (p *Postgres) GetTombstone(ctx context.Context, id string) (*schema.Tombstone, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	var t schema.Tombstone
+	err := p.DB.QueryRowContext(ctx, "SELECT id, erased_by, erased_date FROM tombstones WHERE id = $1", id).
+		Scan(&t.ID, &t.ErasedBy, &t.ErasedDate)
+	if err == sql.ErrNoRows {
+		return nil, persistence.ErrNotFound
+	}
+
+	if err != nil {
+		if ctxErr := contextErr(ctx); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, errors.Wrap(err, "error querying for tombstone")
+	}
+	return &t, nil
+}
+
+func scanIdentity(ctx context.Context, row rowScanner) (*schema.Identity, error) {
+	var i schema.Identity
+	var reason, disabledBy sql.NullString
+	var disabledDate pq.NullTime
+
+	err := row.Scan(&i.ID, &i.Name, &i.Email, &i.Password, &i.UserType, &i.TemporaryPassword, &i.Migrated, &i.Deleted,
+		&i.CreatedDate, &i.Verified, &i.Disabled, &reason, &disabledBy, &disabledDate)
+	if err == sql.ErrNoRows {
+		return nil, persistence.ErrNotFound
+	}
+
+	if err != nil {
+		if ctxErr := contextErr(ctx); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, errors.Wrap(err, "error querying for identity")
+	}
+
+	i.DisabledReason = reason.String
+	i.DisabledBy = disabledBy.String
+	if disabledDate.Valid {
+		i.DisabledDate = &disabledDate.Time
+	}
+	return &i, nil
+}
@@ -0,0 +1,152 @@
+package postgres
+
+import (
+	"database/sql"
+	"github.com/ONSdigital/go-ns/log"
+	"github.com/pkg/errors"
+)
+
+// migrationLockID is the advisory lock key held while migrations are applied, preventing multiple instances
+// migrating the schema concurrently.
+const migrationLockID = 23800
+
+// migrations are the schema migrations applied in order. The index of a migration is its version, existing
+// migrations must never be modified - add a new migration instead.
+var migrations = []string{
+	// 1: identities and tokens tables. Emails are unique across active identities and each identity has at most one
+	// active token.
+	`CREATE TABLE identities (
+		id                 TEXT PRIMARY KEY,
+		name               TEXT NOT NULL,
+		email              TEXT NOT NULL,
+		password           TEXT NOT NULL,
+		user_type          TEXT NOT NULL DEFAULT '',
+		temporary_password BOOLEAN NOT NULL DEFAULT false,
+		migrated           BOOLEAN NOT NULL DEFAULT false,
+		deleted            BOOLEAN NOT NULL DEFAULT false,
+		created_date       TIMESTAMPTZ NOT NULL
+	);
+
+	CREATE UNIQUE INDEX identities_active_email_idx ON identities (email) WHERE NOT deleted;
+
+	CREATE TABLE tokens (
+		token_id      TEXT PRIMARY KEY,
+		identity_id   TEXT NOT NULL,
+		created_date  TIMESTAMPTZ NOT NULL,
+		expiry_date   TIMESTAMPTZ NOT NULL,
+		last_modified TIMESTAMPTZ NOT NULL,
+		deleted       BOOLEAN NOT NULL DEFAULT false
+	);
+
+	CREATE UNIQUE INDEX tokens_active_identity_idx ON tokens (identity_id) WHERE NOT deleted;`,
+
+	// 2: token last used time, existing tokens are treated as last used when they were created.
+	`ALTER TABLE tokens ADD COLUMN last_used TIMESTAMPTZ;
+	UPDATE tokens SET last_used = created_date;
+	ALTER TABLE tokens ALTER COLUMN last_used SET NOT NULL;`,
+
+	// 3: email verification, existing identities are treated as verified. Verification tokens are only set until the
+	// identity is verified.
+	`ALTER TABLE identities ADD COLUMN verified BOOLEAN NOT NULL DEFAULT false;
+	ALTER TABLE identities ADD COLUMN verification_token TEXT;
+	ALTER TABLE identities ADD COLUMN verification_expiry TIMESTAMPTZ;
+	UPDATE identities SET verified = true;
+
+	CREATE UNIQUE INDEX identities_verification_token_idx ON identities (verification_token)
+		WHERE verification_token IS NOT NULL;`,
+
+	// 4: pending email changes, only set until the change is confirmed.
+	`ALTER TABLE identities ADD COLUMN pending_email TEXT;
+	ALTER TABLE identities ADD COLUMN email_change_token TEXT;
+	ALTER TABLE identities ADD COLUMN email_change_expiry TIMESTAMPTZ;
+
+	CREATE UNIQUE INDEX identities_email_change_token_idx ON identities (email_change_token)
+		WHERE email_change_token IS NOT NULL;`,
+
+	// 5: disabled identities, the reason, actor and date are only set while the identity is disabled.
+	`ALTER TABLE identities ADD COLUMN disabled BOOLEAN NOT NULL DEFAULT false;
+	ALTER TABLE identities ADD COLUMN disabled_reason TEXT;
+	ALTER TABLE identities ADD COLUMN disabled_by TEXT;
+	ALTER TABLE identities ADD COLUMN disabled_date TIMESTAMPTZ;`,
+
+	// 6: audit events about each identity, for data exports, and tombstones recording erased identities.
+	`CREATE TABLE audit_events (
+		identity_id TEXT        NOT NULL,
+		action      TEXT        NOT NULL,
+		result      TEXT        NOT NULL,
+		params      JSONB,
+		created     TIMESTAMPTZ NOT NULL
+	);
+
+	CREATE INDEX audit_events_identity_id_idx ON audit_events (identity_id, created);
+
+	CREATE TABLE tombstones (
+		id          TEXT PRIMARY KEY,
+		erased_by   TEXT        NOT NULL,
+		erased_date TIMESTAMPTZ NOT NULL
+	);`,
+
+	// 7: impersonation tokens, each identity has at most one active token of its own and one per impersonating admin.
+	`ALTER TABLE tokens ADD COLUMN impersonated_by TEXT NOT NULL DEFAULT '';
+
+	DROP INDEX tokens_active_identity_idx;
+	CREATE UNIQUE INDEX tokens_active_identity_idx ON tokens (identity_id, impersonated_by) WHERE NOT deleted;`,
+
+	// 8: rate limit buckets shared by every instance, storing the theoretical arrival time (TAT) of each bucket.
+	`CREATE TABLE rate_limits (
+		key TEXT        PRIMARY KEY,
+		tat TIMESTAMPTZ NOT NULL
+	);
+
+	CREATE INDEX rate_limits_tat_idx ON rate_limits (tat);`,
+}
+
+// migrate applies any migrations not yet applied to the database in a single transaction.
+func (p *Postgres) migrate() error {
+	tx, err := p.DB.Begin()
+	if err != nil {
+		return errors.Wrap(err, "migrate: error beginning transaction")
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock($1)", migrationLockID); err != nil {
+		return errors.Wrap(err, "migrate: error acquiring migration lock")
+	}
+
+	if _, err := tx.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`); err != nil {
+		return errors.Wrap(err, "migrate: error creating schema_migrations table")
+	}
+
+	current, err := currentVersion(tx)
+	if err != nil {
+		return err
+	}
+
+	for i := current; i < len(migrations); i++ {
+		version := i + 1
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			return errors.Wrapf(err, "migrate: error applying migration %d", version)
+		}
+
+		if _, err := tx.Exec("INSERT INTO schema_migrations (version) VALUES ($1)", version); err != nil {
+			return errors.Wrapf(err, "migrate: error recording migration %d", version)
+		}
+		log.Info("postgres: applied schema migration", log.Data{"version": version})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "migrate: error committing migrations")
+	}
+	return nil
+}
+
+func currentVersion(tx *sql.Tx) (int, error) {
+	var version int
+	if err := tx.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version); err != nil {
+		return 0, errors.Wrap(err, "migrate: error querying current schema version")
+	}
+	return version, nil
+}
@@ -0,0 +1,57 @@
+// Package postgres provides a PostgreSQL implementation of persistence.IdentityStore and persistence.TokenStore.
+package postgres
+
+import (
+	"database/sql"
+	"github.com/ONSdigital/dp-identity-api/config"
+	"github.com/ONSdigital/go-ns/log"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"time"
+)
+
+const (
+	// uniqueViolation is the PostgreSQL error code returned when a unique constraint is violated.
+	uniqueViolation = "23505"
+)
+
+// Postgres is a PostgreSQL identity and token store.
+type Postgres struct {
+	DB           *sql.DB
+	QueryTimeout time.Duration
+}
+
+// New construct a new Postgres store connected to the configured database, applying any pending schema migrations.
+func New(cfg config.PostgresConfig) (*Postgres, error) {
+	db, err := sql.Open("postgres", cfg.URL)
+	if err != nil {
+		return nil, errors.Wrap(err, "error opening postgres connection")
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, "error connecting to postgres")
+	}
+
+	p := &Postgres{DB: db, QueryTimeout: cfg.QueryTimeout}
+	if err := p.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	log.Info("postgres: connected and schema up to date", nil)
+	return p, nil
+}
+
+// Close closes the underlying database connection pool.
+func (p *Postgres) Close() error {
+	return p.DB.Close()
+}
+
+// isUniqueViolation return true if the error was caused by a unique constraint violation.
+func isUniqueViolation(err error) bool {
+	if pqErr, ok := err.(*pq.Error); ok {
+		return pqErr.Code == uniqueViolation
+	}
+	return false
+}
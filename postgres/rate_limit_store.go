@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// GetRateLimit return the TAT of the rate limit bucket with the provided key, or the zero time if no bucket exists.
+func (p *Postgres) GetRateLimit(ctx context.Context, key string) (time.Time, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	var tat time.Time
+	err := p.DB.QueryRowContext(ctx, "SELECT tat FROM rate_limits WHERE key = $1", key).Scan(&tat)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+
+	if err != nil {
+		if ctxErr := contextErr(ctx); ctxErr != nil {
+			return time.Time{}, ctxErr
+		}
+		return time.Time{}, errors.Wrap(err, "rateLimitStore: error querying for rate limit")
+	}
+	return tat, nil
+}
+
+// SwapRateLimit set the TAT of the rate limit bucket with the provided key if its TAT equals old, or insert the bucket
+// if old is zero. Returns false if the bucket has changed.
+func (p *Postgres) SwapRateLimit(ctx context.Context, key string, old time.Time, tat time.Time) (bool, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	var res sql.Result
+	var err error
+	if old.IsZero() {
+		res, err = p.DB.ExecContext(ctx,
+			"INSERT INTO rate_limits (key, tat) VALUES ($1, $2) ON CONFLICT (key) DO NOTHING", key, tat)
+	} else {
+		res, err = p.DB.ExecContext(ctx,
+			"UPDATE rate_limits SET tat = $3 WHERE key = $1 AND tat = $2", key, old, tat)
+	}
+
+	if err != nil {
+		if ctxErr := contextErr(ctx); ctxErr != nil {
+			return false, ctxErr
+		}
+		return false, errors.Wrap(err, "rateLimitStore: error updating rate limit")
+	}
+
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return false, errors.Wrap(err, "rateLimitStore: error getting updated rate limit count")
+	}
+	return updated == 1, nil
+}
+
+// PurgeRateLimits remove the rate limit buckets with a TAT before the provided time.
+func (p *Postgres) PurgeRateLimits(ctx context.Context, before time.Time) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	if _, err := p.DB.ExecContext(ctx, "DELETE FROM rate_limits WHERE tat < $1", before); err != nil {
+		if ctxErr := contextErr(ctx); ctxErr != nil {
+			return ctxErr
+		}
+		return errors.Wrap(err, "rateLimitStore: error purging rate limits")
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/ONSdigital/dp-identity-api/config"
+	"github.com/ONSdigital/dp-identity-api/persistence"
+	"github.com/ONSdigital/dp-identity-api/persistence/persistencetest"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	. "github.com/smartystreets/goconvey/convey"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testURLEnv = "POSTGRES_TEST_URL"
+
+// TestPostgres_Contract runs the persistence contract tests against a local Postgres instance. Skipped unless
+// POSTGRES_TEST_URL is set. All rows are deleted before each test case.
+func TestPostgres_Contract(t *testing.T) {
+	url := os.Getenv(testURLEnv)
+	if url == "" {
+		t.Skipf("%s not set skipping postgres contract tests", testURLEnv)
+	}
+
+	p, err := New(config.PostgresConfig{URL: url})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	persistencetest.RunContractTests(t, func() persistence.Store {
+		if _, err := p.DB.Exec("TRUNCATE identities, tokens, audit_events, tombstones, rate_limits"); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	})
+}
+
+func TestPostgres_MigrateIdempotent(t *testing.T) {
+	url := os.Getenv(testURLEnv)
+	if url == "" {
+		t.Skipf("%s not set skipping postgres migration tests", testURLEnv)
+	}
+
+	Convey("given the schema migrations have been applied", t, func() {
+		p, err := New(config.PostgresConfig{URL: url})
+		So(err, ShouldBeNil)
+		defer p.Close()
+
+		Convey("when the migrations are applied again", func() {
+			err := p.migrate()
+
+			Convey("then no error is returned and the schema is at the latest version", func() {
+				So(err, ShouldBeNil)
+
+				var version int
+				So(p.DB.QueryRow("SELECT MAX(version) FROM schema_migrations").Scan(&version), ShouldBeNil)
+				So(version, ShouldEqual, len(migrations))
+			})
+		})
+	})
+}
+
+func TestPostgres_StoreTokenConcurrent(t *testing.T) {
+	url := os.Getenv(testURLEnv)
+	if url == "" {
+		t.Skipf("%s not set skipping postgres concurrent login tests", testURLEnv)
+	}
+
+	Convey("given an identity logging in concurrently", t, func() {
+		p, err := New(config.PostgresConfig{URL: url})
+		So(err, ShouldBeNil)
+		defer p.Close()
+
+		_, err = p.DB.Exec("TRUNCATE identities, tokens, audit_events, tombstones, rate_limits")
+		So(err, ShouldBeNil)
+
+		ctx := context.Background()
+		i := schema.Identity{Name: "Peter Venkman", Email: "venkman@ghostbusters.com", Password: "hashed"}
+		i.ID, err = p.SaveIdentity(ctx, i)
+		So(err, ShouldBeNil)
+
+		Convey("when pairs of tokens are stored at the same time", func() {
+			var errs []error
+			var mutex sync.Mutex
+
+			for n := 0; n < 50; n++ {
+				var wg sync.WaitGroup
+				for c := 0; c < 2; c++ {
+					wg.Add(1)
+					go func(id string) {
+						defer wg.Done()
+						now := time.Now()
+						tkn := schema.Token{ID: id, IdentityID: i.ID, CreatedDate: now, ExpiryDate: now.Add(time.Hour), LastUsed: now}
+						if err := p.StoreToken(ctx, tkn, i); err != nil {
+							mutex.Lock()
+							errs = append(errs, err)
+							mutex.Unlock()
+						}
+					}(fmt.Sprintf("token-%d-%d", n, c))
+				}
+				wg.Wait()
+			}
+
+			Convey("then every token is stored without error and only one is active", func() {
+				So(errs, ShouldBeEmpty)
+
+				var active int
+				So(p.DB.QueryRow("SELECT COUNT(*) FROM tokens WHERE identity_id = $1 AND NOT deleted", i.ID).Scan(&active), ShouldBeNil)
+				So(active, ShouldEqual, 1)
+			})
+		})
+	})
+}
@@ -0,0 +1,216 @@
+package postgres
+
+import (
+	"context"
+	"github.com/ONSdigital/dp-identity-api/persistence"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/ONSdigital/go-ns/log"
+	"github.com/pkg/errors"
+	"time"
+)
+
+const (
+	identityIDKey = "identity_id"
+	tokenColumns  = "token_id, identity_id, created_date, expiry_date, last_modified, last_used, deleted, impersonated_by"
+)
+
+// StoreToken store a new token in the tokens table. Any active token associated with the identity and issued to the
+// same impersonator will be marked as deleted in the same transaction. Sets the last modified date on all rows updated.
+//
+// A concurrent login for the same identity may commit its token between the update and the insert, violating the
+// unique index of active tokens. The transaction is retried once in that case, replacing the concurrent token.
+func (p *Postgres) StoreToken(ctx context.Context, tkn schema.Token, i schema.Identity) error {
+	logD := log.Data{identityIDKey: i.ID}
+	log.InfoCtx(ctx, "tokenStore: storing identity token", logD)
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	err := p.replaceActiveToken(ctx, tkn, i)
+	if err != nil && isUniqueViolation(errors.Cause(err)) {
+		log.InfoCtx(ctx, "tokenStore: active token stored concurrently, retrying", logD)
+		err = p.replaceActiveToken(ctx, tkn, i)
+	}
+	if err != nil {
+		return err
+	}
+
+	log.InfoCtx(ctx, "tokenStore: store new active identity token successful", logD)
+	return nil
+}
+
+// replaceActiveToken mark the active tokens of the identity issued to the same impersonator as deleted and insert the
+// new token in a single transaction.
+func (p *Postgres) replaceActiveToken(ctx context.Context, tkn schema.Token, i schema.Identity) error {
+	tx, err := p.DB.BeginTx(ctx, nil)
+	if err != nil {
+		if ctxErr := contextErr(ctx); ctxErr != nil {
+			return ctxErr
+		}
+		return errors.Wrap(err, "tokenStore: error beginning transaction")
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE tokens SET deleted = true, last_modified = $1 WHERE identity_id = $2 AND impersonated_by = $3 AND NOT deleted",
+		now, i.ID, tkn.ImpersonatedBy)
+	if err != nil {
+		if ctxErr := contextErr(ctx); ctxErr != nil {
+			return ctxErr
+		}
+		return errors.Wrap(err, "tokenStore: error deleting active token(s) for identity")
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO tokens ("+tokenColumns+") VALUES ($1, $2, $3, $4, $5, $6, false, $7)",
+		tkn.ID, tkn.IdentityID, tkn.CreatedDate, tkn.ExpiryDate, now, tkn.LastUsed, tkn.ImpersonatedBy,
+	)
+	if err != nil {
+		if ctxErr := contextErr(ctx); ctxErr != nil {
+			return ctxErr
+		}
+		return errors.Wrap(err, "tokenStore: error while storing new active identity token")
+	}
+
+	if err := tx.Commit(); err != nil {
+		if ctxErr := contextErr(ctx); ctxErr != nil {
+			return ctxErr
+		}
+		return errors.Wrap(err, "tokenStore: error committing new active identity token")
+	}
+	return nil
+}
+
+// GetIdentityByToken return the identity and active token for the provided token digest. The token and its active
+// identity are retrieved in a single round trip by joining the identities table onto the matching token, so a token
+// whose identity has been deleted is not found.
+func (p *Postgres) GetIdentityByToken(ctx context.Context, token string) (*schema.Identity, *schema.Token, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	row := p.DB.QueryRowContext(ctx,
+		"SELECT "+qualifiedColumns("t", tokenColumns)+", "+qualifiedColumns("i", identityColumns)+
+			" FROM tokens t JOIN identities i ON i.id = t.identity_id AND NOT i.deleted"+
+			" WHERE t.token_id = $1 AND NOT t.deleted", token)
+
+	var t schema.Token
+	i, err := scanIdentity(ctx, prefixedScanner{row: row, prefix: []interface{}{
+		&t.ID, &t.IdentityID, &t.CreatedDate, &t.ExpiryDate, &t.LastModified, &t.LastUsed, &t.Deleted, &t.ImpersonatedBy,
+	}})
+	if err == persistence.ErrNotFound {
+		log.InfoCtx(ctx, "active token for this values does not exist", nil)
+		return nil, nil, err
+	}
+	if err == persistence.ErrTimeout || err == persistence.ErrUnavailable {
+		return nil, nil, err
+	}
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error querying for active token and identity")
+	}
+	return i, &t, nil
+}
+
+// RevokeTokens mark the active tokens of the identity with the provided ID as deleted, returning their digests.
+func (p *Postgres) RevokeTokens(ctx context.Context, identityID string) ([]string, error) {
+	return p.queryDigests(ctx, "tokenStore: error revoking active token(s) for identity",
+		"UPDATE tokens SET deleted = true, last_modified = $1 WHERE identity_id = $2 AND NOT deleted RETURNING token_id",
+		time.Now(), identityID)
+}
+
+// GetTokens return every token of the identity with the provided ID ordered by created date.
+func (p *Postgres) GetTokens(ctx context.Context, identityID string) ([]schema.Token, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.DB.QueryContext(ctx,
+		"SELECT "+tokenColumns+" FROM tokens WHERE identity_id = $1 ORDER BY created_date", identityID)
+	if err != nil {
+		if ctxErr := contextErr(ctx); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, errors.Wrap(err, "tokenStore: error querying for identity tokens")
+	}
+	defer rows.Close()
+
+	tokens := []schema.Token{}
+	for rows.Next() {
+		var t schema.Token
+		if err := rows.Scan(&t.ID, &t.IdentityID, &t.CreatedDate, &t.ExpiryDate, &t.LastModified, &t.LastUsed, &t.Deleted, &t.ImpersonatedBy); err != nil {
+			return nil, errors.Wrap(err, "tokenStore: error scanning identity token")
+		}
+		tokens = append(tokens, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		if ctxErr := contextErr(ctx); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, errors.Wrap(err, "tokenStore: error querying for identity tokens")
+	}
+	return tokens, nil
+}
+
+// DeleteTokens remove every token of the identity with the provided ID, returning their digests.
+func (p *Postgres) DeleteTokens(ctx context.Context, identityID string) ([]string, error) {
+	return p.queryDigests(ctx, "tokenStore: error removing identity tokens",
+		"DELETE FROM tokens WHERE identity_id = $1 RETURNING token_id", identityID)
+}
+
+// queryDigests execute a query returning the token_id of each row it affects.
+func (p *Postgres) queryDigests(ctx context.Context, msg string, query string, args ...interface{}) ([]string, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		if ctxErr := contextErr(ctx); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, errors.Wrap(err, msg)
+	}
+	defer rows.Close()
+
+	digests := []string{}
+	for rows.Next() {
+		var digest string
+		if err := rows.Scan(&digest); err != nil {
+			return nil, errors.Wrap(err, "tokenStore: error scanning token")
+		}
+		digests = append(digests, digest)
+	}
+
+	if err := rows.Err(); err != nil {
+		if ctxErr := contextErr(ctx); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, errors.Wrap(err, msg)
+	}
+	return digests, nil
+}
+
+// UpdateLastUsed set the last used time of the active token with the provided digest. Returns persistence.ErrNotFound
+// if no active token exists.
+func (p *Postgres) UpdateLastUsed(ctx context.Context, token string, lastUsed time.Time) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	res, err := p.DB.ExecContext(ctx, "UPDATE tokens SET last_used = $1 WHERE token_id = $2 AND NOT deleted", lastUsed, token)
+	if err != nil {
+		if ctxErr := contextErr(ctx); ctxErr != nil {
+			return ctxErr
+		}
+		return errors.Wrap(err, "tokenStore: error updating token last used time")
+	}
+
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "tokenStore: error getting updated token count")
+	}
+
+	if updated == 0 {
+		return persistence.ErrNotFound
+	}
+	return nil
+}
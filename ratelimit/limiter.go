@@ -0,0 +1,111 @@
+// Package ratelimit provides a token bucket rate limiter storing its buckets in a persistence.RateLimitStore, so
+// instances sharing a store share their limits.
+//
+// Buckets are implemented using the generic cell rate algorithm (GCRA), storing only the theoretical arrival time (TAT)
+// of the next request for each bucket. A request is allowed if it arrives no earlier than the TAT less the burst
+// tolerance, advancing the TAT by one emission interval.
+package ratelimit
+
+import (
+	"context"
+	"github.com/ONSdigital/dp-identity-api/persistence"
+	"github.com/ONSdigital/go-ns/log"
+	"github.com/pkg/errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// maxSwapAttempts is the number of times a bucket is read and swapped before giving up when other requests for the same
+// key keep changing it first.
+const maxSwapAttempts = 5
+
+// ErrContention is returned if a bucket could not be updated because concurrent requests kept changing it.
+var ErrContention = errors.New("rate limit bucket contention")
+
+// Limit is the rate at which a bucket refills in requests per second, and the number of requests it holds.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Limiter allows requests while the bucket for their key holds a token. Buckets with a TAT in the past no longer limit
+// any request and are purged from the Store at most once every PurgeInterval, or never if PurgeInterval is zero.
+type Limiter struct {
+	Store         persistence.RateLimitStore
+	PurgeInterval time.Duration
+	now           func() time.Time
+	mutex         sync.Mutex
+	lastPurge     time.Time
+}
+
+// Take a token from the bucket with the provided key, returning zero if the request is allowed or how long to wait
+// before the bucket will hold a token if it is not.
+func (l *Limiter) Take(ctx context.Context, key string, limit Limit) (time.Duration, error) {
+	interval := time.Duration(float64(time.Second) / limit.Rate)
+	tolerance := interval * time.Duration(limit.Burst)
+
+	now := l.clock()
+	l.purge(ctx, now)
+
+	for i := 0; i < maxSwapAttempts; i++ {
+		old, err := l.Store.GetRateLimit(ctx, key)
+		if err != nil {
+			return 0, errors.Wrap(err, "error getting rate limit")
+		}
+
+		tat := old
+		if tat.Before(now) {
+			tat = now
+		}
+
+		next := tat.Add(interval)
+		if wait := next.Sub(now) - tolerance; wait > 0 {
+			return wait, nil
+		}
+
+		// stores may not preserve sub-millisecond precision, which would prevent the TAT being compared when swapped
+		swapped, err := l.Store.SwapRateLimit(ctx, key, old, next.Truncate(time.Millisecond))
+		if err != nil {
+			return 0, errors.Wrap(err, "error swapping rate limit")
+		}
+
+		if swapped {
+			return 0, nil
+		}
+	}
+	return 0, ErrContention
+}
+
+// RetryAfter return the whole number of seconds to wait before retrying a request, rounded up so a client waiting this
+// long is allowed.
+func RetryAfter(wait time.Duration) int {
+	return int(math.Ceil(wait.Seconds()))
+}
+
+func (l *Limiter) clock() time.Time {
+	if l.now != nil {
+		return l.now()
+	}
+	return time.Now()
+}
+
+// purge removes the buckets with a TAT in the past if PurgeInterval has elapsed since the last purge. Errors are
+// logged rather than returned as they do not affect the request being limited.
+func (l *Limiter) purge(ctx context.Context, now time.Time) {
+	if l.PurgeInterval <= 0 {
+		return
+	}
+
+	l.mutex.Lock()
+	if now.Sub(l.lastPurge) < l.PurgeInterval {
+		l.mutex.Unlock()
+		return
+	}
+	l.lastPurge = now
+	l.mutex.Unlock()
+
+	if err := l.Store.PurgeRateLimits(ctx, now); err != nil {
+		log.ErrorCtx(ctx, errors.WithMessage(err, "rate limit: failed to purge buckets"), nil)
+	}
+}
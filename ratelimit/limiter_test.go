@@ -0,0 +1,135 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"github.com/ONSdigital/dp-identity-api/persistence/memory"
+	"github.com/ONSdigital/dp-identity-api/persistence/persistencetest"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+var errTest = errors.New("test error")
+
+func TestLimiter_Take(t *testing.T) {
+	Convey("given a limiter allowing 1 request per second with a burst of 2", t, func() {
+		ctx := context.Background()
+		now := time.Now().Truncate(time.Millisecond)
+		l := &Limiter{Store: memory.New(), now: func() time.Time { return now }}
+		limit := Limit{Rate: 1, Burst: 2}
+
+		Convey("when the burst is taken", func() {
+			first, err := l.Take(ctx, "ip:127.0.0.1", limit)
+			So(err, ShouldBeNil)
+			second, err := l.Take(ctx, "ip:127.0.0.1", limit)
+			So(err, ShouldBeNil)
+
+			Convey("then the requests are allowed", func() {
+				So(first, ShouldBeZeroValue)
+				So(second, ShouldBeZeroValue)
+			})
+
+			Convey("and the next request must wait for the bucket to refill", func() {
+				wait, err := l.Take(ctx, "ip:127.0.0.1", limit)
+				So(err, ShouldBeNil)
+				So(wait, ShouldEqual, time.Second)
+			})
+
+			Convey("and a request is allowed once the bucket has refilled", func() {
+				now = now.Add(time.Second)
+				wait, err := l.Take(ctx, "ip:127.0.0.1", limit)
+				So(err, ShouldBeNil)
+				So(wait, ShouldBeZeroValue)
+			})
+
+			Convey("and requests with another key are allowed", func() {
+				wait, err := l.Take(ctx, "ip:10.0.0.1", limit)
+				So(err, ShouldBeNil)
+				So(wait, ShouldBeZeroValue)
+			})
+		})
+	})
+
+	Convey("given a limiter whose store returns an error", t, func() {
+		store := &persistencetest.RateLimitStoreMock{
+			GetRateLimitFunc: func(ctx context.Context, key string) (time.Time, error) {
+				return time.Time{}, errTest
+			},
+		}
+		l := &Limiter{Store: store}
+
+		Convey("when a request is taken", func() {
+			_, err := l.Take(context.Background(), "ip:127.0.0.1", Limit{Rate: 1, Burst: 2})
+
+			Convey("then the error is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, errTest.Error())
+			})
+		})
+	})
+
+	Convey("given a limiter whose buckets are always changed by other requests", t, func() {
+		store := &persistencetest.RateLimitStoreMock{
+			GetRateLimitFunc: func(ctx context.Context, key string) (time.Time, error) {
+				return time.Time{}, nil
+			},
+			SwapRateLimitFunc: func(ctx context.Context, key string, old time.Time, tat time.Time) (bool, error) {
+				return false, nil
+			},
+		}
+		l := &Limiter{Store: store}
+
+		Convey("when a request is taken", func() {
+			_, err := l.Take(context.Background(), "ip:127.0.0.1", Limit{Rate: 1, Burst: 2})
+
+			Convey("then ErrContention is returned after retrying the swap", func() {
+				So(err, ShouldEqual, ErrContention)
+				So(store.SwapRateLimitCalls(), ShouldHaveLength, maxSwapAttempts)
+			})
+		})
+	})
+
+	Convey("given a limiter with a purge interval", t, func() {
+		now := time.Now()
+		store := &persistencetest.RateLimitStoreMock{
+			GetRateLimitFunc: func(ctx context.Context, key string) (time.Time, error) {
+				return time.Time{}, nil
+			},
+			SwapRateLimitFunc: func(ctx context.Context, key string, old time.Time, tat time.Time) (bool, error) {
+				return true, nil
+			},
+			PurgeRateLimitsFunc: func(ctx context.Context, before time.Time) error {
+				return errTest
+			},
+		}
+		l := &Limiter{Store: store, PurgeInterval: time.Minute, now: func() time.Time { return now }}
+
+		Convey("when requests are taken within the interval", func() {
+			for i := 0; i < 3; i++ {
+				_, err := l.Take(context.Background(), "ip:127.0.0.1", Limit{Rate: 1, Burst: 2})
+				So(err, ShouldBeNil)
+			}
+
+			Convey("then expired buckets are purged once and purge errors do not fail the request", func() {
+				So(store.PurgeRateLimitsCalls(), ShouldHaveLength, 1)
+				So(store.PurgeRateLimitsCalls()[0].Before, ShouldEqual, now)
+			})
+
+			Convey("and expired buckets are purged again once the interval has elapsed", func() {
+				now = now.Add(time.Minute)
+				_, err := l.Take(context.Background(), "ip:127.0.0.1", Limit{Rate: 1, Burst: 2})
+				So(err, ShouldBeNil)
+				So(store.PurgeRateLimitsCalls(), ShouldHaveLength, 2)
+			})
+		})
+	})
+}
+
+func TestRetryAfter(t *testing.T) {
+	Convey("RetryAfter rounds the wait up to whole seconds", t, func() {
+		So(RetryAfter(time.Second), ShouldEqual, 1)
+		So(RetryAfter(1500*time.Millisecond), ShouldEqual, 2)
+		So(RetryAfter(time.Millisecond), ShouldEqual, 1)
+	})
+}
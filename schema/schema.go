@@ -5,45 +5,115 @@ import (
 	"time"
 )
 
+const (
+	// UserTypeAdmin identities may create identities of any user type.
+	UserTypeAdmin = "admin"
+
+	// UserTypeService identities are other services, they may create identities of any user type except admin.
+	UserTypeService = "service"
+
+	// UserTypeUser is the non-privileged user type assigned to self-registered identities.
+	UserTypeUser = "user"
+)
+
 var (
 	ErrIdentityNil        = ValidationErr{message: "identity required but was nil"}
-	ErrNameValidation     = ValidationErr{message: "mandatory field name was empty"}
-	ErrEmailValidation    = ValidationErr{message: "mandatory field email was empty"}
-	ErrPasswordValidation = ValidationErr{message: "mandatory field password was empty"}
+	ErrNameValidation     = ValidationErr{message: "mandatory field name was empty", field: "name"}
+	ErrEmailValidation    = ValidationErr{message: "mandatory field email was empty", field: "email"}
+	ErrPasswordValidation = ValidationErr{message: "mandatory field password was empty", field: "password"}
+	ErrUserTypeValidation = ValidationErr{message: "field user_type must be empty, admin, service or user", field: "user_type"}
 	ErrTokenExpired       = errors.New("token expired")
 	ErrTokenNotFound      = errors.New("token not found")
 	NilIdentity           = Identity{}
 )
 
+// ValidationErr is returned when an identity is invalid. Field is the name of the invalid field, or empty if the error
+// does not relate to a single field.
 type ValidationErr struct {
 	message string
+	field   string
 }
 
 func (e ValidationErr) Error() string {
 	return e.message
 }
 
-// Token is a structure that represents an authentication token for the Identity API
+// Field return the name of the invalid field.
+func (e ValidationErr) Field() string {
+	return e.field
+}
+
+// Token is a structure that represents an authentication token for the Identity API. LastUsed is the last time the
+// token was recorded as used, writes are throttled so it may lag behind the most recent use.
+//
+// ImpersonatedBy is the ID of the admin identity the token was issued to so it could act as the identity with
+// IdentityID, it is empty for tokens issued to the identity itself.
 type Token struct {
-	ID           string    `bson:"token_id"`
-	IdentityID   string    `bson:"identity_id"`
-	CreatedDate  time.Time `bson:"created_date"`
-	ExpiryDate   time.Time `bson:"expiry_date"`
-	LastModified time.Time `bson:"last_modified"`
-	Deleted      bool      `bson:"deleted"`
+	ID             string    `bson:"token_id"`
+	IdentityID     string    `bson:"identity_id"`
+	CreatedDate    time.Time `bson:"created_date"`
+	ExpiryDate     time.Time `bson:"expiry_date"`
+	LastModified   time.Time `bson:"last_modified"`
+	LastUsed       time.Time `bson:"last_used"`
+	Deleted        bool      `bson:"deleted"`
+	ImpersonatedBy string    `bson:"impersonated_by,omitempty"`
 }
 
-//Identity is an object representation of a user identity.
+//Identity is an object representation of a user identity. Migrated is true for identities imported from Zebedee
+// whose legacy password hash has not yet been upgraded. The password hash is never included in the JSON
+// representation.
+//
+// Verified is true once the identity's email address has been confirmed. VerificationToken is the digest of the token
+// issued to confirm the email address, valid until VerificationExpiry, and is cleared once the identity is verified.
+//
+// PendingEmail is the address the identity has requested to change its email to. The change is applied once the
+// token with the digest EmailChangeToken, sent to the pending address, is confirmed before EmailChangeExpiry.
+//
+// Disabled identities are suspended but, unlike deleted identities, retain their email. DisabledReason, DisabledBy and
+// DisabledDate record why, by whom and when the identity was disabled and are cleared when it is enabled, DisabledDate
+// is nil while the identity is enabled.
+//
+// Impersonator is the admin identity acting as the identity when it is retrieved by an impersonation token, it is
+// never stored.
 type Identity struct {
-	ID                string    `bson:"id" json:"id"`
-	Name              string    `bson:"name" json:"name"`
-	Email             string    `bson:"email" json:"email"`
-	Password          string    `bson:"password" json:"password"`
-	UserType          string    `bson:"user_type" json:"user_type"`
-	TemporaryPassword bool      `bson:"temporary_password" json:"temporary_password"`
-	Migrated          bool      `bson:"migrated" json:"migrated"`
-	Deleted           bool      `bson:"deleted" json:"deleted"`
-	CreatedDate       time.Time `bson:"createdDate" json:"createdDate"`
+	ID                 string     `bson:"id" json:"id"`
+	Name               string     `bson:"name" json:"name"`
+	Email              string     `bson:"email" json:"email"`
+	Password           string     `bson:"password" json:"-"`
+	UserType           string     `bson:"user_type" json:"user_type"`
+	TemporaryPassword  bool       `bson:"temporary_password" json:"temporary_password"`
+	Migrated           bool       `bson:"migrated" json:"migrated"`
+	Deleted            bool       `bson:"deleted" json:"deleted"`
+	CreatedDate        time.Time  `bson:"createdDate" json:"createdDate"`
+	Verified           bool       `bson:"verified" json:"verified"`
+	VerificationToken  string     `bson:"verification_token,omitempty" json:"-"`
+	VerificationExpiry time.Time  `bson:"verification_expiry,omitempty" json:"-"`
+	PendingEmail       string     `bson:"pending_email,omitempty" json:"-"`
+	EmailChangeToken   string     `bson:"email_change_token,omitempty" json:"-"`
+	EmailChangeExpiry  time.Time  `bson:"email_change_expiry,omitempty" json:"-"`
+	Disabled           bool       `bson:"disabled" json:"disabled"`
+	DisabledReason     string     `bson:"disabled_reason,omitempty" json:"disabled_reason,omitempty"`
+	DisabledBy         string     `bson:"disabled_by,omitempty" json:"disabled_by,omitempty"`
+	DisabledDate       *time.Time `bson:"disabled_date,omitempty" json:"disabled_date,omitempty"`
+	Impersonator       *Identity  `bson:"-" json:"-"`
+}
+
+// AuditEvent is a record of an audited action about the identity with IdentityID, stored so it can be included in an
+// export of the identity's data.
+type AuditEvent struct {
+	IdentityID string            `bson:"identity_id" json:"identity_id"`
+	Action     string            `bson:"action" json:"action"`
+	Result     string            `bson:"result" json:"result"`
+	Params     map[string]string `bson:"params,omitempty" json:"params,omitempty"`
+	Created    time.Time         `bson:"created" json:"created"`
+}
+
+// Tombstone records the erasure of the identity with ID, so the erasure remains auditable after the identity's data
+// has been purged. ErasedBy is the ID of the identity that requested the erasure.
+type Tombstone struct {
+	ID         string    `bson:"id" json:"id"`
+	ErasedBy   string    `bson:"erased_by" json:"erased_by"`
+	ErasedDate time.Time `bson:"erased_date" json:"erased_date"`
 }
 
 func (i *Identity) Validate() (err error) {
@@ -59,5 +129,10 @@ func (i *Identity) Validate() (err error) {
 	if i.Password == "" {
 		return ErrPasswordValidation
 	}
+	switch i.UserType {
+	case "", UserTypeAdmin, UserTypeService, UserTypeUser:
+	default:
+		return ErrUserTypeValidation
+	}
 	return nil
 }
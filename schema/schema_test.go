@@ -1,8 +1,10 @@
 package schema
 
 import (
+	"encoding/json"
 	. "github.com/smartystreets/goconvey/convey"
 	"testing"
+	"time"
 )
 
 func TestIdentity_Validate(t *testing.T) {
@@ -40,4 +42,41 @@ func TestIdentity_Validate(t *testing.T) {
 		err := i.Validate()
 		So(err, ShouldResemble, ErrPasswordValidation)
 	})
+
+	Convey("should error if identity.user_type is not a known user type", t, func() {
+		i := &Identity{Name: "Bucky O'Hare", Email: "captain@TheRighteousIndignation.com", Password: "S.P.A.C.E", UserType: "superuser"}
+		err := i.Validate()
+		So(err, ShouldResemble, ErrUserTypeValidation)
+	})
+}
+
+func TestIdentity_JSON(t *testing.T) {
+	Convey("the JSON representation of an identity should not include the password", t, func() {
+		b, err := json.Marshal(Identity{Name: "Bucky O'Hare", Password: "$2a$10$hashed"})
+		So(err, ShouldBeNil)
+
+		var fields map[string]interface{}
+		So(json.Unmarshal(b, &fields), ShouldBeNil)
+		So(fields, ShouldNotContainKey, "password")
+		So(fields["name"], ShouldEqual, "Bucky O'Hare")
+		So(string(b), ShouldNotContainSubstring, "$2a$10$hashed")
+	})
+	Convey("the JSON representation of an enabled identity should not include a disabled date", t, func() {
+		b, err := json.Marshal(Identity{Name: "Bucky O'Hare"})
+		So(err, ShouldBeNil)
+
+		var fields map[string]interface{}
+		So(json.Unmarshal(b, &fields), ShouldBeNil)
+		So(fields, ShouldNotContainKey, "disabled_date")
+	})
+
+	Convey("the JSON representation of a disabled identity should include the disabled date", t, func() {
+		disabledDate := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+		b, err := json.Marshal(Identity{Name: "Bucky O'Hare", Disabled: true, DisabledDate: &disabledDate})
+		So(err, ShouldBeNil)
+
+		var fields map[string]interface{}
+		So(json.Unmarshal(b, &fields), ShouldBeNil)
+		So(fields["disabled_date"], ShouldEqual, "2018-06-01T12:00:00Z")
+	})
 }
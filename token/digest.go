@@ -0,0 +1,42 @@
+package token
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Digester computes the value a token is stored and cached against, ensuring the plain text token is never persisted.
+type Digester interface {
+	Digest(token string) string
+}
+
+// SHA256Digester is a Digester returning the hex encoded SHA-256 digest of a token.
+type SHA256Digester struct{}
+
+// Digest return the hex encoded SHA-256 digest of the token.
+func (d SHA256Digester) Digest(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// HMACDigester is a Digester returning the hex encoded HMAC-SHA256 of a token keyed with a server side secret.
+type HMACDigester struct {
+	secret []byte
+}
+
+// Digest return the hex encoded HMAC-SHA256 of the token.
+func (d *HMACDigester) Digest(token string) string {
+	mac := hmac.New(sha256.New, d.secret)
+	mac.Write([]byte(token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewDigester construct a new Digester. If a secret is provided tokens are digested using HMAC-SHA256 keyed with the
+// secret otherwise a plain SHA-256 digest is used. Changing the secret invalidates all existing tokens.
+func NewDigester(secret string) Digester {
+	if secret == "" {
+		return SHA256Digester{}
+	}
+	return &HMACDigester{secret: []byte(secret)}
+}
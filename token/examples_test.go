@@ -27,7 +27,7 @@ func ExampleTokens_GetTTL() {
 
 	tokens := Tokens{
 		TimeHelper: NewExpiryHelper(23, 59, 59),
-		MaxTTL: time.Minute * 15,
+		MaxTTL:     time.Minute * 15,
 	}
 
 	now := time.Now()
@@ -54,7 +54,7 @@ func ExampleTokens_GetTTL() {
 	// Expiry date is in the past return ErrTokenExpired
 
 	token = &schema.Token{
-		ExpiryDate: now.Add(time.Minute * - 10),
+		ExpiryDate: now.Add(time.Minute * -10),
 	}
 
 	ttl, _ = tokens.GetTokenTTL(token)
@@ -3,6 +3,7 @@ package token
 import (
 	"fmt"
 	"github.com/ONSdigital/go-ns/log"
+	"github.com/pkg/errors"
 	"time"
 )
 
@@ -16,13 +17,27 @@ const (
 
 var (
 	invalidTimeFMT = "invalid time value, must be gte 0 and lt %d defaulting to 0"
+
+	// ErrInvalidExpiryTime is returned if a daily expiry time is not in the format HH:MM or HH:MM:SS.
+	ErrInvalidExpiryTime = errors.New("invalid daily expiry time, expected HH:MM or HH:MM:SS")
+
+	// expiryTimeFormats are the accepted formats for a daily expiry time.
+	expiryTimeFormats = []string{timeFMT, "15:04"}
 )
 
-// ExpiryHelper provides helper functions for calculating token expiry and TTL times.
+// ExpiryHelper provides helper functions for calculating token expiry and TTL times. Tokens either expire a fixed
+// duration after they are issued, or at a fixed time of day in a configured time zone.
 type ExpiryHelper struct {
 	expiryHour   int64
 	expiryMinute int64
 	expirySecond int64
+
+	// daily mode only.
+	daily       bool
+	location    *time.Location
+	minLifetime time.Duration
+
+	clock func() time.Time
 }
 
 // NewExpiryHelper construct a new NewExpiryHelper instance. Params expiryHour, expiryMinute, expirySecond specify
@@ -42,6 +57,62 @@ func NewExpiryHelper(expiryHour, expiryMinute, expirySecond int64) *ExpiryHelper
 	return helper
 }
 
+// NewLifetimeExpiryHelper construct a new ExpiryHelper for tokens that expire after the provided lifetime. Unlike
+// NewExpiryHelper the lifetime is not limited to less than a day.
+func NewLifetimeExpiryHelper(lifetime time.Duration) *ExpiryHelper {
+	return &ExpiryHelper{
+		expiryHour:   int64(lifetime / time.Hour),
+		expiryMinute: int64(lifetime % time.Hour / time.Minute),
+		expirySecond: int64(lifetime % time.Minute / time.Second),
+	}
+}
+
+// NewDailyExpiryHelper construct a new ExpiryHelper for tokens that expire at the same time each day. expiryTime is the
+// time of day in the format HH:MM or HH:MM:SS in the provided location. A token issued less than minLifetime before
+// the next expiry time expires at the following one instead, so tokens issued just before the cut-off remain usable.
+func NewDailyExpiryHelper(expiryTime string, location *time.Location, minLifetime time.Duration) (*ExpiryHelper, error) {
+	t, err := ParseExpiryTime(expiryTime)
+	if err != nil {
+		return nil, err
+	}
+
+	if location == nil {
+		location = time.UTC
+	}
+
+	helper := &ExpiryHelper{
+		expiryHour:   int64(t.Hour()),
+		expiryMinute: int64(t.Minute()),
+		expirySecond: int64(t.Second()),
+		daily:        true,
+		location:     location,
+		minLifetime:  minLifetime,
+	}
+
+	log.Info("token daily expiry time", log.Data{
+		"expiry_time":  t.Format(timeFMT),
+		"time_zone":    location.String(),
+		"min_lifetime": minLifetime.String(),
+	})
+	return helper, nil
+}
+
+// ParseExpiryTime parse a daily expiry time in the format HH:MM or HH:MM:SS. Returns ErrInvalidExpiryTime if the
+// value is not valid.
+func ParseExpiryTime(expiryTime string) (time.Time, error) {
+	for _, layout := range expiryTimeFormats {
+		if t, err := time.Parse(layout, expiryTime); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, ErrInvalidExpiryTime
+}
+
+// SetClock replace the function used to get the current time. Intended for tests, defaults to time.Now.
+func (e *ExpiryHelper) SetClock(clock func() time.Time) {
+	e.clock = clock
+}
+
 func (e *ExpiryHelper) GetExpiryHour() int64 {
 	return e.expiryHour
 }
@@ -64,16 +135,51 @@ func getValOrDefault(val int64, max int64) int64 {
 
 // Now return the current time.
 func (e *ExpiryHelper) Now() time.Time {
+	if e.clock != nil {
+		return e.clock()
+	}
 	return time.Now()
 }
 
-// GetExpiry calculate the expiry time for a token relative to the current date.
+// GetExpiry calculate the expiry time for a token issued now.
 func (e *ExpiryHelper) GetExpiry() time.Time {
-	expiry := time.Now()
+	if e.daily {
+		return e.getDailyExpiry()
+	}
+
+	expiry := e.Now()
 	expiry = expiry.Add(time.Duration(e.expiryHour) * time.Hour)
 	expiry = expiry.Add(time.Duration(e.expiryMinute) * time.Minute)
 	expiry = expiry.Add(time.Duration(e.expirySecond) * time.Second)
+	return expiry
+}
+
+// getDailyExpiry return the next occurrence of the expiry time that is at least minLifetime after now. Days are
+// advanced using the calendar date rather than adding 24 hours so the expiry time is kept across DST transitions.
+func (e *ExpiryHelper) getDailyExpiry() time.Time {
+	now := e.Now().In(e.location)
+
+	for day := 0; ; day++ {
+		expiry := e.expiryOn(now.Year(), now.Month(), now.Day()+day)
+		if expiry.After(now) && expiry.Sub(now) >= e.minLifetime {
+			return expiry
+		}
+	}
+}
 
-	//return time.Date(expiry.Year(), expiry.Month(), expiry.Day(), e.expiryHour, e.expiryMinute, e.expirySecond, 0, time.UTC)
+// expiryOn return the expiry time on the provided date. If the expiry time does not exist on that date because the
+// clocks go forward it is moved forward by the length of the gap. If it occurs twice because the clocks go back the
+// earlier of the two is returned.
+func (e *ExpiryHelper) expiryOn(year int, month time.Month, day int) time.Time {
+	expiry := time.Date(year, month, day, int(e.expiryHour), int(e.expiryMinute), int(e.expirySecond), 0, e.location)
+
+	_, offset := expiry.Zone()
+	_, earlierOffset := expiry.Add(-time.Hour * 3).Zone()
+	if earlierOffset > offset {
+		earlier := expiry.Add(-time.Duration(earlierOffset-offset) * time.Second)
+		if earlier.Hour() == expiry.Hour() && earlier.Minute() == expiry.Minute() && earlier.Second() == expiry.Second() {
+			return earlier
+		}
+	}
 	return expiry
 }
@@ -0,0 +1,41 @@
+package token
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"github.com/pkg/errors"
+)
+
+const (
+	// IdentityTokenPrefix identifies a token as a dp-identity-api user token, allowing secret scanners to recognise
+	// leaked tokens.
+	IdentityTokenPrefix = "dpidt_"
+
+	// VerificationTokenPrefix identifies a token as a dp-identity-api email verification token.
+	VerificationTokenPrefix = "dpidv_"
+
+	// EmailChangeTokenPrefix identifies a token as a dp-identity-api email change confirmation token.
+	EmailChangeTokenPrefix = "dpide_"
+
+	tokenBytes = 32
+)
+
+// Generator generates new opaque token values.
+type Generator interface {
+	Generate() (string, error)
+}
+
+// RandomGenerator is a Generator returning 256 bit tokens read from crypto/rand, URL safe base64 encoded and prefixed
+// with the token type.
+type RandomGenerator struct {
+	Prefix string
+}
+
+// Generate return a new random token.
+func (g RandomGenerator) Generate() (string, error) {
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "error reading random bytes for token")
+	}
+	return g.Prefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -2,15 +2,17 @@ package token
 
 import (
 	"context"
+	"github.com/ONSdigital/dp-identity-api/metrics"
 	"github.com/ONSdigital/dp-identity-api/persistence"
 	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/ONSdigital/dp-identity-api/tracing"
 	"github.com/ONSdigital/go-ns/log"
 	"github.com/pkg/errors"
-	"github.com/satori/go.uuid"
+	"go.opentelemetry.io/otel/attribute"
 	"time"
 )
 
-//go:generate moq -out tokentest/generate_mocks.go -pkg tokentest . ExpiryTimeHelper Cache
+//go:generate moq -out tokentest/generate_mocks.go -pkg tokentest . ExpiryTimeHelper Cache Digester Generator
 
 const (
 	nilTTL = 0
@@ -21,12 +23,16 @@ var (
 	ErrTokenNil = errors.New("token required but was nil")
 
 	cacheStoreFailed = "warning failed to write token to cache"
+
+	defaultDigester  = SHA256Digester{}
+	defaultGenerator = RandomGenerator{Prefix: IdentityTokenPrefix}
 )
 
-// Cache defines a cache for storing/retrieving an identity against a token ID .
+// Cache defines a cache for storing/retrieving an identity against a token digest.
 type Cache interface {
 	StoreToken(ctx context.Context, token string, i schema.Identity, ttl time.Duration) error
 	GetIdentityByToken(ctx context.Context, token string) (*schema.Identity, time.Duration, error)
+	DeleteToken(ctx context.Context, token string) error
 }
 
 // ExpiryTimeHelper provides functions for getting the current time and calculating a token's expiry data.
@@ -35,23 +41,55 @@ type ExpiryTimeHelper interface {
 	GetExpiry() time.Time
 }
 
-// Tokens provides functionality for creating new tokens and getting existing ones.
+// Tokens provides functionality for creating new tokens and getting existing ones. Tokens are stored and cached against
+// their digest, if no Digester is provided a SHA-256 digest is used. If no Generator is provided tokens are generated
+// using a RandomGenerator with the IdentityTokenPrefix.
+//
+// The expiry of a new token is calculated by the UserTypeTimeHelpers entry matching the identity's UserType, or by
+// TimeHelper if there is none.
+//
+// If IdleTimeout is greater than zero tokens that have not been used for longer than IdleTimeout are rejected as
+// expired. Use of a token is recorded in the Store at most once every LastUsedInterval.
+//
+// A new token for an identity with an Impersonator is an impersonation token, which expires as calculated by
+// ImpersonationTimeHelper, if there is one, and does not replace the identity's own token. The impersonator of a token
+// is looked up in Identities when the token is retrieved from the Store, the token is rejected if the impersonator is
+// no longer an active admin identity. Impersonation tokens are never cached, so the impersonator is checked every time
+// the token is used.
 type Tokens struct {
-	TimeHelper ExpiryTimeHelper
-	Cache      Cache
-	Store      persistence.TokenStore
-	MaxTTL     time.Duration
+	TimeHelper              ExpiryTimeHelper
+	UserTypeTimeHelpers     map[string]ExpiryTimeHelper
+	ImpersonationTimeHelper ExpiryTimeHelper
+	Cache                   Cache
+	Store                   persistence.TokenStore
+	Identities              persistence.IdentityStore
+	Digester                Digester
+	Generator               Generator
+	MaxTTL                  time.Duration
+	IdleTimeout             time.Duration
+	LastUsedInterval        time.Duration
+	Metrics                 metrics.Recorder
 }
 
 // NewToken creates and stores a new token for the provided identity. Returns the generated token and its time to live,
-// or an error is unsuccessful
+// or an error is unsuccessful. The returned token contains the plain text token ID, only its digest is persisted.
 func (t *Tokens) NewToken(ctx context.Context, identity schema.Identity) (token *schema.Token, ttl time.Duration, err error) {
+	ctx, span := tracing.Start(ctx, "token.Tokens.NewToken")
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
 	logD := log.Data{"identity_id": identity.ID}
 	if token, err = t.newToken(identity); err != nil {
 		return
 	}
 
-	if err = t.Store.StoreToken(ctx, *token, identity); err != nil {
+	digest := t.digest(token.ID)
+
+	stored := *token
+	stored.ID = digest
+	if err = t.Store.StoreToken(ctx, stored, identity); err != nil {
 		token = nil
 		return
 	}
@@ -60,32 +98,105 @@ func (t *Tokens) NewToken(ctx context.Context, identity schema.Identity) (token
 		token = nil
 		return
 	}
+	var cacheable bool
+	ttl, cacheable = t.capIdleTTL(ttl, token.LastUsed, token.CreatedDate)
 
-	if err = t.Cache.StoreToken(ctx, token.ID, identity, ttl); err != nil {
-		// We consider this non critical. Log an error that it happened so any monitoring is aware the cache might be
-		// down/borked but return a success response as the token has been generated and successfully stored in the
-		// DB so the caller can still use the service.
-		log.ErrorCtx(ctx, errors.Wrap(err, cacheStoreFailed), logD)
-		err = nil
+	if cacheable && identity.Impersonator == nil {
+		if err = t.Cache.StoreToken(ctx, digest, identity, ttl); err != nil {
+			// We consider this non critical. Log an error that it happened so any monitoring is aware the cache might be
+			// down/borked but return a success response as the token has been generated and successfully stored in the
+			// DB so the caller can still use the service.
+			log.ErrorCtx(ctx, errors.Wrap(err, cacheStoreFailed), logD)
+			err = nil
+		}
 	}
+	t.recorder().TokenIssued()
 	log.InfoCtx(ctx, "successfully generated token for identity", logD)
 	return
 }
 
+// Revoke revokes the active tokens of the identity and removes them from the cache so they are rejected immediately.
+func (t *Tokens) Revoke(ctx context.Context, identityID string) (err error) {
+	ctx, span := tracing.Start(ctx, "token.Tokens.Revoke")
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
+	revoked, err := t.Store.RevokeTokens(ctx, identityID)
+	if err != nil {
+		return err
+	}
+
+	for _, digest := range revoked {
+		if err = t.Cache.DeleteToken(ctx, digest); err != nil {
+			// unlike a failed write a failed delete is critical, the revoked token would be accepted until it expired
+			// from the cache.
+			return errors.Wrap(err, "error removing revoked token from cache")
+		}
+	}
+
+	log.InfoCtx(ctx, "revoked active tokens for identity", log.Data{"identity_id": identityID, "revoked": len(revoked)})
+	return nil
+}
+
+// Erase permanently removes every token of the identity from the store and the cache.
+func (t *Tokens) Erase(ctx context.Context, identityID string) (err error) {
+	ctx, span := tracing.Start(ctx, "token.Tokens.Erase")
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
+	deleted, err := t.Store.DeleteTokens(ctx, identityID)
+	if err != nil {
+		return err
+	}
+
+	for _, digest := range deleted {
+		if err = t.Cache.DeleteToken(ctx, digest); err != nil {
+			return errors.Wrap(err, "error removing erased token from cache")
+		}
+	}
+
+	log.InfoCtx(ctx, "erased tokens for identity", log.Data{"identity_id": identityID, "erased": len(deleted)})
+	return nil
+}
+
+// List returns every token of the identity, including revoked and replaced tokens, ordered by created date.
+func (t *Tokens) List(ctx context.Context, identityID string) ([]schema.Token, error) {
+	ctx, span := tracing.Start(ctx, "token.Tokens.List")
+	defer span.End()
+
+	tokens, err := t.Store.GetTokens(ctx, identityID)
+	tracing.RecordError(span, err)
+	return tokens, err
+}
+
 // GetIdentityByToken return the identity associated with the token (if it exists) and the tokens time to live. Return an error if
-// unsuccessful
+// unsuccessful.
 func (t *Tokens) GetIdentityByToken(ctx context.Context, tokenStr string) (*schema.Identity, time.Duration, error) {
-	identity, ttl, err := t.Cache.GetIdentityByToken(ctx, tokenStr)
+	ctx, span := tracing.Start(ctx, "token.Tokens.GetIdentityByToken")
+	defer span.End()
+
+	digest := t.digest(tokenStr)
+
+	cacheCtx, cacheSpan := tracing.Start(ctx, "cache.GetIdentityByToken")
+	identity, ttl, err := t.Cache.GetIdentityByToken(cacheCtx, digest)
+	tracing.RecordError(cacheSpan, err)
+	cacheSpan.End()
 	if err != nil {
 		return nil, 0, err
 	}
 
+	t.recorder().TokenLookup(identity != nil)
+	span.SetAttributes(attribute.Bool("cache.hit", identity != nil))
 	if identity != nil {
 		return identity, ttl, err
 	}
 
 	var token *schema.Token
-	if identity, token, err = t.Store.GetIdentityByToken(ctx, tokenStr); err != nil {
+	if identity, token, err = t.Store.GetIdentityByToken(ctx, digest); err != nil {
 		if err == persistence.ErrNotFound {
 			return nil, 0, schema.ErrTokenNotFound
 		}
@@ -96,7 +207,22 @@ func (t *Tokens) GetIdentityByToken(ctx context.Context, tokenStr string) (*sche
 		return nil, 0, err
 	}
 
-	if err = t.Cache.StoreToken(ctx, tokenStr, *identity, ttl); err != nil {
+	if token.ImpersonatedBy != "" {
+		if identity.Impersonator, err = t.impersonator(ctx, token.ImpersonatedBy); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	var cacheable bool
+	if ttl, cacheable, err = t.recordUse(ctx, digest, token, ttl); err != nil {
+		return nil, 0, err
+	}
+
+	if !cacheable || token.ImpersonatedBy != "" {
+		return identity, ttl, nil
+	}
+
+	if err = t.Cache.StoreToken(ctx, digest, *identity, ttl); err != nil {
 		// We consider this non critical as the token exists and the user can still use the service.
 		// So we log an error to record that it happened, clear the error var and carry on.
 		log.ErrorCtx(ctx, errors.Wrap(err, cacheStoreFailed), log.Data{"identity_id": identity.ID})
@@ -133,18 +259,138 @@ func (t *Tokens) GetTokenTTL(token *schema.Token) (time.Duration, error) {
 	return remainder, nil
 }
 
+// recordUse return schema.ErrTokenExpired if the token has been idle for longer than the IdleTimeout, otherwise records
+// the token as used if it was last recorded more than LastUsedInterval ago. Returns the ttl capped by capIdleTTL and
+// whether the token may be cached.
+func (t *Tokens) recordUse(ctx context.Context, digest string, token *schema.Token, ttl time.Duration) (time.Duration, bool, error) {
+	if t.IdleTimeout <= 0 {
+		return ttl, true, nil
+	}
+
+	now := t.TimeHelper.Now()
+	lastUsed := token.LastUsed
+	if lastUsed.IsZero() {
+		// tokens issued before last use was recorded are treated as last used when they were created.
+		lastUsed = token.CreatedDate
+	}
+
+	if now.Sub(lastUsed) > t.IdleTimeout {
+		return nilTTL, false, schema.ErrTokenExpired
+	}
+
+	if now.Sub(lastUsed) >= t.LastUsedInterval {
+		if err := t.Store.UpdateLastUsed(ctx, digest, now); err != nil {
+			// We consider this non critical as the token is valid, its use will be recorded on a subsequent request.
+			log.ErrorCtx(ctx, errors.Wrap(err, "warning failed to record token last used time"), log.Data{"identity_id": token.IdentityID})
+		} else {
+			lastUsed = now
+		}
+	}
+	ttl, cacheable := t.capIdleTTL(ttl, lastUsed, now)
+	return ttl, cacheable, nil
+}
+
+// capIdleTTL cap the ttl at the remaining idle window of a token last used at lastUsed, less the LastUsedInterval.
+// Requests served from the cache are not recorded as use, so cached entries must expire early enough for the token to
+// be looked up in the store, and its use recorded, before it could be considered idle. Returns whether the token may be
+// cached, if there is no such window left the ttl is instead capped at the time remaining until the token is idle and
+// the token must not be cached.
+func (t *Tokens) capIdleTTL(ttl time.Duration, lastUsed time.Time, now time.Time) (time.Duration, bool) {
+	if t.IdleTimeout <= 0 {
+		return ttl, true
+	}
+
+	idle := t.IdleTimeout - now.Sub(lastUsed)
+	if cacheable := idle - t.LastUsedInterval; cacheable > 0 {
+		return minDuration(ttl, cacheable), true
+	}
+	if idle < 0 {
+		idle = 0
+	}
+	return minDuration(ttl, idle), false
+}
+
+// minDuration return the smaller of a and b.
+func minDuration(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// digest return the digest of the provided token, the value used to store and cache it.
+func (t *Tokens) digest(tokenStr string) string {
+	if t.Digester == nil {
+		return defaultDigester.Digest(tokenStr)
+	}
+	return t.Digester.Digest(tokenStr)
+}
+
+// recorder return the configured metrics.Recorder, or a no-op recorder if none is configured.
+func (t *Tokens) recorder() metrics.Recorder {
+	if t.Metrics == nil {
+		return metrics.Nop{}
+	}
+	return t.Metrics
+}
+
+// impersonator return the admin identity with the provided ID impersonating the identity of a token. Returns
+// schema.ErrTokenNotFound if the impersonator is no longer an active admin identity.
+func (t *Tokens) impersonator(ctx context.Context, id string) (*schema.Identity, error) {
+	if t.Identities == nil {
+		return nil, schema.ErrTokenNotFound
+	}
+
+	i, err := t.Identities.GetIdentityByID(ctx, id)
+	if err != nil {
+		if err == persistence.ErrNotFound {
+			return nil, schema.ErrTokenNotFound
+		}
+		return nil, err
+	}
+
+	if i.UserType != schema.UserTypeAdmin || i.Disabled {
+		log.InfoCtx(ctx, "impersonation token rejected as impersonator is not an active admin", log.Data{"impersonated_by": id})
+		return nil, schema.ErrTokenNotFound
+	}
+	return i, nil
+}
+
+// timeHelperFor return the ExpiryTimeHelper for tokens issued to the provided identity.
+func (t *Tokens) timeHelperFor(i schema.Identity) ExpiryTimeHelper {
+	if i.Impersonator != nil && t.ImpersonationTimeHelper != nil {
+		return t.ImpersonationTimeHelper
+	}
+	if helper, ok := t.UserTypeTimeHelpers[i.UserType]; ok {
+		return helper
+	}
+	return t.TimeHelper
+}
+
 // newToken construct a new token.
 func (t *Tokens) newToken(i schema.Identity) (*schema.Token, error) {
-	uuid, err := uuid.NewV4()
+	generator := t.Generator
+	if generator == nil {
+		generator = defaultGenerator
+	}
+
+	tokenStr, err := generator.Generate()
 	if err != nil {
 		return nil, err
 	}
 
-	return &schema.Token{
-		ID:          uuid.String(),
+	now := t.TimeHelper.Now()
+	token := &schema.Token{
+		ID:          tokenStr,
 		IdentityID:  i.ID,
-		CreatedDate: t.TimeHelper.Now(),
-		ExpiryDate:  t.TimeHelper.GetExpiry(),
+		CreatedDate: now,
+		ExpiryDate:  t.timeHelperFor(i).GetExpiry(),
+		LastUsed:    now,
 		Deleted:     false,
-	}, nil
+	}
+
+	if i.Impersonator != nil {
+		token.ImpersonatedBy = i.Impersonator.ID
+	}
+	return token, nil
 }
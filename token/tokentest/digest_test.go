@@ -0,0 +1,64 @@
+package tokentest
+
+import (
+	"context"
+	"github.com/ONSdigital/dp-identity-api/persistence/persistencetest"
+	"github.com/ONSdigital/dp-identity-api/token"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestNewDigester(t *testing.T) {
+	Convey("given no secret is configured", t, func() {
+		d := token.NewDigester("")
+
+		Convey("then a SHA-256 digester is returned", func() {
+			So(d, ShouldResemble, token.SHA256Digester{})
+			So(d.Digest(testID), ShouldEqual, "c7e616822f366fb1b5e0756af498cc11d2c0862edcb32ca65882f622ff39de1b")
+		})
+	})
+
+	Convey("given a secret is configured", t, func() {
+		d := token.NewDigester("shh")
+
+		Convey("then the digest is keyed with the secret", func() {
+			So(d.Digest(testID), ShouldNotEqual, token.SHA256Digester{}.Digest(testID))
+			So(d.Digest(testID), ShouldEqual, token.NewDigester("shh").Digest(testID))
+			So(d.Digest(testID), ShouldNotEqual, token.NewDigester("other").Digest(testID))
+		})
+	})
+}
+
+func TestTokens_NewTokenStoresDigest(t *testing.T) {
+	Convey("given a digester is configured", t, func() {
+		cache := &CacheMock{StoreTokenFunc: cacheStoreTokenNoErr}
+		store := &persistencetest.TokenStoreMock{StoreTokenFunc: dbStoreTokenNoErr}
+		digester := &DigesterMock{
+			DigestFunc: func(token string) string {
+				return "digest"
+			},
+		}
+
+		tokens := token.Tokens{
+			Cache:      cache,
+			Store:      store,
+			Digester:   digester,
+			TimeHelper: token.NewExpiryHelper(1, 0, 0),
+			MaxTTL:     testTTL,
+		}
+
+		Convey("when a new token is created", func() {
+			tkn, _, err := tokens.NewToken(context.Background(), *testIdentity)
+			So(err, ShouldBeNil)
+
+			Convey("then only the digest of the token is stored and cached", func() {
+				So(digester.DigestCalls(), ShouldHaveLength, 1)
+				So(digester.DigestCalls()[0].Token, ShouldEqual, tkn.ID)
+
+				So(store.StoreTokenCalls()[0].Token.ID, ShouldEqual, "digest")
+				So(cache.StoreTokenCalls()[0].Token, ShouldEqual, "digest")
+				So(tkn.ID, ShouldNotEqual, "digest")
+			})
+		})
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/ONSdigital/dp-identity-api/token"
 	. "github.com/smartystreets/goconvey/convey"
 	"testing"
+	"time"
 )
 
 type scenario = struct {
@@ -25,6 +26,15 @@ func TestNewExpiryHelper(t *testing.T) {
 	})
 }
 
+func TestNewLifetimeExpiryHelper(t *testing.T) {
+	Convey("should set hour, min and sec values from the lifetime including lifetimes longer than a day", t, func() {
+		helper := token.NewLifetimeExpiryHelper(time.Hour*36 + time.Minute*5 + time.Second*30)
+		So(helper.GetExpiryHour(), ShouldEqual, 36)
+		So(helper.GetExpiryMin(), ShouldEqual, 5)
+		So(helper.GetExpirySec(), ShouldEqual, 30)
+	})
+}
+
 func TestNewExpiryHelperInvalidInput(t *testing.T) {
 	scenarios := []scenario{
 		{desc: "hour < 0", inputH: -1, inputM: 0, inputS: 0, expectH: 0, expectM: 0, expectS: 0},
@@ -48,3 +58,119 @@ func TestNewExpiryHelperInvalidInput(t *testing.T) {
 		}
 	})
 }
+
+func TestExpiryHelper_GetExpiryRelative(t *testing.T) {
+	Convey("should return the current time plus the configured lifetime", t, func() {
+		now := time.Date(2026, 3, 28, 23, 30, 0, 0, time.UTC)
+		helper := token.NewLifetimeExpiryHelper(time.Hour)
+		helper.SetClock(func() time.Time { return now })
+
+		So(helper.Now(), ShouldEqual, now)
+		So(helper.GetExpiry(), ShouldEqual, now.Add(time.Hour))
+	})
+}
+
+func TestExpiryHelper_GetExpiryDaily(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %s", err)
+	}
+
+	scenarios := []struct {
+		desc        string
+		expiryTime  string
+		location    *time.Location
+		minLifetime time.Duration
+		now         time.Time
+		expected    time.Time
+	}{
+		{
+			desc:       "issued after the cut-off expires the next day",
+			expiryTime: "02:00",
+			location:   time.UTC,
+			now:        time.Date(2026, 1, 10, 10, 0, 0, 0, time.UTC),
+			expected:   time.Date(2026, 1, 11, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:        "issued before the cut-off expires the same day",
+			expiryTime:  "02:00",
+			location:    time.UTC,
+			minLifetime: time.Hour,
+			now:         time.Date(2026, 1, 10, 0, 30, 0, 0, time.UTC),
+			expected:    time.Date(2026, 1, 10, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:        "issued within the minimum lifetime of the cut-off expires the next day",
+			expiryTime:  "02:00",
+			location:    time.UTC,
+			minLifetime: time.Hour,
+			now:         time.Date(2026, 1, 10, 1, 30, 0, 0, time.UTC),
+			expected:    time.Date(2026, 1, 11, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:       "issued exactly at the cut-off expires the next day",
+			expiryTime: "02:00:00",
+			location:   time.UTC,
+			now:        time.Date(2026, 1, 10, 2, 0, 0, 0, time.UTC),
+			expected:   time.Date(2026, 1, 11, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:       "expiry time is in the configured time zone",
+			expiryTime: "02:00",
+			location:   london,
+			now:        time.Date(2026, 7, 10, 10, 0, 0, 0, time.UTC),
+			expected:   time.Date(2026, 7, 11, 1, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:       "clock time is kept when the clocks go forward",
+			expiryTime: "09:00",
+			location:   london,
+			now:        time.Date(2026, 3, 28, 10, 0, 0, 0, london),
+			expected:   time.Date(2026, 3, 29, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:       "clock time is kept when the clocks go back",
+			expiryTime: "09:00",
+			location:   london,
+			now:        time.Date(2026, 10, 24, 10, 0, 0, 0, london),
+			expected:   time.Date(2026, 10, 25, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:       "expiry time skipped when the clocks go forward is moved forward by the gap",
+			expiryTime: "01:30",
+			location:   london,
+			now:        time.Date(2026, 3, 28, 12, 0, 0, 0, london),
+			expected:   time.Date(2026, 3, 29, 1, 30, 0, 0, time.UTC),
+		},
+		{
+			desc:       "expiry time repeated when the clocks go back uses the first occurrence",
+			expiryTime: "01:30",
+			location:   london,
+			now:        time.Date(2026, 10, 24, 12, 0, 0, 0, london),
+			expected:   time.Date(2026, 10, 25, 0, 30, 0, 0, time.UTC),
+		},
+	}
+
+	Convey("should return the next occurrence of the expiry time", t, func() {
+		for i, s := range scenarios {
+			helper, err := token.NewDailyExpiryHelper(s.expiryTime, s.location, s.minLifetime)
+			So(err, ShouldBeNil)
+
+			now := s.now
+			helper.SetClock(func() time.Time { return now })
+
+			So(helper.GetExpiry().Equal(s.expected), ShouldBeTrue)
+			t.Logf("scenario: %d, description: %s successful", i, s.desc)
+		}
+	})
+}
+
+func TestNewDailyExpiryHelperInvalidInput(t *testing.T) {
+	Convey("should return ErrInvalidExpiryTime if the expiry time is invalid", t, func() {
+		for _, expiryTime := range []string{"", "2pm", "25:00", "02:60", "02"} {
+			helper, err := token.NewDailyExpiryHelper(expiryTime, time.UTC, 0)
+			So(helper, ShouldBeNil)
+			So(err, ShouldEqual, token.ErrInvalidExpiryTime)
+		}
+	})
+}
@@ -104,6 +104,7 @@ func (mock *ExpiryTimeHelperMock) NowCalls() []struct {
 }
 
 var (
+	lockCacheMockDeleteToken        sync.RWMutex
 	lockCacheMockGetIdentityByToken sync.RWMutex
 	lockCacheMockStoreToken         sync.RWMutex
 )
@@ -114,6 +115,9 @@ var (
 //
 //         // make and configure a mocked Cache
 //         mockedCache := &CacheMock{
+//             DeleteTokenFunc: func(ctx context.Context, token string) error {
+// 	               panic("TODO: mock out the DeleteToken method")
+//             },
 //             GetIdentityByTokenFunc: func(ctx context.Context, token string) (*schema.Identity, time.Duration, error) {
 // 	               panic("TODO: mock out the GetIdentityByToken method")
 //             },
@@ -127,6 +131,9 @@ var (
 //
 //     }
 type CacheMock struct {
+	// DeleteTokenFunc mocks the DeleteToken method.
+	DeleteTokenFunc func(ctx context.Context, token string) error
+
 	// GetIdentityByTokenFunc mocks the GetIdentityByToken method.
 	GetIdentityByTokenFunc func(ctx context.Context, token string) (*schema.Identity, time.Duration, error)
 
@@ -135,6 +142,13 @@ type CacheMock struct {
 
 	// calls tracks calls to the methods.
 	calls struct {
+		// DeleteToken holds details about calls to the DeleteToken method.
+		DeleteToken []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Token is the token argument value.
+			Token string
+		}
 		// GetIdentityByToken holds details about calls to the GetIdentityByToken method.
 		GetIdentityByToken []struct {
 			// Ctx is the ctx argument value.
@@ -156,6 +170,41 @@ type CacheMock struct {
 	}
 }
 
+// DeleteToken calls DeleteTokenFunc.
+func (mock *CacheMock) DeleteToken(ctx context.Context, token string) error {
+	if mock.DeleteTokenFunc == nil {
+		panic("moq: CacheMock.DeleteTokenFunc is nil but Cache.DeleteToken was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Token string
+	}{
+		Ctx:   ctx,
+		Token: token,
+	}
+	lockCacheMockDeleteToken.Lock()
+	mock.calls.DeleteToken = append(mock.calls.DeleteToken, callInfo)
+	lockCacheMockDeleteToken.Unlock()
+	return mock.DeleteTokenFunc(ctx, token)
+}
+
+// DeleteTokenCalls gets all the calls that were made to DeleteToken.
+// Check the length with:
+//     len(mockedCache.DeleteTokenCalls())
+func (mock *CacheMock) DeleteTokenCalls() []struct {
+	Ctx   context.Context
+	Token string
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Token string
+	}
+	lockCacheMockDeleteToken.RLock()
+	calls = mock.calls.DeleteToken
+	lockCacheMockDeleteToken.RUnlock()
+	return calls
+}
+
 // GetIdentityByToken calls GetIdentityByTokenFunc.
 func (mock *CacheMock) GetIdentityByToken(ctx context.Context, token string) (*schema.Identity, time.Duration, error) {
 	if mock.GetIdentityByTokenFunc == nil {
@@ -233,3 +282,124 @@ func (mock *CacheMock) StoreTokenCalls() []struct {
 	lockCacheMockStoreToken.RUnlock()
 	return calls
 }
+
+var (
+	lockDigesterMockDigest sync.RWMutex
+)
+
+// DigesterMock is a mock implementation of Digester.
+//
+//     func TestSomethingThatUsesDigester(t *testing.T) {
+//
+//         // make and configure a mocked Digester
+//         mockedDigester := &DigesterMock{
+//             DigestFunc: func(token string) string {
+// 	               panic("TODO: mock out the Digest method")
+//             },
+//         }
+//
+//         // TODO: use mockedDigester in code that requires Digester
+//         //       and then make assertions.
+//
+//     }
+type DigesterMock struct {
+	// DigestFunc mocks the Digest method.
+	DigestFunc func(token string) string
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Digest holds details about calls to the Digest method.
+		Digest []struct {
+			// Token is the token argument value.
+			Token string
+		}
+	}
+}
+
+// Digest calls DigestFunc.
+func (mock *DigesterMock) Digest(token string) string {
+	if mock.DigestFunc == nil {
+		panic("moq: DigesterMock.DigestFunc is nil but Digester.Digest was just called")
+	}
+	callInfo := struct {
+		Token string
+	}{
+		Token: token,
+	}
+	lockDigesterMockDigest.Lock()
+	mock.calls.Digest = append(mock.calls.Digest, callInfo)
+	lockDigesterMockDigest.Unlock()
+	return mock.DigestFunc(token)
+}
+
+// DigestCalls gets all the calls that were made to Digest.
+// Check the length with:
+//     len(mockedDigester.DigestCalls())
+func (mock *DigesterMock) DigestCalls() []struct {
+	Token string
+} {
+	var calls []struct {
+		Token string
+	}
+	lockDigesterMockDigest.RLock()
+	calls = mock.calls.Digest
+	lockDigesterMockDigest.RUnlock()
+	return calls
+}
+
+var (
+	lockGeneratorMockGenerate sync.RWMutex
+)
+
+// GeneratorMock is a mock implementation of Generator.
+//
+//     func TestSomethingThatUsesGenerator(t *testing.T) {
+//
+//         // make and configure a mocked Generator
+//         mockedGenerator := &GeneratorMock{
+//             GenerateFunc: func() (string, error) {
+// 	               panic("TODO: mock out the Generate method")
+//             },
+//         }
+//
+//         // TODO: use mockedGenerator in code that requires Generator
+//         //       and then make assertions.
+//
+//     }
+type GeneratorMock struct {
+	// GenerateFunc mocks the Generate method.
+	GenerateFunc func() (string, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Generate holds details about calls to the Generate method.
+		Generate []struct {
+		}
+	}
+}
+
+// Generate calls GenerateFunc.
+func (mock *GeneratorMock) Generate() (string, error) {
+	if mock.GenerateFunc == nil {
+		panic("moq: GeneratorMock.GenerateFunc is nil but Generator.Generate was just called")
+	}
+	callInfo := struct {
+	}{}
+	lockGeneratorMockGenerate.Lock()
+	mock.calls.Generate = append(mock.calls.Generate, callInfo)
+	lockGeneratorMockGenerate.Unlock()
+	return mock.GenerateFunc()
+}
+
+// GenerateCalls gets all the calls that were made to Generate.
+// Check the length with:
+//     len(mockedGenerator.GenerateCalls())
+func (mock *GeneratorMock) GenerateCalls() []struct {
+} {
+	var calls []struct {
+	}
+	lockGeneratorMockGenerate.RLock()
+	calls = mock.calls.Generate
+	lockGeneratorMockGenerate.RUnlock()
+	return calls
+}
@@ -0,0 +1,68 @@
+package tokentest
+
+import (
+	"context"
+	"encoding/base64"
+	"github.com/ONSdigital/dp-identity-api/persistence/persistencetest"
+	"github.com/ONSdigital/dp-identity-api/token"
+	. "github.com/smartystreets/goconvey/convey"
+	"strings"
+	"testing"
+)
+
+func TestRandomGenerator_Generate(t *testing.T) {
+	Convey("given a random generator with a prefix", t, func() {
+		g := token.RandomGenerator{Prefix: token.IdentityTokenPrefix}
+
+		Convey("when generate is called", func() {
+			tkn, err := g.Generate()
+			So(err, ShouldBeNil)
+
+			Convey("then a prefixed 256 bit URL safe base64 token is returned", func() {
+				So(strings.HasPrefix(tkn, token.IdentityTokenPrefix), ShouldBeTrue)
+
+				b, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(tkn, token.IdentityTokenPrefix))
+				So(err, ShouldBeNil)
+				So(b, ShouldHaveLength, 32)
+			})
+
+			Convey("and subsequent tokens are unique", func() {
+				other, err := g.Generate()
+				So(err, ShouldBeNil)
+				So(other, ShouldNotEqual, tkn)
+			})
+		})
+	})
+}
+
+func TestTokens_NewTokenGeneratorError(t *testing.T) {
+	Convey("given the token generator returns an error", t, func() {
+		cache := &CacheMock{StoreTokenFunc: cacheStoreTokenNoErr}
+		store := &persistencetest.TokenStoreMock{StoreTokenFunc: dbStoreTokenNoErr}
+		generator := &GeneratorMock{
+			GenerateFunc: func() (string, error) {
+				return "", errTest
+			},
+		}
+
+		tokens := token.Tokens{
+			Cache:     cache,
+			Store:     store,
+			Generator: generator,
+			MaxTTL:    testTTL,
+		}
+
+		Convey("when a new token is created", func() {
+			tkn, ttl, err := tokens.NewToken(context.Background(), *testIdentity)
+
+			Convey("then the expected error is returned and nothing is stored", func() {
+				So(err, ShouldEqual, errTest)
+				So(tkn, ShouldBeNil)
+				So(ttl, ShouldEqual, 0)
+				So(generator.GenerateCalls(), ShouldHaveLength, 1)
+				So(store.StoreTokenCalls(), ShouldHaveLength, 0)
+				So(cache.StoreTokenCalls(), ShouldHaveLength, 0)
+			})
+		})
+	})
+}
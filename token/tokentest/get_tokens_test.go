@@ -39,7 +39,7 @@ func TestTokens_GetCacheError(t *testing.T) {
 				So(ttl, ShouldEqual, 0)
 
 				So(cache.GetIdentityByTokenCalls(), ShouldHaveLength, 1)
-				So(cache.GetIdentityByTokenCalls()[0].Token, ShouldEqual, testID)
+				So(cache.GetIdentityByTokenCalls()[0].Token, ShouldEqual, digest(testID))
 				So(store.GetIdentityByTokenCalls(), ShouldHaveLength, 0)
 			})
 		})
@@ -71,7 +71,7 @@ func TestTokens_GetExistsInCache(t *testing.T) {
 				So(err, ShouldBeNil)
 
 				So(cache.GetIdentityByTokenCalls(), ShouldHaveLength, 1)
-				So(cache.GetIdentityByTokenCalls()[0].Token, ShouldEqual, testID)
+				So(cache.GetIdentityByTokenCalls()[0].Token, ShouldEqual, digest(testID))
 				So(store.GetIdentityByTokenCalls(), ShouldHaveLength, 0)
 			})
 		})
@@ -107,10 +107,10 @@ func TestTokens_GetStoreError(t *testing.T) {
 				So(ttl, ShouldEqual, 0)
 
 				So(cache.GetIdentityByTokenCalls(), ShouldHaveLength, 1)
-				So(cache.GetIdentityByTokenCalls()[0].Token, ShouldEqual, testID)
+				So(cache.GetIdentityByTokenCalls()[0].Token, ShouldEqual, digest(testID))
 
 				So(store.GetIdentityByTokenCalls(), ShouldHaveLength, 1)
-				So(store.GetIdentityByTokenCalls()[0].Token, ShouldEqual, testID)
+				So(store.GetIdentityByTokenCalls()[0].Token, ShouldEqual, digest(testID))
 			})
 		})
 	})
@@ -118,8 +118,8 @@ func TestTokens_GetStoreError(t *testing.T) {
 
 func TestTokens_GetStoreTokenExpired(t *testing.T) {
 	Convey("given store.GetIdentityByToken returns an expired token", t, func() {
-		created := time.Now().Add(time.Hour * - 24) // created a day ago
-		expires := time.Now().Add(time.Hour * -1)   // expired an hour ago
+		created := time.Now().Add(time.Hour * -24) // created a day ago
+		expires := time.Now().Add(time.Hour * -1)  // expired an hour ago
 		tkn := newTestToken(created, expires)
 
 		cache := &CacheMock{
@@ -156,10 +156,10 @@ func TestTokens_GetStoreTokenExpired(t *testing.T) {
 				So(ttl, ShouldEqual, 0)
 
 				So(cache.GetIdentityByTokenCalls(), ShouldHaveLength, 1)
-				So(cache.GetIdentityByTokenCalls()[0].Token, ShouldEqual, testID)
+				So(cache.GetIdentityByTokenCalls()[0].Token, ShouldEqual, digest(testID))
 
 				So(store.GetIdentityByTokenCalls(), ShouldHaveLength, 1)
-				So(store.GetIdentityByTokenCalls()[0].Token, ShouldEqual, testID)
+				So(store.GetIdentityByTokenCalls()[0].Token, ShouldEqual, digest(testID))
 			})
 		})
 	})
@@ -208,13 +208,13 @@ func TestTokens_GetStoreCacheStoreTokenError(t *testing.T) {
 				So(ttl, ShouldEqual, testTTL)
 
 				So(cache.GetIdentityByTokenCalls(), ShouldHaveLength, 1)
-				So(cache.GetIdentityByTokenCalls()[0].Token, ShouldEqual, testID)
+				So(cache.GetIdentityByTokenCalls()[0].Token, ShouldEqual, digest(testID))
 
 				So(store.GetIdentityByTokenCalls(), ShouldHaveLength, 1)
-				So(store.GetIdentityByTokenCalls()[0].Token, ShouldEqual, testID)
+				So(store.GetIdentityByTokenCalls()[0].Token, ShouldEqual, digest(testID))
 
 				So(cache.StoreTokenCalls(), ShouldHaveLength, 1)
-				So(cache.StoreTokenCalls()[0].Token, ShouldEqual, testID)
+				So(cache.StoreTokenCalls()[0].Token, ShouldEqual, digest(testID))
 			})
 		})
 	})
@@ -222,7 +222,7 @@ func TestTokens_GetStoreCacheStoreTokenError(t *testing.T) {
 
 func TestTokens_GetStoreSuccess(t *testing.T) {
 	Convey("given store.GetIdentityByToken returns an non expired token", t, func() {
-		created := time.Now().Add(time.Hour * - 24)
+		created := time.Now().Add(time.Hour * -24)
 		expires := time.Now().Add(time.Hour * 1)
 		tkn := newTestToken(created, expires)
 
@@ -261,13 +261,133 @@ func TestTokens_GetStoreSuccess(t *testing.T) {
 				So(ttl, ShouldEqual, testTTL)
 
 				So(cache.GetIdentityByTokenCalls(), ShouldHaveLength, 1)
-				So(cache.GetIdentityByTokenCalls()[0].Token, ShouldEqual, testID)
+				So(cache.GetIdentityByTokenCalls()[0].Token, ShouldEqual, digest(testID))
 
 				So(store.GetIdentityByTokenCalls(), ShouldHaveLength, 1)
-				So(store.GetIdentityByTokenCalls()[0].Token, ShouldEqual, testID)
+				So(store.GetIdentityByTokenCalls()[0].Token, ShouldEqual, digest(testID))
 
 				So(timeHelp.NowCalls(), ShouldHaveLength, 1)
 			})
 		})
 	})
 }
+
+func TestTokens_GetIdleTimeout(t *testing.T) {
+	Convey("given an idle timeout is configured", t, func() {
+		now := time.Now()
+		tkn := &schema.Token{
+			ID:          digest(testID),
+			IdentityID:  testIdentity.ID,
+			CreatedDate: now.Add(-time.Hour),
+			ExpiryDate:  now.Add(time.Hour * 8),
+		}
+
+		cache := &CacheMock{
+			GetIdentityByTokenFunc: func(ctx context.Context, token string) (*schema.Identity, time.Duration, error) {
+				return nil, 0, nil
+			},
+			StoreTokenFunc: cacheStoreTokenNoErr,
+		}
+
+		store := &persistencetest.TokenStoreMock{
+			GetIdentityByTokenFunc: func(ctx context.Context, token string) (*schema.Identity, *schema.Token, error) {
+				return testIdentity, tkn, nil
+			},
+			UpdateLastUsedFunc: func(ctx context.Context, token string, lastUsed time.Time) error {
+				return nil
+			},
+		}
+
+		tokens := token.Tokens{
+			Cache:            cache,
+			Store:            store,
+			TimeHelper:       &ExpiryTimeHelperMock{NowFunc: func() time.Time { return now }},
+			MaxTTL:           testTTL,
+			IdleTimeout:      time.Minute * 30,
+			LastUsedInterval: time.Minute,
+		}
+
+		Convey("when the token has been idle for longer than the timeout", func() {
+			tkn.LastUsed = now.Add(-time.Minute * 31)
+			identity, ttl, err := tokens.GetIdentityByToken(context.Background(), testID)
+
+			Convey("then schema.ErrTokenExpired is returned", func() {
+				So(err, ShouldEqual, schema.ErrTokenExpired)
+				So(identity, ShouldBeNil)
+				So(ttl, ShouldEqual, 0)
+				So(store.UpdateLastUsedCalls(), ShouldHaveLength, 0)
+				So(cache.StoreTokenCalls(), ShouldHaveLength, 0)
+			})
+		})
+
+		Convey("when the token has no last used time and was created longer ago than the timeout", func() {
+			tkn.LastUsed = time.Time{}
+			_, _, err := tokens.GetIdentityByToken(context.Background(), testID)
+
+			Convey("then schema.ErrTokenExpired is returned", func() {
+				So(err, ShouldEqual, schema.ErrTokenExpired)
+			})
+		})
+
+		Convey("when the token was last used longer ago than the last used interval", func() {
+			tkn.LastUsed = now.Add(-time.Minute * 20)
+			identity, ttl, err := tokens.GetIdentityByToken(context.Background(), testID)
+
+			Convey("then the use is recorded and the cache TTL is capped at the idle window", func() {
+				So(err, ShouldBeNil)
+				So(identity, ShouldResemble, testIdentity)
+				So(ttl, ShouldEqual, testTTL)
+
+				So(store.UpdateLastUsedCalls(), ShouldHaveLength, 1)
+				So(store.UpdateLastUsedCalls()[0].Token, ShouldEqual, digest(testID))
+				So(store.UpdateLastUsedCalls()[0].LastUsed, ShouldEqual, now)
+				So(cache.StoreTokenCalls()[0].TTL, ShouldEqual, testTTL)
+			})
+		})
+
+		Convey("when the token was last used within the last used interval", func() {
+			tkn.LastUsed = now.Add(-time.Second * 30)
+			tokens.IdleTimeout = time.Minute * 10
+			_, ttl, err := tokens.GetIdentityByToken(context.Background(), testID)
+
+			Convey("then the use is not recorded and the TTL is capped at the remaining idle window", func() {
+				So(err, ShouldBeNil)
+				So(store.UpdateLastUsedCalls(), ShouldHaveLength, 0)
+				So(ttl, ShouldEqual, time.Minute*8+time.Second*30)
+				So(cache.StoreTokenCalls()[0].TTL, ShouldEqual, time.Minute*8+time.Second*30)
+			})
+		})
+
+		Convey("when recording the use returns an error", func() {
+			tkn.LastUsed = now.Add(-time.Minute * 5)
+			tokens.IdleTimeout = time.Minute * 10
+			store.UpdateLastUsedFunc = func(ctx context.Context, token string, lastUsed time.Time) error {
+				return errTest
+			}
+
+			_, ttl, err := tokens.GetIdentityByToken(context.Background(), testID)
+
+			Convey("then the token is still valid and the TTL is capped at the previously recorded idle window", func() {
+				So(err, ShouldBeNil)
+				So(store.UpdateLastUsedCalls(), ShouldHaveLength, 1)
+				So(ttl, ShouldEqual, time.Minute*4)
+			})
+		})
+
+		Convey("when recording the use returns an error and the token is within the last used interval of being idle", func() {
+			tkn.LastUsed = now.Add(-time.Minute*9 - time.Second*30)
+			tokens.IdleTimeout = time.Minute * 10
+			store.UpdateLastUsedFunc = func(ctx context.Context, token string, lastUsed time.Time) error {
+				return errTest
+			}
+
+			_, ttl, err := tokens.GetIdentityByToken(context.Background(), testID)
+
+			Convey("then the token is not cached and the TTL is the time remaining until it is idle", func() {
+				So(err, ShouldBeNil)
+				So(ttl, ShouldEqual, time.Second*30)
+				So(cache.StoreTokenCalls(), ShouldHaveLength, 0)
+			})
+		})
+	})
+}
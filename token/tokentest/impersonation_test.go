@@ -0,0 +1,191 @@
+package tokentest
+
+import (
+	"context"
+	"github.com/ONSdigital/dp-identity-api/persistence"
+	"github.com/ONSdigital/dp-identity-api/persistence/persistencetest"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/ONSdigital/dp-identity-api/token"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+func TestTokens_NewImpersonationToken(t *testing.T) {
+	Convey("given a time helper is configured for impersonation tokens", t, func() {
+		now := time.Now()
+		store := &persistencetest.TokenStoreMock{StoreTokenFunc: dbStoreTokenNoErr}
+
+		defaultHelper := &ExpiryTimeHelperMock{
+			GetExpiryFunc: func() time.Time {
+				return now.Add(time.Hour)
+			},
+			NowFunc: func() time.Time {
+				return now
+			},
+		}
+
+		impersonationHelper := &ExpiryTimeHelperMock{
+			GetExpiryFunc: func() time.Time {
+				return now.Add(time.Minute * 15)
+			},
+		}
+
+		tokens := token.Tokens{
+			Cache:                   &CacheMock{StoreTokenFunc: cacheStoreTokenNoErr},
+			Store:                   store,
+			TimeHelper:              defaultHelper,
+			ImpersonationTimeHelper: impersonationHelper,
+			MaxTTL:                  time.Hour * 24,
+		}
+
+		Convey("when a token is created for an identity with an impersonator", func() {
+			impersonated := *testIdentity
+			impersonated.Impersonator = &schema.Identity{ID: "999", UserType: schema.UserTypeAdmin}
+
+			tkn, ttl, err := tokens.NewToken(context.Background(), impersonated)
+
+			Convey("then the token is marked as impersonated by the admin", func() {
+				So(err, ShouldBeNil)
+				So(tkn.ImpersonatedBy, ShouldEqual, "999")
+				So(store.StoreTokenCalls(), ShouldHaveLength, 1)
+				So(store.StoreTokenCalls()[0].Token.ImpersonatedBy, ShouldEqual, "999")
+			})
+
+			Convey("and the expiry is calculated using the impersonation time helper", func() {
+				So(tkn.ExpiryDate, ShouldEqual, now.Add(time.Minute*15))
+				So(ttl, ShouldEqual, time.Minute*15)
+				So(defaultHelper.GetExpiryCalls(), ShouldHaveLength, 0)
+			})
+
+			Convey("and the token is not cached", func() {
+				So(tokens.Cache.(*CacheMock).StoreTokenCalls(), ShouldHaveLength, 0)
+			})
+		})
+
+		Convey("when a token is created for an identity without an impersonator", func() {
+			tkn, _, err := tokens.NewToken(context.Background(), *testIdentity)
+
+			Convey("then the token is not marked as impersonated", func() {
+				So(err, ShouldBeNil)
+				So(tkn.ImpersonatedBy, ShouldBeEmpty)
+				So(tkn.ExpiryDate, ShouldEqual, now.Add(time.Hour))
+				So(impersonationHelper.GetExpiryCalls(), ShouldHaveLength, 0)
+			})
+		})
+	})
+}
+
+func TestTokens_GetImpersonationToken(t *testing.T) {
+	Convey("given store.GetIdentityByToken returns an impersonation token", t, func() {
+		tkn := newTestToken(time.Now(), time.Now().Add(time.Hour))
+		tkn.ImpersonatedBy = "999"
+
+		admin := &schema.Identity{ID: "999", UserType: schema.UserTypeAdmin}
+
+		cache := &CacheMock{
+			GetIdentityByTokenFunc: func(ctx context.Context, token string) (*schema.Identity, time.Duration, error) {
+				return nil, 0, nil
+			},
+			StoreTokenFunc: cacheStoreTokenNoErr,
+		}
+
+		store := &persistencetest.TokenStoreMock{
+			GetIdentityByTokenFunc: func(ctx context.Context, token string) (*schema.Identity, *schema.Token, error) {
+				i := *testIdentity
+				return &i, tkn, nil
+			},
+		}
+
+		identities := &persistencetest.IdentityStoreMock{
+			GetIdentityByIDFunc: func(ctx context.Context, id string) (*schema.Identity, error) {
+				return admin, nil
+			},
+		}
+
+		tokens := token.Tokens{
+			Cache:      cache,
+			Store:      store,
+			Identities: identities,
+			TimeHelper: &ExpiryTimeHelperMock{NowFunc: time.Now},
+			MaxTTL:     testTTL,
+		}
+
+		Convey("when get token is called", func() {
+			identity, _, err := tokens.GetIdentityByToken(context.Background(), testID)
+
+			Convey("then the identity is returned with the admin as its impersonator", func() {
+				So(err, ShouldBeNil)
+				So(identity.ID, ShouldEqual, testIdentity.ID)
+				So(identity.Impersonator, ShouldResemble, admin)
+
+				So(identities.GetIdentityByIDCalls(), ShouldHaveLength, 1)
+				So(identities.GetIdentityByIDCalls()[0].ID, ShouldEqual, "999")
+			})
+
+			Convey("and the identity is not cached", func() {
+				So(cache.StoreTokenCalls(), ShouldHaveLength, 0)
+			})
+		})
+
+		Convey("when get token is called again after the impersonator is disabled", func() {
+			_, _, err := tokens.GetIdentityByToken(context.Background(), testID)
+			So(err, ShouldBeNil)
+
+			admin.Disabled = true
+			identity, _, err := tokens.GetIdentityByToken(context.Background(), testID)
+
+			Convey("then the impersonator is checked again and schema.ErrTokenNotFound is returned", func() {
+				So(err, ShouldEqual, schema.ErrTokenNotFound)
+				So(identity, ShouldBeNil)
+				So(identities.GetIdentityByIDCalls(), ShouldHaveLength, 2)
+			})
+		})
+
+		Convey("when the impersonator is no longer an admin", func() {
+			admin.UserType = schema.UserTypeUser
+
+			identity, _, err := tokens.GetIdentityByToken(context.Background(), testID)
+
+			Convey("then schema.ErrTokenNotFound is returned", func() {
+				So(err, ShouldEqual, schema.ErrTokenNotFound)
+				So(identity, ShouldBeNil)
+				So(cache.StoreTokenCalls(), ShouldHaveLength, 0)
+			})
+		})
+
+		Convey("when the impersonator is disabled", func() {
+			admin.Disabled = true
+
+			_, _, err := tokens.GetIdentityByToken(context.Background(), testID)
+
+			Convey("then schema.ErrTokenNotFound is returned", func() {
+				So(err, ShouldEqual, schema.ErrTokenNotFound)
+			})
+		})
+
+		Convey("when the impersonator does not exist", func() {
+			identities.GetIdentityByIDFunc = func(ctx context.Context, id string) (*schema.Identity, error) {
+				return nil, persistence.ErrNotFound
+			}
+
+			_, _, err := tokens.GetIdentityByToken(context.Background(), testID)
+
+			Convey("then schema.ErrTokenNotFound is returned", func() {
+				So(err, ShouldEqual, schema.ErrTokenNotFound)
+			})
+		})
+
+		Convey("when looking up the impersonator returns an error", func() {
+			identities.GetIdentityByIDFunc = func(ctx context.Context, id string) (*schema.Identity, error) {
+				return nil, persistence.ErrTimeout
+			}
+
+			_, _, err := tokens.GetIdentityByToken(context.Background(), testID)
+
+			Convey("then the error is returned", func() {
+				So(err, ShouldEqual, persistence.ErrTimeout)
+			})
+		})
+	})
+}
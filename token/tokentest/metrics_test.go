@@ -0,0 +1,104 @@
+package tokentest
+
+import (
+	"context"
+	"github.com/ONSdigital/dp-identity-api/metrics/metricstest"
+	"github.com/ONSdigital/dp-identity-api/persistence"
+	"github.com/ONSdigital/dp-identity-api/persistence/persistencetest"
+	"github.com/ONSdigital/dp-identity-api/schema"
+	"github.com/ONSdigital/dp-identity-api/token"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+func TestTokens_NewTokenRecordsMetrics(t *testing.T) {
+	Convey("given a metrics recorder is configured", t, func() {
+		m := &metricstest.RecorderMock{TokenIssuedFunc: func() {}}
+		now := time.Now()
+
+		timeHelper := &ExpiryTimeHelperMock{
+			GetExpiryFunc: func() time.Time { return now.Add(time.Hour) },
+			NowFunc:       func() time.Time { return now },
+		}
+
+		tokens := token.Tokens{
+			Cache:      &CacheMock{StoreTokenFunc: cacheStoreTokenNoErr},
+			TimeHelper: timeHelper,
+			MaxTTL:     testTTL,
+			Metrics:    m,
+		}
+
+		Convey("when a token is issued successfully", func() {
+			tokens.Store = &persistencetest.TokenStoreMock{StoreTokenFunc: dbStoreTokenNoErr}
+			_, _, err := tokens.NewToken(context.Background(), *testIdentity)
+
+			Convey("then the issued token is recorded", func() {
+				So(err, ShouldBeNil)
+				So(m.TokenIssuedCalls(), ShouldHaveLength, 1)
+			})
+		})
+
+		Convey("when storing the token returns an error", func() {
+			tokens.Store = &persistencetest.TokenStoreMock{
+				StoreTokenFunc: func(ctx context.Context, token schema.Token, i schema.Identity) error {
+					return errTest
+				},
+			}
+			_, _, err := tokens.NewToken(context.Background(), *testIdentity)
+
+			Convey("then no issued token is recorded", func() {
+				So(err, ShouldEqual, errTest)
+				So(m.TokenIssuedCalls(), ShouldHaveLength, 0)
+			})
+		})
+	})
+}
+
+func TestTokens_GetIdentityByTokenRecordsLookup(t *testing.T) {
+	Convey("given a metrics recorder is configured", t, func() {
+		m := &metricstest.RecorderMock{TokenLookupFunc: func(cacheHit bool) {}}
+		store := &persistencetest.TokenStoreMock{
+			GetIdentityByTokenFunc: func(ctx context.Context, token string) (*schema.Identity, *schema.Token, error) {
+				return nil, nil, persistence.ErrNotFound
+			},
+		}
+
+		Convey("when the token exists in the cache", func() {
+			tokens := token.Tokens{
+				Cache: &CacheMock{
+					GetIdentityByTokenFunc: func(ctx context.Context, token string) (*schema.Identity, time.Duration, error) {
+						return testIdentity, testTTL, nil
+					},
+				},
+				Store:   store,
+				Metrics: m,
+			}
+			tokens.GetIdentityByToken(context.Background(), testID)
+
+			Convey("then a cache hit is recorded", func() {
+				So(m.TokenLookupCalls(), ShouldHaveLength, 1)
+				So(m.TokenLookupCalls()[0].CacheHit, ShouldBeTrue)
+			})
+		})
+
+		Convey("when the token is not in the cache", func() {
+			tokens := token.Tokens{
+				Cache: &CacheMock{
+					GetIdentityByTokenFunc: func(ctx context.Context, token string) (*schema.Identity, time.Duration, error) {
+						return nil, 0, nil
+					},
+				},
+				Store:   store,
+				Metrics: m,
+			}
+			_, _, err := tokens.GetIdentityByToken(context.Background(), testID)
+
+			Convey("then a cache miss is recorded", func() {
+				So(err, ShouldEqual, schema.ErrTokenNotFound)
+				So(m.TokenLookupCalls(), ShouldHaveLength, 1)
+				So(m.TokenLookupCalls()[0].CacheHit, ShouldBeFalse)
+			})
+		})
+	})
+}
@@ -26,6 +26,8 @@ var (
 
 	testTTL = time.Minute * 15
 
+	digest = token.SHA256Digester{}.Digest
+
 	cacheStoreTokenNoErr = func(ctx context.Context, token string, i schema.Identity, ttl time.Duration) error {
 		return nil
 	}
@@ -65,13 +67,15 @@ func TestTokens_NewTokenMaxTTL(t *testing.T) {
 
 		Convey("then store.StoreToken should be called 1 time with the expected params", func() {
 			So(store.StoreTokenCalls(), ShouldHaveLength, 1)
-			So(store.StoreTokenCalls()[0].Token, ShouldResemble, *token)
+			expected := *token
+			expected.ID = digest(token.ID)
+			So(store.StoreTokenCalls()[0].Token, ShouldResemble, expected)
 			So(store.StoreTokenCalls()[0].I, ShouldResemble, *testIdentity)
 		})
 
 		Convey("and cache.StoreToken should be called 1 time with the expected params", func() {
 			So(cache.StoreTokenCalls(), ShouldHaveLength, 1)
-			So(cache.StoreTokenCalls()[0].Token, ShouldEqual, token.ID)
+			So(cache.StoreTokenCalls()[0].Token, ShouldEqual, digest(token.ID))
 			So(cache.StoreTokenCalls()[0].I, ShouldResemble, *testIdentity)
 			So(cache.StoreTokenCalls()[0].TTL, ShouldEqual, testTTL)
 
@@ -112,13 +116,15 @@ func TestTokens_NewTokenLessThatMaxTTL(t *testing.T) {
 
 		Convey("then store.StoreToken should be called 1 time with the expected params", func() {
 			So(store.StoreTokenCalls(), ShouldHaveLength, 1)
-			So(store.StoreTokenCalls()[0].Token, ShouldResemble, *token)
+			expected := *token
+			expected.ID = digest(token.ID)
+			So(store.StoreTokenCalls()[0].Token, ShouldResemble, expected)
 			So(store.StoreTokenCalls()[0].I, ShouldResemble, *testIdentity)
 		})
 
 		Convey("and cache.StoreToken should be called 1 time with the expected params", func() {
 			So(cache.StoreTokenCalls(), ShouldHaveLength, 1)
-			So(cache.StoreTokenCalls()[0].Token, ShouldEqual, token.ID)
+			So(cache.StoreTokenCalls()[0].Token, ShouldEqual, digest(token.ID))
 			So(cache.StoreTokenCalls()[0].I, ShouldResemble, *testIdentity)
 			So(cache.StoreTokenCalls()[0].TTL, ShouldEqual, time.Minute*5)
 
@@ -279,3 +285,60 @@ func TestTokens_NewTokenGetTTLErrors(t *testing.T) {
 		})
 	})
 }
+
+func TestTokens_NewTokenUserTypeLifetime(t *testing.T) {
+	Convey("given a time helper is configured for the identity's user type", t, func() {
+		now := time.Now()
+		store := &persistencetest.TokenStoreMock{StoreTokenFunc: dbStoreTokenNoErr}
+
+		defaultHelper := &ExpiryTimeHelperMock{
+			GetExpiryFunc: func() time.Time {
+				return now.Add(time.Hour)
+			},
+			NowFunc: func() time.Time {
+				return now
+			},
+		}
+
+		adminHelper := &ExpiryTimeHelperMock{
+			GetExpiryFunc: func() time.Time {
+				return now.Add(time.Minute * 30)
+			},
+		}
+
+		tokens := token.Tokens{
+			Cache:               &CacheMock{StoreTokenFunc: cacheStoreTokenNoErr},
+			Store:               store,
+			TimeHelper:          defaultHelper,
+			UserTypeTimeHelpers: map[string]token.ExpiryTimeHelper{"admin": adminHelper},
+			MaxTTL:              time.Hour * 24,
+		}
+
+		Convey("when a token is created for an identity of that user type", func() {
+			admin := *testIdentity
+			admin.UserType = "admin"
+
+			tkn, ttl, err := tokens.NewToken(context.Background(), admin)
+
+			Convey("then the expiry is calculated using the user type time helper", func() {
+				So(err, ShouldBeNil)
+				So(tkn.ExpiryDate, ShouldEqual, now.Add(time.Minute*30))
+				So(ttl, ShouldEqual, time.Minute*30)
+				So(adminHelper.GetExpiryCalls(), ShouldHaveLength, 1)
+				So(defaultHelper.GetExpiryCalls(), ShouldHaveLength, 0)
+			})
+		})
+
+		Convey("when a token is created for an identity of another user type", func() {
+			tkn, ttl, err := tokens.NewToken(context.Background(), *testIdentity)
+
+			Convey("then the expiry is calculated using the default time helper", func() {
+				So(err, ShouldBeNil)
+				So(tkn.ExpiryDate, ShouldEqual, now.Add(time.Hour))
+				So(ttl, ShouldEqual, time.Hour)
+				So(adminHelper.GetExpiryCalls(), ShouldHaveLength, 0)
+				So(defaultHelper.GetExpiryCalls(), ShouldHaveLength, 1)
+			})
+		})
+	})
+}
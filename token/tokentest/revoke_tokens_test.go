@@ -0,0 +1,121 @@
+package tokentest
+
+import (
+	"context"
+	"github.com/ONSdigital/dp-identity-api/persistence"
+	"github.com/ONSdigital/dp-identity-api/persistence/persistencetest"
+	"github.com/ONSdigital/dp-identity-api/token"
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestTokens_Revoke(t *testing.T) {
+	Convey("given an identity with active tokens", t, func() {
+		store := &persistencetest.TokenStoreMock{
+			RevokeTokensFunc: func(ctx context.Context, identityID string) ([]string, error) {
+				return []string{"first", "second"}, nil
+			},
+		}
+		cache := &CacheMock{
+			DeleteTokenFunc: func(ctx context.Context, token string) error {
+				return nil
+			},
+		}
+
+		tokens := token.Tokens{Store: store, Cache: cache}
+
+		Convey("when the tokens are revoked", func() {
+			err := tokens.Revoke(context.Background(), "666")
+
+			Convey("then the tokens are revoked in the store and removed from the cache", func() {
+				So(err, ShouldBeNil)
+				So(store.RevokeTokensCalls(), ShouldHaveLength, 1)
+				So(store.RevokeTokensCalls()[0].IdentityID, ShouldEqual, "666")
+				So(cache.DeleteTokenCalls(), ShouldHaveLength, 2)
+				So(cache.DeleteTokenCalls()[0].Token, ShouldEqual, "first")
+				So(cache.DeleteTokenCalls()[1].Token, ShouldEqual, "second")
+			})
+		})
+
+		Convey("when revoking the tokens in the store returns an error", func() {
+			store.RevokeTokensFunc = func(ctx context.Context, identityID string) ([]string, error) {
+				return nil, persistence.ErrTimeout
+			}
+
+			err := tokens.Revoke(context.Background(), "666")
+
+			Convey("then the error is returned and the cache is not updated", func() {
+				So(err, ShouldEqual, persistence.ErrTimeout)
+				So(cache.DeleteTokenCalls(), ShouldHaveLength, 0)
+			})
+		})
+
+		Convey("when removing a token from the cache returns an error", func() {
+			cache.DeleteTokenFunc = func(ctx context.Context, token string) error {
+				return errTest
+			}
+
+			err := tokens.Revoke(context.Background(), "666")
+
+			Convey("then the error is returned", func() {
+				So(errors.Cause(err), ShouldEqual, errTest)
+			})
+		})
+	})
+}
+
+func TestTokens_Erase(t *testing.T) {
+	Convey("given an identity with tokens", t, func() {
+		store := &persistencetest.TokenStoreMock{
+			DeleteTokensFunc: func(ctx context.Context, identityID string) ([]string, error) {
+				return []string{"first", "second"}, nil
+			},
+		}
+		cache := &CacheMock{
+			DeleteTokenFunc: func(ctx context.Context, token string) error {
+				return nil
+			},
+		}
+
+		tokens := token.Tokens{Store: store, Cache: cache}
+
+		Convey("when the tokens are erased", func() {
+			err := tokens.Erase(context.Background(), "666")
+
+			Convey("then the tokens are removed from the store and the cache", func() {
+				So(err, ShouldBeNil)
+				So(store.DeleteTokensCalls(), ShouldHaveLength, 1)
+				So(store.DeleteTokensCalls()[0].IdentityID, ShouldEqual, "666")
+				So(cache.DeleteTokenCalls(), ShouldHaveLength, 2)
+				So(cache.DeleteTokenCalls()[0].Token, ShouldEqual, "first")
+				So(cache.DeleteTokenCalls()[1].Token, ShouldEqual, "second")
+			})
+		})
+
+		Convey("when removing the tokens from the store returns an error", func() {
+			store.DeleteTokensFunc = func(ctx context.Context, identityID string) ([]string, error) {
+				return nil, persistence.ErrTimeout
+			}
+
+			err := tokens.Erase(context.Background(), "666")
+
+			Convey("then the error is returned and the cache is not updated", func() {
+				So(err, ShouldEqual, persistence.ErrTimeout)
+				So(cache.DeleteTokenCalls(), ShouldHaveLength, 0)
+			})
+		})
+
+		Convey("when removing a token from the cache returns an error", func() {
+			cache.DeleteTokenFunc = func(ctx context.Context, token string) error {
+				return errTest
+			}
+
+			err := tokens.Erase(context.Background(), "666")
+
+			Convey("then the error is returned", func() {
+				So(errors.Cause(err), ShouldEqual, errTest)
+			})
+		})
+	})
+}
@@ -12,7 +12,7 @@ import (
 func TestTokens_GetTTLTokenExpire(t *testing.T) {
 	Convey("should return ErrTokenExpired if token is expired", t, func() {
 		now := time.Now()
-		tkn := newTestToken(now.Add(time.Hour*- 2), now)
+		tkn := newTestToken(now.Add(time.Hour*-2), now)
 
 		timeHelper := &ExpiryTimeHelperMock{
 			NowFunc: func() time.Time {
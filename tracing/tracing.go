@@ -0,0 +1,90 @@
+// Package tracing configures OpenTelemetry tracing for the service and provides helpers for creating spans.
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ONSdigital/dp-identity-api/config"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/jaeger"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	serviceName         = "dp-identity-api"
+	instrumentationName = "github.com/ONSdigital/dp-identity-api"
+)
+
+var traceContext = propagation.TraceContext{}
+
+// Provider is a trace.TracerProvider which can be shut down, exporting any spans not yet exported.
+type Provider interface {
+	trace.TracerProvider
+	Shutdown(ctx context.Context) error
+}
+
+type nopProvider struct {
+	trace.TracerProvider
+}
+
+func (nopProvider) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// New construct a Provider exporting spans using the exporter selected in the config. If the exporter is
+// config.TracingNone a no-op Provider is returned and no spans are recorded.
+func New(cfg config.TracingConfig) (Provider, error) {
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(serviceName))),
+	}
+
+	switch cfg.Exporter {
+	case config.TracingNone:
+		return nopProvider{trace.NewNoopTracerProvider()}, nil
+	case config.TracingStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create stdout trace exporter")
+		}
+		opts = append(opts, sdktrace.WithSyncer(exporter))
+	case config.TracingJaeger:
+		exporter, err := jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(cfg.JaegerEndpoint)))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create jaeger trace exporter")
+		}
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	default:
+		return nil, config.ErrInvalidTraceExporter
+	}
+
+	return sdktrace.NewTracerProvider(opts...), nil
+}
+
+// Start creates a span as a child of any span in the ctx, returning the span and a ctx containing it. The span uses
+// the globally registered TracerProvider and must be ended by the caller.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// RecordError records the error on the span and marks the span as failed. Does nothing if err is nil.
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// Extract return the request ctx with the W3C trace context propagated in the request headers, if any.
+func Extract(r *http.Request) context.Context {
+	return traceContext.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+}
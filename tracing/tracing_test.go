@@ -0,0 +1,78 @@
+package tracing
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ONSdigital/dp-identity-api/config"
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const testTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestNew(t *testing.T) {
+	Convey("New should return a TracerProvider for each supported exporter", t, func() {
+		for _, exporter := range []string{config.TracingNone, config.TracingStdout, config.TracingJaeger} {
+			tp, err := New(config.TracingConfig{Exporter: exporter, JaegerEndpoint: "http://localhost:14268/api/traces", SampleRatio: 1})
+			So(err, ShouldBeNil)
+			So(tp, ShouldNotBeNil)
+			So(tp.Shutdown(context.Background()), ShouldBeNil)
+		}
+	})
+
+	Convey("New should return an error for an unsupported exporter", t, func() {
+		tp, err := New(config.TracingConfig{Exporter: "zipkin"})
+		So(tp, ShouldBeNil)
+		So(err, ShouldEqual, config.ErrInvalidTraceExporter)
+	})
+}
+
+func TestStart(t *testing.T) {
+	Convey("given a request with a W3C traceparent header", t, func() {
+		sr := tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+
+		r := httptest.NewRequest("GET", "/identity", nil)
+		r.Header.Set("traceparent", testTraceParent)
+
+		Convey("when a span is started from the extracted ctx", func() {
+			ctx, span := Start(Extract(r), "parent")
+			_, child := Start(ctx, "child")
+			child.End()
+			span.End()
+
+			Convey("then the spans continue the propagated trace", func() {
+				So(sr.Ended(), ShouldHaveLength, 2)
+				for _, s := range sr.Ended() {
+					So(s.SpanContext().TraceID().String(), ShouldEqual, "4bf92f3577b34da6a3ce929d0e0e4736")
+				}
+				So(sr.Ended()[1].Parent().SpanID().String(), ShouldEqual, "00f067aa0ba902b7")
+				So(sr.Ended()[1].Parent().IsRemote(), ShouldBeTrue)
+				So(sr.Ended()[0].Parent().SpanID(), ShouldEqual, span.SpanContext().SpanID())
+			})
+		})
+
+		Convey("when an error is recorded on the span", func() {
+			_, span := Start(Extract(r), "failed")
+			RecordError(span, errors.New("explosions"))
+			span.End()
+
+			Convey("then the span status is error", func() {
+				So(sr.Ended(), ShouldHaveLength, 1)
+				So(sr.Ended()[0].Status().Code, ShouldEqual, codes.Error)
+				So(sr.Ended()[0].Status().Description, ShouldEqual, "explosions")
+			})
+		})
+
+		Reset(func() {
+			otel.SetTracerProvider(trace.NewNoopTracerProvider())
+		})
+	})
+}